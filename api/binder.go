@@ -2,7 +2,6 @@ package api
 
 import (
 	"bytes"
-	"context"
 	"errors"
 	"fmt"
 	"github.com/globalsign/mgo/bson"
@@ -530,10 +529,10 @@ func (b *ChangeMerchantDataRequestBinder) Bind(i interface{}, ctx echo.Context)
 	}
 
 	mReq := &grpc.GetMerchantByRequest{MerchantId: merchantId}
-	mRsp, err := b.billingService.GetMerchantBy(context.Background(), mReq)
+	mRsp, err := b.billingService.GetMerchantBy(ctx.Request().Context(), mReq)
 
 	if err != nil {
-		b.logError(`Call billing server method "GetMerchantBy" failed`, []interface{}{"error", err.Error(), "request", mReq})
+		b.logError(ctx.Request().Context(), `Call billing server method "GetMerchantBy" failed`, []interface{}{"error", err.Error(), "request", mReq})
 		return errors.New(errorUnknown)
 	}
 
@@ -609,10 +608,10 @@ func (b *ChangeProjectRequestBinder) Bind(i interface{}, ctx echo.Context) error
 	}
 
 	pReq := &grpc.GetProjectRequest{ProjectId: projectId}
-	pRsp, err := b.billingService.GetProject(context.Background(), pReq)
+	pRsp, err := b.billingService.GetProject(ctx.Request().Context(), pReq)
 
 	if err != nil {
-		b.logError(`Call billing server method "GetProject" failed`, []interface{}{"error", err.Error(), "request", pReq})
+		b.logError(ctx.Request().Context(), `Call billing server method "GetProject" failed`, []interface{}{"error", err.Error(), "request", pReq})
 		return errors.New(errorUnknown)
 	}
 
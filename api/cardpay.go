@@ -1,15 +1,14 @@
 package api
 
 import (
-	"context"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/database/model"
-	"github.com/paysuper/paysuper-management-api/manager"
 	"github.com/paysuper/paysuper-management-api/payment_system/entity"
 	"net/http"
+	"strconv"
 )
 
 const (
@@ -29,6 +28,19 @@ func (api *Api) InitCardPayWebHookRoutes() *Api {
 	return api
 }
 
+// @Summary CardPay payment notification
+// @Description Process notification about payment status change from CardPay. Repeated deliveries of processed notification and stale notifications are accepted without processing. Notifications with invalid signature are rejected
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param Signature header string false "signature of notification body by payment system"
+// @Param data body object true "Payment notification"
+// @Success 200 {object} object "Notification processed, repeated or stale notification ignored"
+// @Failure 400 {object} model.Error "Invalid request data or signature"
+// @Failure 409 {object} model.Error "Notification with same payment status is in process"
+// @Failure 410 {object} model.Error "Notification can not be processed now, must be sent later"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /webhook/cardpay/notify [post]
 func (h *CardPayWebHook) paymentCallback(ctx echo.Context) error {
 	st := &billing.CardPayPaymentCallback{}
 
@@ -37,42 +49,69 @@ func (h *CardPayWebHook) paymentCallback(ctx echo.Context) error {
 	}
 
 	if err := h.validate.Struct(st); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, manager.GetFirstValidationError(err))
+		return echo.NewHTTPError(http.StatusBadRequest, h.getValidationError(err))
+	}
+
+	if st.MerchantOrder == nil || st.PaymentData == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, model.ResponseMessageInvalidRequestData)
+	}
+
+	record := &model.WebhookInboxRecord{
+		Provider:     pkg.PaymentSystemHandlerCardPay,
+		Type:         model.WebhookInboxTypePayment,
+		OrderId:      st.MerchantOrder.Id,
+		PaymentId:    st.PaymentData.Id,
+		PaymentState: st.PaymentData.Status,
+	}
+
+	if process, err := h.receiveWebhook(ctx, record, st.PaymentMethod, st.CallbackTime); !process {
+		return err
 	}
 
 	req := &grpc.PaymentNotifyRequest{
 		OrderId:   st.MerchantOrder.Id,
-		Request:   []byte(h.rawBody),
+		Request:   []byte(getRequestContext(ctx).RawBody),
 		Signature: ctx.Request().Header.Get(entity.CardPayPaymentResponseHeaderSignature),
 	}
 
-	rsp, err := h.billingService.PaymentCallbackProcess(context.TODO(), req)
+	rsp, err := h.billingService.PaymentCallbackProcess(ctx.Request().Context(), req)
 
 	if err != nil {
+		h.completeWebhook(ctx, record, false, err.Error())
+		incWebHookNotifications(metricsWebHookTypePayment, metricsWebHookStatusError)
 		return echo.NewHTTPError(http.StatusBadRequest, model.ResponseMessageUnknownError)
 	}
 
-	var httpStatus int
-	var message = map[string]string{"message": rsp.Error}
+	incWebHookNotifications(metricsWebHookTypePayment, strconv.Itoa(int(rsp.Status)))
+
+	processed := rsp.Status != pkg.StatusErrorValidation && rsp.Status != pkg.StatusErrorSystem &&
+		rsp.Status != pkg.StatusTemporary
+	h.completeWebhook(ctx, record, processed, rsp.Error)
 
 	switch rsp.Status {
 	case pkg.StatusErrorValidation:
-		httpStatus = http.StatusBadRequest
-		break
+		return echo.NewHTTPError(http.StatusBadRequest, rsp.Error)
 	case pkg.StatusErrorSystem:
-		httpStatus = http.StatusInternalServerError
-		break
+		return echo.NewHTTPError(http.StatusInternalServerError, rsp.Error)
 	case pkg.StatusTemporary:
-		httpStatus = http.StatusGone
-		break
-	default:
-		httpStatus = http.StatusOK
-		message["message"] = "Payment successfully complete"
+		return echo.NewHTTPError(http.StatusGone, rsp.Error)
 	}
 
-	return ctx.JSON(httpStatus, message)
+	return ctx.JSON(http.StatusOK, map[string]string{"message": "Payment successfully complete"})
 }
 
+// @Summary CardPay refund notification
+// @Description Process notification about refund status change from CardPay. Repeated deliveries of processed notification and stale notifications are accepted without processing. Notifications with invalid signature are rejected
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param Signature header string false "signature of notification body by payment system"
+// @Param data body object true "Refund notification"
+// @Success 200 {object} object "Notification processed, repeated or stale notification ignored"
+// @Failure 400 {object} model.Error "Invalid request data or signature"
+// @Failure 409 {object} model.Error "Notification with same refund status is in process"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /webhook/cardpay/refund [post]
 func (h *CardPayWebHook) refundCallback(ctx echo.Context) error {
 	st := &billing.CardPayRefundCallback{}
 	err := ctx.Bind(st)
@@ -84,21 +123,43 @@ func (h *CardPayWebHook) refundCallback(ctx echo.Context) error {
 	err = h.validate.Struct(st)
 
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, manager.GetFirstValidationError(err))
+		return echo.NewHTTPError(http.StatusBadRequest, h.getValidationError(err))
+	}
+
+	if st.MerchantOrder == nil || st.PaymentData == nil || st.RefundData == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
+	}
+
+	record := &model.WebhookInboxRecord{
+		Provider:     pkg.PaymentSystemHandlerCardPay,
+		Type:         model.WebhookInboxTypeRefund,
+		OrderId:      st.MerchantOrder.Id,
+		PaymentId:    st.PaymentData.Id,
+		RefundId:     st.RefundData.Id,
+		PaymentState: st.RefundData.Status,
+	}
+
+	if process, err := h.receiveWebhook(ctx, record, st.PaymentMethod, st.CallbackTime); !process {
+		return err
 	}
 
 	req := &grpc.CallbackRequest{
 		Handler:   pkg.PaymentSystemHandlerCardPay,
-		Body:      []byte(h.rawBody),
+		Body:      []byte(getRequestContext(ctx).RawBody),
 		Signature: ctx.Request().Header.Get(entity.CardPayPaymentResponseHeaderSignature),
 	}
 
-	rsp, err := h.billingService.ProcessRefundCallback(context.TODO(), req)
+	rsp, err := h.billingService.ProcessRefundCallback(ctx.Request().Context(), req)
 
 	if err != nil {
+		h.completeWebhook(ctx, record, false, err.Error())
+		incWebHookNotifications(metricsWebHookTypeRefund, metricsWebHookStatusError)
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
 
+	incWebHookNotifications(metricsWebHookTypeRefund, strconv.Itoa(int(rsp.Status)))
+	h.completeWebhook(ctx, record, rsp.Status == pkg.ResponseStatusOk, rsp.Error)
+
 	if rsp.Status != pkg.ResponseStatusOk {
 		return echo.NewHTTPError(int(rsp.Status), rsp.Error)
 	}
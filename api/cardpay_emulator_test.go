@@ -0,0 +1,350 @@
+package api
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/cardpay"
+	"github.com/paysuper/paysuper-management-api/payment_system"
+	"github.com/paysuper/paysuper-management-api/payment_system/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	cardPayEmulatorTestPassword       = "secret"
+	cardPayEmulatorTestCallbackSecret = "callback_secret"
+)
+
+type CardPayEmulatorTestSuite struct {
+	suite.Suite
+	emulator *cardpay.Emulator
+	server   *httptest.Server
+	receiver *httptest.Server
+	// tokens and circuit breakers shared by all handlers, so each test uses own terminal
+	terminal string
+}
+
+func Test_CardPayEmulator(t *testing.T) {
+	suite.Run(t, new(CardPayEmulatorTestSuite))
+}
+
+func (suite *CardPayEmulatorTestSuite) SetupTest() {
+	suite.terminal = bson.NewObjectId().Hex()
+	suite.receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	suite.emulator = cardpay.NewEmulator(&cardpay.Terminal{
+		Code:           suite.terminal,
+		Password:       cardPayEmulatorTestPassword,
+		CallbackSecret: cardPayEmulatorTestCallbackSecret,
+	})
+	suite.emulator.CallbackUrl = suite.receiver.URL
+	suite.server = httptest.NewServer(suite.emulator)
+	suite.emulator.Url = suite.server.URL
+}
+
+func (suite *CardPayEmulatorTestSuite) TearDownTest() {
+	suite.emulator.Wait()
+	suite.server.Close()
+	suite.receiver.Close()
+}
+
+func (suite *CardPayEmulatorTestSuite) getProvider(o *model.Order, password string) payment_system.Provider {
+	config := map[string]interface{}{
+		payment_system.PaymentSystemHandlerCardPay: map[interface{}]interface{}{
+			"create_payment_url": suite.server.URL,
+			"BANKCARD": map[interface{}]interface{}{
+				"terminal_id":          suite.terminal,
+				"secret_word":          password,
+				"callback_secret_word": cardPayEmulatorTestCallbackSecret,
+			},
+		},
+	}
+
+	r, err := payment_system.NewRegistry(config, &payment_system.PaymentSystemSetting{Logger: zap.NewNop().Sugar()})
+
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	p, err := r.GetProvider(o)
+
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	return p
+}
+
+func (suite *CardPayEmulatorTestSuite) getOrder(pan string) *model.Order {
+	email := "test@unit.test"
+
+	return &model.Order{
+		Id:          bson.NewObjectId(),
+		Status:      model.OrderStatusPaymentSystemCreate,
+		Description: "Emulator test payment",
+		PaymentMethod: &model.OrderPaymentMethod{
+			Id:     bson.NewObjectId(),
+			Name:   "Bank card",
+			Params: &model.PaymentMethodParams{Handler: payment_system.PaymentSystemHandlerCardPay, ExternalId: "BANKCARD"},
+		},
+		PayerData:                    &model.PayerData{Email: &email, Ip: "127.0.0.1"},
+		PaymentMethodOutcomeAmount:   100,
+		PaymentMethodOutcomeCurrency: &model.Currency{CodeA3: "USD"},
+		PaymentRequisites: map[string]string{
+			entity.BankCardFieldPan:    pan,
+			entity.BankCardFieldCvv:    "123",
+			entity.BankCardFieldMonth:  "12",
+			entity.BankCardFieldYear:   strconv.Itoa(time.Now().UTC().Year() + 1),
+			entity.BankCardFieldHolder: "UNIT TEST",
+		},
+	}
+}
+
+// Get payment callbacks sent by emulator as notifications processed by payment system handler
+func (suite *CardPayEmulatorTestSuite) getNotifications(o *model.Order) []*model.OrderPaymentNotification {
+	var notifications []*model.OrderPaymentNotification
+
+	for _, cb := range suite.emulator.GetCallbacks() {
+		if cb.Path != cardpay.PathWebHookPayment {
+			continue
+		}
+
+		assert.NoError(suite.T(), cb.Err)
+		assert.Equal(suite.T(), http.StatusOK, cb.StatusCode)
+
+		req := &entity.CardPayPaymentNotificationWebHookRequest{}
+		assert.NoError(suite.T(), json.Unmarshal(cb.Body, req))
+		req.Signature = cb.Signature
+
+		notifications = append(notifications, &model.OrderPaymentNotification{
+			Id:         o.Id.Hex(),
+			Request:    req,
+			RawRequest: string(cb.Body),
+		})
+	}
+
+	return notifications
+}
+
+// Create payment and process single callback about it
+func (suite *CardPayEmulatorTestSuite) pay(o *model.Order) *payment_system.PaymentResponse {
+	p := suite.getProvider(o, cardPayEmulatorTestPassword)
+
+	rsp := p.CreatePayment()
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+
+	suite.emulator.Wait()
+
+	notifications := suite.getNotifications(o)
+
+	if !assert.Len(suite.T(), notifications, 1) {
+		suite.FailNow("payment callback not received")
+	}
+
+	return p.ProcessPayment(o, notifications[0])
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_Approve_Ok() {
+	o := suite.getOrder(cardpay.CardApprove)
+	rsp := suite.pay(o)
+
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemComplete, o.Status)
+	assert.NotEmpty(suite.T(), o.PaymentMethodOrderId)
+	assert.Equal(suite.T(), float64(100), o.PaymentMethodIncomeAmount)
+	assert.Equal(suite.T(), "USD", o.PaymentMethodIncomeCurrencyA3)
+	assert.Equal(suite.T(), "400000...0002", o.PaymentMethodPayerAccount)
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_Decline_Ok() {
+	o := suite.getOrder(cardpay.CardDecline)
+	rsp := suite.pay(o)
+
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemDeclined, o.Status)
+	assert.Equal(suite.T(), cardpay.DeclineCodeDefault, o.PaymentMethodTxnParams[entity.TxnParamsFieldDeclineCode])
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_Cancel_Ok() {
+	o := suite.getOrder(cardpay.CardCancel)
+	rsp := suite.pay(o)
+
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemCanceled, o.Status)
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_ScenarioOfOrder_Ok() {
+	o := suite.getOrder(cardpay.CardApprove)
+	suite.emulator.SetScenario(o.Id.Hex(), &cardpay.Scenario{
+		Status:        entity.CardPayPaymentResponseStatusDeclined,
+		DeclineCode:   "05",
+		DeclineReason: "Do not honor",
+	})
+
+	rsp := suite.pay(o)
+
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemDeclined, o.Status)
+	assert.Equal(suite.T(), "05", o.PaymentMethodTxnParams[entity.TxnParamsFieldDeclineCode])
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_3ds_Ok() {
+	o := suite.getOrder(cardpay.Card3ds)
+	o.UrlSuccess = "http://localhost/success"
+	p := suite.getProvider(o, cardPayEmulatorTestPassword)
+
+	rsp := p.CreatePayment()
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+	assert.True(suite.T(), strings.HasPrefix(rsp.RedirectUrl, suite.server.URL+cardpay.Path3ds))
+
+	suite.emulator.Wait()
+	assert.Empty(suite.T(), suite.getNotifications(o))
+
+	page, err := http.Get(rsp.RedirectUrl)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, page.StatusCode)
+	assert.NoError(suite.T(), page.Body.Close())
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	confirm, err := client.PostForm(rsp.RedirectUrl, url.Values{"result": []string{"success"}})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusFound, confirm.StatusCode)
+	assert.Equal(suite.T(), o.UrlSuccess, confirm.Header.Get("Location"))
+	assert.NoError(suite.T(), confirm.Body.Close())
+
+	suite.emulator.Wait()
+	notifications := suite.getNotifications(o)
+
+	if !assert.Len(suite.T(), notifications, 1) {
+		return
+	}
+
+	res := p.ProcessPayment(o, notifications[0])
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, res.Status, res.Error)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemComplete, o.Status)
+	assert.Equal(suite.T(), true, o.PaymentMethodTxnParams[entity.TxnParamsFieldBankCardIs3DS])
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_DelayedCallback_StatusRequested() {
+	o := suite.getOrder(cardpay.CardApprove)
+	suite.emulator.SetScenario(o.Id.Hex(), &cardpay.Scenario{
+		Status:        entity.CardPayPaymentResponseStatusCompleted,
+		CallbackDelay: 100 * time.Millisecond,
+	})
+	p := suite.getProvider(o, cardPayEmulatorTestPassword)
+
+	rsp := p.CreatePayment()
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+	assert.Empty(suite.T(), suite.getNotifications(o))
+
+	// status of payment requested from payment system while callback is not received yet
+	o.PaymentMethodOrderId = suite.emulator.GetPaymentId(o.Id.Hex())
+	o.PaymentMethodIncomeAmount = o.PaymentMethodOutcomeAmount
+	o.PaymentMethodIncomeCurrencyA3 = o.PaymentMethodOutcomeCurrency.CodeA3
+
+	rsp = p.GetPaymentStatus()
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemComplete, o.Status)
+
+	suite.emulator.Wait()
+	notifications := suite.getNotifications(o)
+
+	if assert.Len(suite.T(), notifications, 1) {
+		req := notifications[0].Request.(*entity.CardPayPaymentNotificationWebHookRequest)
+		assert.Equal(suite.T(), o.PaymentMethodOrderId, req.PaymentData.Id)
+		assert.Equal(suite.T(), entity.CardPayPaymentResponseStatusCompleted, req.PaymentData.Status)
+	}
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_Refund_Ok() {
+	o := suite.getOrder(cardpay.CardApprove)
+	suite.pay(o)
+
+	p := suite.getProvider(o, cardPayEmulatorTestPassword)
+	rsp := p.CreateRefund(&payment_system.Refund{Id: bson.NewObjectId().Hex(), Amount: 40, Currency: "USD"})
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+	assert.NotEmpty(suite.T(), rsp.ExternalId)
+	assert.Equal(suite.T(), entity.CardPayPaymentResponseStatusPartiallyRefunded, suite.emulator.GetPaymentStatus(o.PaymentMethodOrderId))
+
+	suite.emulator.Wait()
+
+	var callbacks []*cardpay.Callback
+
+	for _, cb := range suite.emulator.GetCallbacks() {
+		if cb.Path == cardpay.PathWebHookRefund {
+			callbacks = append(callbacks, cb)
+		}
+	}
+
+	if !assert.Len(suite.T(), callbacks, 1) {
+		return
+	}
+
+	h := sha512.New()
+	h.Write([]byte(string(callbacks[0].Body) + cardPayEmulatorTestCallbackSecret))
+	assert.Equal(suite.T(), hex.EncodeToString(h.Sum(nil)), callbacks[0].Signature)
+
+	req := &entity.CardPayRefundNotificationWebHookRequest{}
+	assert.NoError(suite.T(), json.Unmarshal(callbacks[0].Body, req))
+	assert.Equal(suite.T(), o.Id.Hex(), req.MerchantOrder.Id)
+	assert.Equal(suite.T(), o.PaymentMethodOrderId, req.PaymentData.Id)
+	assert.Equal(suite.T(), float64(60), req.PaymentData.RemainingAmount)
+	assert.Equal(suite.T(), rsp.ExternalId, req.RefundData.Id)
+	assert.Equal(suite.T(), entity.CardPayPaymentResponseStatusCompleted, req.RefundData.Status)
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_RefundAmountExceeded_Error() {
+	o := suite.getOrder(cardpay.CardApprove)
+	suite.pay(o)
+
+	p := suite.getProvider(o, cardPayEmulatorTestPassword)
+	rsp := p.CreateRefund(&payment_system.Refund{Id: bson.NewObjectId().Hex(), Amount: 150, Currency: "USD"})
+	assert.Equal(suite.T(), payment_system.PaymentStatusErrorValidation, rsp.Status)
+	assert.Equal(suite.T(), entity.CardPayPaymentResponseStatusCompleted, suite.emulator.GetPaymentStatus(o.PaymentMethodOrderId))
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_InvalidCredentials_Error() {
+	o := suite.getOrder(cardpay.CardApprove)
+
+	rsp := suite.getProvider(o, "invalid_password").CreatePayment()
+	assert.Equal(suite.T(), payment_system.PaymentStatusErrorSystem, rsp.Status)
+	assert.Empty(suite.T(), suite.emulator.GetPaymentId(o.Id.Hex()))
+}
+
+func (suite *CardPayEmulatorTestSuite) TestCardPayEmulator_InvalidSignature_Rejected() {
+	o := suite.getOrder(cardpay.CardApprove)
+	p := suite.getProvider(o, cardPayEmulatorTestPassword)
+
+	rsp := p.CreatePayment()
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status, rsp.Error)
+
+	suite.emulator.Wait()
+	notifications := suite.getNotifications(o)
+
+	if !assert.Len(suite.T(), notifications, 1) {
+		return
+	}
+
+	notifications[0].RawRequest = strings.Replace(notifications[0].RawRequest, "100", "1", 1)
+
+	res := p.ProcessPayment(o, notifications[0])
+	assert.Equal(suite.T(), payment_system.PaymentStatusErrorValidation, res.Status)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemReject, o.Status)
+}
@@ -36,6 +36,7 @@ func (suite *CardPayTestSuite) SetupTest() {
 		Http:           echo.New(),
 		validate:       validator.New(),
 		billingService: mock.NewBillingServerOkMock(),
+		webhookInbox:   NewMemoryWebhookInbox(),
 	}
 
 	suite.api.webhookRouteGroup = suite.api.Http.Group(apiWebHookGroupPath)
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/payment_system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	cardPayTokenTestGoroutines = 200
+	cardPayTokenTestRequests   = 5
+)
+
+// fake CardPay server which issues tokens and checks them on payments status requests
+type cardPayTokenTestServer struct {
+	*httptest.Server
+	accessExpire  int
+	refreshExpire int
+	refreshStatus int
+
+	mu         sync.Mutex
+	issued     map[string]bool
+	authCalls  int32
+	refreshes  int32
+	rejected   int32
+	statusReqs int32
+}
+
+type CardPayTokenTestSuite struct {
+	suite.Suite
+	server *cardPayTokenTestServer
+	// tokens shared by all handlers, so each test uses own terminal
+	terminalId string
+}
+
+func Test_CardPayToken(t *testing.T) {
+	suite.Run(t, new(CardPayTokenTestSuite))
+}
+
+func (suite *CardPayTokenTestSuite) SetupTest() {
+	suite.server = &cardPayTokenTestServer{
+		accessExpire:  300,
+		refreshExpire: 3600,
+		refreshStatus: http.StatusOK,
+		issued:        make(map[string]bool),
+	}
+	suite.terminalId = bson.NewObjectId().Hex()
+	// server started after settings of test changed
+	suite.server.Server = httptest.NewUnstartedServer(http.HandlerFunc(suite.server.handle))
+}
+
+func (suite *CardPayTokenTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (s *cardPayTokenTestServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/auth/token" {
+		s.issueToken(w, r)
+		return
+	}
+
+	atomic.AddInt32(&s.statusReqs, 1)
+
+	s.mu.Lock()
+	ok := s.issued[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	s.mu.Unlock()
+
+	if !ok {
+		atomic.AddInt32(&s.rejected, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	_, _ = w.Write([]byte(`{"payment_data": {"id": "1", "status": "IN_PROGRESS"}}`))
+}
+
+func (s *cardPayTokenTestServer) issueToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var n int32
+
+	if r.PostForm.Get("grant_type") == "refresh_token" {
+		n = atomic.AddInt32(&s.refreshes, 1)
+
+		if s.refreshStatus != http.StatusOK {
+			w.WriteHeader(s.refreshStatus)
+			return
+		}
+	} else {
+		n = atomic.AddInt32(&s.authCalls, 1)
+	}
+
+	token := fmt.Sprintf("token_%s_%d", r.PostForm.Get("grant_type"), n)
+
+	s.mu.Lock()
+	s.issued[token] = true
+	s.mu.Unlock()
+
+	b, _ := json.Marshal(map[string]interface{}{
+		"token_type":         "bearer",
+		"access_token":       token,
+		"refresh_token":      "refresh_" + token,
+		"expires_in":         s.accessExpire,
+		"refresh_expires_in": s.refreshExpire,
+	})
+	_, _ = w.Write(b)
+}
+
+func (suite *CardPayTokenTestSuite) getRegistry() *payment_system.Registry {
+	if suite.server.URL == "" {
+		suite.server.Start()
+	}
+
+	config := map[string]interface{}{
+		payment_system.PaymentSystemHandlerCardPay: map[interface{}]interface{}{
+			"create_payment_url": suite.server.URL,
+			"BANKCARD": map[interface{}]interface{}{
+				"terminal_id":          suite.terminalId,
+				"secret_word":          "secret",
+				"callback_secret_word": "callback_secret",
+			},
+		},
+	}
+
+	r, err := payment_system.NewRegistry(config, &payment_system.PaymentSystemSetting{Logger: zap.NewNop().Sugar()})
+
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	return r
+}
+
+// Request payment status by new handler for each request from many goroutines at once
+func (suite *CardPayTokenTestSuite) runConcurrently(r *payment_system.Registry) []*payment_system.PaymentResponse {
+	var wg sync.WaitGroup
+
+	responses := make([]*payment_system.PaymentResponse, cardPayTokenTestGoroutines*cardPayTokenTestRequests)
+
+	for i := 0; i < cardPayTokenTestGoroutines; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < cardPayTokenTestRequests; j++ {
+				o := &model.Order{
+					Id:                   bson.NewObjectId(),
+					Status:               model.OrderStatusPaymentSystemCreate,
+					PaymentMethodOrderId: "1",
+					PaymentMethod: &model.OrderPaymentMethod{
+						Params: &model.PaymentMethodParams{
+							Handler:    payment_system.PaymentSystemHandlerCardPay,
+							ExternalId: "BANKCARD",
+						},
+					},
+				}
+
+				p, err := r.GetProvider(o)
+
+				if err != nil {
+					continue
+				}
+
+				responses[i*cardPayTokenTestRequests+j] = p.GetPaymentStatus()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	return responses
+}
+
+func (suite *CardPayTokenTestSuite) assertResponses(responses []*payment_system.PaymentResponse) {
+	for _, rsp := range responses {
+		if assert.NotNil(suite.T(), rsp) {
+			assert.Equal(suite.T(), payment_system.PaymentStatusTemporary, rsp.Status, rsp.Error)
+		}
+	}
+
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&suite.server.rejected))
+}
+
+func (suite *CardPayTokenTestSuite) TestCardPayToken_SingleAuthentication_Ok() {
+	responses := suite.runConcurrently(suite.getRegistry())
+
+	suite.assertResponses(responses)
+	assert.Equal(suite.T(), int32(len(responses)), atomic.LoadInt32(&suite.server.statusReqs))
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.server.authCalls))
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&suite.server.refreshes))
+}
+
+func (suite *CardPayTokenTestSuite) TestCardPayToken_TokenSharedByRegistries_Ok() {
+	suite.runConcurrently(suite.getRegistry())
+	responses := suite.runConcurrently(suite.getRegistry())
+
+	suite.assertResponses(responses)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.server.authCalls))
+}
+
+func (suite *CardPayTokenTestSuite) TestCardPayToken_RefreshBeforeExpiration_Ok() {
+	// access token expires sooner than refresh interval, so it refreshed while it's still valid
+	suite.server.accessExpire = 10
+	responses := suite.runConcurrently(suite.getRegistry())
+
+	suite.assertResponses(responses)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.server.authCalls))
+	assert.True(suite.T(), atomic.LoadInt32(&suite.server.refreshes) > 0)
+}
+
+func (suite *CardPayTokenTestSuite) TestCardPayToken_RefreshRejected_PasswordGrant() {
+	suite.server.accessExpire = 10
+	suite.server.refreshStatus = http.StatusUnauthorized
+	responses := suite.runConcurrently(suite.getRegistry())
+
+	suite.assertResponses(responses)
+	assert.True(suite.T(), atomic.LoadInt32(&suite.server.refreshes) > 0)
+	assert.Equal(
+		suite.T(),
+		atomic.LoadInt32(&suite.server.refreshes)+1,
+		atomic.LoadInt32(&suite.server.authCalls),
+	)
+}
+
+func (suite *CardPayTokenTestSuite) TestCardPayToken_RefreshTokenExpired_PasswordGrant() {
+	suite.server.accessExpire = 10
+	suite.server.refreshExpire = 0
+	responses := suite.runConcurrently(suite.getRegistry())
+
+	suite.assertResponses(responses)
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&suite.server.refreshes))
+	assert.True(suite.T(), atomic.LoadInt32(&suite.server.authCalls) > 1)
+}
@@ -4,6 +4,7 @@ const (
 	apiWebHookGroupPath     = "/webhook"
 	apiAuthProjectGroupPath = "/api/v1"
 	apiAuthUserGroupPath    = "/admin/api/v1"
+	apiAccessGroupPath      = "/api/v1/s"
 
 	LimitDefault  = 100
 	OffsetDefault = 0
@@ -17,7 +18,12 @@ const (
 	requestParameterPaymentMethodId          = "method_id"
 	requestParameterOrderId                  = "order_id"
 	requestParameterRefundId                 = "refund_id"
+	requestParameterReason                   = "reason"
 	requestParameterNotificationId           = "notification_id"
+	requestParameterFilterId                 = "filter_id"
+	requestParameterJobId                    = "job_id"
+	requestParameterApprovalId               = "approval_id"
+	requestParameterRuleId                   = "rule_id"
 	requestParameterUserId                   = "user"
 	requestParameterLimit                    = "limit"
 	requestParameterOffset                   = "offset"
@@ -50,6 +56,9 @@ const (
 	requestParameterUrlRedirectFail          = "url_redirect_fail"
 	requestParameterUrlRedirectSuccess       = "url_redirect_success"
 	requestParameterStatus                   = "status"
+	requestParameterOrderProjectId           = "PP_PROJECT_ID"
+	requestParameterFormat                   = "format"
+	requestParameterColumns                  = "columns"
 	requestAuthorizationTokenRegex           = "Bearer ([A-z0-9_.-]{10,})"
 
 	errorIdIsEmpty                                    = "identifier can't be empty"
@@ -60,6 +69,10 @@ const (
 	errorIncorrectPaymentMethodId                     = "incorrect payment method identifier"
 	errorIncorrectProductId                           = "incorrect product identifier"
 	errorIncorrectPaylinkId                           = "incorrect paylink identifier"
+	errorIncorrectOrderFilterId                       = "incorrect orders filter identifier"
+	errorIncorrectOrderBulkJobId                      = "incorrect bulk operation identifier"
+	errorIncorrectRefundApprovalId                    = "incorrect refund approval identifier"
+	errorIncorrectRefundApprovalRuleId                = "incorrect refund approval rule identifier"
 	errorMessageAccessDenied                          = "access denied"
 	errorMessageAuthorizationHeaderNotFound           = "authorization header not found"
 	errorMessageAuthorizationTokenNotFound            = "authorization token not found"
@@ -98,10 +111,50 @@ const (
 	errorMessageUrlRedirectSuccessIncorrectType       = "url redirect success parameter has incorrect type"
 	errorMessageStatusIncorrectType                   = "status parameter has incorrect type"
 	errorMessageSignatureHeaderIsEmpty                = "header with request signature can't be empty"
+	errorMessageIdempotencyKeyIncorrect               = "idempotency key can't be longer than 255 characters"
+	errorMessageIdempotencyKeyReused                  = "idempotency key already used for request with other parameters"
+	errorMessageIdempotencyRequestInProcess           = "request with same idempotency key is in process"
+	errorMessageRateLimitExceeded                     = "too many requests. try request later"
+	errorMessageWebhookCallbackTimeIncorrect          = "callback time of notification is incorrect"
+	errorMessageWebhookInProcess                      = "notification with same status is in process"
+	errorMessageWebhookSignatureIncorrect             = "notification signature is invalid"
+	errorMessageExportFormatIncorrect                 = "export format must be one of: csv, xlsx, jsonl"
+	errorMessageExportColumnUnknown                   = "export column \"%s\" is unknown"
+	errorMessageCursorIncorrect                       = "cursor is incorrect"
+	errorMessageCursorWithOffset                      = "cursor can't be used together with offset"
+	errorMessageCursorSortIncorrect                   = "cursor pagination supports sorting by one field only"
+	errorMessageCountModeIncorrect                    = "count must be one of: exact, estimated, none"
+	errorMessageOrderFilterQueryIncorrect             = "orders filter query parameter \"%s\" is unknown"
+	errorMessageOrderReportNotFound                   = "report by orders filter not generated yet"
+	errorMessageOrderBulkOrdersRequired               = "one of order_ids or query must be specified"
+	errorMessageOrderBulkNoteRequired                 = "note must be specified for add_note action"
+	errorMessageOrderBulkTooManyOrders                = "bulk operation can't be applied to more than %d orders"
+	errorMessageOrderBulkOrderNotFound                = "order not found"
+	errorMessageOrderBulkResendNotAllowed             = "notification can be resent only for paid orders which not completed by project"
+	errorMessageOrderBulkReviewRequested              = "order already marked for review"
+	errorMessageOrderBulkMerchantNotFound             = "projects of merchant not found"
+	errorMessageOrderBulkJobFailed                    = "bulk operation stopped by internal error"
+	errorMessageRefundReasonUnknown                   = "refund reason must be one of: fraud, customer_request, duplicate, technical"
+	errorMessageRefundAmountExceeded                  = "refund amount can't be greater than refundable amount %.*f %s"
+	errorMessageRefundAmountIncorrect                 = "refund amount must be greater than zero"
+	errorMessageRefundInProcess                       = "other refund of order is in process. try request later"
+	errorMessageRefundApprovalDecided                 = "refund already approved or rejected"
+	errorMessageRefundApproverIsCreator               = "refund must be approved or rejected by user other than refund creator"
+	errorMessageRefundApprovalStatusIncorrect         = "refund approval status must be one of: pending_approval, approved, rejected"
+	errorMessageOrderReversalStatusIncorrect          = "status of refunds and chargebacks list must be one of: 9, 10"
+	errorMessageOrderReversalDateIncorrect            = "date_from and date_to must be unix timestamps and date_from can't be greater than date_to"
+	errorMessageOrderReversalCurrencyIncorrect        = "currency must be 3 letters code by ISO 4217"
+	errorMessagePaylinkNotFound                       = "payment link not found"
+	errorMessageCustomerTokenNotFound                 = "token not found"
+	errorMessageCustomerTokenRevoked                  = "token revoked or expired"
 
 	HeaderAcceptLanguage      = "Accept-Language"
 	HeaderUserAgent           = "User-Agent"
 	HeaderXApiSignatureHeader = "X-API-SIGNATURE"
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	HeaderXTraceId            = "X-Trace-Id"
+	HeaderRetryAfter          = "Retry-After"
 
 	EnvironmentProduction        = "prod"
 	CustomerTokenCookiesName     = "_ps_ctkn"
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/database/model"
+)
+
+const (
+	requestContextKey        = "paysuper_request_context"
+	requestContextKeyJwtUser = "user"
+)
+
+// RequestContext contains state of single http request filled by api middlewares.
+// Handlers must read authorized user, raw request body and listing parameters only from
+// this structure, because Api instance is shared between all concurrent requests.
+type RequestContext struct {
+	AuthUser           *AuthUser
+	MerchantIdentifier string
+	RouteGroup         string
+	RawBody            string
+	Limit              int32
+	Offset             int32
+	Sort               []string
+	// cursor pagination requested by client. empty cursor means first page
+	CursorPaging bool
+	Cursor       string
+	CountMode    string
+}
+
+func newRequestContext() *RequestContext {
+	return &RequestContext{
+		AuthUser: &AuthUser{
+			Merchants: make(map[string]bool),
+			Roles:     make(map[string]bool),
+		},
+		RouteGroup: metricsRouteGroupPublic,
+		Limit:      model.DefaultLimit,
+		Offset:     model.DefaultOffset,
+		Sort:       model.DefaultSort,
+	}
+}
+
+// Get request context from echo context. If request context not exists yet, then it will be created
+// with default values and saved to echo context
+func getRequestContext(ctx echo.Context) *RequestContext {
+	if rc, ok := ctx.Get(requestContextKey).(*RequestContext); ok {
+		return rc
+	}
+
+	rc := newRequestContext()
+	ctx.Set(requestContextKey, rc)
+
+	return rc
+}
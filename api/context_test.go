@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	requestContextTestHeaderUserId = "X-Test-User-Id"
+	requestContextTestRequestCount = 200
+)
+
+type requestContextTestResponse struct {
+	UserId             string `json:"user_id"`
+	MerchantIdentifier string `json:"merchant_identifier"`
+	RawBody            string `json:"raw_body"`
+	Limit              int32  `json:"limit"`
+	Offset             int32  `json:"offset"`
+}
+
+type RequestContextTestSuite struct {
+	suite.Suite
+	api *Api
+}
+
+func Test_RequestContext(t *testing.T) {
+	suite.Run(t, new(RequestContextTestSuite))
+}
+
+func (suite *RequestContextTestSuite) SetupTest() {
+	suite.api = &Api{
+		Http:     echo.New(),
+		validate: validator.New(),
+	}
+
+	// emulate jwt middleware which put information about authorized user to echo context
+	jwtMock := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			userId := ctx.Request().Header.Get(requestContextTestHeaderUserId)
+
+			if userId != "" {
+				ctx.Set(requestContextKeyJwtUser, &jwtverifier.UserInfo{UserID: userId})
+			}
+
+			return next(ctx)
+		}
+	}
+
+	handler := func(ctx echo.Context) error {
+		rc := getRequestContext(ctx)
+		rsp := &requestContextTestResponse{
+			UserId:             rc.AuthUser.Id,
+			MerchantIdentifier: rc.MerchantIdentifier,
+			RawBody:            rc.RawBody,
+			Limit:              rc.Limit,
+			Offset:             rc.Offset,
+		}
+
+		return ctx.JSON(http.StatusOK, rsp)
+	}
+
+	suite.api.Http.Use(suite.api.RawBodyMiddleware)
+	suite.api.Http.Use(suite.api.LimitOffsetSortMiddleware)
+
+	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
+	suite.api.authUserRouteGroup.Use(jwtMock)
+	suite.api.authUserRouteGroup.Use(suite.api.AuthUserMiddleware)
+	suite.api.authUserRouteGroup.GET("/context", handler)
+	suite.api.authUserRouteGroup.POST("/context", handler)
+
+	suite.api.accessRouteGroup = suite.api.Http.Group("/api/v1/s")
+	suite.api.accessRouteGroup.Use(jwtMock)
+	suite.api.accessRouteGroup.Use(suite.api.MerchantIdentifierMiddleware)
+	suite.api.accessRouteGroup.GET("/context", handler)
+}
+
+func (suite *RequestContextTestSuite) TearDownTest() {}
+
+func (suite *RequestContextTestSuite) TestRequestContext_GetRequestContext_Defaults() {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx := suite.api.Http.NewContext(req, httptest.NewRecorder())
+
+	rc := getRequestContext(ctx)
+	assert.NotNil(suite.T(), rc.AuthUser)
+	assert.Empty(suite.T(), rc.AuthUser.Id)
+	assert.Empty(suite.T(), rc.RawBody)
+	assert.EqualValues(suite.T(), LimitDefault, rc.Limit)
+	assert.EqualValues(suite.T(), OffsetDefault, rc.Offset)
+
+	rc.RawBody = "some body"
+	assert.Equal(suite.T(), "some body", getRequestContext(ctx).RawBody)
+}
+
+func (suite *RequestContextTestSuite) TestRequestContext_AuthUserMiddleware_UserNotFound_Error() {
+	req := httptest.NewRequest(http.MethodGet, apiAuthUserGroupPath+"/context", nil)
+	rsp := httptest.NewRecorder()
+
+	suite.api.Http.ServeHTTP(rsp, req)
+	assert.Equal(suite.T(), http.StatusUnauthorized, rsp.Code)
+}
+
+func (suite *RequestContextTestSuite) TestRequestContext_MerchantIdentifierMiddleware_UserNotFound_Error() {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/s/context", nil)
+	rsp := httptest.NewRecorder()
+
+	suite.api.Http.ServeHTTP(rsp, req)
+	assert.Equal(suite.T(), http.StatusUnauthorized, rsp.Code)
+}
+
+func (suite *RequestContextTestSuite) TestRequestContext_ParallelRequests_Ok() {
+	var wg sync.WaitGroup
+
+	for i := 0; i < requestContextTestRequestCount; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			userId := bson.NewObjectId().Hex()
+			body := fmt.Sprintf(`{"user_id": "%s", "number": %d}`, userId, i)
+
+			var req *http.Request
+
+			switch i % 3 {
+			case 0:
+				req = httptest.NewRequest(http.MethodPost, apiAuthUserGroupPath+"/context", strings.NewReader(body))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				break
+			case 1:
+				url := apiAuthUserGroupPath + "/context?limit=" + strconv.Itoa(i+1) + "&offset=" + strconv.Itoa(i)
+				req = httptest.NewRequest(http.MethodGet, url, nil)
+				break
+			default:
+				req = httptest.NewRequest(http.MethodGet, "/api/v1/s/context", nil)
+			}
+
+			req.Header.Set(requestContextTestHeaderUserId, userId)
+			rsp := httptest.NewRecorder()
+
+			suite.api.Http.ServeHTTP(rsp, req)
+
+			if !assert.Equal(suite.T(), http.StatusOK, rsp.Code) {
+				return
+			}
+
+			data := &requestContextTestResponse{}
+			err := json.Unmarshal(rsp.Body.Bytes(), data)
+
+			if !assert.NoError(suite.T(), err) {
+				return
+			}
+
+			switch i % 3 {
+			case 0:
+				assert.Equal(suite.T(), userId, data.UserId)
+				assert.Equal(suite.T(), body, data.RawBody)
+				break
+			case 1:
+				assert.Equal(suite.T(), userId, data.UserId)
+				assert.Empty(suite.T(), data.RawBody)
+				assert.EqualValues(suite.T(), i+1, data.Limit)
+				assert.EqualValues(suite.T(), i, data.Offset)
+				break
+			default:
+				assert.Equal(suite.T(), userId, data.MerchantIdentifier)
+				assert.Empty(suite.T(), data.UserId)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
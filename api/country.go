@@ -40,7 +40,9 @@ func (cApiV1 *CountryApiV1) get(ctx echo.Context) error {
 		return ctx.JSON(http.StatusOK, cApiV1.countryManager.FindByName(name))
 	}
 
-	return ctx.JSON(http.StatusOK, cApiV1.countryManager.FindAll(cApiV1.limit, cApiV1.offset))
+	rc := getRequestContext(ctx)
+
+	return ctx.JSON(http.StatusOK, cApiV1.countryManager.FindAll(rc.Limit, rc.Offset))
 }
 
 // @Summary Get country by numeric ISO 3166-1 code
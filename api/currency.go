@@ -40,7 +40,9 @@ func (cApiV1 *CurrencyApiV1) get(ctx echo.Context) error {
 		return ctx.JSON(http.StatusOK, cApiV1.currencyManager.FindByName(name))
 	}
 
-	return ctx.JSON(http.StatusOK, cApiV1.currencyManager.FindAll(cApiV1.limit, cApiV1.offset))
+	rc := getRequestContext(ctx)
+
+	return ctx.JSON(http.StatusOK, cApiV1.currencyManager.FindAll(rc.Limit, rc.Offset))
 }
 
 // @Summary Get currency by numeric ISO 4217 code
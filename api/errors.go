@@ -0,0 +1,178 @@
+package api
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	microErrors "github.com/micro/go-micro/errors"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+)
+
+// Stable machine-readable error codes. Any change of this list must be reflected in
+// error codes catalogue of api specification (spec/swagger.yaml)
+const (
+	errorCodeBadRequest           = "bad_request"
+	errorCodeValidationFailed     = "validation_failed"
+	errorCodeUnauthorized         = "unauthorized"
+	errorCodeAccessDenied         = "access_denied"
+	errorCodeNotFound             = "not_found"
+	errorCodeMethodNotAllowed     = "method_not_allowed"
+	errorCodeConflict             = "conflict"
+	errorCodeRequestInProcess     = "request_in_process"
+	errorCodeGone                 = "gone"
+	errorCodeRequestTooLarge      = "request_too_large"
+	errorCodeUnsupportedMediaType = "unsupported_media_type"
+	errorCodeUnprocessableEntity  = "unprocessable_entity"
+	errorCodeIdempotencyKeyReused = "idempotency_key_reused"
+	errorCodeRateLimitExceeded    = "rate_limit_exceeded"
+	errorCodeInternal             = "internal_error"
+	errorCodeUpstream             = "upstream_error"
+	errorCodeServiceUnavailable   = "service_unavailable"
+	errorCodeUpstreamTimeout      = "upstream_timeout"
+)
+
+var errorCodesByHttpStatus = map[int]string{
+	http.StatusBadRequest:            errorCodeBadRequest,
+	http.StatusUnauthorized:          errorCodeUnauthorized,
+	http.StatusForbidden:             errorCodeAccessDenied,
+	http.StatusNotFound:              errorCodeNotFound,
+	http.StatusMethodNotAllowed:      errorCodeMethodNotAllowed,
+	http.StatusConflict:              errorCodeConflict,
+	http.StatusGone:                  errorCodeGone,
+	http.StatusRequestEntityTooLarge: errorCodeRequestTooLarge,
+	http.StatusUnsupportedMediaType:  errorCodeUnsupportedMediaType,
+	http.StatusUnprocessableEntity:   errorCodeUnprocessableEntity,
+	http.StatusTooManyRequests:       errorCodeRateLimitExceeded,
+	http.StatusInternalServerError:   errorCodeInternal,
+	http.StatusBadGateway:            errorCodeUpstream,
+	http.StatusServiceUnavailable:    errorCodeServiceUnavailable,
+	http.StatusGatewayTimeout:        errorCodeUpstreamTimeout,
+}
+
+// Create http error with machine-readable code
+func newError(httpStatus int, code, message string) *echo.HTTPError {
+	return echo.NewHTTPError(httpStatus, newErrorResponse(code, message))
+}
+
+// Create http error with all failed validation rules of request fields
+func newValidationError(err error) *echo.HTTPError {
+	return echo.NewHTTPError(http.StatusBadRequest, getValidationErrorResponse(err))
+}
+
+// Create http error from error of micro service call
+func newUpstreamError(err error) *echo.HTTPError {
+	httpStatus, rsp := getUpstreamErrorResponse(err)
+	return echo.NewHTTPError(httpStatus, rsp)
+}
+
+func newErrorResponse(code, message string) *model.Error {
+	return &model.Error{Code: code, Message: message, Details: []*model.ErrorDetail{}}
+}
+
+func getValidationErrorResponse(err error) *model.Error {
+	vErrs, ok := err.(validator.ValidationErrors)
+
+	if !ok || len(vErrs) == 0 {
+		return newErrorResponse(errorCodeValidationFailed, errorQueryParamsIncorrect)
+	}
+
+	rsp := newErrorResponse(errorCodeValidationFailed, fmt.Sprintf(errorMessageMask, vErrs[0].Field(), vErrs[0].Tag()))
+
+	for _, vErr := range vErrs {
+		rsp.Details = append(rsp.Details, &model.ErrorDetail{
+			Field:   vErr.Namespace(),
+			Code:    vErr.Tag(),
+			Message: fmt.Sprintf(errorMessageMask, vErr.Field(), vErr.Tag()),
+		})
+	}
+
+	return rsp
+}
+
+// Micro service errors with client error status passed to api client as is, server errors
+// of micro services hidden behind upstream error, because they may contain internal information
+func getUpstreamErrorResponse(err error) (int, *model.Error) {
+	mErr := microErrors.Parse(err.Error())
+
+	switch {
+	case mErr.Code == http.StatusRequestTimeout:
+		return http.StatusGatewayTimeout, newErrorResponse(errorCodeUpstreamTimeout, errorUnknown)
+	case mErr.Code >= http.StatusBadRequest && mErr.Code < http.StatusInternalServerError:
+		return int(mErr.Code), newErrorResponse(getErrorCode(int(mErr.Code)), mErr.Detail)
+	case mErr.Code >= http.StatusInternalServerError:
+		return http.StatusBadGateway, newErrorResponse(errorCodeUpstream, errorUnknown)
+	default:
+		return http.StatusInternalServerError, newErrorResponse(errorCodeInternal, errorUnknown)
+	}
+}
+
+func getErrorCode(httpStatus int) string {
+	if code, ok := errorCodesByHttpStatus[httpStatus]; ok {
+		return code
+	}
+
+	if httpStatus >= http.StatusInternalServerError {
+		return errorCodeInternal
+	}
+
+	return errorCodeBadRequest
+}
+
+// Convert any error returned by handler or middleware to http status and error response
+func getErrorResponse(err error) (int, *model.Error) {
+	he, ok := err.(*echo.HTTPError)
+
+	if !ok {
+		return getUpstreamErrorResponse(err)
+	}
+
+	switch msg := he.Message.(type) {
+	case *model.Error:
+		rsp := *msg
+
+		if rsp.Code == "" {
+			rsp.Code = getErrorCode(he.Code)
+		}
+
+		if rsp.Details == nil {
+			rsp.Details = []*model.ErrorDetail{}
+		}
+
+		return he.Code, &rsp
+	case string:
+		return he.Code, newErrorResponse(getErrorCode(he.Code), msg)
+	case error:
+		_, rsp := getUpstreamErrorResponse(msg)
+		rsp.Code = getErrorCode(he.Code)
+
+		return he.Code, rsp
+	default:
+		return he.Code, newErrorResponse(getErrorCode(he.Code), http.StatusText(he.Code))
+	}
+}
+
+// Write all errors in same format with machine-readable code and identifier of request
+func (api *Api) HTTPErrorHandler(err error, ctx echo.Context) {
+	httpStatus, rsp := getErrorResponse(err)
+	rsp.RequestId = ctx.Response().Header().Get(echo.HeaderXRequestID)
+
+	if httpStatus >= http.StatusInternalServerError {
+		data := []interface{}{"error", err.Error(), "path", ctx.Path(), "request_id", rsp.RequestId}
+		api.logError(ctx.Request().Context(), "Request processing failed", data)
+	}
+
+	if ctx.Response().Committed {
+		return
+	}
+
+	if ctx.Request().Method == http.MethodHead {
+		err = ctx.NoContent(httpStatus)
+	} else {
+		err = ctx.JSON(httpStatus, rsp)
+	}
+
+	if err != nil {
+		api.logError(ctx.Request().Context(), "Error response sending failed", []interface{}{"error", err.Error()})
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	microErrors "github.com/micro/go-micro/errors"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type errorsTestRequest struct {
+	ProjectId string `json:"project" validate:"required,hexadecimal"`
+	Amount    int    `json:"amount" validate:"required,min=1"`
+}
+
+type ErrorsTestSuite struct {
+	suite.Suite
+	api *Api
+	err error
+}
+
+func Test_Errors(t *testing.T) {
+	suite.Run(t, new(ErrorsTestSuite))
+}
+
+func (suite *ErrorsTestSuite) SetupTest() {
+	suite.api = &Api{
+		Http:     echo.New(),
+		logger:   zap.NewNop().Sugar(),
+		validate: validator.New(),
+	}
+
+	suite.api.Http.HTTPErrorHandler = suite.api.HTTPErrorHandler
+	suite.api.Http.Use(middleware.RequestID())
+	suite.api.Http.Any("/errors_test", func(ctx echo.Context) error {
+		return suite.err
+	})
+}
+
+func (suite *ErrorsTestSuite) TearDownTest() {}
+
+func (suite *ErrorsTestSuite) serve(method string, err error) (*httptest.ResponseRecorder, *model.Error) {
+	suite.err = err
+
+	req := httptest.NewRequest(method, "/errors_test", nil)
+	rsp := httptest.NewRecorder()
+	suite.api.Http.ServeHTTP(rsp, req)
+
+	data := &model.Error{}
+
+	if method != http.MethodHead {
+		assert.NoError(suite.T(), json.Unmarshal(rsp.Body.Bytes(), data))
+	}
+
+	return rsp, data
+}
+
+func (suite *ErrorsTestSuite) TestErrors_HttpErrorWithString_Ok() {
+	rsp, data := suite.serve(http.MethodGet, echo.NewHTTPError(http.StatusForbidden, errorMessageAccessDenied))
+
+	assert.Equal(suite.T(), http.StatusForbidden, rsp.Code)
+	assert.Equal(suite.T(), errorCodeAccessDenied, data.Code)
+	assert.Equal(suite.T(), errorMessageAccessDenied, data.Message)
+	assert.NotNil(suite.T(), data.Details)
+	assert.Empty(suite.T(), data.Details)
+	assert.NotEmpty(suite.T(), data.RequestId)
+	assert.Equal(suite.T(), rsp.Header().Get(echo.HeaderXRequestID), data.RequestId)
+}
+
+func (suite *ErrorsTestSuite) TestErrors_ValidationError_Ok() {
+	err := suite.api.validate.Struct(&errorsTestRequest{ProjectId: "not_hex"})
+	assert.Error(suite.T(), err)
+
+	rsp, data := suite.serve(http.MethodPost, echo.NewHTTPError(http.StatusBadRequest, suite.api.getValidationError(err)))
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rsp.Code)
+	assert.Equal(suite.T(), errorCodeValidationFailed, data.Code)
+	assert.Regexp(suite.T(), "ProjectId", data.Message)
+
+	if assert.Len(suite.T(), data.Details, 2) {
+		assert.Equal(suite.T(), "errorsTestRequest.ProjectId", data.Details[0].Field)
+		assert.Equal(suite.T(), "hexadecimal", data.Details[0].Code)
+		assert.Equal(suite.T(), "errorsTestRequest.Amount", data.Details[1].Field)
+		assert.Equal(suite.T(), "required", data.Details[1].Code)
+	}
+}
+
+func (suite *ErrorsTestSuite) TestErrors_BillingStatus_Ok() {
+	rsp, data := suite.serve(http.MethodGet, echo.NewHTTPError(http.StatusNotFound, "project not found"))
+
+	assert.Equal(suite.T(), http.StatusNotFound, rsp.Code)
+	assert.Equal(suite.T(), errorCodeNotFound, data.Code)
+	assert.Equal(suite.T(), "project not found", data.Message)
+}
+
+func (suite *ErrorsTestSuite) TestErrors_CustomCode_Ok() {
+	rsp, data := suite.serve(
+		http.MethodPost,
+		newError(http.StatusUnprocessableEntity, errorCodeIdempotencyKeyReused, errorMessageIdempotencyKeyReused),
+	)
+
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, rsp.Code)
+	assert.Equal(suite.T(), errorCodeIdempotencyKeyReused, data.Code)
+	assert.Equal(suite.T(), errorMessageIdempotencyKeyReused, data.Message)
+}
+
+func (suite *ErrorsTestSuite) TestErrors_MicroServiceErrors_Ok() {
+	rsp, data := suite.serve(http.MethodGet, microErrors.BadRequest("p1paybilling", "order amount is invalid"))
+	assert.Equal(suite.T(), http.StatusBadRequest, rsp.Code)
+	assert.Equal(suite.T(), errorCodeBadRequest, data.Code)
+	assert.Equal(suite.T(), "order amount is invalid", data.Message)
+
+	rsp, data = suite.serve(http.MethodGet, microErrors.InternalServerError("p1paybilling", "mongo connection lost"))
+	assert.Equal(suite.T(), http.StatusBadGateway, rsp.Code)
+	assert.Equal(suite.T(), errorCodeUpstream, data.Code)
+	assert.NotContains(suite.T(), data.Message, "mongo")
+
+	rsp, data = suite.serve(http.MethodGet, microErrors.Timeout("go.micro.client", "request timeout"))
+	assert.Equal(suite.T(), http.StatusGatewayTimeout, rsp.Code)
+	assert.Equal(suite.T(), errorCodeUpstreamTimeout, data.Code)
+
+	rsp, data = suite.serve(http.MethodGet, newUpstreamError(microErrors.NotFound("p1paytax", "rate not found")))
+	assert.Equal(suite.T(), http.StatusNotFound, rsp.Code)
+	assert.Equal(suite.T(), errorCodeNotFound, data.Code)
+	assert.Equal(suite.T(), "rate not found", data.Message)
+}
+
+func (suite *ErrorsTestSuite) TestErrors_UnknownError_Ok() {
+	rsp, data := suite.serve(http.MethodGet, errors.New("some internal error"))
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, rsp.Code)
+	assert.Equal(suite.T(), errorCodeInternal, data.Code)
+	assert.Equal(suite.T(), errorUnknown, data.Message)
+
+	rsp, data = suite.serve(http.MethodGet, echo.NewHTTPError(http.StatusInternalServerError, errors.New("some internal error")))
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, rsp.Code)
+	assert.Equal(suite.T(), errorCodeInternal, data.Code)
+	assert.Equal(suite.T(), errorUnknown, data.Message)
+}
+
+func (suite *ErrorsTestSuite) TestErrors_RouteNotFound_Ok() {
+	req := httptest.NewRequest(http.MethodGet, "/errors_test_not_exists", nil)
+	rsp := httptest.NewRecorder()
+	suite.api.Http.ServeHTTP(rsp, req)
+
+	data := &model.Error{}
+	err := json.Unmarshal(rsp.Body.Bytes(), data)
+
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), http.StatusNotFound, rsp.Code)
+		assert.Equal(suite.T(), errorCodeNotFound, data.Code)
+	}
+}
+
+func (suite *ErrorsTestSuite) TestErrors_HeadRequest_Ok() {
+	rsp, _ := suite.serve(http.MethodHead, echo.NewHTTPError(http.StatusNotFound, "not found"))
+
+	assert.Equal(suite.T(), http.StatusNotFound, rsp.Code)
+	assert.Empty(suite.T(), rsp.Body.String())
+}
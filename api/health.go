@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	debugProto "github.com/micro/go-micro/server/debug/proto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	healthCheckTimeout = 5 * time.Second
+	healthStatusOk     = "ok"
+
+	microServiceHealthEndpoint = "Debug.Health"
+)
+
+// ReadinessCheck is a check of service dependency availability. Check must return error if dependency
+// not available for service
+type ReadinessCheck func(ctx context.Context) error
+
+type readinessCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func (api *Api) initHealthRoutes() *Api {
+	api.Http.GET("/health", api.health)
+	api.Http.GET("/ready", api.ready)
+	api.Http.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+
+	return api
+}
+
+// Add check which must be passed for service readiness
+func (api *Api) AddReadinessCheck(name string, check ReadinessCheck) {
+	api.readinessChecksMx.Lock()
+	defer api.readinessChecksMx.Unlock()
+
+	api.readinessChecks = append(api.readinessChecks, &readinessCheck{name: name, check: check})
+}
+
+// @Summary Check api liveness
+// @Description Always returns ok status while api process is alive
+// @Tags Service
+// @Produce json
+// @Success 200 {object} health.HealthResponse "OK"
+// @Router /health [get]
+func (api *Api) health(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, &HealthResponse{Status: healthStatusOk})
+}
+
+// @Summary Check api readiness
+// @Description Check availability of all dependencies of api: database, micro services, storages
+// @Tags Service
+// @Produce json
+// @Success 200 {object} health.HealthResponse "All dependencies are available"
+// @Failure 503 {object} health.HealthResponse "Some of dependencies are not available"
+// @Router /ready [get]
+func (api *Api) ready(ctx echo.Context) error {
+	api.readinessChecksMx.Lock()
+	checks := api.readinessChecks
+	api.readinessChecksMx.Unlock()
+
+	rsp := &HealthResponse{Status: healthStatusOk, Checks: make(map[string]string, len(checks))}
+	httpStatus := http.StatusOK
+
+	checkCtx, cancel := context.WithTimeout(ctx.Request().Context(), healthCheckTimeout)
+	defer cancel()
+
+	var mx sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, check := range checks {
+		wg.Add(1)
+
+		go func(check *readinessCheck) {
+			defer wg.Done()
+
+			status := healthStatusOk
+			err := check.check(checkCtx)
+
+			if err != nil {
+				status = err.Error()
+			}
+
+			mx.Lock()
+			defer mx.Unlock()
+
+			rsp.Checks[check.name] = status
+
+			if err != nil {
+				rsp.Status = http.StatusText(http.StatusServiceUnavailable)
+				httpStatus = http.StatusServiceUnavailable
+			}
+		}(check)
+	}
+
+	wg.Wait()
+
+	return ctx.JSON(httpStatus, rsp)
+}
+
+func (api *Api) databaseReadinessCheck(_ context.Context) error {
+	return api.database.Ping()
+}
+
+// Create check of micro service availability through debug handler which registered by go-micro for every service
+func (api *Api) microServiceReadinessCheck(name string) ReadinessCheck {
+	return func(ctx context.Context) error {
+		clt := api.service.Client()
+		req := clt.NewRequest(name, microServiceHealthEndpoint, &debugProto.HealthRequest{})
+		rsp := &debugProto.HealthResponse{}
+
+		err := clt.Call(ctx, req, rsp)
+
+		if err != nil {
+			return err
+		}
+
+		if rsp.Status != healthStatusOk {
+			return fmt.Errorf("service %s has status %s", name, rsp.Status)
+		}
+
+		return nil
+	}
+}
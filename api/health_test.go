@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	healthTestMetricName = "paysuper_management_api_http_request_duration_seconds"
+)
+
+type HealthTestSuite struct {
+	suite.Suite
+	api *Api
+}
+
+func Test_Health(t *testing.T) {
+	suite.Run(t, new(HealthTestSuite))
+}
+
+func (suite *HealthTestSuite) SetupTest() {
+	suite.api = &Api{
+		Http: echo.New(),
+	}
+
+	suite.api.Http.Use(suite.api.MetricsMiddleware)
+
+	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
+	suite.api.authUserRouteGroup.Use(suite.api.routeGroupMiddleware(metricsRouteGroupAuthUser))
+	suite.api.authUserRouteGroup.GET("/health_test/ok", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	})
+	suite.api.authUserRouteGroup.GET("/health_test/error", func(ctx echo.Context) error {
+		return echo.NewHTTPError(http.StatusForbidden, errorMessageAccessDenied)
+	})
+
+	suite.api.initHealthRoutes()
+}
+
+func (suite *HealthTestSuite) TearDownTest() {}
+
+func (suite *HealthTestSuite) serve(path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rsp := httptest.NewRecorder()
+
+	suite.api.Http.ServeHTTP(rsp, req)
+
+	return rsp
+}
+
+func (suite *HealthTestSuite) getRequestsCount(group, route, status string) uint64 {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(suite.T(), err)
+
+	for _, mf := range mfs {
+		if mf.GetName() != healthTestMetricName {
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+
+			if labels["group"] == group && labels["route"] == route && labels["status"] == status {
+				return m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+
+	return 0
+}
+
+func (suite *HealthTestSuite) TestHealth_Health_Ok() {
+	rsp := suite.serve("/health")
+
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Contains(suite.T(), rsp.Body.String(), healthStatusOk)
+}
+
+func (suite *HealthTestSuite) TestHealth_Ready_Ok() {
+	suite.api.AddReadinessCheck("mongo", func(ctx context.Context) error { return nil })
+	suite.api.AddReadinessCheck("billing", func(ctx context.Context) error { return nil })
+
+	rsp := suite.serve("/ready")
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	data := &HealthResponse{}
+	err := json.Unmarshal(rsp.Body.Bytes(), data)
+
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), healthStatusOk, data.Status)
+		assert.Len(suite.T(), data.Checks, 2)
+		assert.Equal(suite.T(), healthStatusOk, data.Checks["mongo"])
+		assert.Equal(suite.T(), healthStatusOk, data.Checks["billing"])
+	}
+}
+
+func (suite *HealthTestSuite) TestHealth_Ready_CheckFailed_Error() {
+	suite.api.AddReadinessCheck("mongo", func(ctx context.Context) error { return nil })
+	suite.api.AddReadinessCheck("billing", func(ctx context.Context) error { return errors.New("service not found") })
+
+	rsp := suite.serve("/ready")
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, rsp.Code)
+
+	data := &HealthResponse{}
+	err := json.Unmarshal(rsp.Body.Bytes(), data)
+
+	if assert.NoError(suite.T(), err) {
+		assert.NotEqual(suite.T(), healthStatusOk, data.Status)
+		assert.Equal(suite.T(), healthStatusOk, data.Checks["mongo"])
+		assert.Equal(suite.T(), "service not found", data.Checks["billing"])
+	}
+}
+
+func (suite *HealthTestSuite) TestHealth_MetricsMiddleware_Ok() {
+	okRoute := apiAuthUserGroupPath + "/health_test/ok"
+	errorRoute := apiAuthUserGroupPath + "/health_test/error"
+
+	okCount := suite.getRequestsCount(metricsRouteGroupAuthUser, okRoute, "200")
+	errorCount := suite.getRequestsCount(metricsRouteGroupAuthUser, errorRoute, "403")
+	healthCount := suite.getRequestsCount(metricsRouteGroupPublic, "/health", "200")
+
+	suite.serve(okRoute)
+	suite.serve(okRoute)
+	suite.serve(errorRoute)
+	suite.serve("/health")
+
+	assert.Equal(suite.T(), okCount+2, suite.getRequestsCount(metricsRouteGroupAuthUser, okRoute, "200"))
+	assert.Equal(suite.T(), errorCount+1, suite.getRequestsCount(metricsRouteGroupAuthUser, errorRoute, "403"))
+	assert.Equal(suite.T(), healthCount+1, suite.getRequestsCount(metricsRouteGroupPublic, "/health", "200"))
+
+	rsp := suite.serve("/metrics")
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Contains(suite.T(), rsp.Body.String(), healthTestMetricName)
+}
+
+func (suite *HealthTestSuite) TestHealth_WebHookNotifications_Ok() {
+	counter := webHookNotifications.WithLabelValues(metricsWebHookTypePayment, metricsWebHookStatusError)
+	count := testutil.ToFloat64(counter)
+
+	incWebHookNotifications(metricsWebHookTypePayment, metricsWebHookStatusError)
+	assert.Equal(suite.T(), count+1, testutil.ToFloat64(counter))
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyKeyMaxLength  = 255
+	idempotencyKeyTtlDefault = 24 * time.Hour
+
+	idempotencyScopeUser    = "user"
+	idempotencyScopeProject = "project"
+	idempotencyScopeClient  = "client"
+	// lock of refunds creation of order, not scope of idempotency keys
+	idempotencyScopeRefundLock = "refund_lock"
+
+	refundLockTimeout = time.Minute
+)
+
+// Get identifier of principal which sent request. Idempotency keys of different principals never collide
+type idempotencyScopeFunc func(ctx echo.Context) (string, error)
+
+// IdempotencyStore is a storage of requests processed with Idempotency-Key header
+type IdempotencyStore interface {
+	// Insert record if record with same key not exists or already expired. If not expired record
+	// with same key exists, then existing record will be returned and inserted flag will be false
+	Insert(record *model.IdempotencyRecord) (*model.IdempotencyRecord, bool, error)
+	Update(record *model.IdempotencyRecord) error
+	Delete(id string) error
+}
+
+type memoryIdempotencyStore struct {
+	mx      sync.Mutex
+	records map[string]*model.IdempotencyRecord
+}
+
+// Create idempotency store which keep records in memory of current process.
+// Must be used only for single instance installations and tests
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]*model.IdempotencyRecord)}
+}
+
+func (s *memoryIdempotencyStore) Insert(record *model.IdempotencyRecord) (*model.IdempotencyRecord, bool, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if exists, ok := s.records[record.Id]; ok && exists.ExpiresAt.After(time.Now()) {
+		copied := *exists
+		return &copied, false, nil
+	}
+
+	copied := *record
+	s.records[record.Id] = &copied
+
+	return record, true, nil
+}
+
+func (s *memoryIdempotencyStore) Update(record *model.IdempotencyRecord) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	copied := *record
+	s.records[record.Id] = &copied
+
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Delete(id string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	delete(s.records, id)
+
+	return nil
+}
+
+type idempotencyResponseWriter struct {
+	http.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Keys of idempotent requests scoped by authenticated user
+func (api *Api) idempotencyByUser() echo.MiddlewareFunc {
+	return api.idempotencyMiddleware(func(ctx echo.Context) (string, error) {
+		userId := getRequestContext(ctx).AuthUser.Id
+
+		if userId == "" {
+			return "", echo.NewHTTPError(http.StatusUnauthorized, errorMessageAccessDenied)
+		}
+
+		return getIdempotencyScope(idempotencyScopeUser, userId), nil
+	})
+}
+
+// Keys of idempotent requests scoped by project. Signature of request checked before lookup of key,
+// so response of processed request can't be got without signature of project
+func (api *Api) idempotencyByProject() echo.MiddlewareFunc {
+	return api.idempotencyMiddleware(func(ctx echo.Context) (string, error) {
+		projectId := getTokenProjectId(ctx)
+
+		if err := api.checkProjectAuthRequestSignature(ctx, projectId); err != nil {
+			return "", err
+		}
+
+		return getIdempotencyScope(idempotencyScopeProject, projectId), nil
+	})
+}
+
+// Keys of idempotent requests of public routes scoped by ip address of client and by object identifier
+// returned by keyFunc, e.g. project or order identifier
+func (api *Api) idempotencyByClient(keyFunc rateLimitKeyFunc) echo.MiddlewareFunc {
+	return api.idempotencyMiddleware(func(ctx echo.Context) (string, error) {
+		return getIdempotencyScope(idempotencyScopeClient, ctx.RealIP(), keyFunc(ctx)), nil
+	})
+}
+
+// Make request with Idempotency-Key header processed only once. Response of processed request saved to
+// idempotency store and returned to all repeated requests with same key in same scope. If key was used early
+// for request with other body, then request will be rejected
+func (api *Api) idempotencyMiddleware(scopeFunc idempotencyScopeFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			key := req.Header.Get(HeaderIdempotencyKey)
+
+			if key == "" || req.Method == http.MethodGet || req.Method == http.MethodHead {
+				return next(ctx)
+			}
+
+			if len(key) > idempotencyKeyMaxLength {
+				return echo.NewHTTPError(http.StatusBadRequest, errorMessageIdempotencyKeyIncorrect)
+			}
+
+			scope, err := scopeFunc(ctx)
+
+			if err != nil {
+				return err
+			}
+
+			rc := getRequestContext(ctx)
+			record := &model.IdempotencyRecord{
+				Id:          api.getIdempotencyRecordId(scope, req.Method, req.URL.Path, key),
+				Fingerprint: api.getIdempotencyFingerprint(rc.RawBody),
+				Status:      model.IdempotencyRecordStatusProcessing,
+				CreatedAt:   time.Now(),
+				ExpiresAt:   time.Now().Add(api.getIdempotencyKeyTtl()),
+			}
+
+			exists, inserted, err := api.idempotencyStore.Insert(record)
+
+			if err != nil {
+				api.logError(req.Context(), "Insert idempotency record failed", []interface{}{"error", err.Error(), "id", record.Id})
+				return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+			}
+
+			if !inserted {
+				if exists.Fingerprint != record.Fingerprint {
+					return newError(http.StatusUnprocessableEntity, errorCodeIdempotencyKeyReused, errorMessageIdempotencyKeyReused)
+				}
+
+				if exists.Status != model.IdempotencyRecordStatusCompleted {
+					return newError(http.StatusConflict, errorCodeRequestInProcess, errorMessageIdempotencyRequestInProcess)
+				}
+
+				ctx.Response().Header().Set(HeaderIdempotentReplayed, "true")
+
+				return ctx.Blob(exists.ResponseStatus, exists.ResponseContentType, exists.ResponseBody)
+			}
+
+			rsp := ctx.Response()
+			writer := &idempotencyResponseWriter{ResponseWriter: rsp.Writer, body: new(bytes.Buffer)}
+			rsp.Writer = writer
+
+			// recover middleware placed before this middleware, so record of panicked request must be released
+			// here, otherwise repeated requests will be rejected as requests in process until record expiration
+			defer func() {
+				if r := recover(); r != nil {
+					rsp.Writer = writer.ResponseWriter
+					api.releaseIdempotencyRecord(ctx, record.Id)
+
+					panic(r)
+				}
+			}()
+
+			err = next(ctx)
+			rsp.Writer = writer.ResponseWriter
+
+			// failed requests not saved to allow client repeat request with same key
+			if err != nil || !rsp.Committed || rsp.Status >= http.StatusInternalServerError {
+				api.releaseIdempotencyRecord(ctx, record.Id)
+				return err
+			}
+
+			record.Status = model.IdempotencyRecordStatusCompleted
+			record.ResponseStatus = rsp.Status
+			record.ResponseContentType = rsp.Header().Get(echo.HeaderContentType)
+			record.ResponseBody = writer.body.Bytes()
+
+			if err1 := api.idempotencyStore.Update(record); err1 != nil {
+				api.logError(req.Context(), "Update idempotency record failed", []interface{}{"error", err1.Error(), "id", record.Id})
+			}
+
+			return nil
+		}
+	}
+}
+
+// Delete record of request which wasn't processed successfully, so client can repeat request with same key
+func (api *Api) releaseIdempotencyRecord(ctx echo.Context, id string) {
+	if err := api.idempotencyStore.Delete(id); err != nil {
+		api.logError(ctx.Request().Context(), "Delete idempotency record failed", []interface{}{"error", err.Error(), "id", id})
+	}
+}
+
+func (api *Api) getIdempotencyRecordId(scope, method, path, key string) string {
+	h := sha256.New()
+	h.Write([]byte(strings.Join([]string{scope, method, path, key}, "\n")))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (api *Api) getIdempotencyFingerprint(body string) string {
+	h := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(h[:])
+}
+
+func (api *Api) getIdempotencyKeyTtl() time.Duration {
+	if api.config == nil || api.config.IdempotencyKeyTtl <= 0 {
+		return idempotencyKeyTtlDefault
+	}
+
+	return time.Duration(api.config.IdempotencyKeyTtl) * time.Second
+}
+
+func getIdempotencyScope(name string, values ...string) string {
+	return name + ":" + strings.Join(values, ":")
+}
+
+// Get project identifier from json body of token create request
+func getTokenProjectId(ctx echo.Context) string {
+	data := &struct {
+		Settings struct {
+			ProjectId string `json:"project_id"`
+		} `json:"settings"`
+	}{}
+
+	if err := json.Unmarshal([]byte(getRequestContext(ctx).RawBody), data); err != nil {
+		return ""
+	}
+
+	return data.Settings.ProjectId
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	idempotencyTestKey        = "a4e9cc2a-6a1c-4d5b-8c34-3f1f1d4f9e01"
+	idempotencyTestProjectId  = "5be2c3022b9bb6000765d132"
+	idempotencyTestBody       = `{"settings": {"project_id": "5be2c3022b9bb6000765d132"}, "amount": 10}`
+	idempotencyTestHeaderUser = "X-Test-User"
+)
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+	api   *Api
+	calls int32
+}
+
+func Test_Idempotency(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
+
+func (suite *IdempotencyTestSuite) SetupTest() {
+	suite.api = &Api{
+		Http:             echo.New(),
+		billingService:   mock.NewBillingServerOkMock(),
+		idempotencyStore: NewMemoryIdempotencyStore(),
+	}
+	suite.calls = 0
+
+	handler := func(ctx echo.Context) error {
+		calls := atomic.AddInt32(&suite.calls, 1)
+		return ctx.JSON(http.StatusOK, map[string]interface{}{"id": calls})
+	}
+
+	errorHandler := func(ctx echo.Context) error {
+		atomic.AddInt32(&suite.calls, 1)
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	suite.api.Http.Use(suite.api.RawBodyMiddleware)
+	suite.api.apiAuthProjectGroup = suite.api.Http.Group(apiAuthProjectGroupPath)
+	suite.api.apiAuthProjectGroup.Use(suite.api.idempotencyByProject())
+	suite.api.apiAuthProjectGroup.POST("/order", handler)
+	suite.api.apiAuthProjectGroup.POST("/tokens", handler)
+	suite.api.apiAuthProjectGroup.POST("/error", errorHandler)
+	suite.api.apiAuthProjectGroup.POST("/panic", func(ctx echo.Context) error {
+		atomic.AddInt32(&suite.calls, 1)
+		panic("some panic")
+	})
+
+	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
+	suite.api.authUserRouteGroup.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			getRequestContext(ctx).AuthUser.Id = ctx.Request().Header.Get(idempotencyTestHeaderUser)
+			return next(ctx)
+		}
+	})
+	suite.api.authUserRouteGroup.Use(suite.api.idempotencyByUser())
+	suite.api.authUserRouteGroup.POST("/order", handler)
+
+	suite.api.Http.POST("/api/v1/order", handler, suite.api.idempotencyByClient(getJsonProjectId))
+}
+
+func (suite *IdempotencyTestSuite) TearDownTest() {}
+
+func (suite *IdempotencyTestSuite) serve(path, key, body string) *httptest.ResponseRecorder {
+	return suite.serveRequest(apiAuthProjectGroupPath+path, key, body, nil)
+}
+
+func (suite *IdempotencyTestSuite) serveRequest(
+	path, key, body string,
+	headers map[string]string,
+) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderXApiSignatureHeader, "unit_test_signature")
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+
+	rsp := httptest.NewRecorder()
+	suite.api.Http.ServeHTTP(rsp, req)
+
+	return rsp
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_Replay_Ok() {
+	rsp1 := suite.serve("/order", idempotencyTestKey, idempotencyTestBody)
+	assert.Equal(suite.T(), http.StatusOK, rsp1.Code)
+	assert.Empty(suite.T(), rsp1.Header().Get(HeaderIdempotentReplayed))
+
+	rsp2 := suite.serve("/order", idempotencyTestKey, idempotencyTestBody)
+	assert.Equal(suite.T(), http.StatusOK, rsp2.Code)
+	assert.Equal(suite.T(), "true", rsp2.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(suite.T(), rsp1.Body.String(), rsp2.Body.String())
+	assert.Equal(suite.T(), rsp1.Header().Get(echo.HeaderContentType), rsp2.Header().Get(echo.HeaderContentType))
+	assert.EqualValues(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_WithoutKey_Ok() {
+	rsp1 := suite.serve("/order", "", idempotencyTestBody)
+	rsp2 := suite.serve("/order", "", idempotencyTestBody)
+
+	assert.Equal(suite.T(), http.StatusOK, rsp1.Code)
+	assert.Equal(suite.T(), http.StatusOK, rsp2.Code)
+	assert.NotEqual(suite.T(), rsp1.Body.String(), rsp2.Body.String())
+	assert.EqualValues(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_SameKeyOtherRoute_Ok() {
+	suite.serve("/order", idempotencyTestKey, idempotencyTestBody)
+	rsp := suite.serve("/tokens", idempotencyTestKey, idempotencyTestBody)
+
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Empty(suite.T(), rsp.Header().Get(HeaderIdempotentReplayed))
+	assert.EqualValues(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_KeyReusedWithOtherBody_Error() {
+	suite.serve("/order", idempotencyTestKey, idempotencyTestBody)
+	rsp := suite.serve("/order", idempotencyTestKey, `{"settings": {"project_id": "5be2c3022b9bb6000765d132"}, "amount": 20}`)
+
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, rsp.Code)
+	assert.Contains(suite.T(), rsp.Body.String(), errorMessageIdempotencyKeyReused)
+	assert.EqualValues(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_KeyTooLong_Error() {
+	rsp := suite.serve("/order", strings.Repeat("a", idempotencyKeyMaxLength+1), idempotencyTestBody)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rsp.Code)
+	assert.EqualValues(suite.T(), 0, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_FailedRequestNotSaved_Ok() {
+	rsp := suite.serve("/error", idempotencyTestKey, idempotencyTestBody)
+	assert.Equal(suite.T(), http.StatusInternalServerError, rsp.Code)
+
+	rsp = suite.serve("/error", idempotencyTestKey, idempotencyTestBody)
+	assert.Equal(suite.T(), http.StatusInternalServerError, rsp.Code)
+	assert.Empty(suite.T(), rsp.Header().Get(HeaderIdempotentReplayed))
+	assert.EqualValues(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_RequestInProcess_Error() {
+	record := &model.IdempotencyRecord{
+		Id:          suite.api.getIdempotencyRecordId(getIdempotencyScope(idempotencyScopeProject, idempotencyTestProjectId), http.MethodPost, apiAuthProjectGroupPath+"/order", idempotencyTestKey),
+		Fingerprint: suite.api.getIdempotencyFingerprint(idempotencyTestBody),
+		Status:      model.IdempotencyRecordStatusProcessing,
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	_, inserted, err := suite.api.idempotencyStore.Insert(record)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), inserted)
+
+	rsp := suite.serve("/order", idempotencyTestKey, idempotencyTestBody)
+	assert.Equal(suite.T(), http.StatusConflict, rsp.Code)
+	assert.EqualValues(suite.T(), 0, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_ExpiredRecord_Ok() {
+	record := &model.IdempotencyRecord{
+		Id:          suite.api.getIdempotencyRecordId(getIdempotencyScope(idempotencyScopeProject, idempotencyTestProjectId), http.MethodPost, apiAuthProjectGroupPath+"/order", idempotencyTestKey),
+		Fingerprint: suite.api.getIdempotencyFingerprint("other body"),
+		Status:      model.IdempotencyRecordStatusCompleted,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	}
+	_, _, err := suite.api.idempotencyStore.Insert(record)
+	assert.NoError(suite.T(), err)
+
+	rsp := suite.serve("/order", idempotencyTestKey, idempotencyTestBody)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.EqualValues(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_SameKeyOtherProject_Ok() {
+	rsp1 := suite.serve("/order", "1", idempotencyTestBody)
+	rsp2 := suite.serve("/order", "1", `{"settings": {"project_id": "5be2c3022b9bb6000765d133"}, "amount": 20}`)
+
+	assert.Equal(suite.T(), http.StatusOK, rsp1.Code)
+	assert.Equal(suite.T(), http.StatusOK, rsp2.Code)
+	assert.Empty(suite.T(), rsp2.Header().Get(HeaderIdempotentReplayed))
+	assert.NotEqual(suite.T(), rsp1.Body.String(), rsp2.Body.String())
+	assert.EqualValues(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_ProjectSignatureIncorrect_Error() {
+	suite.serve("/order", idempotencyTestKey, idempotencyTestBody)
+	suite.api.billingService = mock.NewBillingServerErrorMock()
+
+	// response of processed request not returned without valid signature of project
+	rsp := suite.serve("/order", idempotencyTestKey, idempotencyTestBody)
+	assert.Equal(suite.T(), http.StatusBadRequest, rsp.Code)
+	assert.Empty(suite.T(), rsp.Header().Get(HeaderIdempotentReplayed))
+	assert.EqualValues(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_SameKeyOtherUser_Ok() {
+	path := apiAuthUserGroupPath + "/order"
+
+	rsp1 := suite.serveRequest(path, "1", idempotencyTestBody, map[string]string{idempotencyTestHeaderUser: "user1"})
+	rsp2 := suite.serveRequest(path, "1", idempotencyTestBody, map[string]string{idempotencyTestHeaderUser: "user2"})
+	rsp3 := suite.serveRequest(path, "1", idempotencyTestBody, map[string]string{idempotencyTestHeaderUser: "user1"})
+
+	assert.Equal(suite.T(), http.StatusOK, rsp1.Code)
+	assert.Equal(suite.T(), http.StatusOK, rsp2.Code)
+	assert.Empty(suite.T(), rsp2.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(suite.T(), "true", rsp3.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(suite.T(), rsp1.Body.String(), rsp3.Body.String())
+	assert.EqualValues(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_SameKeyOtherClient_Ok() {
+	body := `{"project": "5be2c3022b9bb6000765d132", "amount": 10}`
+
+	rsp1 := suite.serveRequest("/api/v1/order", "1", body, map[string]string{echo.HeaderXRealIP: "127.0.0.1"})
+	rsp2 := suite.serveRequest("/api/v1/order", "1", body, map[string]string{echo.HeaderXRealIP: "127.0.0.2"})
+
+	assert.Equal(suite.T(), http.StatusOK, rsp1.Code)
+	assert.Equal(suite.T(), http.StatusOK, rsp2.Code)
+	assert.Empty(suite.T(), rsp2.Header().Get(HeaderIdempotentReplayed))
+	assert.EqualValues(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_PanickedRequestNotSaved_Ok() {
+	assert.PanicsWithValue(suite.T(), "some panic", func() {
+		suite.serve("/panic", idempotencyTestKey, idempotencyTestBody)
+	})
+
+	assert.PanicsWithValue(suite.T(), "some panic", func() {
+		suite.serve("/panic", idempotencyTestKey, idempotencyTestBody)
+	})
+	assert.EqualValues(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestIdempotency_PanickedRequestRecovered_Ok() {
+	suite.api.Http.Use(middleware.Recover())
+
+	rsp := suite.serve("/panic", idempotencyTestKey, idempotencyTestBody)
+	assert.Equal(suite.T(), http.StatusInternalServerError, rsp.Code)
+
+	rsp = suite.serve("/panic", idempotencyTestKey, idempotencyTestBody)
+	assert.Equal(suite.T(), http.StatusInternalServerError, rsp.Code)
+	assert.NotContains(suite.T(), rsp.Body.String(), errorMessageIdempotencyRequestInProcess)
+	assert.EqualValues(suite.T(), 2, suite.calls)
+}
@@ -35,9 +35,9 @@ func (api *Api) InitMerchantRoutes() *Api {
 // @Failure 401 {object} model.Error "Unauthorized"
 // @Failure 404 {object} model.Error "Not found"
 // @Failure 500 {object} model.Error "Some unknown error"
-// @Router /api/v1/s/merchant/{id} [get]
+// @Router /api/v1/s/merchant [get]
 func (mApiV1 *MerchantApiV1) get(ctx echo.Context) error {
-	m := mApiV1.merchantManager.FindById(mApiV1.Merchant.Identifier)
+	m := mApiV1.merchantManager.FindById(getRequestContext(ctx).MerchantIdentifier)
 
 	if m == nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Merchant not found")
@@ -58,7 +58,7 @@ func (mApiV1 *MerchantApiV1) get(ctx echo.Context) error {
 // @Failure 500 {object} model.Error "Some unknown error"
 // @Router /api/v1/s/merchant [post]
 func (mApiV1 *MerchantApiV1) create(ctx echo.Context) error {
-	ms := &model.MerchantScalar{Id: mApiV1.Merchant.Identifier}
+	ms := &model.MerchantScalar{Id: getRequestContext(ctx).MerchantIdentifier}
 
 	err := ctx.Bind(ms)
 
@@ -69,7 +69,7 @@ func (mApiV1 *MerchantApiV1) create(ctx echo.Context) error {
 	err = mApiV1.validate.Struct(ms)
 
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, manager.GetFirstValidationError(err))
+		return echo.NewHTTPError(http.StatusBadRequest, mApiV1.getValidationError(err))
 	}
 
 	if ms.Email == nil {
@@ -112,12 +112,12 @@ func (mApiV1 *MerchantApiV1) update(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request param: "+err.Error())
 	}
 
-	ms.Id = mApiV1.Merchant.Identifier
+	ms.Id = getRequestContext(ctx).MerchantIdentifier
 
 	err = mApiV1.validate.Struct(ms)
 
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, manager.GetFirstValidationError(err))
+		return echo.NewHTTPError(http.StatusBadRequest, mApiV1.getValidationError(err))
 	}
 
 	m := mApiV1.merchantManager.FindById(ms.Id)
@@ -146,7 +146,7 @@ func (mApiV1 *MerchantApiV1) update(ctx echo.Context) error {
 // @Failure 500 {object} model.Error "Some unknown error"
 // @Router /api/v1/s/merchant [delete]
 func (mApiV1 *MerchantApiV1) delete(ctx echo.Context) error {
-	m := mApiV1.merchantManager.FindById(mApiV1.Merchant.Identifier)
+	m := mApiV1.merchantManager.FindById(getRequestContext(ctx).MerchantIdentifier)
 
 	if m == nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Merchant not found")
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	metricsNamespace = "paysuper"
+	metricsSubsystem = "management_api"
+
+	metricsRouteGroupAuthUser    = "auth_user"
+	metricsRouteGroupAccess      = "access"
+	metricsRouteGroupWebHook     = "webhook"
+	metricsRouteGroupAuthProject = "auth_project"
+	metricsRouteGroupPublic      = "public"
+
+	metricsWebHookTypePayment            = "payment"
+	metricsWebHookTypeRefund             = "refund"
+	metricsWebHookStatusError            = "error"
+	metricsWebHookStatusDuplicate        = "duplicate"
+	metricsWebHookStatusStale            = "stale"
+	metricsWebHookStatusSignatureInvalid = "signature_invalid"
+)
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of http requests processing by route group, route and response status",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"group", "method", "route", "status"},
+	)
+	webHookNotifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "webhook_notifications_total",
+			Help:      "Count of payment system notifications by notification type and billing server processing status",
+		},
+		[]string{"type", "status"},
+	)
+	rateLimitExceeded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "rate_limit_exceeded_total",
+			Help:      "Count of requests rejected by rate limits by limit name and route",
+		},
+		[]string{"limit", "route"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(httpRequestDuration, webHookNotifications, rateLimitExceeded)
+}
+
+func (api *Api) MetricsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		start := time.Now()
+		err := next(ctx)
+
+		status := ctx.Response().Status
+
+		if err != nil {
+			status = http.StatusInternalServerError
+
+			if he, ok := err.(*echo.HTTPError); ok {
+				status = he.Code
+			}
+		}
+
+		httpRequestDuration.
+			WithLabelValues(getRequestContext(ctx).RouteGroup, ctx.Request().Method, ctx.Path(), strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+// Mark request as belonging to route group for metrics. Must be first middleware of route group
+func (api *Api) routeGroupMiddleware(name string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			getRequestContext(ctx).RouteGroup = name
+			return next(ctx)
+		}
+	}
+}
+
+func incWebHookNotifications(notificationType, status string) {
+	webHookNotifications.WithLabelValues(notificationType, status).Inc()
+}
+
+func incRateLimitExceeded(limit, route string) {
+	rateLimitExceeded.WithLabelValues(limit, route).Inc()
+}
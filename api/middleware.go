@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-management-api/database/model"
 	"io/ioutil"
@@ -35,9 +36,16 @@ func (api *Api) LimitOffsetSortMiddleware(next echo.HandlerFunc) echo.HandlerFun
 			sort = s
 		}
 
-		api.limit = int32(limit)
-		api.offset = int32(offset)
-		api.sort = sort
+		rc := getRequestContext(ctx)
+		rc.Limit = int32(limit)
+		rc.Offset = int32(offset)
+		rc.Sort = sort
+		rc.CountMode = ctx.QueryParam(model.QueryParameterNameCount)
+
+		if c, ok := qParams[model.QueryParameterNameCursor]; ok {
+			rc.CursorPaging = true
+			rc.Cursor = c[0]
+		}
 
 		return next(ctx)
 	}
@@ -49,7 +57,40 @@ func (api *Api) RawBodyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		rdr := ioutil.NopCloser(bytes.NewBuffer(buf))
 
 		ctx.Request().Body = rdr
-		api.rawBody = string(buf)
+		getRequestContext(ctx).RawBody = string(buf)
+
+		return next(ctx)
+	}
+}
+
+func (api *Api) MerchantIdentifierMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		ui, ok := ctx.Get(requestContextKeyJwtUser).(*jwtverifier.UserInfo)
+
+		if !ok || ui.UserID == "" {
+			return echo.NewHTTPError(http.StatusUnauthorized, errorMessageAuthorizedUserNotFound)
+		}
+
+		getRequestContext(ctx).MerchantIdentifier = ui.UserID
+
+		return next(ctx)
+	}
+}
+
+func (api *Api) AuthUserMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		ui, ok := ctx.Get(requestContextKeyJwtUser).(*jwtverifier.UserInfo)
+
+		if !ok || ui.UserID == "" {
+			return echo.NewHTTPError(http.StatusUnauthorized, errorMessageAuthorizedUserNotFound)
+		}
+
+		getRequestContext(ctx).AuthUser = &AuthUser{
+			Id:        ui.UserID,
+			Name:      "System User",
+			Merchants: make(map[string]bool),
+			Roles:     make(map[string]bool),
+		}
 
 		return next(ctx)
 	}
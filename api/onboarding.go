@@ -65,31 +65,46 @@ func (api *Api) initOnboardingRoutes() (*Api, error) {
 	}
 
 	route.mClt = mClt
+	api.s3Client = mClt
+	api.AddReadinessCheck("s3", route.storageReadinessCheck)
 
-	api.authUserRouteGroup.GET("/merchants", route.listMerchants)
-	api.authUserRouteGroup.GET("/merchants/:id", route.getMerchant)
+	api.authUserRouteGroup.GET("/merchants", route.listMerchants, api.requireRoles(RoleSystemAdmin))
+	api.authUserRouteGroup.GET("/merchants/:id", route.getMerchant, api.requireMerchantAccess(requestParameterId, rolesMerchantRead...))
 	api.authUserRouteGroup.GET("/merchants/user", route.getMerchantByUser)
 	api.authUserRouteGroup.POST("/merchants", route.changeMerchant)
 	api.authUserRouteGroup.PUT("/merchants", route.changeMerchant)
-	api.authUserRouteGroup.PUT("/merchants/:id/change-status", route.changeMerchantStatus)
-	api.authUserRouteGroup.PATCH("/merchants/:id", route.changeAgreement)
+	api.authUserRouteGroup.PUT("/merchants/:id/change-status", route.changeMerchantStatus, api.requireRoles(RoleSystemAdmin))
+	api.authUserRouteGroup.PATCH("/merchants/:id", route.changeAgreement, api.requireMerchantAccess(requestParameterId, rolesMerchantOwner...))
 
-	api.authUserRouteGroup.GET("/merchants/:id/agreement", route.generateAgreement)
-	api.authUserRouteGroup.GET("/merchants/:id/agreement/document", route.getAgreementDocument)
-	api.authUserRouteGroup.POST("/merchants/:id/agreement/document", route.uploadAgreementDocument)
+	api.authUserRouteGroup.GET("/merchants/:id/agreement", route.generateAgreement, api.requireMerchantAccess(requestParameterId, rolesMerchantOwner...))
+	api.authUserRouteGroup.GET("/merchants/:id/agreement/document", route.getAgreementDocument, api.requireMerchantAccess(requestParameterId, rolesMerchantFinance...))
+	api.authUserRouteGroup.POST("/merchants/:id/agreement/document", route.uploadAgreementDocument, api.requireMerchantAccess(requestParameterId, rolesMerchantOwner...))
 
-	api.authUserRouteGroup.POST("/merchants/:merchant_id/notifications", route.createNotification)
-	api.authUserRouteGroup.GET("/merchants/:merchant_id/notifications/:notification_id", route.getNotification)
-	api.authUserRouteGroup.GET("/merchants/:merchant_id/notifications", route.listNotifications)
-	api.authUserRouteGroup.PUT("/merchants/:merchant_id/notifications/:notification_id/mark-as-read", route.markAsReadNotification)
+	api.authUserRouteGroup.POST("/merchants/:merchant_id/notifications", route.createNotification, api.requireRoles(RoleSystemAdmin))
+	api.authUserRouteGroup.GET("/merchants/:merchant_id/notifications/:notification_id", route.getNotification, api.requireMerchantAccess(requestParameterMerchantId, rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/merchants/:merchant_id/notifications", route.listNotifications, api.requireMerchantAccess(requestParameterMerchantId, rolesMerchantRead...))
+	api.authUserRouteGroup.PUT("/merchants/:merchant_id/notifications/:notification_id/mark-as-read", route.markAsReadNotification, api.requireMerchantAccess(requestParameterMerchantId, rolesMerchantRead...))
 
-	api.authUserRouteGroup.GET("/merchants/:merchant_id/methods/:method_id", route.getPaymentMethod)
-	api.authUserRouteGroup.GET("/merchants/:merchant_id/methods", route.listPaymentMethods)
-	api.authUserRouteGroup.PUT("/merchants/:merchant_id/methods/:method_id", route.changePaymentMethod)
+	api.authUserRouteGroup.GET("/merchants/:merchant_id/methods/:method_id", route.getPaymentMethod, api.requireMerchantAccess(requestParameterMerchantId, rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/merchants/:merchant_id/methods", route.listPaymentMethods, api.requireMerchantAccess(requestParameterMerchantId, rolesMerchantRead...))
+	api.authUserRouteGroup.PUT("/merchants/:merchant_id/methods/:method_id", route.changePaymentMethod, api.requireMerchantAccess(requestParameterMerchantId, rolesMerchantOwner...))
 
 	return api, nil
 }
 
+// @Summary Get merchant by identifier
+// @Description Get list of merchants
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "merchant identifier"
+// @Success 200 {object} onboarding.Merchant "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{id} [get]
 func (r *onboardingRoute) getMerchant(ctx echo.Context) error {
 	id := ctx.Param(requestParameterId)
 
@@ -98,10 +113,10 @@ func (r *onboardingRoute) getMerchant(ctx echo.Context) error {
 	}
 
 	req := &grpc.GetMerchantByRequest{MerchantId: id}
-	rsp, err := r.billingService.GetMerchantBy(context.TODO(), req)
+	rsp, err := r.billingService.GetMerchantBy(ctx.Request().Context(), req)
 
 	if err != nil {
-		r.logError("Call billing-server method GetMerchantBy failed", []interface{}{"error", err.Error(), "request", req})
+		r.logError(ctx.Request().Context(), "Call billing-server method GetMerchantBy failed", []interface{}{"error", err.Error(), "request", req})
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
 
@@ -112,12 +127,26 @@ func (r *onboardingRoute) getMerchant(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.Item)
 }
 
+// @Summary Get user merchant
+// @Description Get user merchant
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} onboarding.Merchant "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/user [get]
 func (r *onboardingRoute) getMerchantByUser(ctx echo.Context) error {
-	if r.authUser.Id == "" {
+	authUser := getRequestContext(ctx).AuthUser
+
+	if authUser.Id == "" {
 		return echo.NewHTTPError(http.StatusUnauthorized, errorMessageAccessDenied)
 	}
 
-	rsp, err := r.billingService.GetMerchantBy(context.TODO(), &grpc.GetMerchantByRequest{UserId: r.authUser.Id})
+	rsp, err := r.billingService.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: authUser.Id})
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -130,6 +159,42 @@ func (r *onboardingRoute) getMerchantByUser(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.Item)
 }
 
+func (r *onboardingRoute) storageReadinessCheck(_ context.Context) error {
+	exists, err := r.mClt.BucketExists(r.config.S3.BucketName)
+
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("bucket %s not found", r.config.S3.BucketName)
+	}
+
+	return nil
+}
+
+// @Summary Get list of merchants
+// @Description Get list of merchants
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Param quick_search query string false "string to quick search by merchant name or user owner email"
+// @Param name query string false "merchant name"
+// @Param is_signed query boolean false "query parameter to return merchants with full signed agreement"
+// @Param last_payout_date_from query integer false "start date to filter merchants by last payout date. parameter format must be a unix timestamp"
+// @Param last_payout_date_to query integer false "end date to filter merchants by last payout date. parameter format must be a unix timestamp"
+// @Param last_payout_amount query integer false "last payout amount"
+// @Param status query array false "array of merchant statuses"
+// @Param limit query integer false "maximum number of returning orders. default value is 100"
+// @Param offset query integer false "offset from which you want to return the list of orders. default value is 0"
+// @Param cursor query string false "cursor of page returned in next_cursor or prev_cursor. empty value requests first page in cursor pagination mode"
+// @Param sort[] query array false "fields list for sorting"
+// @Success 200 {array} onboarding.Merchant "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants [get]
 func (r *onboardingRoute) listMerchants(ctx echo.Context) error {
 	req := &grpc.MerchantListingRequest{}
 	err := (&OnboardingMerchantListingBinder{}).Bind(req, ctx)
@@ -144,15 +209,45 @@ func (r *onboardingRoute) listMerchants(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ListMerchants(context.TODO(), req)
+	rc := getRequestContext(ctx)
+	req.Offset, err = getListingCursorOffset(rc, req.Offset)
+
+	if err != nil {
+		return err
+	}
+
+	rsp, err := r.billingService.ListMerchants(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
 
-	return ctx.JSON(http.StatusOK, rsp)
+	cursors, err := getListingCursors(rc, req.Offset, req.Limit, int32(rsp.Count))
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusOK, &struct {
+		*grpc.MerchantListingResponse
+		*listingCursors
+	}{rsp, cursors})
 }
 
+// @Summary Create new merchant in system
+// @Description Create new merchant in system
+// @Tags Onboarding
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body onboarding.Merchant.CreateRequest true "Merchant data"
+// @Success 200 {array} onboarding.Merchant "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants [post]
+// @Router /admin/api/v1/merchants [put]
 func (r *onboardingRoute) changeMerchant(ctx echo.Context) error {
 	req := &grpc.OnboardingRequest{}
 	err := ctx.Bind(req)
@@ -161,9 +256,20 @@ func (r *onboardingRoute) changeMerchant(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
 	}
 
+	// merchant without identifier is created or updated for authorized user, so access is checked
+	// only for merchant with identifier passed in body
+	if req.Id != "" {
+		err = r.checkMerchantAccess(ctx, req.Id, rolesMerchantOwner...)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	authUser := getRequestContext(ctx).AuthUser
 	req.User = &billing.MerchantUser{
-		Id:    r.authUser.Id,
-		Email: r.authUser.Email,
+		Id:    authUser.Id,
+		Email: authUser.Email,
 	}
 	err = r.validate.Struct(req)
 
@@ -171,7 +277,7 @@ func (r *onboardingRoute) changeMerchant(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ChangeMerchant(context.TODO(), req)
+	rsp, err := r.billingService.ChangeMerchant(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -180,6 +286,21 @@ func (r *onboardingRoute) changeMerchant(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp)
 }
 
+// @Summary Change merchant status
+// @Description Change merchant status
+// @Tags Onboarding
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "merchant identifier"
+// @Param data body onboarding.Merchant.ChangeStatus true "Data required to change status"
+// @Success 200 {object} onboarding.Merchant "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{id}/change-status [put]
 func (r *onboardingRoute) changeMerchantStatus(ctx echo.Context) error {
 	req := &grpc.MerchantChangeStatusRequest{}
 	err := (&OnboardingChangeMerchantStatusBinder{}).Bind(req, ctx)
@@ -194,8 +315,8 @@ func (r *onboardingRoute) changeMerchantStatus(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	req.UserId = r.authUser.Id
-	rsp, err := r.billingService.ChangeMerchantStatus(context.TODO(), req)
+	req.UserId = getRequestContext(ctx).AuthUser.Id
+	rsp, err := r.billingService.ChangeMerchantStatus(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -204,6 +325,20 @@ func (r *onboardingRoute) changeMerchantStatus(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp)
 }
 
+// @Summary Create new notification
+// @Description Create new notification
+// @Tags Onboarding
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param merchant_id path string true "merchant identifier"
+// @Param data body onboarding.Merchant.Notification.CreateRequest true "Notification data"
+// @Success 201 {object} onboarding.Merchant.Notification "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{merchant_id}/notifications [post]
 func (r *onboardingRoute) createNotification(ctx echo.Context) error {
 	req := &grpc.NotificationRequest{}
 	err := (&OnboardingCreateNotificationBinder{}).Bind(req, ctx)
@@ -218,8 +353,8 @@ func (r *onboardingRoute) createNotification(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	req.UserId = r.authUser.Id
-	rsp, err := r.billingService.CreateNotification(context.TODO(), req)
+	req.UserId = getRequestContext(ctx).AuthUser.Id
+	rsp, err := r.billingService.CreateNotification(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -228,6 +363,20 @@ func (r *onboardingRoute) createNotification(ctx echo.Context) error {
 	return ctx.JSON(http.StatusCreated, rsp)
 }
 
+// @Summary Get notification by identifier
+// @Description Get notification by identifier
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Param merchant_id path string true "merchant identifier"
+// @Param notification_id path string true "notification identifier"
+// @Success 200 {object} onboarding.Merchant.Notification "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{merchant_id}/notifications/{notification_id} [get]
 func (r *onboardingRoute) getNotification(ctx echo.Context) error {
 	merchantId := ctx.Param(requestParameterMerchantId)
 	notificationId := ctx.Param(requestParameterNotificationId)
@@ -244,7 +393,7 @@ func (r *onboardingRoute) getNotification(ctx echo.Context) error {
 		MerchantId:     merchantId,
 		NotificationId: notificationId,
 	}
-	rsp, err := r.billingService.GetNotification(context.TODO(), req)
+	rsp, err := r.billingService.GetNotification(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, err.Error())
@@ -253,6 +402,23 @@ func (r *onboardingRoute) getNotification(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp)
 }
 
+// @Summary Get list of notifications to merchant
+// @Description Get list of notifications to merchant
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Param merchant_id path string true "merchant identifier"
+// @Param user query string false "user who sent notification"
+// @Param is_system query integer false "flag to filter return notification: 0 - return only notification; 1 - return only history"
+// @Param limit query integer false "limit of returning records"
+// @Param offset query integer false "offset of returning records"
+// @Success 200 {array} onboarding.Merchant.Notifications "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{merchant_id}/notifications [get]
 func (r *onboardingRoute) listNotifications(ctx echo.Context) error {
 	req := &grpc.ListingNotificationRequest{}
 	err := (&OnboardingNotificationsListBinder{}).Bind(req, ctx)
@@ -267,7 +433,7 @@ func (r *onboardingRoute) listNotifications(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ListNotifications(context.TODO(), req)
+	rsp, err := r.billingService.ListNotifications(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -276,6 +442,20 @@ func (r *onboardingRoute) listNotifications(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp)
 }
 
+// @Summary Mark notification as readed
+// @Description Mark notification as readed
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Param merchant_id path string true "merchant identifier"
+// @Param notification_id path string true "notification identifier"
+// @Success 200 {object} onboarding.Merchant.Notification "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{merchant_id}/notifications/{notification_id}/mark-as-read [put]
 func (r *onboardingRoute) markAsReadNotification(ctx echo.Context) error {
 	merchantId := ctx.Param(requestParameterMerchantId)
 	notificationId := ctx.Param(requestParameterNotificationId)
@@ -292,7 +472,7 @@ func (r *onboardingRoute) markAsReadNotification(ctx echo.Context) error {
 		MerchantId:     merchantId,
 		NotificationId: notificationId,
 	}
-	rsp, err := r.billingService.MarkNotificationAsRead(context.TODO(), req)
+	rsp, err := r.billingService.MarkNotificationAsRead(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -301,6 +481,20 @@ func (r *onboardingRoute) markAsReadNotification(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp)
 }
 
+// @Summary Get parameters of payment method for merchant
+// @Description Get parameters of payment method for merchant
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Param merchant_id path string true "merchant identifier"
+// @Param method_id path string true "payment method identifier"
+// @Success 200 {object} onboarding.Merchant.PaymentMethod "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{merchant_id}/methods/{method_id} [get]
 func (r *onboardingRoute) getPaymentMethod(ctx echo.Context) error {
 	req := &grpc.GetMerchantPaymentMethodRequest{}
 	err := (&OnboardingGetPaymentMethodBinder{}).Bind(req, ctx)
@@ -309,7 +503,7 @@ func (r *onboardingRoute) getPaymentMethod(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	rsp, err := r.billingService.GetMerchantPaymentMethod(context.TODO(), req)
+	rsp, err := r.billingService.GetMerchantPaymentMethod(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -322,6 +516,19 @@ func (r *onboardingRoute) getPaymentMethod(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.Item)
 }
 
+// @Summary List merchant payment methods
+// @Description List merchant payment methods
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Param merchant_id path string true "merchant identifier"
+// @Param payment_method_name query string false "characters to filter merchant payment methods by names"
+// @Success 200 {array} onboarding.Merchant.PaymentMethod "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{merchant_id}/methods [get]
 func (r *onboardingRoute) listPaymentMethods(ctx echo.Context) error {
 	req := &grpc.ListMerchantPaymentMethodsRequest{}
 	err := ctx.Bind(req)
@@ -337,7 +544,7 @@ func (r *onboardingRoute) listPaymentMethods(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ListMerchantPaymentMethods(context.TODO(), req)
+	rsp, err := r.billingService.ListMerchantPaymentMethods(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -346,6 +553,22 @@ func (r *onboardingRoute) listPaymentMethods(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.PaymentMethods)
 }
 
+// @Summary Change parameters of payment method for merchant
+// @Description Change parameters of payment method for merchant
+// @Tags Onboarding
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param merchant_id path string true "merchant identifier"
+// @Param method_id path string true "payment method identifier"
+// @Param data body onboarding.Merchant.PaymentMethod true "payment method parameters"
+// @Success 200 {object} onboarding.Merchant.PaymentMethod "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{merchant_id}/methods/{method_id} [put]
 func (r *onboardingRoute) changePaymentMethod(ctx echo.Context) error {
 	req := &grpc.MerchantPaymentMethodRequest{}
 	err := (&OnboardingChangePaymentMethodBinder{}).Bind(req, ctx)
@@ -354,7 +577,7 @@ func (r *onboardingRoute) changePaymentMethod(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	req.UserId = r.authUser.Id
+	req.UserId = getRequestContext(ctx).AuthUser.Id
 
 	err = r.validate.Struct(req)
 
@@ -362,7 +585,7 @@ func (r *onboardingRoute) changePaymentMethod(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ChangeMerchantPaymentMethod(context.TODO(), req)
+	rsp, err := r.billingService.ChangeMerchantPaymentMethod(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -375,6 +598,21 @@ func (r *onboardingRoute) changePaymentMethod(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.Item)
 }
 
+// @Summary Change merchant information
+// @Description Change merchant agreement information
+// @Tags Onboarding
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "merchant identifier"
+// @Param data body onboarding.Merchant.ChangeAgreementDataRequest true "data for change"
+// @Success 200 {object} onboarding.Merchant "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{id} [patch]
 func (r *onboardingRoute) changeAgreement(ctx echo.Context) error {
 	req := &grpc.ChangeMerchantDataRequest{}
 	binder := &ChangeMerchantDataRequestBinder{Api: r.Api}
@@ -390,10 +628,11 @@ func (r *onboardingRoute) changeAgreement(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ChangeMerchantData(context.TODO(), req)
+	rsp, err := r.billingService.ChangeMerchantData(ctx.Request().Context(), req)
 
 	if err != nil {
 		r.logError(
+			ctx.Request().Context(),
 			`Call billing server method "ChangeMerchantData" failed`,
 			[]interface{}{"error", err.Error(), "request", req},
 		)
@@ -408,6 +647,19 @@ func (r *onboardingRoute) changeAgreement(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.Item)
 }
 
+// @Summary Generate agreement
+// @Description Generate agreement for merchant and return data about printable agreement
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "merchant identifier"
+// @Success 200 {object} onboarding.Merchant.PrintableAgreementData.Response "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{id}/agreement [get]
 func (r *onboardingRoute) generateAgreement(ctx echo.Context) error {
 	merchantId := ctx.Param(requestParameterId)
 
@@ -416,10 +668,11 @@ func (r *onboardingRoute) generateAgreement(ctx echo.Context) error {
 	}
 
 	req := &grpc.GetMerchantByRequest{MerchantId: merchantId}
-	rsp, err := r.billingService.GetMerchantBy(context.Background(), req)
+	rsp, err := r.billingService.GetMerchantBy(ctx.Request().Context(), req)
 
 	if err != nil {
 		r.logError(
+			ctx.Request().Context(),
 			`Call billing server method "GetMerchantBy" failed`,
 			[]interface{}{"error", err.Error(), "request", req},
 		)
@@ -489,10 +742,11 @@ func (r *onboardingRoute) generateAgreement(ctx echo.Context) error {
 	}
 
 	req1 := &grpc.SetMerchantS3AgreementRequest{MerchantId: merchantId, S3AgreementName: agrName}
-	_, err = r.billingService.SetMerchantS3Agreement(context.Background(), req1)
+	_, err = r.billingService.SetMerchantS3Agreement(ctx.Request().Context(), req1)
 
 	if err != nil {
 		r.logError(
+			ctx.Request().Context(),
 			`Call billing server method "SetMerchantS3Agreement" failed`,
 			[]interface{}{"error", err.Error(), "request", req1},
 		)
@@ -508,6 +762,19 @@ func (r *onboardingRoute) generateAgreement(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, fData)
 }
 
+// @Summary Download merchant agreement
+// @Description Download merchant agreement
+// @Tags Onboarding
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path string true "merchant identifier"
+// @Success 200 {string} string "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{id}/agreement/document [get]
 func (r *onboardingRoute) getAgreementDocument(ctx echo.Context) error {
 	merchantId := ctx.Param(requestParameterId)
 
@@ -516,10 +783,11 @@ func (r *onboardingRoute) getAgreementDocument(ctx echo.Context) error {
 	}
 
 	req := &grpc.GetMerchantByRequest{MerchantId: merchantId}
-	rsp, err := r.billingService.GetMerchantBy(context.Background(), req)
+	rsp, err := r.billingService.GetMerchantBy(ctx.Request().Context(), req)
 
 	if err != nil {
 		r.logError(
+			ctx.Request().Context(),
 			`Call billing server method "GetMerchantBy" failed`,
 			[]interface{}{"error", err.Error(), "request", req},
 		)
@@ -544,6 +812,19 @@ func (r *onboardingRoute) getAgreementDocument(ctx echo.Context) error {
 	return ctx.File(filePath)
 }
 
+// @Summary Upload merchant agreement
+// @Description Upload new version of merchant agreement
+// @Tags Onboarding
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "merchant identifier"
+// @Success 200 {object} onboarding.Merchant.PrintableAgreementData.Response "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/merchants/{id}/agreement/document [post]
 func (r *onboardingRoute) uploadAgreementDocument(ctx echo.Context) error {
 	merchantId := ctx.Param(requestParameterId)
 
@@ -552,10 +833,11 @@ func (r *onboardingRoute) uploadAgreementDocument(ctx echo.Context) error {
 	}
 
 	req := &grpc.GetMerchantByRequest{MerchantId: merchantId}
-	rsp, err := r.billingService.GetMerchantBy(context.Background(), req)
+	rsp, err := r.billingService.GetMerchantBy(ctx.Request().Context(), req)
 
 	if err != nil {
 		r.logError(
+			ctx.Request().Context(),
 			`Call billing server method "GetMerchantBy" failed`,
 			[]interface{}{"error", err.Error(), "request", req},
 		)
@@ -611,10 +893,11 @@ func (r *onboardingRoute) uploadAgreementDocument(ctx echo.Context) error {
 	}
 
 	req1 := &grpc.SetMerchantS3AgreementRequest{MerchantId: merchantId, S3AgreementName: agrName}
-	_, err = r.billingService.SetMerchantS3Agreement(context.Background(), req1)
+	_, err = r.billingService.SetMerchantS3Agreement(ctx.Request().Context(), req1)
 
 	if err != nil {
 		r.logError(
+			ctx.Request().Context(),
 			`Call billing server method "SetMerchantS3Agreement" failed`,
 			[]interface{}{"error", err.Error(), "request", req1},
 		)
@@ -48,8 +48,9 @@ var onboardingRoutes = [][]string{
 
 type OnboardingTestSuite struct {
 	suite.Suite
-	handler *onboardingRoute
-	api     *Api
+	handler  *onboardingRoute
+	api      *Api
+	authUser *AuthUser
 }
 
 func Test_Onboarding(t *testing.T) {
@@ -61,14 +62,15 @@ func (suite *OnboardingTestSuite) SetupTest() {
 	err := envconfig.Process("", &s3Cfg)
 	assert.NoError(suite.T(), err)
 
+	suite.authUser = &AuthUser{
+		Id:    "ffffffffffffffffffffffff",
+		Email: "test@unit.test",
+	}
+
 	suite.api = &Api{
 		Http:           echo.New(),
 		validate:       validator.New(),
 		billingService: mock.NewBillingServerOkMock(),
-		authUser: &AuthUser{
-			Id:    "ffffffffffffffffffffffff",
-			Email: "test@unit.test",
-		},
 		config: &config.Config{
 			S3: s3Cfg,
 		},
@@ -334,6 +336,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_CreateMerchant_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err = suite.handler.changeMerchant(ctx)
 
@@ -384,6 +387,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_CreateMerchant_ValidationError(
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err = suite.handler.changeMerchant(ctx)
 	assert.Error(suite.T(), err)
@@ -438,6 +442,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_CreateMerchant_BillingServiceUn
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	suite.handler.billingService = mock.NewBillingServerErrorMock()
 	err = suite.handler.changeMerchant(ctx)
@@ -494,6 +499,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_UpdateMerchant_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err = suite.handler.changeMerchant(ctx)
 
@@ -516,6 +522,9 @@ func (suite *OnboardingTestSuite) TestOnboarding_UpdateMerchant_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp = httptest.NewRecorder()
 	ctx = e.NewContext(req, rsp)
+	suite.authUser.Roles = map[string]bool{RoleMerchantOwner: true}
+	suite.authUser.Merchants = map[string]bool{merchantRsp.Id: true}
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err = suite.handler.changeMerchant(ctx)
 
@@ -532,6 +541,27 @@ func (suite *OnboardingTestSuite) TestOnboarding_UpdateMerchant_Ok() {
 	assert.Equal(suite.T(), merchant.Name, merchantRsp1.Name)
 }
 
+func (suite *OnboardingTestSuite) TestOnboarding_UpdateMerchant_OtherMerchant_AccessDenied() {
+	b, err := json.Marshal(&grpc.OnboardingRequest{Id: bson.NewObjectId().Hex(), Name: "New merchant name"})
+	assert.NoError(suite.T(), err)
+
+	req := httptest.NewRequest(http.MethodPut, "/", bytes.NewReader(b))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rsp := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rsp)
+	suite.authUser.Roles = map[string]bool{RoleMerchantOwner: true}
+	suite.authUser.Merchants = map[string]bool{mock.OnboardingMerchantMock.Id: true}
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	err = suite.handler.changeMerchant(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageAccessDenied, httpErr.Message)
+}
+
 func (suite *OnboardingTestSuite) TestOnboarding_ChangeMerchantStatus_Ok() {
 	merchant := &grpc.OnboardingRequest{
 		Name:               mock.OnboardingMerchantMock.Name,
@@ -576,6 +606,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangeMerchantStatus_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err = suite.handler.changeMerchant(ctx)
 
@@ -601,6 +632,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangeMerchantStatus_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp = httptest.NewRecorder()
 	ctx = e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:id/change-status")
 	ctx.SetParamNames("id")
@@ -657,6 +689,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangeMerchantStatus_Validation
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err = suite.handler.changeMerchant(ctx)
 
@@ -675,6 +708,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangeMerchantStatus_Validation
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp = httptest.NewRecorder()
 	ctx = e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:id/change-status")
 	ctx.SetParamNames("id")
@@ -734,6 +768,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangeMerchantStatus_BillingSer
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err = suite.handler.changeMerchant(ctx)
 
@@ -759,6 +794,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangeMerchantStatus_BillingSer
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp = httptest.NewRecorder()
 	ctx = e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:id/change-status")
 	ctx.SetParamNames("id")
@@ -778,7 +814,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangeMerchantStatus_BillingSer
 func (suite *OnboardingTestSuite) TestOnboarding_CreateNotification_Ok() {
 	n := &grpc.NotificationRequest{
 		MerchantId: bson.NewObjectId().Hex(),
-		UserId:     suite.handler.authUser.Id,
+		UserId:     suite.authUser.Id,
 		Title:      "Title",
 		Message:    "Message",
 	}
@@ -791,6 +827,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_CreateNotification_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:merchant_id/notifications")
 	ctx.SetParamNames(requestParameterMerchantId)
@@ -805,7 +842,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_CreateNotification_Ok() {
 func (suite *OnboardingTestSuite) TestOnboarding_CreateNotification_ValidationError() {
 	n := &grpc.NotificationRequest{
 		MerchantId: bson.NewObjectId().Hex(),
-		UserId:     suite.handler.authUser.Id,
+		UserId:     suite.authUser.Id,
 		Title:      "",
 		Message:    "Message",
 	}
@@ -818,6 +855,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_CreateNotification_ValidationEr
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:merchant_id/notifications")
 	ctx.SetParamNames(requestParameterMerchantId)
@@ -835,7 +873,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_CreateNotification_ValidationEr
 func (suite *OnboardingTestSuite) TestOnboarding_CreateNotification_BillingServerUnavailable_Error() {
 	n := &grpc.NotificationRequest{
 		MerchantId: bson.NewObjectId().Hex(),
-		UserId:     suite.handler.authUser.Id,
+		UserId:     suite.authUser.Id,
 		Title:      "Title",
 		Message:    "Message",
 	}
@@ -848,6 +886,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_CreateNotification_BillingServe
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:merchant_id/notifications")
 	ctx.SetParamNames(requestParameterMerchantId)
@@ -1241,6 +1280,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangePaymentMethod_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:merchant_id/methods/:method_id")
 	ctx.SetParamNames(requestParameterMerchantId, requestParameterPaymentMethodId)
@@ -1281,6 +1321,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangePaymentMethod_BindingErro
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:merchant_id/methods/:method_id")
 
@@ -1321,6 +1362,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangePaymentMethod_ValidationE
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:merchant_id/methods/:method_id")
 	ctx.SetParamNames(requestParameterMerchantId, requestParameterPaymentMethodId)
@@ -1363,6 +1405,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangePaymentMethod_BillingServ
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:merchant_id/methods/:method_id")
 	ctx.SetParamNames(requestParameterMerchantId, requestParameterPaymentMethodId)
@@ -1406,6 +1449,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangePaymentMethod_BillingServ
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:merchant_id/methods/:method_id")
 	ctx.SetParamNames(requestParameterMerchantId, requestParameterPaymentMethodId)
@@ -1435,6 +1479,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_ChangeMerchantStatus_BindError(
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:id/change-status")
 
@@ -1461,6 +1506,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_CreateNotification_BindError()
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/merchants/:merchant_id/notifications")
 
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/config"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	echoPathParamRegex = regexp.MustCompile(`:([^/]+)`)
+
+	openApiValidationOptions = &openapi3filter.Options{
+		// authentication checked by middlewares of route groups, so security requirements of specification skipped
+		AuthenticationFunc: func(_ context.Context, _ *openapi3filter.AuthenticationInput) error {
+			return nil
+		},
+	}
+)
+
+type openApiResponseWriter struct {
+	http.ResponseWriter
+	body *bytes.Buffer
+}
+
+// Load api specification and check that it is a valid OpenAPI 3 document
+func LoadOpenApiSpec(path string) (*openapi3.Swagger, error) {
+	swagger, err := openapi3.NewSwaggerLoader().LoadSwaggerFromFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = swagger.Validate(context.Background())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return swagger, nil
+}
+
+// Convert echo route path to path template of api specification, for example
+// "/merchants/:id" converted to "/merchants/{id}"
+func getOpenApiPath(echoPath string) string {
+	return echoPathParamRegex.ReplaceAllString(echoPath, "{$1}")
+}
+
+// Find operation of api specification by route matched by echo router
+func getOpenApiRoute(swagger *openapi3.Swagger, ctx echo.Context) *openapi3filter.Route {
+	path := getOpenApiPath(ctx.Path())
+	pathItem := swagger.Paths.Find(path)
+
+	if pathItem == nil {
+		return nil
+	}
+
+	method := ctx.Request().Method
+	operation := pathItem.GetOperation(method)
+
+	if operation == nil {
+		return nil
+	}
+
+	return &openapi3filter.Route{
+		Swagger:   swagger,
+		Path:      path,
+		PathItem:  pathItem,
+		Method:    method,
+		Operation: operation,
+	}
+}
+
+// Validate requests and responses of routes by api specification. Violations written to log, in enforce
+// mode requests which not match specification rejected. Routes not described in specification skipped
+func (api *Api) OpenApiValidationMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if strings.HasSuffix(ctx.Path(), "*") {
+			return next(ctx)
+		}
+
+		route := getOpenApiRoute(api.openApiSpec, ctx)
+
+		if route == nil {
+			return next(ctx)
+		}
+
+		pathParams := make(map[string]string, len(ctx.ParamNames()))
+
+		for i, name := range ctx.ParamNames() {
+			pathParams[name] = ctx.ParamValues()[i]
+		}
+
+		reqInput := &openapi3filter.RequestValidationInput{
+			Request:    ctx.Request(),
+			PathParams: pathParams,
+			Route:      route,
+			Options:    openApiValidationOptions,
+		}
+		err := openapi3filter.ValidateRequest(ctx.Request().Context(), reqInput)
+
+		if err != nil {
+			api.logOpenApiViolation(ctx, "Request not matched api specification", err)
+
+			if api.config.OpenApiValidationMode == config.OpenApiValidationEnforce {
+				return newError(http.StatusBadRequest, errorCodeValidationFailed, err.Error())
+			}
+		}
+
+		writer := &openApiResponseWriter{ResponseWriter: ctx.Response().Writer, body: new(bytes.Buffer)}
+		ctx.Response().Writer = writer
+
+		// error must be written to response before response validation
+		if err = next(ctx); err != nil {
+			ctx.Error(err)
+		}
+
+		// bodies of streamed files not collected, so only json responses validated
+		if !writer.isJson() {
+			return nil
+		}
+
+		rspInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: reqInput,
+			Status:                 ctx.Response().Status,
+			Header:                 ctx.Response().Header(),
+			Options:                openApiValidationOptions,
+		}
+		rspInput.SetBodyBytes(writer.body.Bytes())
+		err = openapi3filter.ValidateResponse(ctx.Request().Context(), rspInput)
+
+		if err != nil {
+			api.logOpenApiViolation(ctx, "Response not matched api specification", err)
+		}
+
+		return nil
+	}
+}
+
+func (api *Api) logOpenApiViolation(ctx echo.Context, msg string, err error) {
+	data := []interface{}{
+		"error", err.Error(),
+		"method", ctx.Request().Method,
+		"path", ctx.Path(),
+		"request_id", ctx.Response().Header().Get(echo.HeaderXRequestID),
+		logFieldTraceId, getTraceId(ctx.Request().Context()),
+	}
+
+	api.logger.Warnw(msg, data...)
+}
+
+func (w *openApiResponseWriter) Write(b []byte) (int, error) {
+	if w.isJson() {
+		w.body.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *openApiResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *openApiResponseWriter) isJson() bool {
+	return strings.HasPrefix(w.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON)
+}
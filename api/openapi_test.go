@@ -0,0 +1,220 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/paysuper/paysuper-management-api/config"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gopkg.in/go-playground/validator.v9"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const (
+	openApiTestSpecPath  = "../spec/swagger.yaml"
+	openApiTestRoutePath = "/api/v1/orders/:order_id/language"
+	openApiTestOrderId   = "ffffffff-ffff-ffff-ffff-ffffffffffff"
+)
+
+var openApiTestRouterAnnotationRegex = regexp.MustCompile(`// @Router (\S+) \[(\w+)\]`)
+
+type OpenApiTestSuite struct {
+	suite.Suite
+	api  *Api
+	spec *openapi3.Swagger
+	logs *observer.ObservedLogs
+	rsp  interface{}
+}
+
+func Test_OpenApi(t *testing.T) {
+	suite.Run(t, new(OpenApiTestSuite))
+}
+
+func (suite *OpenApiTestSuite) SetupTest() {
+	spec, err := LoadOpenApiSpec(openApiTestSpecPath)
+
+	if !assert.NoError(suite.T(), err) {
+		suite.T().FailNow()
+	}
+
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	suite.spec = spec
+	suite.logs = logs
+	suite.rsp = map[string]interface{}{"user_address_data_required": false}
+	suite.api = &Api{
+		Http:        echo.New(),
+		logger:      zap.New(core).Sugar(),
+		validate:    validator.New(),
+		config:      &config.Config{OpenApi: config.OpenApi{OpenApiValidationMode: config.OpenApiValidationEnforce}},
+		openApiSpec: spec,
+	}
+
+	suite.api.Http.HTTPErrorHandler = suite.api.HTTPErrorHandler
+	suite.api.Http.Use(middleware.RequestID())
+	suite.api.Http.Use(suite.api.OpenApiValidationMiddleware)
+	suite.api.Http.PATCH(openApiTestRoutePath, func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, suite.rsp)
+	})
+}
+
+func (suite *OpenApiTestSuite) TearDownTest() {}
+
+func (suite *OpenApiTestSuite) serve(body string) *httptest.ResponseRecorder {
+	path := strings.Replace(openApiTestRoutePath, ":order_id", openApiTestOrderId, 1)
+	req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rsp := httptest.NewRecorder()
+	suite.api.Http.ServeHTTP(rsp, req)
+
+	return rsp
+}
+
+func (suite *OpenApiTestSuite) hasOperation(path, method string) bool {
+	pathItem := suite.spec.Paths.Find(path)
+	return pathItem != nil && pathItem.GetOperation(strings.ToUpper(method)) != nil
+}
+
+func (suite *OpenApiTestSuite) TestOpenApi_AllRoutesDescribed_Ok() {
+	s3Cfg := config.S3{}
+	err := envconfig.Process("", &s3Cfg)
+	assert.NoError(suite.T(), err)
+
+	api := &Api{
+		Http:           echo.New(),
+		logger:         zap.NewNop().Sugar(),
+		validate:       validator.New(),
+		billingService: mock.NewBillingServerOkMock(),
+		paylinkService: mock.NewPaymentLinkOkMock(),
+		rateLimiter:    NewMemoryRateLimiter(),
+		config:         &config.Config{S3: s3Cfg},
+	}
+
+	if !assert.NoError(suite.T(), api.initRoutes()) {
+		return
+	}
+
+	groups := map[string]bool{
+		apiAccessGroupPath:      true,
+		apiAuthUserGroupPath:    true,
+		apiWebHookGroupPath:     true,
+		apiAuthProjectGroupPath: true,
+	}
+
+	for _, route := range api.Http.Routes() {
+		// routes registered by echo for middlewares of route groups and static files
+		if strings.HasSuffix(route.Path, "*") || groups[route.Path] {
+			continue
+		}
+
+		path := getOpenApiPath(route.Path)
+		assert.True(suite.T(), suite.hasOperation(path, route.Method), "route %s %s not described in api specification", route.Method, path)
+	}
+}
+
+func (suite *OpenApiTestSuite) TestOpenApi_AllAnnotationsDescribed_Ok() {
+	files, err := filepath.Glob("*.go")
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	count := 0
+
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		src, err := ioutil.ReadFile(file)
+
+		if !assert.NoError(suite.T(), err) {
+			return
+		}
+
+		for _, match := range openApiTestRouterAnnotationRegex.FindAllStringSubmatch(string(src), -1) {
+			count++
+			assert.True(suite.T(), suite.hasOperation(match[1], match[2]), "annotation of %s %s in %s not described in api specification", match[2], match[1], file)
+		}
+	}
+
+	assert.NotZero(suite.T(), count)
+}
+
+func (suite *OpenApiTestSuite) TestOpenApi_GetOpenApiPath_Ok() {
+	assert.Equal(suite.T(), "/admin/api/v1/merchants/{merchant_id}/methods/{method_id}", getOpenApiPath("/admin/api/v1/merchants/:merchant_id/methods/:method_id"))
+	assert.Equal(suite.T(), "/health", getOpenApiPath("/health"))
+}
+
+func (suite *OpenApiTestSuite) TestOpenApi_ValidRequest_Ok() {
+	rsp := suite.serve(`{"lang": "en"}`)
+
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Zero(suite.T(), suite.logs.Len())
+}
+
+func (suite *OpenApiTestSuite) TestOpenApi_InvalidRequest_Enforce_Error() {
+	rsp := suite.serve(`{"language": "en"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, rsp.Code)
+
+	data := &model.Error{}
+	err := json.Unmarshal(rsp.Body.Bytes(), data)
+
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), errorCodeValidationFailed, data.Code)
+		assert.Contains(suite.T(), data.Message, "lang")
+	}
+
+	assert.Equal(suite.T(), 1, suite.logs.FilterMessage("Request not matched api specification").Len())
+}
+
+func (suite *OpenApiTestSuite) TestOpenApi_InvalidRequest_Log_Ok() {
+	suite.api.config.OpenApiValidationMode = config.OpenApiValidationLog
+
+	rsp := suite.serve(`{"language": "en"}`)
+
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Equal(suite.T(), 1, suite.logs.FilterMessage("Request not matched api specification").Len())
+}
+
+func (suite *OpenApiTestSuite) TestOpenApi_InvalidResponse_Logged() {
+	suite.rsp = map[string]interface{}{"user_address_data_required": "yes"}
+
+	rsp := suite.serve(`{"lang": "en"}`)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Contains(suite.T(), rsp.Body.String(), "yes")
+
+	logs := suite.logs.FilterMessage("Response not matched api specification").All()
+
+	if assert.Len(suite.T(), logs, 1) {
+		assert.Equal(suite.T(), openApiTestRoutePath, logs[0].ContextMap()["path"])
+	}
+}
+
+func (suite *OpenApiTestSuite) TestOpenApi_RouteNotInSpecification_Skipped() {
+	suite.api.Http.GET("/openapi_test", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi_test", nil)
+	rsp := httptest.NewRecorder()
+	suite.api.Http.ServeHTTP(rsp, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Zero(suite.T(), suite.logs.Len())
+}
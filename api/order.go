@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/labstack/echo/v4"
 	"github.com/micro/go-micro"
@@ -12,8 +13,10 @@ import (
 	"github.com/paysuper/paysuper-management-api/database/model"
 	"github.com/paysuper/paysuper-management-api/manager"
 	"github.com/paysuper/paysuper-payment-link/proto"
+	"go.opencensus.io/trace"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -21,6 +24,9 @@ const (
 	orderFormTemplateName  = "order.html"
 	orderInlineFormUrlMask = "%s://%s/order/%s"
 	errorTemplateName      = "error.html"
+
+	// refunds list not filtered by status
+	refundStatusAny = -1
 )
 
 type orderRoute struct {
@@ -28,6 +34,15 @@ type orderRoute struct {
 	orderManager   *manager.OrderManager
 	projectManager *manager.ProjectManager
 	publisher      micro.Publisher
+	orderStore     OrderStore
+}
+
+// OrderStore is a source of orders saved by payment api. Used for orders data which billing server not returns
+type OrderStore interface {
+	// Get order by public identifier. If order not exists, then mgo.ErrNotFound must be returned
+	FindByUuid(uuid string) (*model.Order, error)
+	// Get history of order status transitions
+	GetTimeline(o *model.Order) (*model.OrderTimeline, error)
 }
 
 type CreateOrderJsonProjectResponse struct {
@@ -94,29 +109,52 @@ func (api *Api) InitOrderV1Routes() *Api {
 		),
 		projectManager: manager.InitProjectManager(api.database, api.logger, api.billingService),
 	}
+	route.orderStore = route.orderManager
 
 	api.Http.GET("/order/:id", route.getOrderForm)
-	api.Http.GET("/paylink/:id", route.getOrderForPaylink)
-	api.Http.GET("/order/create", route.createFromFormData)
-	api.Http.POST("/order/create", route.createFromFormData)
-
-	api.Http.POST("/api/v1/order", route.createJson)
-
-	api.Http.POST("/api/v1/payment", route.processCreatePayment)
-
-	api.authUserRouteGroup.GET("/order", route.getOrders)
+	api.Http.GET("/paylink/:id", route.getOrderForPaylink, api.rateLimitByIp())
+	api.Http.GET("/order/create", route.createFromFormData, api.rateLimitByIp(), api.rateLimitByProject(getFormProjectId))
+	api.Http.POST("/order/create", route.createFromFormData, api.rateLimitByIp(), api.rateLimitByProject(getFormProjectId))
+
+	api.Http.POST(
+		"/api/v1/order",
+		route.createJson,
+		api.rateLimitByIp(),
+		api.rateLimitByProject(getJsonProjectId),
+		api.idempotencyByClient(getJsonProjectId),
+	)
+
+	api.Http.POST(
+		"/api/v1/payment",
+		route.processCreatePayment,
+		api.rateLimitByIp(),
+		api.rateLimitByOrder(getPaymentOrderId),
+		api.idempotencyByClient(getPaymentOrderId),
+	)
+
+	api.authUserRouteGroup.GET("/order", route.getOrders, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/order/export", route.exportOrders, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/order/reversals", route.getReversals, api.requireRoles(rolesMerchantFinance...))
+	api.authUserRouteGroup.GET("/order/reversals/export", route.exportReversals, api.requireRoles(rolesMerchantFinance...))
 
 	api.accessRouteGroup.GET("/order/:id", route.getOrderJson)
 	api.accessRouteGroup.GET("/order/revenue_dynamic/:period", route.getRevenueDynamic)
 	api.accessRouteGroup.GET("/order/accounting_payment", route.getAccountingPaymentCalculation)
 
-	api.authUserRouteGroup.GET("/order/:order_id/refunds", route.listRefunds)
-	api.authUserRouteGroup.GET("/order/:order_id/refunds/:refund_id", route.getRefund)
-	api.authUserRouteGroup.POST("/order/:order_id/refunds", route.createRefund)
-
-	api.Http.PATCH("/api/v1/orders/:order_id/language", route.changeLanguage)
-	api.Http.PATCH("/api/v1/orders/:order_id/customer", route.changeCustomer)
-	api.Http.POST("/api/v1/orders/:order_id/billing_address", route.processBillingAddress)
+	api.authUserRouteGroup.GET("/order/:order_id/timeline", route.getTimeline, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/order/:order_id/refunds", route.listRefunds, api.requireRoles(rolesMerchantFinance...))
+	api.authUserRouteGroup.GET("/order/:order_id/refunds/preview", route.getRefundPreview, api.requireRoles(rolesMerchantFinance...))
+	api.authUserRouteGroup.GET("/order/:order_id/refunds/:refund_id", route.getRefund, api.requireRoles(rolesMerchantFinance...))
+	api.authUserRouteGroup.POST("/order/:order_id/refunds", route.createRefund, api.requireRoles(rolesMerchantOwner...))
+
+	api.Http.PATCH("/api/v1/orders/:order_id/language", route.changeLanguage, api.rateLimitByIp(), api.rateLimitByOrder(getParamOrderId))
+	api.Http.PATCH("/api/v1/orders/:order_id/customer", route.changeCustomer, api.rateLimitByIp(), api.rateLimitByOrder(getParamOrderId))
+	api.Http.POST(
+		"/api/v1/orders/:order_id/billing_address",
+		route.processBillingAddress,
+		api.rateLimitByIp(),
+		api.rateLimitByOrder(getParamOrderId),
+	)
 
 	return api
 }
@@ -158,10 +196,14 @@ func (r *orderRoute) createFromFormData(ctx echo.Context) error {
 	}
 
 	if err := r.validate.Struct(req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, manager.GetFirstValidationError(err))
+		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	order, err := r.billingService.OrderCreateProcess(context.TODO(), req)
+	if err := r.checkCustomerToken(ctx.FormValue("token")); err != nil {
+		return err
+	}
+
+	order, err := r.billingService.OrderCreateProcess(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -177,6 +219,18 @@ func (r *orderRoute) createFromFormData(ctx echo.Context) error {
 // 1) By project host2host request with sending user (customer) information.
 // 2) By payment form client request with sending prepare created user (customer) identification token.
 // 3) By payment form client request without anything user identification information.
+//
+// @Summary Create order with json request
+// @Description Create a payment order use POST JSON request
+// @Tags Payment Order
+// @Accept json
+// @Produce json
+// @Param data body model.OrderScalar true "Order create data"
+// @Success 200 {object} model.JsonOrderCreateResponse "Object which contain data to render payment form"
+// @Failure 400 {object} model.Error "Object with error message"
+// @Failure 429 {object} model.Error "Too many requests"
+// @Failure 500 {object} model.Error "Object with error message"
+// @Router /api/v1/order [post]
 func (r *orderRoute) createJson(ctx echo.Context) error {
 	req := &billing.OrderCreateRequest{}
 	err := (&OrderJsonBinder{}).Bind(req, ctx)
@@ -188,7 +242,7 @@ func (r *orderRoute) createJson(ctx echo.Context) error {
 	err = r.validate.Struct(req)
 
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, manager.GetFirstValidationError(err))
+		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
 	// If request contain user object then paysuper must check request signature
@@ -200,7 +254,13 @@ func (r *orderRoute) createJson(ctx echo.Context) error {
 		}
 	}
 
-	order, err := r.billingService.OrderCreateProcess(context.TODO(), req)
+	err = r.checkCustomerToken(getJsonCustomerToken(ctx))
+
+	if err != nil {
+		return err
+	}
+
+	order, err := r.billingService.OrderCreateProcess(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -220,7 +280,7 @@ func (r *orderRoute) createJson(ctx echo.Context) error {
 			Locale:  ctx.Request().Header.Get(HeaderAcceptLanguage),
 			Ip:      ctx.RealIP(),
 		}
-		rsp2, err := r.billingService.PaymentFormJsonDataProcess(context.TODO(), req2)
+		rsp2, err := r.billingService.PaymentFormJsonDataProcess(ctx.Request().Context(), req2)
 
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -232,6 +292,15 @@ func (r *orderRoute) createJson(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, response)
 }
 
+// @Summary Render payment form
+// @Description Render html page with payment form of order
+// @Tags Payment Order
+// @Produce html
+// @Produce json
+// @Param id path string true "Order unique identifier"
+// @Success 200 {string} string "Payment form"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Router /order/{id} [get]
 func (r *orderRoute) getOrderForm(ctx echo.Context) error {
 	id := ctx.Param(requestParameterId)
 
@@ -253,7 +322,7 @@ func (r *orderRoute) getOrderForm(ctx echo.Context) error {
 		req.Cookie = cookie.Value
 	}
 
-	rsp, err := r.billingService.PaymentFormJsonDataProcess(context.TODO(), req)
+	rsp, err := r.billingService.PaymentFormJsonDataProcess(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -272,6 +341,21 @@ func (r *orderRoute) getOrderForm(ctx echo.Context) error {
 }
 
 // Create order from payment link and redirect to order payment form
+//
+// @Summary Create order by payment link
+// @Description Create order with products of payment link and redirect user to payment form of order
+// @Tags Paylink
+// @Produce html
+// @Produce json
+// @Param id path string true "Payment link unique identifier"
+// @Param utm_source query string false "utm source to save in order metadata"
+// @Param utm_medium query string false "utm medium to save in order metadata"
+// @Param utm_campaign query string false "utm campaign to save in order metadata"
+// @Success 302 "Redirect user to payment form of created order"
+// @Failure 400 {string} string "Page with error description"
+// @Failure 404 {string} string "Page with error description"
+// @Failure 429 {object} model.Error "Too many requests"
+// @Router /paylink/{id} [get]
 func (r *orderRoute) getOrderForPaylink(ctx echo.Context) error {
 
 	paylinkId := ctx.Param(requestParameterId)
@@ -282,11 +366,11 @@ func (r *orderRoute) getOrderForPaylink(ctx echo.Context) error {
 
 	err := r.validate.Struct(req)
 	if err != nil {
-		r.logError("Cannot validate request", []interface{}{"error", err.Error(), "request", req})
+		r.logError(ctx.Request().Context(), "Cannot validate request", []interface{}{"error", err.Error(), "request", req})
 		return ctx.Render(http.StatusBadRequest, errorTemplateName, map[string]interface{}{})
 	}
 
-	pl, err := r.paylinkService.GetPaylink(context.Background(), req)
+	pl, err := r.paylinkService.GetPaylink(ctx.Request().Context(), req)
 	if err != nil {
 		return ctx.Render(http.StatusNotFound, errorTemplateName, map[string]interface{}{})
 	}
@@ -310,9 +394,9 @@ func (r *orderRoute) getOrderForPaylink(ctx echo.Context) error {
 		oReq.PrivateMetadata[requestParameterUtmCampaign] = v[0]
 	}
 
-	order, err := r.billingService.OrderCreateProcess(context.Background(), oReq)
+	order, err := r.billingService.OrderCreateProcess(ctx.Request().Context(), oReq)
 	if err != nil {
-		r.logError("Cannot create order for paylink", []interface{}{"error", err.Error(), "request", req})
+		r.logError(ctx.Request().Context(), "Cannot create order for paylink", []interface{}{"error", err.Error(), "request", req})
 		return ctx.Render(http.StatusBadRequest, errorTemplateName, map[string]interface{}{})
 	}
 
@@ -322,12 +406,15 @@ func (r *orderRoute) getOrderForPaylink(ctx echo.Context) error {
 		inlineFormRedirectUrl += "?" + qs
 	}
 
+	// request context will be cancelled after response sending, so only trace span taken from it
+	statCtx := trace.NewContext(context.Background(), trace.FromContext(ctx.Request().Context()))
+
 	go func() {
-		_, err := r.paylinkService.IncrPaylinkVisits(context.Background(), &paylink.PaylinkRequest{
+		_, err := r.paylinkService.IncrPaylinkVisits(statCtx, &paylink.PaylinkRequest{
 			Id: paylinkId,
 		})
 		if err != nil {
-			r.logError("Cannot update paylink stat", []interface{}{"error", err.Error(), "request", req})
+			r.logError(statCtx, "Cannot update paylink stat", []interface{}{"error", err.Error(), "request", req})
 		}
 	}()
 	return ctx.Redirect(http.StatusFound, inlineFormRedirectUrl)
@@ -353,7 +440,8 @@ func (r *orderRoute) getOrderJson(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, model.ResponseMessageInvalidRequestData)
 	}
 
-	p, merchant, err := r.projectManager.FilterProjects(r.Merchant.Identifier, []bson.ObjectId{})
+	rc := getRequestContext(ctx)
+	p, merchant, err := r.projectManager.FilterProjects(ctx.Request().Context(), rc.MerchantIdentifier, []bson.ObjectId{})
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusForbidden, err)
@@ -363,8 +451,8 @@ func (r *orderRoute) getOrderJson(ctx echo.Context) error {
 		Values:   url.Values{"id": []string{id}},
 		Projects: p,
 		Merchant: merchant,
-		Limit:    r.GetParams.limit,
-		Offset:   r.GetParams.offset,
+		Limit:    rc.Limit,
+		Offset:   rc.Offset,
 	}
 
 	pOrders, err := r.orderManager.FindAll(params)
@@ -395,66 +483,167 @@ func (r *orderRoute) getOrderJson(ctx echo.Context) error {
 // @Param pm_date_to query integer false "end date when payment was closed to get orders filtered by they"
 // @Param project_date_from query integer false "start date when payment was created to get orders filtered by they"
 // @Param project_date_to query integer false "end date when payment was closed in project to get orders filtered by they"
-// @Param quick_filter query string false "string for full text search in quick filter"
+// @Param quick_filter query string false "search by beginning of order identifier, order identifier in project or payment system, account, payer email or phone, masked card number or its last 4 digits, name of project, fixed package or payment method. value in double quotes searched by exact match"
 // @Param limit query integer false "maximum number of returning orders. default value is 100"
 // @Param offset query integer false "offset from which you want to return the list of orders. default value is 0"
+// @Param cursor query string false "cursor of page returned in next_cursor or prev_cursor. empty value requests first page in cursor pagination mode"
+// @Param count query string false "mode of count calculation: exact, estimated or none"
 // @Param sort query array false "fields list for sorting"
 // @Success 200 {object} model.OrderPaginate "OK"
 // @Failure 404 {object} model.Error "Invalid request data"
 // @Failure 401 {object} model.Error "Unauthorized"
 // @Failure 500 {object} model.Error "Object with error message"
-// @Router /api/v1/s/order [get]
+// @Router /admin/api/v1/order [get]
 func (r *orderRoute) getOrders(ctx echo.Context) error {
+	params, err := r.getFindAllParams(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	pOrders, err := r.orderManager.FindAll(params)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(http.StatusOK, pOrders)
+}
+
+// Get filters of orders search from query parameters, search limited by projects of authenticated merchant
+func (r *orderRoute) getFindAllParams(ctx echo.Context) (*manager.FindAll, error) {
 	values := ctx.QueryParams()
+	fp, err := getOrderFilterProjects(values)
 
-	var fp []bson.ObjectId
+	if err != nil {
+		return nil, err
+	}
+
+	p, merchant, err := r.getMerchantProjects(ctx, fp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rc := getRequestContext(ctx)
+	params := &manager.FindAll{
+		Values:   values,
+		Projects: p,
+		Merchant: merchant,
+		Limit:    rc.Limit,
+		Offset:   rc.Offset,
+		SortBy:   rc.Sort,
+	}
+
+	params.Paginator, err = getCursorPaginator(rc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	params.CountMode, err = getCountMode(rc)
+
+	if err != nil {
+		return nil, err
+	}
 
-	if fProjects, ok := values[model.OrderFilterFieldProjects]; ok {
-		for _, project := range fProjects {
-			if bson.IsObjectIdHex(project) == false {
-				return echo.NewHTTPError(http.StatusBadRequest, model.ResponseMessageProjectIdIsInvalid)
-			}
+	return params, nil
+}
+
+// Get identifiers of projects from filter of orders list
+func getOrderFilterProjects(values url.Values) ([]bson.ObjectId, error) {
+	var fp []bson.ObjectId
 
-			fp = append(fp, bson.ObjectIdHex(project))
+	for _, project := range values[model.OrderFilterFieldProjects] {
+		if bson.IsObjectIdHex(project) == false {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, model.ResponseMessageProjectIdIsInvalid)
 		}
+
+		fp = append(fp, bson.ObjectIdHex(project))
 	}
 
-	rsp, err := r.billingService.GetMerchantBy(context.TODO(), &grpc.GetMerchantByRequest{UserId: r.authUser.Id})
+	return fp, nil
+}
+
+// Get projects of merchant of authenticated user. If projects filter is not empty, then only projects
+// from filter returned
+func (r *orderRoute) getMerchantProjects(
+	ctx echo.Context,
+	fp []bson.ObjectId,
+) (map[bson.ObjectId]string, *billing.Merchant, error) {
+	return r.getUserMerchantProjects(ctx.Request().Context(), getRequestContext(ctx).AuthUser.Id, fp)
+}
+
+// Get projects of merchant of user. Used for requests of authenticated user and for background jobs
+// started by user early
+func (r *orderRoute) getUserMerchantProjects(
+	ctx context.Context,
+	userId string,
+	fp []bson.ObjectId,
+) (map[bson.ObjectId]string, *billing.Merchant, error) {
+	rsp, err := r.billingService.GetMerchantBy(ctx, &grpc.GetMerchantByRequest{UserId: userId})
 
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
 
 	if rsp.Status != pkg.ResponseStatusOk {
-		return echo.NewHTTPError(int(rsp.Status), rsp.Message)
+		return nil, nil, echo.NewHTTPError(int(rsp.Status), rsp.Message)
 	}
 
-	p, _, err := r.projectManager.FilterProjects(rsp.Item.Id, fp)
+	p, _, err := r.projectManager.FilterProjects(ctx, rsp.Item.Id, fp)
 
 	if err != nil {
-		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		return nil, nil, echo.NewHTTPError(http.StatusForbidden, err.Error())
 	}
 
-	params := &manager.FindAll{
-		Values:   values,
-		Projects: p,
-		Merchant: rsp.Item,
-		Limit:    r.GetParams.limit,
-		Offset:   r.GetParams.offset,
-		SortBy:   r.GetParams.sort,
+	return p, rsp.Item, nil
+}
+
+// @Summary Get order timeline
+// @Description Get history of order status transitions with initiator and source of every transition
+// @Tags Payment Order
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "order public identifier (uuid)"
+// @Success 200 {object} model.OrderTimeline "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/{order_id}/timeline [get]
+func (r *orderRoute) getTimeline(ctx echo.Context) error {
+	o, err := r.getMerchantOrder(ctx, ctx.Param(requestParameterOrderId))
+
+	if err != nil {
+		return err
 	}
 
-	pOrders, err := r.orderManager.FindAll(params)
+	timeline, err := r.orderStore.GetTimeline(o)
 
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
 
-	return ctx.JSON(http.StatusOK, pOrders)
+	return ctx.JSON(http.StatusOK, timeline)
 }
 
 // Create payment by order
 // route POST /api/v1/payment
+//
+// @Summary Create payment
+// @Description Create payment by order
+// @Tags Payment Order
+// @Accept json
+// @Produce json
+// @Param data body model.OrderCreatePaymentRequest true "data to create payment"
+// @Success 200 {object} payment_system.PaymentResponse "contain url to redirect user"
+// @Failure 400 {object} payment_system.PaymentResponse "contain error description about data validation error"
+// @Failure 402 {object} payment_system.PaymentResponse "contain error description about error on payment system side"
+// @Failure 429 {object} model.Error "Too many requests"
+// @Failure 500 {object} payment_system.PaymentResponse "contain error description about error on PSP (P1) side"
+// @Router /api/v1/payment [post]
 func (r *orderRoute) processCreatePayment(ctx echo.Context) error {
 	data := make(map[string]string)
 	err := (&PaymentCreateProcessBinder{}).Bind(data, ctx)
@@ -469,7 +658,7 @@ func (r *orderRoute) processCreatePayment(ctx echo.Context) error {
 		UserAgent:      ctx.Request().Header.Get(HeaderUserAgent),
 		Ip:             ctx.RealIP(),
 	}
-	rsp, err := r.billingService.PaymentCreateProcess(context.TODO(), req)
+	rsp, err := r.billingService.PaymentCreateProcess(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, model.ResponseMessageUnknownError)
@@ -509,7 +698,7 @@ func (r *orderRoute) getRevenueDynamic(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	pMap, _, err := r.projectManager.FilterProjects(r.Merchant.Identifier, rdr.Project)
+	pMap, _, err := r.projectManager.FilterProjects(ctx.Request().Context(), getRequestContext(ctx).MerchantIdentifier, rdr.Project)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusForbidden, err.Error())
@@ -548,7 +737,7 @@ func (r *orderRoute) getAccountingPaymentCalculation(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := r.orderManager.GetAccountingPayment(rdr, r.Merchant.Identifier)
+	res, err := r.orderManager.GetAccountingPayment(rdr, getRequestContext(ctx).MerchantIdentifier)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
@@ -557,6 +746,20 @@ func (r *orderRoute) getAccountingPaymentCalculation(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
+// @Summary Get refund data
+// @Description Get refund data
+// @Tags Order
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "order identifier"
+// @Param refund_id path string true "refund identifier"
+// @Success 200 {object} order.Refund "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/{order_id}/refunds/{refund_id} [get]
 func (r *orderRoute) getRefund(ctx echo.Context) error {
 	req := &grpc.GetRefundRequest{
 		OrderId:  ctx.Param(requestParameterOrderId),
@@ -569,7 +772,13 @@ func (r *orderRoute) getRefund(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.GetRefund(context.TODO(), req)
+	_, err = r.getMerchantOrder(ctx, req.OrderId)
+
+	if err != nil {
+		return err
+	}
+
+	rsp, err := r.billingService.GetRefund(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -582,6 +791,24 @@ func (r *orderRoute) getRefund(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.Item)
 }
 
+// @Summary Get list of refunds to order
+// @Description Get list of refunds to order
+// @Tags Order
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "order identifier"
+// @Param limit query string false "count of records to need to return"
+// @Param offset query string false "number of record which must be first in listing"
+// @Param cursor query string false "cursor of page returned in next_cursor or prev_cursor. empty value requests first page in cursor pagination mode"
+// @Param reason query string false "code of refund reason: fraud, customer_request, duplicate or technical"
+// @Param status query integer false "refund status: 0 - created, 1 - rejected, 2 - in processing, 3 - completed"
+// @Success 200 {array} order.Refund "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/{order_id}/refunds [get]
 func (r *orderRoute) listRefunds(ctx echo.Context) error {
 	req := &grpc.ListRefundsRequest{}
 	err := (&OrderListRefundsBinder{}).Bind(req, ctx)
@@ -596,15 +823,74 @@ func (r *orderRoute) listRefunds(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ListRefunds(context.TODO(), req)
+	_, err = r.getMerchantOrder(ctx, req.OrderId)
+
+	if err != nil {
+		return err
+	}
+
+	reason, status, err := getRefundsFilter(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	rc := getRequestContext(ctx)
+	req.Offset, err = getListingCursorOffset(rc, req.Offset)
+
+	if err != nil {
+		return err
+	}
+
+	var rsp *grpc.ListRefundsResponse
+
+	// billing server can't filter refunds, so all refunds of order filtered and paginated here
+	if reason != "" || status != refundStatusAny {
+		rsp, err = r.listFilteredRefunds(ctx.Request().Context(), req, reason, status)
+
+		if err != nil {
+			return err
+		}
+	} else {
+		rsp, err = r.billingService.ListRefunds(ctx.Request().Context(), req)
+
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+		}
+	}
+
+	cursors, err := getListingCursors(rc, req.Offset, req.Limit, int32(rsp.Count))
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
 
-	return ctx.JSON(http.StatusOK, rsp)
+	return ctx.JSON(http.StatusOK, &struct {
+		*grpc.ListRefundsResponse
+		*listingCursors
+	}{rsp, cursors})
 }
 
+// @Summary Create new refund to order
+// @Description Create new refund to order. Order can be refunded partially by several refunds, amount of refund
+// @Description can't be greater than refundable amount returned by refund preview. Reason of refund must be code
+// @Description from catalogue of refund reasons. If refund matched by approval rule of merchant, then refund
+// @Description not created immediately and waits for approval by other user
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "order identifier"
+// @Param data body order.Refund.CreateRequest true "refund data"
+// @Success 201 {object} order.Refund "OK"
+// @Success 202 {object} model.RefundApproval "Refund waits for approval"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 409 {object} model.Error "Other refund of order is in process"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/{order_id}/refunds [post]
 func (r *orderRoute) createRefund(ctx echo.Context) error {
 	req := &grpc.CreateRefundRequest{}
 	err := ctx.Bind(req)
@@ -620,8 +906,71 @@ func (r *orderRoute) createRefund(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	req.CreatorId = r.authUser.Id
-	rsp, err := r.billingService.CreateRefund(context.TODO(), req)
+	if !model.IsRefundReasonValid(req.Reason) {
+		return echo.NewHTTPError(http.StatusBadRequest, errorMessageRefundReasonUnknown)
+	}
+
+	unlock, err := r.lockOrderRefunds(ctx, req.OrderId)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	o, preview, err := r.getRefundPreviewByOrder(ctx, req.OrderId)
+
+	if err != nil {
+		return err
+	}
+
+	// amount compared with refundable amount and sent to billing server in minor units of currency
+	req.Amount = model.RoundCurrencyAmount(req.Amount, preview.Currency)
+
+	if req.Amount <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, errorMessageRefundAmountIncorrect)
+	}
+
+	if req.Amount > preview.RefundableAmount {
+		return echo.NewHTTPError(
+			http.StatusBadRequest,
+			fmt.Sprintf(
+				errorMessageRefundAmountExceeded,
+				model.GetCurrencyPrecision(preview.Currency),
+				preview.RefundableAmount,
+				preview.Currency,
+			),
+		)
+	}
+
+	req.CreatorId = getRequestContext(ctx).AuthUser.Id
+	rules, err := r.refundApprovalStore.FindRules(o.Project.Merchant.Id)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	// refund matched by approval rule created in billing server only after approval by other user
+	if rule := model.FindRefundApprovalRule(rules, o, preview.Currency, req.Amount); rule != nil {
+		a := &model.RefundApproval{
+			OrderId:    req.OrderId,
+			MerchantId: o.Project.Merchant.Id,
+			RuleId:     rule.Id,
+			Amount:     req.Amount,
+			Currency:   preview.Currency,
+			Reason:     req.Reason,
+			CreatorId:  req.CreatorId,
+		}
+		err = r.refundApprovalStore.Insert(a)
+
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+		}
+
+		return ctx.JSON(http.StatusAccepted, a)
+	}
+
+	rsp, err := r.billingService.CreateRefund(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -634,6 +983,244 @@ func (r *orderRoute) createRefund(ctx echo.Context) error {
 	return ctx.JSON(http.StatusCreated, rsp.Item)
 }
 
+// @Summary Get refund preview
+// @Description Get amounts which can be refunded to order after previous refunds in order payment currency and
+// @Description in merchant accounting currency, and catalogue of refund reasons
+// @Tags Order
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "order identifier"
+// @Success 200 {object} model.RefundPreview "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/{order_id}/refunds/preview [get]
+func (r *orderRoute) getRefundPreview(ctx echo.Context) error {
+	_, preview, err := r.getRefundPreviewByOrder(ctx, ctx.Param(requestParameterOrderId))
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, preview)
+}
+
+// Lock creation of refunds of order while refundable amount checked and refund created, so concurrent
+// requests can't refund more than refundable amount. Lock saved to idempotency store, so it shared between
+// instances of service and expires if instance stopped while holding it. Returned function releases lock
+func (r *orderRoute) lockOrderRefunds(ctx echo.Context, orderId string) (func(), error) {
+	now := time.Now()
+	record := &model.IdempotencyRecord{
+		Id:        getIdempotencyScope(idempotencyScopeRefundLock, orderId),
+		Status:    model.IdempotencyRecordStatusProcessing,
+		CreatedAt: now,
+		ExpiresAt: now.Add(refundLockTimeout),
+	}
+	_, inserted, err := r.idempotencyStore.Insert(record)
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	if !inserted {
+		return nil, echo.NewHTTPError(http.StatusConflict, errorMessageRefundInProcess)
+	}
+
+	return func() { r.releaseIdempotencyRecord(ctx, record.Id) }, nil
+}
+
+// Get refund preview of order of merchant of authenticated user
+func (r *orderRoute) getRefundPreviewByOrder(ctx echo.Context, orderId string) (*model.Order, *model.RefundPreview, error) {
+	o, err := r.getMerchantOrder(ctx, orderId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	preview, err := r.getOrderRefundPreview(ctx.Request().Context(), o, orderId, "")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return o, preview, nil
+}
+
+// Get order of merchant of authenticated user by order uuid
+func (r *orderRoute) getMerchantOrder(ctx echo.Context, orderId string) (*model.Order, error) {
+	if err := r.validate.Var(orderId, "required,uuid"); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errorIncorrectOrderId)
+	}
+
+	merchant, err := r.getAuthMerchant(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := r.orderStore.FindByUuid(orderId)
+
+	if err == mgo.ErrNotFound {
+		return nil, echo.NewHTTPError(http.StatusNotFound, model.ResponseMessageNotFound)
+	}
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	// order of other merchant shown as not existing order to not disclose identifiers of orders
+	if o.Project == nil || o.Project.Merchant == nil || o.Project.Merchant.Id != merchant.Id {
+		return nil, echo.NewHTTPError(http.StatusNotFound, model.ResponseMessageNotFound)
+	}
+
+	return o, nil
+}
+
+// Get merchant of authenticated user
+func (r *orderRoute) getAuthMerchant(ctx echo.Context) (*billing.Merchant, error) {
+	req := &grpc.GetMerchantByRequest{UserId: getRequestContext(ctx).AuthUser.Id}
+	rsp, err := r.billingService.GetMerchantBy(ctx.Request().Context(), req)
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	if rsp.Status != pkg.ResponseStatusOk {
+		return nil, echo.NewHTTPError(int(rsp.Status), rsp.Message)
+	}
+
+	return rsp.Item, nil
+}
+
+// Calculate refund preview of order. Refunds waiting for approval decrease refundable amount, except of
+// approval with identifier skipId which is being approved now
+func (r *orderRoute) getOrderRefundPreview(
+	ctx context.Context,
+	o *model.Order,
+	orderId string,
+	skipId bson.ObjectId,
+) (*model.RefundPreview, error) {
+	refunds, err := r.listAllRefunds(ctx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := r.refundApprovalStore.FindPendingByOrderId(orderId)
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	preview := model.NewRefundPreview(o, orderId, refunds)
+
+	for _, a := range pending {
+		if a.Id != skipId {
+			preview.AddPendingApproval(a)
+		}
+	}
+
+	return preview, nil
+}
+
+func (r *orderRoute) listAllRefunds(ctx context.Context, orderId string) ([]*billing.Refund, error) {
+	var refunds []*billing.Refund
+
+	req := &grpc.ListRefundsRequest{OrderId: orderId, Limit: LimitDefault}
+
+	for {
+		rsp, err := r.billingService.ListRefunds(ctx, req)
+
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+		}
+
+		refunds = append(refunds, rsp.Items...)
+		req.Offset += int32(len(rsp.Items))
+
+		if len(rsp.Items) == 0 || req.Offset >= int32(rsp.Count) {
+			return refunds, nil
+		}
+	}
+}
+
+// Get page of refunds of order with specified reason and status. Status filter not applied if it equals
+// to refundStatusAny
+func (r *orderRoute) listFilteredRefunds(
+	ctx context.Context,
+	req *grpc.ListRefundsRequest,
+	reason string,
+	status int,
+) (*grpc.ListRefundsResponse, error) {
+	refunds, err := r.listAllRefunds(ctx, req.OrderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []*billing.Refund
+
+	for _, v := range refunds {
+		if (reason != "" && v.Reason != reason) || (status != refundStatusAny && int(v.Status) != status) {
+			continue
+		}
+
+		filtered = append(filtered, v)
+	}
+
+	rsp := &grpc.ListRefundsResponse{Count: int32(len(filtered)), Items: []*billing.Refund{}}
+
+	if int(req.Offset) < len(filtered) {
+		end := int(req.Offset + req.Limit)
+
+		if end > len(filtered) {
+			end = len(filtered)
+		}
+
+		rsp.Items = filtered[req.Offset:end]
+	}
+
+	return rsp, nil
+}
+
+// Get filters of refunds list from query parameters. If status not specified, then refundStatusAny returned
+func getRefundsFilter(ctx echo.Context) (string, int, error) {
+	reason := ctx.QueryParam(requestParameterReason)
+
+	if reason != "" && !model.IsRefundReasonValid(reason) {
+		return "", 0, echo.NewHTTPError(http.StatusBadRequest, errorMessageRefundReasonUnknown)
+	}
+
+	value := ctx.QueryParam(requestParameterStatus)
+
+	if value == "" {
+		return reason, refundStatusAny, nil
+	}
+
+	status, err := strconv.Atoi(value)
+
+	if err != nil || status < model.RefundStatusCreated || status > model.RefundStatusCompleted {
+		return "", 0, echo.NewHTTPError(http.StatusBadRequest, errorMessageStatusIncorrectType)
+	}
+
+	return reason, status, nil
+}
+
+// @Summary Change payment form language
+// @Description Change language of payment form and recalculate order data by language
+// @Tags Payment Order
+// @Accept json
+// @Produce json
+// @Param order_id path string true "Order unique identifier"
+// @Param data body order.ChangeLanguageRequest true "New language of payment form"
+// @Success 200 {object} order.PaymentFormChangedResponse "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 429 {object} model.Error "Too many requests"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /api/v1/orders/{order_id}/language [patch]
 func (r *orderRoute) changeLanguage(ctx echo.Context) error {
 	orderId := ctx.Param(requestParameterOrderId)
 
@@ -658,7 +1245,7 @@ func (r *orderRoute) changeLanguage(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.PaymentFormLanguageChanged(context.TODO(), req)
+	rsp, err := r.billingService.PaymentFormLanguageChanged(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -671,6 +1258,19 @@ func (r *orderRoute) changeLanguage(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.Item)
 }
 
+// @Summary Change payment account
+// @Description Change user payment account on payment form and recalculate order data by account
+// @Tags Payment Order
+// @Accept json
+// @Produce json
+// @Param order_id path string true "Order unique identifier"
+// @Param data body order.ChangeCustomerRequest true "New payment account of user"
+// @Success 200 {object} order.PaymentFormChangedResponse "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 429 {object} model.Error "Too many requests"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /api/v1/orders/{order_id}/customer [patch]
 func (r *orderRoute) changeCustomer(ctx echo.Context) error {
 	orderId := ctx.Param(requestParameterOrderId)
 
@@ -695,7 +1295,7 @@ func (r *orderRoute) changeCustomer(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.PaymentFormPaymentAccountChanged(context.TODO(), req)
+	rsp, err := r.billingService.PaymentFormPaymentAccountChanged(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -708,6 +1308,19 @@ func (r *orderRoute) changeCustomer(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.Item)
 }
 
+// @Summary Set billing address
+// @Description Set user billing address to order and recalculate order amounts by address
+// @Tags Payment Order
+// @Accept json
+// @Produce json
+// @Param order_id path string true "Order unique identifier"
+// @Param data body order.BillingAddress true "User billing address"
+// @Success 200 {object} order.BillingAddressResponse "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 429 {object} model.Error "Too many requests"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /api/v1/orders/{order_id}/billing_address [post]
 func (r *orderRoute) processBillingAddress(ctx echo.Context) error {
 	orderId := ctx.Param(requestParameterOrderId)
 
@@ -729,7 +1342,7 @@ func (r *orderRoute) processBillingAddress(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ProcessBillingAddress(context.TODO(), req)
+	rsp, err := r.billingService.ProcessBillingAddress(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
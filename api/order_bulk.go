@@ -0,0 +1,325 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// progress of job saved after every batch of processed orders
+	orderBulkJobProgressBatch = 50
+	orderBulkJobEventReason   = "notification resent by bulk operation %s"
+)
+
+type orderBulkRoute struct {
+	*orderRoute
+	jobManager *manager.OrderBulkJobManager
+}
+
+func (api *Api) initOrderBulkRoutes() *Api {
+	route := &orderBulkRoute{
+		orderRoute: &orderRoute{
+			Api: api,
+			orderManager: manager.InitOrderManager(
+				api.database,
+				api.logger,
+				api.notifierPub,
+				api.repository,
+				api.geoService,
+			),
+			projectManager: manager.InitProjectManager(api.database, api.logger, api.billingService),
+		},
+		jobManager: manager.InitOrderBulkJobManager(api.database, api.logger),
+	}
+
+	api.authUserRouteGroup.POST("/order/bulk", route.createJob, api.requireRoles(rolesMerchantWrite...))
+	api.authUserRouteGroup.GET("/order/bulk/:job_id", route.getJob, api.requireRoles(rolesMerchantWrite...))
+
+	api.backgroundWorkers = append(api.backgroundWorkers, route.runJobs)
+
+	return api
+}
+
+// @Summary Start bulk operation with orders
+// @Description Start background job which executes action for every order from list of identifiers or for
+// @Description every order matched by query of orders list. Available actions: resend_notification sends
+// @Description notification about paid order to project again, mark_for_review marks order for manual review,
+// @Description add_note adds text note to order. Progress and per-order results of job can be get by job identifier
+// @Tags Payment Order
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body model.OrderBulkJob true "Bulk operation data"
+// @Success 202 {object} model.OrderBulkJob "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/bulk [post]
+func (r *orderBulkRoute) createJob(ctx echo.Context) error {
+	j := &model.OrderBulkJob{}
+	err := ctx.Bind(j)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
+	}
+
+	err = r.validate.Struct(j)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
+	}
+
+	if (len(j.OrderIds) > 0) == (len(j.Query) > 0) {
+		return echo.NewHTTPError(http.StatusBadRequest, errorMessageOrderBulkOrdersRequired)
+	}
+
+	if j.Action == model.OrderBulkActionAddNote && strings.TrimSpace(j.Note) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, errorMessageOrderBulkNoteRequired)
+	}
+
+	for field := range j.Query {
+		if !model.OrderFilterQueryFields[field] {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(errorMessageOrderFilterQueryIncorrect, field))
+		}
+	}
+
+	values := url.Values(j.Query)
+	fp, err := getOrderFilterProjects(values)
+
+	if err != nil {
+		return err
+	}
+
+	p, merchant, err := r.getMerchantProjects(ctx, fp)
+
+	if err != nil {
+		return err
+	}
+
+	if len(j.Query) > 0 {
+		params := &manager.FindAll{Values: values, Projects: p, Merchant: merchant, SortBy: model.DefaultSort}
+
+		if sort, ok := values[model.QueryParameterNameSort]; ok {
+			params.SortBy = sort
+		}
+
+		// one more order requested to find out that query matches too many orders
+		j.OrderIds, err = r.orderManager.FindAllIds(params, model.OrderBulkMaxOrders+1)
+
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+		}
+
+		if len(j.OrderIds) > model.OrderBulkMaxOrders {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				fmt.Sprintf(errorMessageOrderBulkTooManyOrders, model.OrderBulkMaxOrders),
+			)
+		}
+	}
+
+	j.OrderIds = getUniqueOrderIds(j.OrderIds)
+	j.UserId = getRequestContext(ctx).AuthUser.Id
+	j.MerchantId = merchant.Id
+	err = r.jobManager.Insert(j)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusAccepted, j)
+}
+
+// @Summary Get bulk operation with orders
+// @Description Get status, progress and per-order results of bulk operation started by authenticated user
+// @Tags Payment Order
+// @Produce json
+// @Security BearerAuth
+// @Param job_id path string true "bulk operation unique identifier"
+// @Success 200 {object} model.OrderBulkJob "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/bulk/{job_id} [get]
+func (r *orderBulkRoute) getJob(ctx echo.Context) error {
+	id := ctx.Param(requestParameterJobId)
+
+	if !bson.IsObjectIdHex(id) {
+		return echo.NewHTTPError(http.StatusBadRequest, errorIncorrectOrderBulkJobId)
+	}
+
+	j, err := r.jobManager.FindById(bson.ObjectIdHex(id), getRequestContext(ctx).AuthUser.Id)
+
+	if err == mgo.ErrNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, model.ResponseMessageNotFound)
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusOK, j)
+}
+
+// Process pending bulk operations. Runs until context canceled
+func (r *orderBulkRoute) runJobs(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(r.config.OrderBulkJobInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.processJobs(ctx)
+		}
+	}
+}
+
+func (r *orderBulkRoute) processJobs(ctx context.Context) {
+	for ctx.Err() == nil {
+		// job reserved by one instance of service only, so several instances can process jobs concurrently
+		j, err := r.jobManager.Reserve(time.Now())
+
+		if err != nil || j == nil {
+			return
+		}
+
+		r.processReservedJob(ctx, j)
+	}
+}
+
+// Panic while processing of job fails only this job, other jobs processed further
+func (r *orderBulkRoute) processReservedJob(ctx context.Context, j *model.OrderBulkJob) {
+	defer func() {
+		rec := recover()
+
+		if rec == nil {
+			return
+		}
+
+		r.logError(ctx, "Bulk operation panicked", []interface{}{"error", fmt.Sprint(rec), "job_id", j.Id.Hex()})
+
+		j.Status = model.OrderBulkJobStatusFailed
+		j.Error = errorMessageOrderBulkJobFailed
+
+		if err := r.jobManager.SaveProgress(j); err != nil {
+			r.logError(ctx, "Save of bulk operation progress failed", []interface{}{"error", err.Error(), "job_id", j.Id.Hex()})
+		}
+	}()
+
+	r.processJob(ctx, j)
+}
+
+// Execute action of job for orders which not processed yet. Progress saved periodically, so job
+// abandoned by stopped instance of service continued from last saved order
+func (r *orderBulkRoute) processJob(ctx context.Context, j *model.OrderBulkJob) {
+	// projects requested again because user can lose access to merchant after job creation
+	p, merchant, err := r.getUserMerchantProjects(ctx, j.UserId, []bson.ObjectId{})
+
+	if err != nil || merchant.Id != j.MerchantId {
+		j.Status = model.OrderBulkJobStatusFailed
+		j.Error = errorMessageOrderBulkMerchantNotFound
+		_ = r.jobManager.SaveProgress(j)
+
+		return
+	}
+
+	for j.Processed < j.Total {
+		// job stays in progress and will be continued by other instance of service
+		if ctx.Err() != nil {
+			break
+		}
+
+		id := j.OrderIds[j.Processed]
+		status, message := r.processJobOrder(j, id, p)
+		j.AddResult(id, status, message)
+
+		if j.Processed%orderBulkJobProgressBatch == 0 && j.Processed < j.Total {
+			_ = r.jobManager.SaveProgress(j)
+		}
+	}
+
+	err = r.jobManager.SaveProgress(j)
+
+	if err != nil {
+		r.logError(ctx, "Save of bulk operation progress failed", []interface{}{"error", err.Error(), "job_id", j.Id.Hex()})
+	}
+}
+
+// Execute action of job for one order. Returns status of result and reason why action skipped or failed
+func (r *orderBulkRoute) processJobOrder(
+	j *model.OrderBulkJob,
+	id bson.ObjectId,
+	p map[bson.ObjectId]string,
+) (string, string) {
+	o := r.orderManager.FindById(id.Hex())
+
+	// order of other merchant shown as not existing order to not disclose identifiers of orders
+	if o == nil || o.Project == nil {
+		return model.OrderBulkResultStatusFailed, errorMessageOrderBulkOrderNotFound
+	}
+
+	if _, ok := p[o.Project.Id]; !ok {
+		return model.OrderBulkResultStatusFailed, errorMessageOrderBulkOrderNotFound
+	}
+
+	var err error
+
+	switch j.Action {
+	case model.OrderBulkActionResendNotification:
+		if !o.CanResendNotification() {
+			return model.OrderBulkResultStatusSkipped, errorMessageOrderBulkResendNotAllowed
+		}
+
+		event := &model.OrderEvent{
+			Actor:  j.UserId,
+			Source: model.OrderEventSourceAdmin,
+			Reason: fmt.Sprintf(orderBulkJobEventReason, j.Id.Hex()),
+		}
+		err = r.orderManager.ResendNotification(o, event)
+	case model.OrderBulkActionMarkForReview:
+		if o.Review != nil {
+			return model.OrderBulkResultStatusSkipped, errorMessageOrderBulkReviewRequested
+		}
+
+		err = r.orderManager.MarkForReview(o, j.UserId)
+	case model.OrderBulkActionAddNote:
+		err = r.orderManager.AddNote(o, j.UserId, j.Note)
+	}
+
+	if err != nil {
+		return model.OrderBulkResultStatusFailed, err.Error()
+	}
+
+	return model.OrderBulkResultStatusOk, ""
+}
+
+// Remove repeated identifiers from list of orders keeping order of first occurrences
+func getUniqueOrderIds(ids []bson.ObjectId) []bson.ObjectId {
+	unique := make([]bson.ObjectId, 0, len(ids))
+	exists := make(map[bson.ObjectId]bool, len(ids))
+
+	for _, id := range ids {
+		if exists[id] {
+			continue
+		}
+
+		unique = append(unique, id)
+		exists[id] = true
+	}
+
+	return unique
+}
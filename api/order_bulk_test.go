@@ -0,0 +1,199 @@
+package api
+
+import (
+	"fmt"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/config"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type OrderBulkTestSuite struct {
+	suite.Suite
+	router *orderBulkRoute
+	api    *Api
+}
+
+func Test_OrderBulk(t *testing.T) {
+	suite.Run(t, new(OrderBulkTestSuite))
+}
+
+func (suite *OrderBulkTestSuite) SetupTest() {
+	suite.api = &Api{
+		Http:           echo.New(),
+		validate:       validator.New(),
+		billingService: mock.NewBillingServerOkMock(),
+		config: &config.Config{
+			Environment: "test",
+		},
+	}
+	suite.router = &orderBulkRoute{orderRoute: &orderRoute{Api: suite.api}}
+}
+
+func (suite *OrderBulkTestSuite) TearDownTest() {}
+
+func (suite *OrderBulkTestSuite) createJob(body string) error {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	ctx := suite.api.Http.NewContext(req, httptest.NewRecorder())
+	ctx.SetPath("/order/bulk")
+
+	return suite.router.createJob(ctx)
+}
+
+func (suite *OrderBulkTestSuite) TestOrderBulk_Create_Error() {
+	id := bson.NewObjectId().Hex()
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{
+			name:    "incorrect json",
+			body:    `{"action":`,
+			message: errorQueryParamsIncorrect,
+		},
+		{
+			name:    "incorrect order identifier",
+			body:    `{"action":"mark_for_review","order_ids":["order"]}`,
+			message: errorQueryParamsIncorrect,
+		},
+		{
+			name:    "action not specified",
+			body:    `{"order_ids":["` + id + `"]}`,
+			message: fmt.Sprintf(errorMessageMask, "Action", "required"),
+		},
+		{
+			name:    "unknown action",
+			body:    `{"action":"refund","order_ids":["` + id + `"]}`,
+			message: fmt.Sprintf(errorMessageMask, "Action", "oneof"),
+		},
+		{
+			name:    "orders not specified",
+			body:    `{"action":"mark_for_review"}`,
+			message: errorMessageOrderBulkOrdersRequired,
+		},
+		{
+			name:    "both orders and query specified",
+			body:    `{"action":"mark_for_review","order_ids":["` + id + `"],"query":{"status[]":["7"]}}`,
+			message: errorMessageOrderBulkOrdersRequired,
+		},
+		{
+			name:    "note not specified",
+			body:    `{"action":"add_note","note":"  ","order_ids":["` + id + `"]}`,
+			message: errorMessageOrderBulkNoteRequired,
+		},
+		{
+			name:    "unknown query parameter",
+			body:    `{"action":"resend_notification","query":{"amount":["100"]}}`,
+			message: fmt.Sprintf(errorMessageOrderFilterQueryIncorrect, "amount"),
+		},
+		{
+			name:    "incorrect project identifier",
+			body:    `{"action":"resend_notification","query":{"project[]":["project"]}}`,
+			message: model.ResponseMessageProjectIdIsInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		err := suite.createJob(tt.body)
+
+		if !assert.Error(suite.T(), err, tt.name) {
+			continue
+		}
+
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok, tt.name)
+		assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code, tt.name)
+
+		// validation errors returned in structured form
+		if vErr, ok := httpErr.Message.(*model.Error); ok {
+			assert.Equal(suite.T(), tt.message, vErr.Message, tt.name)
+			continue
+		}
+
+		assert.Equal(suite.T(), tt.message, httpErr.Message, tt.name)
+	}
+}
+
+func (suite *OrderBulkTestSuite) TestOrderBulk_Create_BillingServerError() {
+	suite.router.billingService = mock.NewBillingServerSystemErrorMock()
+	err := suite.createJob(`{"action":"add_note","note":"game server was down","query":{"status[]":["7"]}}`)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusInternalServerError, httpErr.Code)
+	assert.Equal(suite.T(), errorUnknown, httpErr.Message)
+}
+
+func (suite *OrderBulkTestSuite) TestOrderBulk_Get_JobIdIncorrect_Error() {
+	ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	ctx.SetPath("/order/bulk/:job_id")
+	ctx.SetParamNames(requestParameterJobId)
+	ctx.SetParamValues("job")
+
+	err := suite.router.getJob(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), errorIncorrectOrderBulkJobId, httpErr.Message)
+}
+
+func (suite *OrderBulkTestSuite) TestOrderBulk_UniqueOrderIds_Ok() {
+	id1, id2 := bson.NewObjectId(), bson.NewObjectId()
+	ids := getUniqueOrderIds([]bson.ObjectId{id1, id2, id1, id2, id1})
+	assert.Equal(suite.T(), []bson.ObjectId{id1, id2}, ids)
+}
+
+func (suite *OrderBulkTestSuite) TestOrderBulk_AddResult_Ok() {
+	j := &model.OrderBulkJob{OrderIds: []bson.ObjectId{bson.NewObjectId(), bson.NewObjectId(), bson.NewObjectId()}}
+	j.AddResult(j.OrderIds[0], model.OrderBulkResultStatusOk, "")
+	j.AddResult(j.OrderIds[1], model.OrderBulkResultStatusSkipped, errorMessageOrderBulkReviewRequested)
+	j.AddResult(j.OrderIds[2], model.OrderBulkResultStatusFailed, errorMessageOrderBulkOrderNotFound)
+
+	assert.Equal(suite.T(), 3, j.Processed)
+	assert.Equal(suite.T(), 1, j.Failed)
+	assert.Len(suite.T(), j.Results, 3)
+	assert.Equal(suite.T(), j.OrderIds[2], j.Results[2].OrderId)
+	assert.Equal(suite.T(), errorMessageOrderBulkOrderNotFound, j.Results[2].Message)
+}
+
+func (suite *OrderBulkTestSuite) TestOrderBulk_CanResendNotification() {
+	allowed := map[int]bool{
+		model.OrderStatusPaymentSystemComplete: true,
+		model.OrderStatusProjectInProgress:     true,
+		model.OrderStatusProjectPending:        true,
+	}
+
+	for status := range model.OrderStatusesNames {
+		o := &model.Order{Status: status}
+		assert.Equal(suite.T(), allowed[status], o.CanResendNotification(), model.OrderStatusesNames[status])
+	}
+}
+
+func (suite *OrderBulkTestSuite) TestOrderBulk_ResendNotification_NotifierNotConfigured_Error() {
+	om := manager.InitOrderManager(nil, zap.NewNop().Sugar(), nil, nil, nil)
+	o := &model.Order{Id: bson.NewObjectId(), Status: model.OrderStatusProjectPending}
+
+	assert.NotPanics(suite.T(), func() {
+		err := om.ResendNotification(o, &model.OrderEvent{})
+		assert.Error(suite.T(), err)
+	})
+
+	// order not changed, because notification can't be sent
+	assert.Equal(suite.T(), model.OrderStatusProjectPending, o.Status)
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"github.com/paysuper/paysuper-management-api/utils"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	orderExportFileNameMask = "attachment; filename=\"%s_%s.%s\""
+	orderExportFileTimeMask = "20060102_150405"
+	orderExportFlushRows    = 1000
+)
+
+// Column of orders export. Amounts of columns are in accounting currency of merchant
+type orderExportColumn struct {
+	name  string
+	value func(o *model.OrderSimple, currency string) interface{}
+}
+
+var orderExportColumns = []*orderExportColumn{
+	{name: "id", value: func(o *model.OrderSimple, _ string) interface{} { return o.Id.Hex() }},
+	{name: "project_id", value: func(o *model.OrderSimple, _ string) interface{} { return o.Project.Id.Hex() }},
+	{name: "project_name", value: func(o *model.OrderSimple, _ string) interface{} { return o.Project.Name }},
+	{name: "account", value: func(o *model.OrderSimple, _ string) interface{} { return o.Account }},
+	{name: "order_id", value: func(o *model.OrderSimple, _ string) interface{} { return o.ProjectOrderId }},
+	{
+		name: "payer_country",
+		value: func(o *model.OrderSimple, _ string) interface{} {
+			if o.PayerData == nil {
+				return nil
+			}
+
+			return o.PayerData.CountryCodeA2
+		},
+	},
+	{
+		name: "payment_method",
+		value: func(o *model.OrderSimple, _ string) interface{} {
+			if o.PaymentMethod == nil {
+				return nil
+			}
+
+			return o.PaymentMethod.Name
+		},
+	},
+	{name: "status", value: func(o *model.OrderSimple, _ string) interface{} { return o.Status.Name }},
+	{name: "currency", value: func(_ *model.OrderSimple, currency string) interface{} { return currency }},
+	{
+		name: "merchant_amount_income",
+		value: func(o *model.OrderSimple, _ string) interface{} {
+			if o.MerchantAmountIncome == nil {
+				return float64(0)
+			}
+
+			return o.MerchantAmountIncome.Amount
+		},
+	},
+	{
+		name: "project_amount_outcome",
+		value: func(o *model.OrderSimple, _ string) interface{} {
+			if o.ProjectAmountOutcome == nil {
+				return float64(0)
+			}
+
+			return o.ProjectAmountOutcome.Amount
+		},
+	},
+	{
+		name: "psp_fee",
+		value: func(o *model.OrderSimple, _ string) interface{} {
+			if o.PspFeeAmount == nil {
+				return float64(0)
+			}
+
+			return o.PspFeeAmount.AmountMerchantCurrency
+		},
+	},
+	{
+		name: "ps_fee_amount",
+		value: func(o *model.OrderSimple, _ string) interface{} {
+			if o.PaymentSystemFeeAmount == nil {
+				return float64(0)
+			}
+
+			return o.PaymentSystemFeeAmount.AmountMerchantCurrency
+		},
+	},
+	{
+		name: "project_fee_amount",
+		value: func(o *model.OrderSimple, _ string) interface{} {
+			if o.ProjectFeeAmount == nil {
+				return float64(0)
+			}
+
+			return o.ProjectFeeAmount.AmountMerchantCurrency
+		},
+	},
+	{
+		name: "to_payer_fee_amount",
+		value: func(o *model.OrderSimple, _ string) interface{} {
+			if o.ToPayerFeeAmount == nil {
+				return float64(0)
+			}
+
+			return o.ToPayerFeeAmount.AmountMerchantCurrency
+		},
+	},
+	{name: "created_at", value: func(o *model.OrderSimple, _ string) interface{} { return formatOrderExportDate(o.CreatedAt) }},
+	{name: "confirmed_at", value: func(o *model.OrderSimple, _ string) interface{} { return formatOrderExportDate(o.ConfirmedAt) }},
+	{name: "closed_at", value: func(o *model.OrderSimple, _ string) interface{} { return formatOrderExportDate(o.ClosedAt) }},
+}
+
+// @Summary Export orders
+// @Description Export all orders matched by filters of orders list to file. Orders streamed from database,
+// @Description so export not limited by count of orders. Amounts exported in accounting currency of merchant
+// @Tags Payment Order
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param format query string true "export file format: csv, xlsx or jsonl"
+// @Param columns query string false "comma separated list of exported columns. all columns exported by default"
+// @Param project query array false "list of projects to get orders filtered by they"
+// @Param quick_filter query string false "search by beginning of order identifier, order identifier in project or payment system, account, payer email or phone, masked card number or its last 4 digits, name of project, fixed package or payment method. value in double quotes searched by exact match"
+// @Param sort query array false "fields list for sorting"
+// @Success 200 {file} file "Export file"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/export [get]
+func (r *orderRoute) exportOrders(ctx echo.Context) error {
+	return r.sendOrderExport(ctx, "orders", r.getFindAllParams)
+}
+
+// Send file with orders matched by filters got from request by getParams function. Name of file starts with prefix
+func (r *orderRoute) sendOrderExport(
+	ctx echo.Context,
+	prefix string,
+	getParams func(ctx echo.Context) (*manager.FindAll, error),
+) error {
+	format := ctx.QueryParam(requestParameterFormat)
+	contentType, ok := utils.TableFormatContentTypes[format]
+
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, errorMessageExportFormatIncorrect)
+	}
+
+	columns, err := getOrderExportColumns(ctx.QueryParam(requestParameterColumns))
+
+	if err != nil {
+		return err
+	}
+
+	params, err := getParams(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf(orderExportFileNameMask, prefix, time.Now().UTC().Format(orderExportFileTimeMask), format)
+	rsp := ctx.Response()
+	rsp.Header().Set(echo.HeaderContentType, contentType)
+	rsp.Header().Set(echo.HeaderContentDisposition, name)
+	rsp.WriteHeader(http.StatusOK)
+
+	// response already sent to client, so errors after this point only break file and written to log
+	return r.writeOrderExport(format, rsp, params, columns, func(count int) error {
+		if count%orderExportFlushRows == 0 {
+			rsp.Flush()
+		}
+
+		// stop reading of orders when client closed connection
+		return ctx.Request().Context().Err()
+	})
+}
+
+// Write orders matched by filters to file in specified format. Callback called after every written order
+// with count of written orders, export stopped if callback returned error
+func (r *orderRoute) writeOrderExport(
+	format string,
+	w io.Writer,
+	params *manager.FindAll,
+	columns []*orderExportColumn,
+	fn func(count int) error,
+) error {
+	names := make([]string, len(columns))
+
+	for i, column := range columns {
+		names[i] = column.name
+	}
+
+	currency := ""
+
+	if params.Merchant.Banking != nil && params.Merchant.Banking.Currency != nil {
+		currency = params.Merchant.Banking.Currency.CodeA3
+	}
+
+	tw, err := utils.NewTableWriter(format, w, names)
+
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	err = r.orderManager.FindAllIterate(params, func(o *model.OrderSimple) error {
+		row := make([]interface{}, len(columns))
+
+		for i, column := range columns {
+			row[i] = column.value(o, currency)
+		}
+
+		if err := tw.Write(row); err != nil {
+			return err
+		}
+
+		count++
+
+		return fn(count)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// Get exported columns by comma separated list of names. Empty list means all columns
+func getOrderExportColumns(list string) ([]*orderExportColumn, error) {
+	if list == "" {
+		return orderExportColumns, nil
+	}
+
+	var columns []*orderExportColumn
+
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+
+		for _, column := range orderExportColumns {
+			if column.name == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(errorMessageExportColumnUnknown, name))
+		}
+	}
+
+	return columns, nil
+}
+
+func formatOrderExportDate(ts int64) interface{} {
+	if ts <= 0 {
+		return nil
+	}
+
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type OrderExportTestSuite struct {
+	suite.Suite
+	router *orderRoute
+	order  *model.OrderSimple
+}
+
+func Test_OrderExport(t *testing.T) {
+	suite.Run(t, new(OrderExportTestSuite))
+}
+
+func (suite *OrderExportTestSuite) SetupTest() {
+	api := &Api{
+		Http:           echo.New(),
+		billingService: mock.NewBillingServerOkMock(),
+	}
+	suite.router = &orderRoute{Api: api}
+
+	suite.order = &model.OrderSimple{
+		Id:             bson.NewObjectId(),
+		Project:        &model.SimpleItem{Id: bson.NewObjectId(), Name: "Unit test project"},
+		Account:        "=HYPERLINK(\"http://unit.test\")",
+		ProjectOrderId: "order_1",
+		Status:         &model.Status{Name: "Payment processed"},
+		MerchantAmountIncome: &model.OrderSimpleAmountObject{
+			Amount: 100.5,
+		},
+		PspFeeAmount: &model.OrderFeePsp{AmountMerchantCurrency: 1.25},
+		CreatedAt:    time.Date(2019, 5, 20, 10, 0, 0, 0, time.UTC).Unix(),
+	}
+}
+
+func (suite *OrderExportTestSuite) TearDownTest() {}
+
+func (suite *OrderExportTestSuite) getRow(columns []*orderExportColumn) []interface{} {
+	row := make([]interface{}, len(columns))
+
+	for i, column := range columns {
+		row[i] = column.value(suite.order, "USD")
+	}
+
+	return row
+}
+
+func (suite *OrderExportTestSuite) export(query string) error {
+	req := httptest.NewRequest(http.MethodGet, "/order/export?"+query, nil)
+	rsp := httptest.NewRecorder()
+	ctx := suite.router.Http.NewContext(req, rsp)
+
+	return suite.router.exportOrders(ctx)
+}
+
+func (suite *OrderExportTestSuite) TestOrderExport_FormatIncorrect_Error() {
+	for _, query := range []string{"", "format=pdf"} {
+		err := suite.export(query)
+		assert.Error(suite.T(), err)
+
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok)
+		assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+		assert.Equal(suite.T(), errorMessageExportFormatIncorrect, httpErr.Message)
+	}
+}
+
+func (suite *OrderExportTestSuite) TestOrderExport_ColumnUnknown_Error() {
+	err := suite.export("format=csv&columns=id,secret_key")
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Regexp(suite.T(), "secret_key", httpErr.Message)
+}
+
+func (suite *OrderExportTestSuite) TestOrderExport_GetColumns_Ok() {
+	columns, err := getOrderExportColumns("")
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), columns, len(orderExportColumns))
+
+	columns, err = getOrderExportColumns("order_id, merchant_amount_income,currency,confirmed_at")
+
+	if assert.NoError(suite.T(), err) && assert.Len(suite.T(), columns, 4) {
+		assert.Equal(suite.T(), "order_id", columns[0].name)
+		assert.Equal(suite.T(), []interface{}{"order_1", 100.5, "USD", nil}, suite.getRow(columns))
+	}
+}
+
+func (suite *OrderExportTestSuite) TestOrderExport_Csv_Ok() {
+	columns, err := getOrderExportColumns("order_id,account,psp_fee,created_at")
+	assert.NoError(suite.T(), err)
+
+	buf := new(bytes.Buffer)
+	tw, err := utils.NewTableWriter(utils.TableFormatCsv, buf, []string{"order_id", "account", "psp_fee", "created_at"})
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	assert.NoError(suite.T(), tw.Write(suite.getRow(columns)))
+	assert.NoError(suite.T(), tw.Close())
+
+	records, err := csv.NewReader(buf).ReadAll()
+
+	if assert.NoError(suite.T(), err) && assert.Len(suite.T(), records, 2) {
+		assert.Equal(suite.T(), []string{"order_id", "account", "psp_fee", "created_at"}, records[0])
+		assert.Equal(suite.T(), []string{"order_1", "'" + suite.order.Account, "1.25", "2019-05-20T10:00:00Z"}, records[1])
+	}
+}
+
+func (suite *OrderExportTestSuite) TestOrderExport_Jsonl_Ok() {
+	buf := new(bytes.Buffer)
+	tw, err := utils.NewTableWriter(utils.TableFormatJsonl, buf, []string{"order_id", "merchant_amount_income", "confirmed_at"})
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	assert.NoError(suite.T(), tw.Write([]interface{}{"order_1", 100.5, nil}))
+	assert.NoError(suite.T(), tw.Write([]interface{}{"order_2", float64(0), "2019-05-20T10:00:00Z"}))
+	assert.NoError(suite.T(), tw.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if assert.Len(suite.T(), lines, 2) {
+		assert.Equal(suite.T(), `{"order_id":"order_1","merchant_amount_income":100.5,"confirmed_at":null}`, lines[0])
+
+		data := map[string]interface{}{}
+		assert.NoError(suite.T(), json.Unmarshal([]byte(lines[1]), &data))
+		assert.Equal(suite.T(), "order_2", data["order_id"])
+	}
+}
+
+func (suite *OrderExportTestSuite) TestOrderExport_Xlsx_Ok() {
+	buf := new(bytes.Buffer)
+	tw, err := utils.NewTableWriter(utils.TableFormatXlsx, buf, []string{"order_id", "amount"})
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	assert.NoError(suite.T(), tw.Write([]interface{}{"order <1> & co", 100.5}))
+	assert.NoError(suite.T(), tw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	var sheet string
+
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+
+		r, err := f.Open()
+		assert.NoError(suite.T(), err)
+
+		b, err := ioutil.ReadAll(r)
+		assert.NoError(suite.T(), err)
+
+		sheet = string(b)
+	}
+
+	assert.Len(suite.T(), zr.File, 5)
+	assert.Contains(suite.T(), sheet, "<t xml:space=\"preserve\">order_id</t>")
+	assert.Contains(suite.T(), sheet, "order &lt;1&gt; &amp; co")
+	assert.Contains(suite.T(), sheet, `<c t="n"><v>100.5</v></c>`)
+	assert.True(suite.T(), strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+}
+
+func (suite *OrderExportTestSuite) TestOrderExport_FormatNotSupported_Error() {
+	_, err := utils.NewTableWriter("pdf", new(bytes.Buffer), []string{"id"})
+	assert.Error(suite.T(), err)
+}
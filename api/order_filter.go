@@ -0,0 +1,441 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/minio/minio-go"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"github.com/paysuper/paysuper-management-api/utils"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	orderReportFileMask            = "order_report_%s_%s.%s"
+	orderReportNotificationTitle   = "Orders report \"%s\" is ready"
+	orderReportNotificationMessage = "Scheduled orders report by filter \"%s\" is generated. Download it by url /admin/api/v1/order/filters/%s/report"
+)
+
+type orderFilterRoute struct {
+	*orderRoute
+	filterManager *manager.OrderFilterManager
+	mClt          *minio.Client
+}
+
+func (api *Api) initOrderFilterRoutes() *Api {
+	route := &orderFilterRoute{
+		orderRoute: &orderRoute{
+			Api: api,
+			orderManager: manager.InitOrderManager(
+				api.database,
+				api.logger,
+				api.notifierPub,
+				api.repository,
+				api.geoService,
+			),
+			projectManager: manager.InitProjectManager(api.database, api.logger, api.billingService),
+		},
+		filterManager: manager.InitOrderFilterManager(api.database, api.logger),
+		mClt:          api.s3Client,
+	}
+
+	api.authUserRouteGroup.GET("/order/filters", route.listFilters, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.POST("/order/filters", route.createFilter, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/order/filters/:filter_id", route.getFilter, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.PUT("/order/filters/:filter_id", route.updateFilter, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.DELETE("/order/filters/:filter_id", route.deleteFilter, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/order/filters/:filter_id/report", route.getFilterReport, api.requireRoles(rolesMerchantRead...))
+
+	if route.mClt != nil {
+		api.backgroundWorkers = append(api.backgroundWorkers, route.runReportScheduler)
+	}
+
+	return api
+}
+
+// @Summary Get saved filters of orders list
+// @Description Get list of orders filters saved by authenticated user
+// @Tags Payment Order
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.OrderFilter "OK"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/filters [get]
+func (r *orderFilterRoute) listFilters(ctx echo.Context) error {
+	f, err := r.filterManager.FindByUserId(getRequestContext(ctx).AuthUser.Id)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusOK, f)
+}
+
+// @Summary Save filter of orders list
+// @Description Save named filter of orders list. If filter has schedule, then orders report by filter
+// @Description will be generated daily or weekly and merchant will be notified about it
+// @Tags Payment Order
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body model.OrderFilter true "Filter data"
+// @Success 201 {object} model.OrderFilter "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/filters [post]
+func (r *orderFilterRoute) createFilter(ctx echo.Context) error {
+	f := &model.OrderFilter{}
+	err := r.bindFilter(ctx, f)
+
+	if err != nil {
+		return err
+	}
+
+	f.UserId = getRequestContext(ctx).AuthUser.Id
+	err = r.filterManager.Insert(f)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusCreated, f)
+}
+
+// @Summary Get saved filter of orders list
+// @Description Get orders filter saved by authenticated user
+// @Tags Payment Order
+// @Produce json
+// @Security BearerAuth
+// @Param filter_id path string true "filter unique identifier"
+// @Success 200 {object} model.OrderFilter "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/filters/{filter_id} [get]
+func (r *orderFilterRoute) getFilter(ctx echo.Context) error {
+	f, err := r.findFilter(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, f)
+}
+
+// @Summary Update saved filter of orders list
+// @Description Update name, query or schedule of orders filter saved by authenticated user
+// @Tags Payment Order
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param filter_id path string true "filter unique identifier"
+// @Param data body model.OrderFilter true "Filter data"
+// @Success 200 {object} model.OrderFilter "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/filters/{filter_id} [put]
+func (r *orderFilterRoute) updateFilter(ctx echo.Context) error {
+	prev, err := r.findFilter(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	f := &model.OrderFilter{}
+	err = r.bindFilter(ctx, f)
+
+	if err != nil {
+		return err
+	}
+
+	err = r.filterManager.Update(f, prev)
+
+	if err == mgo.ErrNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, model.ResponseMessageNotFound)
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusOK, f)
+}
+
+// @Summary Delete saved filter of orders list
+// @Description Delete orders filter saved by authenticated user. Scheduled reports by filter will not be generated anymore
+// @Tags Payment Order
+// @Security BearerAuth
+// @Param filter_id path string true "filter unique identifier"
+// @Success 204 {string} html "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/filters/{filter_id} [delete]
+func (r *orderFilterRoute) deleteFilter(ctx echo.Context) error {
+	id := ctx.Param(requestParameterFilterId)
+
+	if !bson.IsObjectIdHex(id) {
+		return echo.NewHTTPError(http.StatusBadRequest, errorIncorrectOrderFilterId)
+	}
+
+	err := r.filterManager.Delete(bson.ObjectIdHex(id), getRequestContext(ctx).AuthUser.Id)
+
+	if err == mgo.ErrNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, model.ResponseMessageNotFound)
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// @Summary Download last report by saved filter
+// @Description Download last orders report generated by schedule of orders filter
+// @Tags Payment Order
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param filter_id path string true "filter unique identifier"
+// @Success 200 {file} file "Report file"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/filters/{filter_id}/report [get]
+func (r *orderFilterRoute) getFilterReport(ctx echo.Context) error {
+	f, err := r.findFilter(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if f.Schedule == nil || f.Schedule.LastReport == "" {
+		return echo.NewHTTPError(http.StatusNotFound, errorMessageOrderReportNotFound)
+	}
+
+	filePath := os.TempDir() + string(os.PathSeparator) + f.Schedule.LastReport
+	err = r.mClt.FGetObject(r.config.S3.BucketName, f.Schedule.LastReport, filePath, minio.GetObjectOptions{})
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	defer os.Remove(filePath)
+
+	// format of schedule can be changed after report generation, so format taken from report file name
+	format := strings.TrimPrefix(filepath.Ext(f.Schedule.LastReport), ".")
+	ctx.Response().Header().Set(echo.HeaderContentType, utils.TableFormatContentTypes[format])
+
+	return ctx.Attachment(filePath, f.Schedule.LastReport)
+}
+
+// Get filter of authenticated user by identifier from request path
+func (r *orderFilterRoute) findFilter(ctx echo.Context) (*model.OrderFilter, error) {
+	id := ctx.Param(requestParameterFilterId)
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errorIncorrectOrderFilterId)
+	}
+
+	f, err := r.filterManager.FindById(bson.ObjectIdHex(id), getRequestContext(ctx).AuthUser.Id)
+
+	if err == mgo.ErrNotFound {
+		return nil, echo.NewHTTPError(http.StatusNotFound, model.ResponseMessageNotFound)
+	}
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return f, nil
+}
+
+// Bind and validate filter data. Projects from filter query must belong to merchant of authenticated user
+func (r *orderFilterRoute) bindFilter(ctx echo.Context, f *model.OrderFilter) error {
+	err := ctx.Bind(f)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
+	}
+
+	err = r.validate.Struct(f)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
+	}
+
+	for field := range f.Query {
+		if !model.OrderFilterQueryFields[field] {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(errorMessageOrderFilterQueryIncorrect, field))
+		}
+	}
+
+	if f.Schedule != nil {
+		_, err = getOrderExportColumns(strings.Join(f.Schedule.Columns, ","))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	fp, err := getOrderFilterProjects(url.Values(f.Query))
+
+	if err != nil {
+		return err
+	}
+
+	_, merchant, err := r.getMerchantProjects(ctx, fp)
+
+	if err != nil {
+		return err
+	}
+
+	f.MerchantId = merchant.Id
+
+	return nil
+}
+
+// Generate reports by saved filters which time of generation reached. Runs until context canceled
+func (r *orderFilterRoute) runReportScheduler(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(r.config.OrderReportSchedulerInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.processScheduledReports(ctx, now)
+		}
+	}
+}
+
+func (r *orderFilterRoute) processScheduledReports(ctx context.Context, now time.Time) {
+	filters, err := r.filterManager.FindScheduled(now)
+
+	if err != nil {
+		return
+	}
+
+	for _, f := range filters {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// report skipped until next period if other instance of service already generates it
+		reserved, err := r.filterManager.ReserveRun(f, now)
+
+		if err != nil || !reserved {
+			continue
+		}
+
+		err = r.createReport(ctx, f, now)
+
+		if err != nil {
+			r.logError(ctx, "Orders report by saved filter failed", []interface{}{"error", err.Error(), "filter_id", f.Id.Hex()})
+		}
+	}
+}
+
+// Export orders matched by filter to file in storage and notify merchant about new report.
+// Previous report by filter removed from storage after new report saved
+func (r *orderFilterRoute) createReport(ctx context.Context, f *model.OrderFilter, now time.Time) error {
+	values := url.Values(f.Query)
+	fp, err := getOrderFilterProjects(values)
+
+	if err != nil {
+		return err
+	}
+
+	p, merchant, err := r.getUserMerchantProjects(ctx, f.UserId, fp)
+
+	if err != nil {
+		return err
+	}
+
+	columns, err := getOrderExportColumns(strings.Join(f.Schedule.Columns, ","))
+
+	if err != nil {
+		return err
+	}
+
+	params := &manager.FindAll{Values: values, Projects: p, Merchant: merchant, SortBy: model.DefaultSort}
+
+	if sort, ok := values[model.QueryParameterNameSort]; ok {
+		params.SortBy = sort
+	}
+
+	name := fmt.Sprintf(orderReportFileMask, f.Id.Hex(), now.UTC().Format(orderExportFileTimeMask), f.Schedule.Format)
+	filePath := os.TempDir() + string(os.PathSeparator) + name
+	file, err := os.Create(filePath)
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(filePath)
+
+	err = r.writeOrderExport(f.Schedule.Format, file, params, columns, func(_ int) error { return ctx.Err() })
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	opts := minio.PutObjectOptions{ContentType: utils.TableFormatContentTypes[f.Schedule.Format]}
+	_, err = r.mClt.FPutObject(r.config.S3.BucketName, name, filePath, opts)
+
+	if err != nil {
+		return err
+	}
+
+	prev := f.Schedule.LastReport
+	err = r.filterManager.SetLastReport(f, now, name)
+
+	if err != nil {
+		return err
+	}
+
+	if prev != "" {
+		if err := r.mClt.RemoveObject(r.config.S3.BucketName, prev); err != nil {
+			r.logError(ctx, "Remove of previous orders report failed", []interface{}{"error", err.Error(), "name", prev})
+		}
+	}
+
+	req := &grpc.NotificationRequest{
+		MerchantId: merchant.Id,
+		UserId:     f.UserId,
+		Title:      fmt.Sprintf(orderReportNotificationTitle, f.Name),
+		Message:    fmt.Sprintf(orderReportNotificationMessage, f.Name, f.Id.Hex()),
+	}
+	_, err = r.billingService.CreateNotification(ctx, req)
+
+	return err
+}
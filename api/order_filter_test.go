@@ -0,0 +1,192 @@
+package api
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/config"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type OrderFilterTestSuite struct {
+	suite.Suite
+	router *orderFilterRoute
+	api    *Api
+}
+
+func Test_OrderFilter(t *testing.T) {
+	suite.Run(t, new(OrderFilterTestSuite))
+}
+
+func (suite *OrderFilterTestSuite) SetupTest() {
+	suite.api = &Api{
+		Http:           echo.New(),
+		validate:       validator.New(),
+		billingService: mock.NewBillingServerOkMock(),
+		config: &config.Config{
+			Environment: "test",
+		},
+	}
+	suite.router = &orderFilterRoute{orderRoute: &orderRoute{Api: suite.api}}
+}
+
+func (suite *OrderFilterTestSuite) TearDownTest() {}
+
+func (suite *OrderFilterTestSuite) createFilter(body string) error {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	ctx := suite.api.Http.NewContext(req, httptest.NewRecorder())
+	ctx.SetPath("/order/filters")
+
+	return suite.router.createFilter(ctx)
+}
+
+func (suite *OrderFilterTestSuite) TestOrderFilter_Create_Error() {
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{
+			name:    "incorrect json",
+			body:    `{"name":`,
+			message: errorQueryParamsIncorrect,
+		},
+		{
+			name:    "name not specified",
+			body:    `{"query":{"status[]":["4"]}}`,
+			message: fmt.Sprintf(errorMessageMask, "Name", "required"),
+		},
+		{
+			name:    "unknown query parameter",
+			body:    `{"name":"paid","query":{"amount":["100"]}}`,
+			message: fmt.Sprintf(errorMessageOrderFilterQueryIncorrect, "amount"),
+		},
+		{
+			name:    "incorrect schedule period",
+			body:    `{"name":"paid","query":{"status[]":["4"]},"schedule":{"period":"monthly","format":"csv"}}`,
+			message: fmt.Sprintf(errorMessageMask, "Period", "oneof"),
+		},
+		{
+			name:    "incorrect schedule hour",
+			body:    `{"name":"paid","query":{"status[]":["4"]},"schedule":{"period":"daily","hour":24,"format":"csv"}}`,
+			message: fmt.Sprintf(errorMessageMask, "Hour", "max"),
+		},
+		{
+			name:    "unknown report column",
+			body:    `{"name":"paid","query":{"status[]":["4"]},"schedule":{"period":"daily","format":"csv","columns":["amount"]}}`,
+			message: fmt.Sprintf(errorMessageExportColumnUnknown, "amount"),
+		},
+		{
+			name:    "incorrect project identifier",
+			body:    `{"name":"paid","query":{"project[]":["project"]}}`,
+			message: model.ResponseMessageProjectIdIsInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		err := suite.createFilter(tt.body)
+
+		if !assert.Error(suite.T(), err, tt.name) {
+			continue
+		}
+
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok, tt.name)
+		assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code, tt.name)
+
+		// validation errors returned in structured form
+		if vErr, ok := httpErr.Message.(*model.Error); ok {
+			assert.Equal(suite.T(), tt.message, vErr.Message, tt.name)
+			continue
+		}
+
+		assert.Equal(suite.T(), tt.message, httpErr.Message, tt.name)
+	}
+}
+
+func (suite *OrderFilterTestSuite) TestOrderFilter_Create_BillingServerError() {
+	suite.router.billingService = mock.NewBillingServerSystemErrorMock()
+	err := suite.createFilter(`{"name":"paid","query":{"status[]":["4"]},"schedule":{"period":"weekly","weekday":1,"format":"xlsx"}}`)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusInternalServerError, httpErr.Code)
+	assert.Equal(suite.T(), errorUnknown, httpErr.Message)
+}
+
+func (suite *OrderFilterTestSuite) TestOrderFilter_FilterIdIncorrect_Error() {
+	handlers := map[string]echo.HandlerFunc{
+		"get":    suite.router.getFilter,
+		"update": suite.router.updateFilter,
+		"delete": suite.router.deleteFilter,
+		"report": suite.router.getFilterReport,
+	}
+
+	for name, handler := range handlers {
+		ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+		ctx.SetPath("/order/filters/:filter_id")
+		ctx.SetParamNames(requestParameterFilterId)
+		ctx.SetParamValues("filter")
+
+		err := handler(ctx)
+
+		if !assert.Error(suite.T(), err, name) {
+			continue
+		}
+
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok, name)
+		assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code, name)
+		assert.Equal(suite.T(), errorIncorrectOrderFilterId, httpErr.Message, name)
+	}
+}
+
+func (suite *OrderFilterTestSuite) TestOrderFilter_ScheduleNextRunAt_Ok() {
+	// wednesday
+	now := time.Date(2019, 5, 22, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		schedule *model.OrderFilterSchedule
+		expected time.Time
+	}{
+		{
+			schedule: &model.OrderFilterSchedule{Period: model.OrderFilterSchedulePeriodDaily, Hour: 11},
+			expected: time.Date(2019, 5, 22, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			schedule: &model.OrderFilterSchedule{Period: model.OrderFilterSchedulePeriodDaily, Hour: 10},
+			expected: time.Date(2019, 5, 23, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			schedule: &model.OrderFilterSchedule{Period: model.OrderFilterSchedulePeriodWeekly, Weekday: 3, Hour: 11},
+			expected: time.Date(2019, 5, 22, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			schedule: &model.OrderFilterSchedule{Period: model.OrderFilterSchedulePeriodWeekly, Weekday: 3, Hour: 9},
+			expected: time.Date(2019, 5, 29, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			schedule: &model.OrderFilterSchedule{Period: model.OrderFilterSchedulePeriodWeekly, Weekday: 1},
+			expected: time.Date(2019, 5, 27, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(suite.T(), tt.expected, tt.schedule.GetNextRunAt(now))
+	}
+
+	// next run calculated in UTC regardless of time zone of date
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	schedule := &model.OrderFilterSchedule{Period: model.OrderFilterSchedulePeriodDaily, Hour: 23}
+	assert.Equal(suite.T(), time.Date(2019, 5, 22, 23, 0, 0, 0, time.UTC), schedule.GetNextRunAt(now.In(loc)))
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// @Summary Get refunds and chargebacks
+// @Description Get refunded and charged back orders of all projects of merchant with totals in merchant
+// @Description accounting currency. Totals calculated by all orders matched by filters, not by current page only.
+// @Description Dates range filters orders by date of last order status change
+// @Tags Payment Order
+// @Produce json
+// @Security BearerAuth
+// @Param project query array false "list of projects to get orders filtered by they"
+// @Param status query array false "list of orders statuses: 9 - refund, 10 - chargeback. both statuses selected by default"
+// @Param currency query array false "list of payment currencies by ISO 4217 to get orders filtered by they"
+// @Param date_from query integer false "start date of refund or chargeback as unix timestamp"
+// @Param date_to query integer false "end date of refund or chargeback as unix timestamp"
+// @Param limit query integer false "maximum number of returning orders. default value is 100"
+// @Param offset query integer false "offset from which you want to return the list of orders. default value is 0"
+// @Param cursor query string false "cursor of page returned in next_cursor or prev_cursor. empty value requests first page in cursor pagination mode"
+// @Param count query string false "mode of count calculation: exact, estimated or none"
+// @Param sort query array false "fields list for sorting"
+// @Success 200 {object} model.OrderReversalList "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/reversals [get]
+func (r *orderRoute) getReversals(ctx echo.Context) error {
+	params, err := r.getReversalsParams(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	pOrders, err := r.orderManager.FindAll(params)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	summary, err := r.orderManager.GetReversalSummary(params)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusOK, &model.OrderReversalList{OrderPaginate: pOrders, Summary: summary})
+}
+
+// @Summary Export refunds and chargebacks
+// @Description Export all refunded and charged back orders matched by filters of refunds and chargebacks list
+// @Description to file. Columns and formats of file are the same as in orders export
+// @Tags Payment Order
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param format query string true "export file format: csv, xlsx or jsonl"
+// @Param columns query string false "comma separated list of exported columns. all columns exported by default"
+// @Param project query array false "list of projects to get orders filtered by they"
+// @Param status query array false "list of orders statuses: 9 - refund, 10 - chargeback. both statuses selected by default"
+// @Param currency query array false "list of payment currencies by ISO 4217 to get orders filtered by they"
+// @Param date_from query integer false "start date of refund or chargeback as unix timestamp"
+// @Param date_to query integer false "end date of refund or chargeback as unix timestamp"
+// @Param sort query array false "fields list for sorting"
+// @Success 200 {file} file "Export file"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/reversals/export [get]
+func (r *orderRoute) exportReversals(ctx echo.Context) error {
+	return r.sendOrderExport(ctx, "reversals", r.getReversalsParams)
+}
+
+// Get filters of refunds and chargebacks search from query parameters. Only refunded and charged back orders
+// of projects of authenticated merchant selected
+func (r *orderRoute) getReversalsParams(ctx echo.Context) (*manager.FindAll, error) {
+	values := ctx.QueryParams()
+	statuses := values[model.OrderFilterFieldStatuses]
+
+	if len(statuses) == 0 {
+		for _, s := range model.OrderReversalStatuses {
+			statuses = append(statuses, strconv.Itoa(s))
+		}
+	}
+
+	for _, s := range statuses {
+		if status, err := strconv.Atoi(s); err != nil || !model.IsOrderReversalStatus(status) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, errorMessageOrderReversalStatusIncorrect)
+		}
+	}
+
+	conditions := bson.M{}
+	dates, err := getOrderReversalDates(values)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dates) > 0 {
+		conditions["updated_at"] = dates
+	}
+
+	var currencies []string
+
+	for _, c := range values[model.OrderReversalFieldCurrencies] {
+		if r.validate.Var(c, "len=3,alpha") != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, errorMessageOrderReversalCurrencyIncorrect)
+		}
+
+		currencies = append(currencies, strings.ToUpper(c))
+	}
+
+	if len(currencies) > 0 {
+		conditions["pm_income_currency.code_a3"] = bson.M{"$in": currencies}
+	}
+
+	params, err := r.getFindAllParams(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// query parameters copied to not change parameters of request
+	params.Values = make(url.Values, len(values)+1)
+
+	for k, v := range values {
+		params.Values[k] = v
+	}
+
+	params.Values[model.OrderFilterFieldStatuses] = statuses
+	params.Conditions = conditions
+
+	return params, nil
+}
+
+// Get condition of dates range of refunds and chargebacks from query parameters
+func getOrderReversalDates(values url.Values) (bson.M, error) {
+	dates := bson.M{}
+
+	var from, to int64
+
+	for _, field := range []string{model.OrderReversalFieldDateFrom, model.OrderReversalFieldDateTo} {
+		value := values.Get(field)
+
+		if value == "" {
+			continue
+		}
+
+		ts, err := strconv.ParseInt(value, 10, 64)
+
+		if err != nil || ts < 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, errorMessageOrderReversalDateIncorrect)
+		}
+
+		if field == model.OrderReversalFieldDateFrom {
+			from = ts
+			dates["$gte"] = time.Unix(ts, 0)
+		} else {
+			to = ts
+			dates["$lte"] = time.Unix(ts, 0)
+		}
+	}
+
+	if len(dates) == 2 && from > to {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errorMessageOrderReversalDateIncorrect)
+	}
+
+	return dates, nil
+}
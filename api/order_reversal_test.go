@@ -0,0 +1,112 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/config"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type OrderReversalTestSuite struct {
+	suite.Suite
+	router *orderRoute
+}
+
+func Test_OrderReversal(t *testing.T) {
+	suite.Run(t, new(OrderReversalTestSuite))
+}
+
+func (suite *OrderReversalTestSuite) SetupTest() {
+	api := &Api{
+		Http:           echo.New(),
+		validate:       validator.New(),
+		billingService: mock.NewBillingServerOkMock(),
+		config: &config.Config{
+			Environment: "test",
+		},
+	}
+	suite.router = &orderRoute{Api: api}
+}
+
+func (suite *OrderReversalTestSuite) TearDownTest() {}
+
+func (suite *OrderReversalTestSuite) TestOrderReversal_Params_Error() {
+	tests := []struct {
+		query   string
+		message string
+	}{
+		{query: "status[]=7", message: errorMessageOrderReversalStatusIncorrect},
+		{query: "status[]=9&status[]=refund", message: errorMessageOrderReversalStatusIncorrect},
+		{query: "date_from=yesterday", message: errorMessageOrderReversalDateIncorrect},
+		{query: "date_to=-1", message: errorMessageOrderReversalDateIncorrect},
+		{query: "date_from=1558000000&date_to=1557000000", message: errorMessageOrderReversalDateIncorrect},
+		{query: "currency[]=RU", message: errorMessageOrderReversalCurrencyIncorrect},
+		{query: "currency[]=USD&currency[]=U1D", message: errorMessageOrderReversalCurrencyIncorrect},
+		{query: "project[]=project", message: model.ResponseMessageProjectIdIsInvalid},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/order/reversals?"+tt.query, nil)
+		ctx := suite.router.Http.NewContext(req, httptest.NewRecorder())
+
+		err := suite.router.getReversals(ctx)
+
+		if !assert.Error(suite.T(), err, tt.query) {
+			continue
+		}
+
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok, tt.query)
+		assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code, tt.query)
+		assert.Equal(suite.T(), tt.message, httpErr.Message, tt.query)
+	}
+}
+
+func (suite *OrderReversalTestSuite) TestOrderReversal_Export_FormatIncorrect_Error() {
+	req := httptest.NewRequest(http.MethodGet, "/order/reversals/export?format=pdf", nil)
+	ctx := suite.router.Http.NewContext(req, httptest.NewRecorder())
+
+	err := suite.router.exportReversals(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageExportFormatIncorrect, httpErr.Message)
+}
+
+func (suite *OrderReversalTestSuite) TestOrderReversal_Dates_Ok() {
+	dates, err := getOrderReversalDates(url.Values{})
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), dates)
+
+	dates, err = getOrderReversalDates(url.Values{"date_from": {"1557000000"}, "date_to": {"1558000000"}})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), time.Unix(1557000000, 0), dates["$gte"])
+	assert.Equal(suite.T(), time.Unix(1558000000, 0), dates["$lte"])
+}
+
+func (suite *OrderReversalTestSuite) TestOrderReversal_Summary_Ok() {
+	amounts := []*model.OrderStatusAmount{
+		{Status: model.OrderStatusRefund, Count: 3, Amount: 100.104},
+		{Status: model.OrderStatusChargeback, Count: 1, Amount: 50.5},
+		{Status: model.OrderStatusProjectComplete, Count: 10, Amount: 1000},
+	}
+
+	s := model.NewOrderReversalSummary("USD", amounts)
+	assert.Equal(suite.T(), "USD", s.Currency)
+	assert.Equal(suite.T(), 3, s.RefundCount)
+	assert.Equal(suite.T(), 100.1, s.RefundAmount)
+	assert.Equal(suite.T(), 1, s.ChargebackCount)
+	assert.Equal(suite.T(), 50.5, s.ChargebackAmount)
+	assert.Equal(suite.T(), 4, s.Count)
+	assert.Equal(suite.T(), 150.6, s.Amount)
+}
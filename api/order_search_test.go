@@ -0,0 +1,135 @@
+package api
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type OrderSearchTestSuite struct {
+	suite.Suite
+}
+
+func Test_OrderSearch(t *testing.T) {
+	suite.Run(t, new(OrderSearchTestSuite))
+}
+
+func (suite *OrderSearchTestSuite) SetupTest() {}
+
+func (suite *OrderSearchTestSuite) TearDownTest() {}
+
+func (suite *OrderSearchTestSuite) TestOrderSearch_ParseQuery_Ok() {
+	tests := []struct {
+		query     string
+		value     string
+		exact     bool
+		condition interface{}
+	}{
+		{
+			query:     " User@Mail.RU ",
+			value:     "user@mail.ru",
+			condition: bson.RegEx{Pattern: "^user@mail\\.ru"},
+		},
+		{
+			query:     "\"User@Mail.RU\"",
+			value:     "user@mail.ru",
+			exact:     true,
+			condition: "user@mail.ru",
+		},
+		{
+			query:     "+7 (999) 123-45-67",
+			value:     "+79991234567",
+			condition: bson.RegEx{Pattern: "^\\+79991234567"},
+		},
+		{
+			query:     "a.*b(",
+			value:     "a.*b(",
+			condition: bson.RegEx{Pattern: "^a\\.\\*b\\("},
+		},
+		{
+			query:     "12",
+			value:     "12",
+			exact:     true,
+			condition: "12",
+		},
+	}
+
+	for _, tt := range tests {
+		q := manager.ParseOrderSearchQuery(tt.query)
+		assert.Equal(suite.T(), tt.value, q.Value, tt.query)
+		assert.Equal(suite.T(), tt.exact, q.Exact, tt.query)
+		assert.Equal(suite.T(), tt.condition, q.GetCondition(), tt.query)
+	}
+}
+
+func (suite *OrderSearchTestSuite) TestOrderSearch_SetSearchKeys_Ok() {
+	email := "Payer@Example.COM"
+	phone := "+7 (999) 123-45-67"
+
+	order := &model.Order{
+		Id:                        bson.NewObjectId(),
+		ProjectOrderId:            "ORDER-1",
+		ProjectAccount:            "payer@example.com",
+		PaymentMethodPayerAccount: "400000******0002",
+		PayerData:                 &model.PayerData{Email: &email, Phone: &phone},
+	}
+	order.SetSearchKeys()
+
+	expected := []string{
+		order.Id.Hex(),
+		"order-1",
+		"payer@example.com",
+		"400000******0002",
+		"+79991234567",
+		"0002",
+	}
+	assert.Equal(suite.T(), expected, order.SearchKeys)
+}
+
+func (suite *OrderSearchTestSuite) TestOrderSearch_SetSearchKeys_Names_Ok() {
+	order := &model.Order{
+		Id:            bson.NewObjectId(),
+		Project:       &model.ProjectOrder{Name: "Test Project"},
+		PaymentMethod: &model.OrderPaymentMethod{Name: "Bank Card"},
+	}
+	order.SetSearchKeys()
+
+	assert.Equal(suite.T(), []string{order.Id.Hex(), "test project", "bank card"}, order.SearchKeys)
+}
+
+func (suite *OrderSearchTestSuite) TestOrderSearch_GetFilter_Ok() {
+	f := manager.ParseOrderSearchQuery("Test.Project").GetFilter()
+	conditions, ok := f["$or"].([]bson.M)
+
+	if !assert.True(suite.T(), ok) || !assert.Len(suite.T(), conditions, 2) {
+		return
+	}
+
+	assert.Equal(suite.T(), bson.M{"search_keys": bson.RegEx{Pattern: "^test\\.project"}}, conditions[0])
+	assert.Equal(suite.T(), bson.M{"$exists": false}, conditions[1]["search_keys"])
+
+	fields, ok := conditions[1]["$or"].([]bson.M)
+
+	if !assert.True(suite.T(), ok) || !assert.Len(suite.T(), fields, len(manager.OrderSearchFallbackFields)) {
+		return
+	}
+
+	for i, field := range manager.OrderSearchFallbackFields {
+		assert.Equal(suite.T(), bson.M{field: bson.RegEx{Pattern: "^Test\\.Project"}}, fields[i])
+	}
+}
+
+func (suite *OrderSearchTestSuite) TestOrderSearch_GetFilter_ExactOrderId_Ok() {
+	id := bson.NewObjectId()
+	f := manager.ParseOrderSearchQuery("\"" + id.Hex() + "\"").GetFilter()
+	conditions := f["$or"].([]bson.M)
+
+	assert.Equal(suite.T(), bson.M{"search_keys": id.Hex()}, conditions[0])
+
+	fields := conditions[1]["$or"].([]bson.M)
+	assert.Equal(suite.T(), bson.M{"_id": id}, fields[0])
+	assert.Equal(suite.T(), bson.M{"project_order_id": id.Hex()}, fields[1])
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/payment_system"
+	"github.com/paysuper/paysuper-management-api/payment_system/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+	"time"
+)
+
+const (
+	orderStatusTestCallbackSecret = "callback_secret"
+	orderStatusTestRawRequest     = `{"payment_data":{"id":"123456"}}`
+)
+
+// Expected edges of order status state machine. Every pair of statuses which not listed here must be rejected
+var orderStatusTestAllowedTransitions = map[int][]int{
+	model.OrderStatusNew: {
+		model.OrderStatusPaymentSystemCreate,
+		model.OrderStatusPaymentSystemRejectOnCreate,
+	},
+	model.OrderStatusPaymentSystemRejectOnCreate: {
+		model.OrderStatusPaymentSystemCreate,
+	},
+	model.OrderStatusPaymentSystemCreate: {
+		model.OrderStatusPaymentSystemReject,
+		model.OrderStatusPaymentSystemComplete,
+		model.OrderStatusPaymentSystemDeclined,
+		model.OrderStatusPaymentSystemCanceled,
+	},
+	model.OrderStatusPaymentSystemReject: {
+		model.OrderStatusPaymentSystemComplete,
+		model.OrderStatusPaymentSystemDeclined,
+		model.OrderStatusPaymentSystemCanceled,
+	},
+	model.OrderStatusPaymentSystemComplete: {
+		model.OrderStatusProjectInProgress,
+		model.OrderStatusRefund,
+		model.OrderStatusChargeback,
+	},
+	model.OrderStatusProjectInProgress: {
+		model.OrderStatusProjectComplete,
+		model.OrderStatusProjectPending,
+		model.OrderStatusProjectReject,
+	},
+	model.OrderStatusProjectPending: {
+		model.OrderStatusProjectInProgress,
+		model.OrderStatusProjectComplete,
+		model.OrderStatusProjectReject,
+		model.OrderStatusRefund,
+		model.OrderStatusChargeback,
+	},
+	model.OrderStatusProjectComplete: {
+		model.OrderStatusRefund,
+		model.OrderStatusChargeback,
+	},
+	model.OrderStatusProjectReject: {
+		model.OrderStatusRefund,
+		model.OrderStatusChargeback,
+	},
+}
+
+type OrderStatusTestSuite struct {
+	suite.Suite
+	logs *observer.ObservedLogs
+}
+
+func Test_OrderStatus(t *testing.T) {
+	suite.Run(t, new(OrderStatusTestSuite))
+}
+
+func (suite *OrderStatusTestSuite) SetupTest() {}
+
+func (suite *OrderStatusTestSuite) TearDownTest() {}
+
+// Order with all data required by guards of transitions
+func (suite *OrderStatusTestSuite) getOrder(status int) *model.Order {
+	return &model.Order{
+		Id:     bson.NewObjectId(),
+		Status: status,
+		PaymentMethod: &model.OrderPaymentMethod{
+			Id:     bson.NewObjectId(),
+			Name:   "Bank card",
+			Params: &model.PaymentMethodParams{Handler: payment_system.PaymentSystemHandlerCardPay, ExternalId: "BANKCARD"},
+		},
+		PaymentMethodOrderId:          "123456",
+		PaymentMethodIncomeAmount:     100,
+		PaymentMethodIncomeCurrencyA3: "USD",
+	}
+}
+
+func (suite *OrderStatusTestSuite) getCardPayHandler(o *model.Order) payment_system.PaymentSystem {
+	core, logs := observer.New(zapcore.InfoLevel)
+	suite.logs = logs
+
+	settings := &payment_system.Settings{
+		Config: &payment_system.CardPayConfig{
+			Terminals: map[string]*payment_system.CardPayTerminal{
+				"BANKCARD": {CallbackSecretWord: orderStatusTestCallbackSecret},
+			},
+		},
+		PaymentSystemSetting: &payment_system.PaymentSystemSetting{Logger: zap.New(core).Sugar()},
+	}
+
+	handler, err := payment_system.NewCardPayHandler(o, settings)
+	assert.NoError(suite.T(), err)
+
+	return handler
+}
+
+func (suite *OrderStatusTestSuite) getNotification(o *model.Order, status string) *model.OrderPaymentNotification {
+	h := sha512.New()
+	h.Write([]byte(orderStatusTestRawRequest + orderStatusTestCallbackSecret))
+
+	req := &entity.CardPayPaymentNotificationWebHookRequest{
+		PaymentMethod: "BANKCARD",
+		CallbackTime:  time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		CardAccount:   &entity.CardPayBankCardAccountResponse{MaskedPan: "400000...0002"},
+		PaymentData: &entity.CardPayPaymentDataResponse{
+			Id:       "123456",
+			Amount:   100,
+			Currency: "USD",
+			Status:   status,
+		},
+		Signature: hex.EncodeToString(h.Sum(nil)),
+	}
+
+	return &model.OrderPaymentNotification{Id: o.Id.Hex(), Request: req, RawRequest: orderStatusTestRawRequest}
+}
+
+func (suite *OrderStatusTestSuite) TestOrderStatus_AllTransitions() {
+	statuses := make([]int, 0, len(model.OrderStatusesNames))
+
+	for status := range model.OrderStatusesNames {
+		statuses = append(statuses, status)
+	}
+
+	for _, from := range statuses {
+		allowed := map[int]bool{from: true}
+
+		for _, to := range orderStatusTestAllowedTransitions[from] {
+			allowed[to] = true
+		}
+
+		for _, to := range statuses {
+			o := suite.getOrder(from)
+			err := o.TransitStatus(to)
+
+			if allowed[to] {
+				assert.NoError(suite.T(), err, "transition %d -> %d must be allowed", from, to)
+				assert.Equal(suite.T(), to, o.Status)
+				continue
+			}
+
+			assert.Error(suite.T(), err, "transition %d -> %d must be rejected", from, to)
+			assert.IsType(suite.T(), &model.OrderStatusTransitionError{}, err)
+			assert.Equal(suite.T(), from, o.Status, "status of order must not be changed by rejected transition")
+		}
+	}
+}
+
+func (suite *OrderStatusTestSuite) TestOrderStatus_Guards() {
+	tests := []struct {
+		name  string
+		from  int
+		to    int
+		clear func(o *model.Order)
+	}{
+		{
+			name:  "create without payment method",
+			from:  model.OrderStatusNew,
+			to:    model.OrderStatusPaymentSystemCreate,
+			clear: func(o *model.Order) { o.PaymentMethod = nil },
+		},
+		{
+			name:  "complete without payment system order id",
+			from:  model.OrderStatusPaymentSystemCreate,
+			to:    model.OrderStatusPaymentSystemComplete,
+			clear: func(o *model.Order) { o.PaymentMethodOrderId = "" },
+		},
+		{
+			name:  "complete without amount",
+			from:  model.OrderStatusPaymentSystemReject,
+			to:    model.OrderStatusPaymentSystemComplete,
+			clear: func(o *model.Order) { o.PaymentMethodIncomeAmount = 0 },
+		},
+		{
+			name:  "complete without currency",
+			from:  model.OrderStatusPaymentSystemCreate,
+			to:    model.OrderStatusPaymentSystemComplete,
+			clear: func(o *model.Order) { o.PaymentMethodIncomeCurrencyA3 = "" },
+		},
+	}
+
+	for _, tt := range tests {
+		o := suite.getOrder(tt.from)
+		tt.clear(o)
+
+		err := o.TransitStatus(tt.to)
+
+		if assert.Error(suite.T(), err, tt.name) {
+			assert.NotEmpty(suite.T(), err.(*model.OrderStatusTransitionError).Reason, tt.name)
+		}
+
+		assert.Equal(suite.T(), tt.from, o.Status, tt.name)
+	}
+
+	// currency object replaces currency code after amounts calculation
+	o := suite.getOrder(model.OrderStatusPaymentSystemCreate)
+	o.PaymentMethodIncomeCurrencyA3 = ""
+	o.PaymentMethodIncomeCurrency = &model.Currency{CodeA3: "USD"}
+	assert.NoError(suite.T(), model.CheckOrderStatusTransition(o, o.Status, model.OrderStatusPaymentSystemComplete))
+}
+
+func (suite *OrderStatusTestSuite) TestOrderStatus_Hooks() {
+	o := suite.getOrder(model.OrderStatusPaymentSystemComplete)
+	assert.Nil(suite.T(), o.ProjectLastRequestedAt)
+
+	assert.NoError(suite.T(), o.TransitStatus(model.OrderStatusProjectInProgress))
+	assert.NotNil(suite.T(), o.ProjectLastRequestedAt)
+	assert.False(suite.T(), o.UpdatedAt.IsZero())
+}
+
+func (suite *OrderStatusTestSuite) TestOrderStatus_CardPay_Complete_Ok() {
+	o := suite.getOrder(model.OrderStatusPaymentSystemCreate)
+	o.PaymentMethodOrderId = ""
+	o.PaymentMethodIncomeAmount = 0
+
+	rsp := suite.getCardPayHandler(o).ProcessPayment(o, suite.getNotification(o, entity.CardPayPaymentResponseStatusCompleted))
+
+	assert.Equal(suite.T(), payment_system.PaymentStatusOK, rsp.Status)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemComplete, o.Status)
+	assert.Equal(suite.T(), "123456", o.PaymentMethodOrderId)
+}
+
+func (suite *OrderStatusTestSuite) TestOrderStatus_CardPay_InvalidSignature_Reject() {
+	o := suite.getOrder(model.OrderStatusPaymentSystemCreate)
+	opn := suite.getNotification(o, entity.CardPayPaymentResponseStatusCompleted)
+	opn.Request.(*entity.CardPayPaymentNotificationWebHookRequest).Signature = "invalid"
+
+	rsp := suite.getCardPayHandler(o).ProcessPayment(o, opn)
+
+	assert.Equal(suite.T(), payment_system.PaymentStatusErrorValidation, rsp.Status)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemReject, o.Status)
+	assert.NotNil(suite.T(), rsp.Order)
+}
+
+func (suite *OrderStatusTestSuite) TestOrderStatus_CardPay_CompletedToDeclined_Error() {
+	o := suite.getOrder(model.OrderStatusPaymentSystemComplete)
+	rsp := suite.getCardPayHandler(o).ProcessPayment(o, suite.getNotification(o, entity.CardPayPaymentResponseStatusDeclined))
+
+	assert.Equal(suite.T(), payment_system.PaymentStatusErrorValidation, rsp.Status)
+	assert.Nil(suite.T(), rsp.Order)
+	assert.Equal(suite.T(), model.OrderStatusPaymentSystemComplete, o.Status)
+	assert.Equal(suite.T(), 1, suite.logs.FilterMessage("Order status transition rejected").Len())
+}
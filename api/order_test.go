@@ -2,12 +2,17 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/globalsign/mgo/bson"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/config"
+	"github.com/paysuper/paysuper-management-api/database/model"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -20,10 +25,40 @@ import (
 	"time"
 )
 
+// billing service which returns merchant of authenticated user and delegates other calls to wrapped service
+type orderTestMerchantBillingService struct {
+	grpc.BillingService
+}
+
+func (s *orderTestMerchantBillingService) GetMerchantBy(
+	ctx context.Context,
+	in *grpc.GetMerchantByRequest,
+	opts ...client.CallOption,
+) (*grpc.MerchantGetMerchantResponse, error) {
+	return mock.NewBillingServerOkMock().GetMerchantBy(ctx, in, opts...)
+}
+
+// billing service which saves request of refund creation and delegates calls to wrapped service
+type orderTestRefundBillingService struct {
+	grpc.BillingService
+	req *grpc.CreateRefundRequest
+}
+
+func (s *orderTestRefundBillingService) CreateRefund(
+	ctx context.Context,
+	in *grpc.CreateRefundRequest,
+	opts ...client.CallOption,
+) (*grpc.CreateRefundResponse, error) {
+	s.req = in
+	return s.BillingService.CreateRefund(ctx, in, opts...)
+}
+
 type OrderTestSuite struct {
 	suite.Suite
-	router *orderRoute
-	api    *Api
+	router      *orderRoute
+	api         *Api
+	authUser    *AuthUser
+	refundOrder *model.Order
 }
 
 func Test_Order(t *testing.T) {
@@ -31,13 +66,14 @@ func Test_Order(t *testing.T) {
 }
 
 func (suite *OrderTestSuite) SetupTest() {
+	suite.authUser = &AuthUser{
+		Id: "ffffffffffffffffffffffff",
+	}
+
 	suite.api = &Api{
 		Http:           echo.New(),
 		validate:       validator.New(),
 		billingService: mock.NewBillingServerOkMock(),
-		authUser: &AuthUser{
-			Id: "ffffffffffffffffffffffff",
-		},
 		config: &config.Config{
 			Environment: "test",
 		},
@@ -51,6 +87,20 @@ func (suite *OrderTestSuite) SetupTest() {
 	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
 	suite.router = &orderRoute{Api: suite.api}
 
+	suite.refundOrder = &model.Order{
+		Id:                                  bson.NewObjectId(),
+		Uuid:                                uuid.New().String(),
+		Project:                             &model.ProjectOrder{Id: bson.NewObjectId(), Merchant: mock.OnboardingMerchantMock},
+		PaymentMethodIncomeAmount:           100,
+		PaymentMethodIncomeCurrency:         &model.Currency{CodeA3: "RUB"},
+		AmountOutMerchantAccountingCurrency: 50,
+		Status:                              model.OrderStatusProjectComplete,
+	}
+	suite.router.orderStore = mock.NewOrderStoreMock(suite.refundOrder)
+	suite.api.refundApprovalStore = mock.NewRefundApprovalStoreMock()
+	suite.api.customerTokenStore = NewMemoryCustomerTokenStore()
+	suite.api.idempotencyStore = NewMemoryIdempotencyStore()
+
 	err := suite.api.validate.RegisterValidation("uuid", suite.api.UuidValidator)
 	assert.NoError(suite.T(), err, "Uuid validator registration failed")
 
@@ -66,10 +116,11 @@ func (suite *OrderTestSuite) TestOrder_GetRefund_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds/:refund_id")
 	ctx.SetParamNames(requestParameterOrderId, requestParameterRefundId)
-	ctx.SetParamValues(uuid.New().String(), bson.NewObjectId().Hex())
+	ctx.SetParamValues(suite.refundOrder.Uuid, bson.NewObjectId().Hex())
 
 	err := suite.router.getRefund(ctx)
 	assert.NoError(suite.T(), err)
@@ -131,12 +182,13 @@ func (suite *OrderTestSuite) TestOrder_GetRefund_BillingServerError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds/:refund_id")
 	ctx.SetParamNames(requestParameterOrderId, requestParameterRefundId)
-	ctx.SetParamValues(uuid.New().String(), bson.NewObjectId().Hex())
+	ctx.SetParamValues(suite.refundOrder.Uuid, bson.NewObjectId().Hex())
 
-	suite.router.billingService = mock.NewBillingServerSystemErrorMock()
+	suite.router.billingService = &orderTestMerchantBillingService{BillingService: mock.NewBillingServerSystemErrorMock()}
 
 	err := suite.router.getRefund(ctx)
 	assert.Error(suite.T(), err)
@@ -153,12 +205,13 @@ func (suite *OrderTestSuite) TestOrder_GetRefund_BillingServer_RefundNotFound_Er
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds/:refund_id")
 	ctx.SetParamNames(requestParameterOrderId, requestParameterRefundId)
-	ctx.SetParamValues(uuid.New().String(), bson.NewObjectId().Hex())
+	ctx.SetParamValues(suite.refundOrder.Uuid, bson.NewObjectId().Hex())
 
-	suite.router.billingService = mock.NewBillingServerErrorMock()
+	suite.router.billingService = &orderTestMerchantBillingService{BillingService: mock.NewBillingServerErrorMock()}
 
 	err := suite.router.getRefund(ctx)
 	assert.Error(suite.T(), err)
@@ -175,10 +228,11 @@ func (suite *OrderTestSuite) TestOrder_ListRefunds_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds")
 	ctx.SetParamNames(requestParameterOrderId)
-	ctx.SetParamValues(uuid.New().String())
+	ctx.SetParamValues(suite.refundOrder.Uuid)
 
 	err := suite.router.listRefunds(ctx)
 	assert.NoError(suite.T(), err)
@@ -210,12 +264,13 @@ func (suite *OrderTestSuite) TestOrder_ListRefunds_BillingServerError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds")
 	ctx.SetParamNames(requestParameterOrderId)
-	ctx.SetParamValues(uuid.New().String())
+	ctx.SetParamValues(suite.refundOrder.Uuid)
 
-	suite.router.billingService = mock.NewBillingServerSystemErrorMock()
+	suite.router.billingService = &orderTestMerchantBillingService{BillingService: mock.NewBillingServerSystemErrorMock()}
 	err := suite.router.listRefunds(ctx)
 	assert.Error(suite.T(), err)
 
@@ -225,18 +280,73 @@ func (suite *OrderTestSuite) TestOrder_ListRefunds_BillingServerError() {
 	assert.Equal(suite.T(), errorUnknown, httpErr.Message)
 }
 
+func (suite *OrderTestSuite) TestOrder_GetRefund_OrderOfOtherMerchant_Error() {
+	suite.refundOrder.Project.Merchant = &billing.Merchant{Id: bson.NewObjectId().Hex()}
+
+	ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/refunds/:refund_id")
+	ctx.SetParamNames(requestParameterOrderId, requestParameterRefundId)
+	ctx.SetParamValues(suite.refundOrder.Uuid, bson.NewObjectId().Hex())
+
+	err := suite.router.getRefund(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+	assert.Equal(suite.T(), model.ResponseMessageNotFound, httpErr.Message)
+}
+
+func (suite *OrderTestSuite) TestOrder_ListRefunds_OrderOfOtherMerchant_Error() {
+	suite.refundOrder.Project.Merchant = &billing.Merchant{Id: bson.NewObjectId().Hex()}
+
+	ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/?status=0", nil), httptest.NewRecorder())
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/refunds")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(suite.refundOrder.Uuid)
+
+	err := suite.router.listRefunds(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+	assert.Equal(suite.T(), model.ResponseMessageNotFound, httpErr.Message)
+}
+
+func (suite *OrderTestSuite) TestOrder_ListRefunds_OrderNotFound_Error() {
+	ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/refunds")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(uuid.New().String())
+
+	err := suite.router.listRefunds(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+}
+
 func (suite *OrderTestSuite) TestOrder_CreateRefund_Ok() {
-	data := `{"amount": 10, "reason": "test"}`
+	data := `{"amount": 10, "reason": "customer_request"}`
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(data))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds")
 	ctx.SetParamNames(requestParameterOrderId)
-	ctx.SetParamValues(uuid.New().String())
+	ctx.SetParamValues(suite.refundOrder.Uuid)
 
 	err := suite.router.createRefund(ctx)
 	assert.NoError(suite.T(), err)
@@ -245,13 +355,14 @@ func (suite *OrderTestSuite) TestOrder_CreateRefund_Ok() {
 }
 
 func (suite *OrderTestSuite) TestOrder_CreateRefund_BindError() {
-	data := `{"amount": "qwerty", "reason": "test"}`
+	data := `{"amount": "qwerty", "reason": "customer_request"}`
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(data))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds")
 	ctx.SetParamNames(requestParameterOrderId)
@@ -267,13 +378,14 @@ func (suite *OrderTestSuite) TestOrder_CreateRefund_BindError() {
 }
 
 func (suite *OrderTestSuite) TestOrder_CreateRefund_ValidationError() {
-	data := `{"amount": -10, "reason": "test"}`
+	data := `{"amount": -10, "reason": "customer_request"}`
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(data))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds")
 	ctx.SetParamNames(requestParameterOrderId)
@@ -289,13 +401,14 @@ func (suite *OrderTestSuite) TestOrder_CreateRefund_ValidationError() {
 }
 
 func (suite *OrderTestSuite) TestOrder_CreateRefund_BillingServerError() {
-	data := `{"amount": 10, "reason": "test"}`
+	data := `{"amount": 10, "reason": "customer_request"}`
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(data))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds")
 	ctx.SetParamNames(requestParameterOrderId)
@@ -312,13 +425,14 @@ func (suite *OrderTestSuite) TestOrder_CreateRefund_BillingServerError() {
 }
 
 func (suite *OrderTestSuite) TestOrder_CreateRefund_BillingServer_CreateError() {
-	data := `{"amount": 10, "reason": "test"}`
+	data := `{"amount": 10, "reason": "customer_request"}`
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(data))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/order/:order_id/refunds")
 	ctx.SetParamNames(requestParameterOrderId)
@@ -334,6 +448,301 @@ func (suite *OrderTestSuite) TestOrder_CreateRefund_BillingServer_CreateError()
 	assert.Equal(suite.T(), mock.SomeError, httpErr.Message)
 }
 
+func (suite *OrderTestSuite) TestOrder_CreateRefund_Error() {
+	tests := []struct {
+		name    string
+		data    string
+		orderId string
+		code    int
+		message string
+	}{
+		{
+			name:    "unknown reason",
+			data:    `{"amount": 10, "reason": "test"}`,
+			orderId: suite.refundOrder.Uuid,
+			code:    http.StatusBadRequest,
+			message: errorMessageRefundReasonUnknown,
+		},
+		{
+			name:    "amount greater than refundable amount",
+			data:    `{"amount": 80.01, "reason": "duplicate"}`,
+			orderId: suite.refundOrder.Uuid,
+			code:    http.StatusBadRequest,
+			message: fmt.Sprintf(errorMessageRefundAmountExceeded, 2, 80.0, "RUB"),
+		},
+		{
+			name:    "order not found",
+			data:    `{"amount": 10, "reason": "fraud"}`,
+			orderId: uuid.New().String(),
+			code:    http.StatusNotFound,
+			message: model.ResponseMessageNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.data))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		ctx := suite.api.Http.NewContext(req, httptest.NewRecorder())
+		getRequestContext(ctx).AuthUser = suite.authUser
+
+		ctx.SetPath("/order/:order_id/refunds")
+		ctx.SetParamNames(requestParameterOrderId)
+		ctx.SetParamValues(tt.orderId)
+
+		err := suite.router.createRefund(ctx)
+
+		if !assert.Error(suite.T(), err, tt.name) {
+			continue
+		}
+
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok, tt.name)
+		assert.Equal(suite.T(), tt.code, httpErr.Code, tt.name)
+		assert.Equal(suite.T(), tt.message, httpErr.Message, tt.name)
+	}
+}
+
+func (suite *OrderTestSuite) TestOrder_CreateRefund_OrderOfOtherMerchant_Error() {
+	suite.refundOrder.Project.Merchant = &billing.Merchant{Id: bson.NewObjectId().Hex()}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 10, "reason": "fraud"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	ctx := suite.api.Http.NewContext(req, httptest.NewRecorder())
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/refunds")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(suite.refundOrder.Uuid)
+
+	err := suite.router.createRefund(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+}
+
+func (suite *OrderTestSuite) TestOrder_GetRefundPreview_Ok() {
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/refunds/preview")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(suite.refundOrder.Uuid)
+
+	err := suite.router.getRefundPreview(ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	preview := &model.RefundPreview{}
+	err = json.Unmarshal(rsp.Body.Bytes(), preview)
+	assert.NoError(suite.T(), err)
+
+	// two not rejected refunds with amount 10 returned by billing server
+	assert.Equal(suite.T(), suite.refundOrder.Uuid, preview.OrderId)
+	assert.Equal(suite.T(), "RUB", preview.Currency)
+	assert.Equal(suite.T(), 2, preview.RefundsCount)
+	assert.Equal(suite.T(), float64(20), preview.RefundedAmount)
+	assert.Equal(suite.T(), float64(80), preview.RefundableAmount)
+	assert.Equal(suite.T(), mock.OnboardingMerchantMock.Banking.Currency.CodeA3, preview.MerchantCurrency)
+	assert.Equal(suite.T(), float64(10), preview.MerchantRefundedAmount)
+	assert.Equal(suite.T(), float64(40), preview.MerchantRefundableAmount)
+	assert.Len(suite.T(), preview.Reasons, len(model.RefundReasons))
+}
+
+func (suite *OrderTestSuite) TestOrder_GetRefundPreview_OrderIdIncorrect_Error() {
+	ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/refunds/preview")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues("order")
+
+	err := suite.router.getRefundPreview(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), errorIncorrectOrderId, httpErr.Message)
+}
+
+func (suite *OrderTestSuite) TestOrder_RefundPreview_NotRefundableOrder() {
+	refunds := []*billing.Refund{
+		{Amount: 30, Status: model.RefundStatusCompleted},
+		{Amount: 50, Status: model.RefundStatusRejected},
+	}
+
+	p := model.NewRefundPreview(suite.refundOrder, suite.refundOrder.Uuid, refunds)
+	assert.Equal(suite.T(), 1, p.RefundsCount)
+	assert.Equal(suite.T(), float64(70), p.RefundableAmount)
+	assert.Equal(suite.T(), float64(35), p.MerchantRefundableAmount)
+
+	suite.refundOrder.Status = model.OrderStatusChargeback
+	p = model.NewRefundPreview(suite.refundOrder, suite.refundOrder.Uuid, refunds)
+	assert.Equal(suite.T(), float64(30), p.RefundedAmount)
+	assert.Zero(suite.T(), p.RefundableAmount)
+	assert.Zero(suite.T(), p.MerchantRefundableAmount)
+}
+
+func (suite *OrderTestSuite) TestOrder_RefundPreview_CurrencyPrecision() {
+	refunds := []*billing.Refund{{Amount: 10, Status: model.RefundStatusCompleted}}
+
+	suite.refundOrder.PaymentMethodIncomeAmount = 20.007
+	suite.refundOrder.PaymentMethodIncomeCurrency = &model.Currency{CodeA3: "KWD"}
+
+	p := model.NewRefundPreview(suite.refundOrder, suite.refundOrder.Uuid, refunds)
+	assert.Equal(suite.T(), 10.007, p.RefundableAmount)
+
+	assert.Equal(suite.T(), float64(1001), model.RoundCurrencyAmount(1000.5, "JPY"))
+	assert.Equal(suite.T(), 10.007, model.RoundCurrencyAmount(10.0069999, "KWD"))
+	assert.Equal(suite.T(), 10.01, model.RoundCurrencyAmount(10.0050001, "USD"))
+}
+
+func (suite *OrderTestSuite) TestOrder_CreateRefund_ThreeDigitsCurrency_AmountExceeded_Error() {
+	// two refunds with amount 10 returned by billing server, so 10.007 can be refunded yet
+	suite.refundOrder.PaymentMethodIncomeAmount = 30.007
+	suite.refundOrder.PaymentMethodIncomeCurrency = &model.Currency{CodeA3: "KWD"}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": 10.01, "reason": "duplicate"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	ctx := suite.api.Http.NewContext(req, httptest.NewRecorder())
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/refunds")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(suite.refundOrder.Uuid)
+
+	err := suite.router.createRefund(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), "refund amount can't be greater than refundable amount 10.007 KWD", httpErr.Message)
+}
+
+func (suite *OrderTestSuite) TestOrder_CreateRefund_AmountRounded_Ok() {
+	billingService := &orderTestRefundBillingService{BillingService: suite.router.billingService}
+	suite.router.billingService = billingService
+
+	err := suite.createRefund(`{"amount": 10.0049, "reason": "duplicate"}`)
+	assert.NoError(suite.T(), err)
+
+	if assert.NotNil(suite.T(), billingService.req) {
+		assert.Equal(suite.T(), float64(10), billingService.req.Amount)
+	}
+}
+
+func (suite *OrderTestSuite) TestOrder_CreateRefund_AmountNotPositive_Error() {
+	for _, amount := range []string{"0", "-5", "0.001"} {
+		err := suite.createRefund(`{"amount": ` + amount + `, "reason": "duplicate"}`)
+
+		if !assert.Error(suite.T(), err, amount) {
+			continue
+		}
+
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok)
+		assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code, amount)
+	}
+
+	// amount less than minor unit of currency rounded to zero
+	err := suite.createRefund(`{"amount": 0.001, "reason": "duplicate"}`)
+	httpErr, ok := err.(*echo.HTTPError)
+
+	if assert.True(suite.T(), ok) {
+		assert.Equal(suite.T(), errorMessageRefundAmountIncorrect, httpErr.Message)
+	}
+}
+
+func (suite *OrderTestSuite) TestOrder_CreateRefund_RefundInProcess_Error() {
+	ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+	unlock, err := suite.router.lockOrderRefunds(ctx, suite.refundOrder.Uuid)
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	err = suite.createRefund(`{"amount": 10, "reason": "duplicate"}`)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusConflict, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageRefundInProcess, httpErr.Message)
+
+	unlock()
+
+	// lock released after every request, so refunds of order can be created one by one
+	assert.NoError(suite.T(), suite.createRefund(`{"amount": 10, "reason": "duplicate"}`))
+	assert.NoError(suite.T(), suite.createRefund(`{"amount": 10, "reason": "duplicate"}`))
+}
+
+func (suite *OrderTestSuite) createRefund(body string) error {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	ctx := suite.api.Http.NewContext(req, httptest.NewRecorder())
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/refunds")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(suite.refundOrder.Uuid)
+
+	return suite.router.createRefund(ctx)
+}
+
+func (suite *OrderTestSuite) TestOrder_ListRefunds_Filter() {
+	tests := []struct {
+		name  string
+		query string
+		count int
+		err   string
+	}{
+		{name: "by status", query: "status=0", count: 2},
+		{name: "by reason", query: "reason=fraud", count: 0},
+		{name: "by reason and status", query: "reason=fraud&status=3", count: 0},
+		{name: "unknown reason", query: "reason=test", err: errorMessageRefundReasonUnknown},
+		{name: "unknown status", query: "status=4", err: errorMessageStatusIncorrectType},
+	}
+
+	for _, tt := range tests {
+		rsp := httptest.NewRecorder()
+		ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil), rsp)
+		getRequestContext(ctx).AuthUser = suite.authUser
+
+		ctx.SetPath("/order/:order_id/refunds")
+		ctx.SetParamNames(requestParameterOrderId)
+		ctx.SetParamValues(suite.refundOrder.Uuid)
+
+		err := suite.router.listRefunds(ctx)
+
+		if tt.err != "" {
+			if !assert.Error(suite.T(), err, tt.name) {
+				continue
+			}
+
+			httpErr, ok := err.(*echo.HTTPError)
+			assert.True(suite.T(), ok, tt.name)
+			assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code, tt.name)
+			assert.Equal(suite.T(), tt.err, httpErr.Message, tt.name)
+			continue
+		}
+
+		assert.NoError(suite.T(), err, tt.name)
+
+		list := &struct {
+			Count int                      `json:"count"`
+			Items []map[string]interface{} `json:"items"`
+		}{}
+		err = json.Unmarshal(rsp.Body.Bytes(), list)
+		assert.NoError(suite.T(), err, tt.name)
+		assert.Equal(suite.T(), tt.count, list.Count, tt.name)
+		assert.Len(suite.T(), list.Items, tt.count, tt.name)
+	}
+}
+
 func (suite *OrderTestSuite) TestOrder_ChangeLanguage_Ok() {
 	body := `{"lang": "en"}`
 
@@ -792,6 +1201,52 @@ func (suite *OrderTestSuite) TestOrder_CreateJson_BindError() {
 	assert.Equal(suite.T(), errorQueryParamsIncorrect, httpErr.Message)
 }
 
+func (suite *OrderTestSuite) TestOrder_CreateJson_RevokedToken_Error() {
+	now := time.Now()
+	err := suite.api.customerTokenStore.Insert(&model.CustomerToken{
+		Id:        getCustomerTokenId("revoked_token"),
+		ProjectId: bson.NewObjectId().Hex(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+		RevokedAt: &now,
+	})
+	assert.NoError(suite.T(), err)
+
+	order := &billing.OrderCreateRequest{
+		ProjectId:     bson.NewObjectId().Hex(),
+		PaymentMethod: "BANKCARD",
+		Currency:      "RUB",
+		Amount:        100,
+		Description:   "unit test",
+		OrderId:       bson.NewObjectId().Hex(),
+	}
+
+	b, err := json.Marshal(order)
+	assert.NoError(suite.T(), err)
+
+	body := make(map[string]interface{})
+	err = json.Unmarshal(b, &body)
+	assert.NoError(suite.T(), err)
+
+	body["token"] = "revoked_token"
+	b, err = json.Marshal(body)
+	assert.NoError(suite.T(), err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rsp := httptest.NewRecorder()
+	ctx := e.NewContext(req, rsp)
+
+	err = suite.api.RawBodyMiddleware(suite.router.createJson)(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageCustomerTokenRevoked, httpErr.Message)
+}
+
 func (suite *OrderTestSuite) TestOrder_CreateJson_WithUser_EmptyRequestSignature_Error() {
 	order := &billing.OrderCreateRequest{
 		ProjectId:     bson.NewObjectId().Hex(),
@@ -1102,3 +1557,78 @@ func (suite *OrderTestSuite) TestOrder_GetOrderForm_BillingServerSystemError() {
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 	assert.Equal(suite.T(), mock.SomeError, httpErr.Message)
 }
+
+func (suite *OrderTestSuite) TestOrder_GetTimeline_Ok() {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/timeline")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(suite.refundOrder.Uuid)
+
+	err := suite.router.getTimeline(ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	timeline := &model.OrderTimeline{}
+	err = json.Unmarshal(rsp.Body.Bytes(), timeline)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.refundOrder.Id, timeline.OrderId)
+}
+
+func (suite *OrderTestSuite) TestOrder_GetTimeline_OrderIdIncorrect_Error() {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+
+	ctx.SetPath("/order/:order_id/timeline")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(bson.NewObjectId().Hex())
+
+	err := suite.router.getTimeline(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), errorIncorrectOrderId, httpErr.Message)
+}
+
+func (suite *OrderTestSuite) TestOrder_GetTimeline_OrderNotFound_Error() {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/order/:order_id/timeline")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(uuid.New().String())
+
+	err := suite.router.getTimeline(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+}
+
+func (suite *OrderTestSuite) TestOrder_GetTimeline_BillingServerError() {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+
+	ctx.SetPath("/order/:order_id/timeline")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(suite.refundOrder.Uuid)
+
+	suite.router.billingService = mock.NewBillingServerSystemErrorMock()
+	err := suite.router.getTimeline(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusInternalServerError, httpErr.Code)
+	assert.Equal(suite.T(), errorUnknown, httpErr.Message)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/database/dao"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"net/http"
+)
+
+// Cursors of listings served by billing server. Billing server supports only limit and offset
+// pagination, so cursors of these listings hold offset of neighbour pages
+type listingCursors struct {
+	// cursor to get next page. empty if next page not exists
+	NextCursor string `json:"next_cursor,omitempty"`
+	// cursor to get previous page. empty if previous page not exists
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Get mode of listing count calculation. Exact count calculated by default for offset pagination
+// and not calculated for cursor pagination
+func getCountMode(rc *RequestContext) (string, error) {
+	switch rc.CountMode {
+	case "":
+		if rc.CursorPaging {
+			return model.CountModeNone, nil
+		}
+
+		return model.CountModeExact, nil
+	case model.CountModeExact, model.CountModeEstimated, model.CountModeNone:
+		return rc.CountMode, nil
+	default:
+		return "", echo.NewHTTPError(http.StatusBadRequest, errorMessageCountModeIncorrect)
+	}
+}
+
+// Get paginator of listing stored in database. Nil paginator returned if client not requested cursor pagination
+func getCursorPaginator(rc *RequestContext) (*dao.CursorPaginator, error) {
+	if !rc.CursorPaging {
+		return nil, nil
+	}
+
+	if rc.Offset > 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errorMessageCursorWithOffset)
+	}
+
+	var cursor *dao.Cursor
+	var err error
+
+	if rc.Cursor != "" {
+		cursor, err = dao.DecodeCursor(rc.Cursor)
+
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, errorMessageCursorIncorrect)
+		}
+	}
+
+	paginator, err := dao.NewCursorPaginator(rc.Sort, cursor, rc.Limit)
+
+	if err == dao.ErrCursorSortUnsupported {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errorMessageCursorSortIncorrect)
+	}
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errorMessageCursorIncorrect)
+	}
+
+	return paginator, nil
+}
+
+// Get offset of listing served by billing server from cursor. Offset of request returned
+// if client not requested cursor pagination
+func getListingCursorOffset(rc *RequestContext, offset int32) (int32, error) {
+	if !rc.CursorPaging {
+		return offset, nil
+	}
+
+	if offset > 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, errorMessageCursorWithOffset)
+	}
+
+	if rc.Cursor == "" {
+		return 0, nil
+	}
+
+	cursor, err := dao.DecodeCursor(rc.Cursor)
+
+	if err != nil || cursor.Id != "" {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, errorMessageCursorIncorrect)
+	}
+
+	return cursor.Offset, nil
+}
+
+// Get cursors to neighbour pages of listing served by billing server. Nil returned if client
+// not requested cursor pagination
+func getListingCursors(rc *RequestContext, offset, limit, count int32) (*listingCursors, error) {
+	if !rc.CursorPaging {
+		return nil, nil
+	}
+
+	cursors := &listingCursors{}
+
+	var err error
+
+	if offset+limit < count {
+		cursors.NextCursor, err = dao.EncodeCursor(&dao.Cursor{Offset: offset + limit})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if offset > 0 {
+		prev := offset - limit
+
+		if prev < 0 {
+			prev = 0
+		}
+
+		cursors.PrevCursor, err = dao.EncodeCursor(&dao.Cursor{Offset: prev})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cursors, nil
+}
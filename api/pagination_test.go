@@ -0,0 +1,249 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/globalsign/mgo/bson"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/database/dao"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type paginationTestDocument struct {
+	Id        bson.ObjectId `bson:"_id"`
+	CreatedAt time.Time     `bson:"created_at"`
+}
+
+type PaginationTestSuite struct {
+	suite.Suite
+	router *orderRoute
+	docs   []*paginationTestDocument
+}
+
+func Test_Pagination(t *testing.T) {
+	suite.Run(t, new(PaginationTestSuite))
+}
+
+func (suite *PaginationTestSuite) SetupTest() {
+	api := &Api{
+		Http:           echo.New(),
+		validate:       validator.New(),
+		billingService: mock.NewBillingServerOkMock(),
+	}
+	suite.router = &orderRoute{Api: api}
+
+	suite.docs = nil
+
+	for i := 0; i < 3; i++ {
+		suite.docs = append(suite.docs, &paginationTestDocument{
+			Id:        bson.NewObjectId(),
+			CreatedAt: time.Date(2019, 5, 20, 10, i, 0, 0, time.UTC),
+		})
+	}
+}
+
+func (suite *PaginationTestSuite) TearDownTest() {}
+
+func (suite *PaginationTestSuite) getRequestContext(query string) *RequestContext {
+	var rc *RequestContext
+
+	req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	ctx := suite.router.Http.NewContext(req, httptest.NewRecorder())
+	err := suite.router.LimitOffsetSortMiddleware(func(ctx echo.Context) error {
+		rc = getRequestContext(ctx)
+		return nil
+	})(ctx)
+	assert.NoError(suite.T(), err)
+
+	return rc
+}
+
+func (suite *PaginationTestSuite) listRefunds(query string) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	rsp := httptest.NewRecorder()
+	ctx := suite.router.Http.NewContext(req, rsp)
+
+	ctx.SetPath("/order/:order_id/refunds")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(uuid.New().String())
+
+	err := suite.router.LimitOffsetSortMiddleware(suite.router.listRefunds)(ctx)
+
+	return rsp, err
+}
+
+func (suite *PaginationTestSuite) TestPagination_Middleware_Ok() {
+	rc := suite.getRequestContext("limit=10")
+	assert.False(suite.T(), rc.CursorPaging)
+
+	rc = suite.getRequestContext("cursor=&count=none")
+	assert.True(suite.T(), rc.CursorPaging)
+	assert.Empty(suite.T(), rc.Cursor)
+	assert.Equal(suite.T(), model.CountModeNone, rc.CountMode)
+
+	rc = suite.getRequestContext("cursor=abc")
+	assert.True(suite.T(), rc.CursorPaging)
+	assert.Equal(suite.T(), "abc", rc.Cursor)
+}
+
+func (suite *PaginationTestSuite) TestPagination_CountMode_Ok() {
+	mode, err := getCountMode(suite.getRequestContext(""))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.CountModeExact, mode)
+
+	mode, err = getCountMode(suite.getRequestContext("cursor="))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.CountModeNone, mode)
+
+	mode, err = getCountMode(suite.getRequestContext("cursor=&count=estimated"))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.CountModeEstimated, mode)
+
+	_, err = getCountMode(suite.getRequestContext("count=all"))
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), errorMessageCountModeIncorrect, err.(*echo.HTTPError).Message)
+}
+
+func (suite *PaginationTestSuite) TestPagination_CursorPaginator_Error() {
+	paginator, err := getCursorPaginator(suite.getRequestContext("offset=10"))
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), paginator)
+
+	_, err = getCursorPaginator(suite.getRequestContext("cursor=&offset=10"))
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), errorMessageCursorWithOffset, err.(*echo.HTTPError).Message)
+
+	_, err = getCursorPaginator(suite.getRequestContext("cursor=not_a_cursor"))
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), errorMessageCursorIncorrect, err.(*echo.HTTPError).Message)
+
+	_, err = getCursorPaginator(suite.getRequestContext("cursor=&sort[]=created_at&sort[]=status"))
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), errorMessageCursorSortIncorrect, err.(*echo.HTTPError).Message)
+
+	// offset cursor of listings served by billing server can't be used as keyset cursor
+	token, err := dao.EncodeCursor(&dao.Cursor{Offset: 100})
+	assert.NoError(suite.T(), err)
+
+	_, err = getCursorPaginator(suite.getRequestContext("cursor=" + token))
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), errorMessageCursorIncorrect, err.(*echo.HTTPError).Message)
+}
+
+func (suite *PaginationTestSuite) TestPagination_CursorPaginator_Ok() {
+	paginator, err := getCursorPaginator(suite.getRequestContext("cursor=&limit=2&sort[]=-created_at"))
+
+	if !assert.NoError(suite.T(), err) || !assert.NotNil(suite.T(), paginator) {
+		return
+	}
+
+	assert.Equal(suite.T(), []string{"-created_at", "-_id"}, paginator.Sort())
+	assert.Equal(suite.T(), 3, paginator.Limit())
+
+	filters := bson.M{"project.id": bson.ObjectIdHex("ffffffffffffffffffffffff")}
+	assert.Equal(suite.T(), filters, paginator.Filter(filters))
+
+	docs := []*paginationTestDocument{suite.docs[2], suite.docs[1], suite.docs[0]}
+	assert.NoError(suite.T(), paginator.Apply(&docs))
+	assert.Equal(suite.T(), []*paginationTestDocument{suite.docs[2], suite.docs[1]}, docs)
+	assert.Empty(suite.T(), paginator.Prev())
+
+	next, err := dao.DecodeCursor(paginator.Next())
+
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), suite.docs[1].Id, next.Id)
+		assert.Equal(suite.T(), suite.docs[1].CreatedAt, next.Value.(time.Time).UTC())
+		assert.False(suite.T(), next.Backward)
+	}
+
+	// second page contains last document and leads back to first page
+	paginator, err = getCursorPaginator(suite.getRequestContext("limit=2&sort[]=-created_at&cursor=" + paginator.Next()))
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	assert.Contains(suite.T(), paginator.Filter(filters), "$and")
+
+	docs = []*paginationTestDocument{suite.docs[0]}
+	assert.NoError(suite.T(), paginator.Apply(&docs))
+	assert.Len(suite.T(), docs, 1)
+	assert.Empty(suite.T(), paginator.Next())
+
+	prev, err := dao.DecodeCursor(paginator.Prev())
+
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), suite.docs[0].Id, prev.Id)
+		assert.True(suite.T(), prev.Backward)
+	}
+
+	// previous page selected in reverse order and restored by paginator
+	paginator, err = getCursorPaginator(suite.getRequestContext("limit=2&sort[]=-created_at&cursor=" + paginator.Prev()))
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	assert.Equal(suite.T(), []string{"created_at", "_id"}, paginator.Sort())
+
+	docs = []*paginationTestDocument{suite.docs[1], suite.docs[2]}
+	assert.NoError(suite.T(), paginator.Apply(&docs))
+	assert.Equal(suite.T(), []*paginationTestDocument{suite.docs[2], suite.docs[1]}, docs)
+	assert.NotEmpty(suite.T(), paginator.Next())
+	assert.Empty(suite.T(), paginator.Prev())
+}
+
+func (suite *PaginationTestSuite) TestPagination_ListRefunds_Cursor_Ok() {
+	rsp, err := suite.listRefunds("cursor=&limit=1")
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	data := &listingCursors{}
+	assert.NoError(suite.T(), json.Unmarshal(rsp.Body.Bytes(), data))
+	assert.Empty(suite.T(), data.PrevCursor)
+	assert.Contains(suite.T(), rsp.Body.String(), `"items"`)
+
+	next, err := dao.DecodeCursor(data.NextCursor)
+
+	if assert.NoError(suite.T(), err) {
+		assert.EqualValues(suite.T(), 1, next.Offset)
+	}
+
+	rsp, err = suite.listRefunds("limit=1&cursor=" + data.NextCursor)
+
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	data = &listingCursors{}
+	assert.NoError(suite.T(), json.Unmarshal(rsp.Body.Bytes(), data))
+	assert.Empty(suite.T(), data.NextCursor)
+	assert.NotEmpty(suite.T(), data.PrevCursor)
+}
+
+func (suite *PaginationTestSuite) TestPagination_ListRefunds_WithoutCursor_Ok() {
+	rsp, err := suite.listRefunds("limit=1")
+
+	if assert.NoError(suite.T(), err) {
+		assert.NotContains(suite.T(), rsp.Body.String(), "next_cursor")
+	}
+}
+
+func (suite *PaginationTestSuite) TestPagination_ListRefunds_CursorIncorrect_Error() {
+	_, err := suite.listRefunds("cursor=&offset=1")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), errorMessageCursorWithOffset, err.(*echo.HTTPError).Message)
+
+	_, err = suite.listRefunds("cursor=not_a_cursor")
+	assert.Error(suite.T(), err)
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-payment-link/proto"
@@ -17,19 +16,32 @@ func (api *Api) InitPaylinkRoutes() *Api {
 		Api: api,
 	}
 
-	api.authUserRouteGroup.GET("/paylinks/project/:project_id", paylinkApiV1.getPaylinksList)
-	api.authUserRouteGroup.GET("/paylinks/:id", paylinkApiV1.getPaylink)
-	api.authUserRouteGroup.GET("/paylinks/:id/stat", paylinkApiV1.getPaylinkStat)
-	api.authUserRouteGroup.GET("/paylinks/:id/url", paylinkApiV1.getPaylinkUrl)
-	api.authUserRouteGroup.DELETE("/paylinks/:id", paylinkApiV1.deletePaylink)
-	api.authUserRouteGroup.POST("/paylinks", paylinkApiV1.createPaylink)
-	api.authUserRouteGroup.PUT("/paylinks/:id", paylinkApiV1.updatePaylink)
+	api.authUserRouteGroup.GET("/paylinks/project/:project_id", paylinkApiV1.getPaylinksList, api.requireProjectAccess(requestParameterProjectId, rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/paylinks/:id", paylinkApiV1.getPaylink, api.requirePaylinkAccess(requestParameterId, rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/paylinks/:id/stat", paylinkApiV1.getPaylinkStat, api.requirePaylinkAccess(requestParameterId, rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/paylinks/:id/url", paylinkApiV1.getPaylinkUrl, api.requirePaylinkAccess(requestParameterId, rolesMerchantRead...))
+	api.authUserRouteGroup.DELETE("/paylinks/:id", paylinkApiV1.deletePaylink, api.requirePaylinkAccess(requestParameterId, rolesMerchantWrite...))
+	api.authUserRouteGroup.POST("/paylinks", paylinkApiV1.createPaylink, api.requireRoles(rolesMerchantWrite...))
+	api.authUserRouteGroup.PUT("/paylinks/:id", paylinkApiV1.updatePaylink, api.requirePaylinkAccess(requestParameterId, rolesMerchantWrite...))
 
 	return api
 }
 
-// @Description Get list of paylinks for project, for authenticated merchant
+// @Summary Get payment links
+// @Description Get list of payment links of project for authenticated merchant
 // @Example GET /admin/api/v1/paylinks/project/21784001599a47e5a69ac28f7af2ec22?offset=0&limit=10
+// @Tags Paylink
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path string true "Project unique identifier"
+// @Param limit query integer false "maximum number of returning payment links. default value is 100"
+// @Param offset query integer false "offset from which you want to return the list of payment links. default value is 0"
+// @Success 200 {object} paylink.ListResponse "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/paylinks/project/{project_id} [get]
 func (r *paylinkRoute) getPaylinksList(ctx echo.Context) error {
 	req := &paylink.GetPaylinksRequest{}
 	err := (&PaylinksListBinder{}).Bind(req, ctx)
@@ -38,7 +50,7 @@ func (r *paylinkRoute) getPaylinksList(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
 	}
 
-	merchant, err := r.billingService.GetMerchantBy(context.TODO(), &grpc.GetMerchantByRequest{UserId: r.authUser.Id})
+	merchant, err := r.billingService.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: getRequestContext(ctx).AuthUser.Id})
 	if err != nil || merchant.Item == nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
@@ -50,7 +62,7 @@ func (r *paylinkRoute) getPaylinksList(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := r.paylinkService.GetPaylinks(context.TODO(), req)
+	res, err := r.paylinkService.GetPaylinks(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -58,8 +70,19 @@ func (r *paylinkRoute) getPaylinksList(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
-// @Description Get paylink, for authenticated merchant
+// @Summary Get payment link
+// @Description Get payment link for authenticated merchant
 // @Example GET /admin/api/v1/paylinks/21784001599a47e5a69ac28f7af2ec22
+// @Tags Paylink
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment link unique identifier"
+// @Success 200 {object} paylink.Paylink "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/paylinks/{id} [get]
 func (r *paylinkRoute) getPaylink(ctx echo.Context) error {
 	id := ctx.Param(requestParameterId)
 
@@ -71,7 +94,7 @@ func (r *paylinkRoute) getPaylink(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := r.paylinkService.GetPaylink(context.TODO(), req)
+	res, err := r.paylinkService.GetPaylink(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -79,8 +102,19 @@ func (r *paylinkRoute) getPaylink(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
-// @Description Get stat for paylink
+// @Summary Get payment link statistic
+// @Description Get visits statistic of payment link
 // @Example GET /admin/api/v1/paylinks/21784001599a47e5a69ac28f7af2ec22/stat
+// @Tags Paylink
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment link unique identifier"
+// @Success 200 {object} object "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/paylinks/{id}/stat [get]
 func (r *paylinkRoute) getPaylinkStat(ctx echo.Context) error {
 	id := ctx.Param(requestParameterId)
 
@@ -92,7 +126,7 @@ func (r *paylinkRoute) getPaylinkStat(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := r.paylinkService.GetPaylinkStat(context.TODO(), req)
+	res, err := r.paylinkService.GetPaylinkStat(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -100,8 +134,22 @@ func (r *paylinkRoute) getPaylinkStat(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
-// @Description paylink public url
+// @Summary Get payment link url
+// @Description Get public url of payment link with utm parameters
 // @Example GET /admin/api/v1/paylinks/21784001599a47e5a69ac28f7af2ec22/url?utm_source=3wefwe&utm_medium=njytrn&utm_campaign=bdfbh5
+// @Tags Paylink
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment link unique identifier"
+// @Param utm_source query string false "utm source to add to url"
+// @Param utm_medium query string false "utm medium to add to url"
+// @Param utm_campaign query string false "utm campaign to add to url"
+// @Success 200 {object} object "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/paylinks/{id}/url [get]
 func (r *paylinkRoute) getPaylinkUrl(ctx echo.Context) error {
 	req := &paylink.GetPaylinkURLRequest{}
 	err := (&PaylinksUrlBinder{}).Bind(req, ctx)
@@ -115,7 +163,7 @@ func (r *paylinkRoute) getPaylinkUrl(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := r.paylinkService.GetPaylinkURL(context.TODO(), req)
+	res, err := r.paylinkService.GetPaylinkURL(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -123,8 +171,19 @@ func (r *paylinkRoute) getPaylinkUrl(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
-// @Description Get paylink, for authenticated merchant
+// @Summary Delete payment link
+// @Description Delete payment link for authenticated merchant
 // @Example DELETE /admin/api/v1/paylinks/21784001599a47e5a69ac28f7af2ec22
+// @Tags Paylink
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment link unique identifier"
+// @Success 204 "Payment link deleted"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/paylinks/{id} [delete]
 func (r *paylinkRoute) deletePaylink(ctx echo.Context) error {
 	id := ctx.Param(requestParameterId)
 
@@ -136,7 +195,7 @@ func (r *paylinkRoute) deletePaylink(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	_, err = r.paylinkService.DeletePaylink(context.TODO(), req)
+	_, err = r.paylinkService.DeletePaylink(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -144,14 +203,39 @@ func (r *paylinkRoute) deletePaylink(ctx echo.Context) error {
 	return ctx.NoContent(http.StatusNoContent)
 }
 
-// @Description Create paylink, for authenticated merchant
+// @Summary Create payment link
+// @Description Create payment link for authenticated merchant
 // @Example POST /admin/api/v1/paylinks
+// @Tags Paylink
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body paylink.CreateRequest true "Payment link data"
+// @Success 200 {object} paylink.Paylink "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/paylinks [post]
 func (r *paylinkRoute) createPaylink(ctx echo.Context) error {
 	return r.createOrUpdatePaylink(ctx, &PaylinksCreateBinder{})
 }
 
-// @Description Update paylink, for authenticated merchant
+// @Summary Update payment link
+// @Description Update payment link for authenticated merchant
 // @Example PUT /admin/api/v1/paylinks/21784001599a47e5a69ac28f7af2ec22
+// @Tags Paylink
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payment link unique identifier"
+// @Param data body paylink.CreateRequest true "Payment link data"
+// @Success 200 {object} paylink.Paylink "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/paylinks/{id} [put]
 func (r *paylinkRoute) updatePaylink(ctx echo.Context) error {
 	return r.createOrUpdatePaylink(ctx, &PaylinksUpdateBinder{})
 }
@@ -164,7 +248,7 @@ func (r *paylinkRoute) createOrUpdatePaylink(ctx echo.Context, binder echo.Binde
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	merchant, err := r.billingService.GetMerchantBy(context.TODO(), &grpc.GetMerchantByRequest{UserId: r.authUser.Id})
+	merchant, err := r.billingService.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: getRequestContext(ctx).AuthUser.Id})
 	if err != nil || merchant.Item == nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
@@ -176,7 +260,12 @@ func (r *paylinkRoute) createOrUpdatePaylink(ctx echo.Context, binder echo.Binde
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := r.paylinkService.CreateOrUpdatePaylink(context.TODO(), req)
+	err = r.checkProjectAccess(ctx, req.ProjectId, rolesMerchantWrite...)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.paylinkService.CreateOrUpdatePaylink(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"context"
 	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -12,10 +15,35 @@ import (
 	"testing"
 )
 
+const (
+	paylinkTestMerchantId = "5c8f6a914dad6a0001839408"
+	paylinkTestProjectId  = "5c10ff51d5be4b0001bca600"
+)
+
+// Billing service which return projects of merchant of paylink mock
+type paylinkTestBillingService struct {
+	grpc.BillingService
+}
+
+func (s *paylinkTestBillingService) GetProject(
+	ctx context.Context,
+	in *grpc.GetProjectRequest,
+	opts ...client.CallOption,
+) (*grpc.ChangeProjectResponse, error) {
+	rsp, err := s.BillingService.GetProject(ctx, in, opts...)
+
+	if err == nil && rsp.Item != nil && in.ProjectId == paylinkTestProjectId {
+		rsp.Item.MerchantId = paylinkTestMerchantId
+	}
+
+	return rsp, err
+}
+
 type PaylinkTestSuite struct {
 	suite.Suite
-	router *paylinkRoute
-	api    *Api
+	router   *paylinkRoute
+	api      *Api
+	authUser *AuthUser
 }
 
 func Test_Paylink(t *testing.T) {
@@ -23,14 +51,17 @@ func Test_Paylink(t *testing.T) {
 }
 
 func (suite *PaylinkTestSuite) SetupTest() {
+	suite.authUser = &AuthUser{
+		Id:        "ffffffffffffffffffffffff",
+		Roles:     map[string]bool{RoleMerchantDeveloper: true},
+		Merchants: map[string]bool{paylinkTestMerchantId: true},
+	}
+
 	suite.api = &Api{
 		Http:           echo.New(),
 		validate:       validator.New(),
-		billingService: mock.NewBillingServerOkMock(),
+		billingService: &paylinkTestBillingService{BillingService: mock.NewBillingServerOkMock()},
 		paylinkService: mock.NewPaymentLinkOkMock(),
-		authUser: &AuthUser{
-			Id: "ffffffffffffffffffffffff",
-		},
 	}
 
 	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
@@ -45,6 +76,7 @@ func (suite *PaylinkTestSuite) TestPaylink_getPaylinksList_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/paylinks/project/:" + requestParameterProjectId)
 	ctx.SetParamNames(requestParameterProjectId)
@@ -135,13 +167,14 @@ func (suite *PaylinkTestSuite) TestPaylink_deletePaylink_Ok() {
 }
 
 func (suite *PaylinkTestSuite) TestPaylink_createPaylink_Ok() {
-	bodyJson := `{"life_days": 7, "products": ["5c3c962781258d0001e65930"], "project_id": "5c8f6a914dad6a0001839408"}`
+	bodyJson := `{"life_days": 7, "products": ["5c3c962781258d0001e65930"], "project_id": "5c10ff51d5be4b0001bca600"}`
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/paylinks", strings.NewReader(bodyJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/paylinks")
 
@@ -154,13 +187,14 @@ func (suite *PaylinkTestSuite) TestPaylink_createPaylink_Ok() {
 }
 
 func (suite *PaylinkTestSuite) TestPaylink_updatePaylink_Ok() {
-	bodyJson := `{"life_days": 30, "products": ["5c3c962781258d0001e65930"], "project_id": "5c8f6a914dad6a0001839408"}`
+	bodyJson := `{"life_days": 30, "products": ["5c3c962781258d0001e65930"], "project_id": "5c10ff51d5be4b0001bca600"}`
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/paylinks/21784001599a47e5a69ac28f7af2ec22", strings.NewReader(bodyJson))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/paylinks/:" + requestParameterId)
 	ctx.SetParamNames(requestParameterId)
@@ -173,3 +207,43 @@ func (suite *PaylinkTestSuite) TestPaylink_updatePaylink_Ok() {
 		assert.NotEmpty(suite.T(), rsp.Body.String())
 	}
 }
+
+func (suite *PaylinkTestSuite) TestPaylink_createPaylink_OtherMerchantProject_AccessDenied() {
+	bodyJson := `{"life_days": 7, "products": ["5c3c962781258d0001e65930"], "project_id": "5c8f6a914dad6a0001839408"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/paylinks", strings.NewReader(bodyJson))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rsp := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	err := suite.router.createPaylink(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageAccessDenied, httpErr.Message)
+}
+
+func (suite *PaylinkTestSuite) TestPaylink_updatePaylink_ReadOnlyRole_AccessDenied() {
+	bodyJson := `{"life_days": 30, "products": ["5c3c962781258d0001e65930"], "project_id": "5c10ff51d5be4b0001bca600"}`
+
+	req := httptest.NewRequest(http.MethodPut, "/paylinks/21784001599a47e5a69ac28f7af2ec22", strings.NewReader(bodyJson))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rsp := httptest.NewRecorder()
+	ctx := echo.New().NewContext(req, rsp)
+	suite.authUser.Roles = map[string]bool{RoleMerchantFinance: true}
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	ctx.SetPath("/paylinks/:" + requestParameterId)
+	ctx.SetParamNames(requestParameterId)
+	ctx.SetParamValues("21784001599a47e5a69ac28f7af2ec22")
+
+	err := suite.router.updatePaylink(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+}
@@ -22,8 +22,16 @@ func (api *Api) InitPaymentMethodRoutes() *Api {
 	return api
 }
 
+// @Summary Get payment methods for filters
+// @Description Get payment methods of all merchant projects to use in orders filters
+// @Tags Payment Method
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} object "OK"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Router /api/v1/s/payment_method/merchant [get]
 func (pmApiV1 *PaymentMethodApiV1) getMerchantPaymentMethodsForFilters(ctx echo.Context) error {
-	p := pmApiV1.projectManager.GetProjectsPaymentMethodsByMerchantMainData(pmApiV1.Merchant.Identifier)
+	p := pmApiV1.projectManager.GetProjectsPaymentMethodsByMerchantMainData(getRequestContext(ctx).MerchantIdentifier)
 
 	return ctx.JSON(http.StatusOK, p)
 }
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/payment_system"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const paymentSystemClientTestMetricName = "paysuper_management_api_payment_system_circuit_state"
+
+type PaymentSystemClientTestSuite struct {
+	suite.Suite
+	pss      *payment_system.PaymentSystemSetting
+	server   *httptest.Server
+	calls    int32
+	statuses []int
+	// circuit breakers shared by all clients, so each test uses own terminal
+	terminal string
+}
+
+func Test_PaymentSystemClient(t *testing.T) {
+	suite.Run(t, new(PaymentSystemClientTestSuite))
+}
+
+func (suite *PaymentSystemClientTestSuite) SetupTest() {
+	suite.calls = 0
+	suite.statuses = nil
+	suite.terminal = bson.NewObjectId().Hex()
+	suite.pss = &payment_system.PaymentSystemSetting{
+		Logger: zap.NewNop().Sugar(),
+		Client: &payment_system.ClientSettings{
+			Timeout:            time.Second,
+			RetryAttempts:      3,
+			RetryBackoff:       time.Millisecond,
+			RetryMaxBackoff:    2 * time.Millisecond,
+			BreakerWindow:      4,
+			BreakerMinRequests: 4,
+			BreakerErrorRate:   0.5,
+			BreakerOpenTimeout: 50 * time.Millisecond,
+		},
+	}
+	// server responds with statuses from list by order of requests and with 200 when list is over
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&suite.calls, 1))
+
+		if n <= len(suite.statuses) {
+			w.WriteHeader(suite.statuses[n-1])
+			return
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	}))
+}
+
+func (suite *PaymentSystemClientTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *PaymentSystemClientTestSuite) send(idempotent bool) (*payment_system.Response, error) {
+	req := &payment_system.Request{Method: http.MethodPost, Url: suite.server.URL, Idempotent: idempotent}
+	return suite.pss.GetClient(payment_system.PaymentSystemHandlerCardPay, suite.terminal).Do(req)
+}
+
+func (suite *PaymentSystemClientTestSuite) getState() int {
+	return payment_system.GetCircuitState(payment_system.PaymentSystemHandlerCardPay, suite.terminal)
+}
+
+func (suite *PaymentSystemClientTestSuite) getStateMetric() float64 {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(suite.T(), err)
+
+	for _, mf := range mfs {
+		if mf.GetName() != paymentSystemClientTestMetricName {
+			continue
+		}
+
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "terminal" && l.GetValue() == suite.terminal {
+					return m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+
+	return -1
+}
+
+func (suite *PaymentSystemClientTestSuite) assertError(err error, errType string, status int) {
+	if !assert.Error(suite.T(), err) {
+		return
+	}
+
+	e, ok := err.(*payment_system.Error)
+
+	if assert.True(suite.T(), ok) {
+		assert.Equal(suite.T(), errType, e.Type)
+	}
+
+	assert.Equal(suite.T(), status, payment_system.GetErrorPaymentStatus(err))
+}
+
+func (suite *PaymentSystemClientTestSuite) TestPaymentSystemClient_Retry_Ok() {
+	suite.statuses = []int{http.StatusServiceUnavailable, http.StatusBadGateway}
+
+	rsp, err := suite.send(true)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.StatusCode)
+	assert.Equal(suite.T(), "{}", string(rsp.Body))
+	assert.Equal(suite.T(), int32(3), atomic.LoadInt32(&suite.calls))
+}
+
+func (suite *PaymentSystemClientTestSuite) TestPaymentSystemClient_RetryAttemptsExceeded_Error() {
+	suite.statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}
+
+	rsp, err := suite.send(true)
+	suite.assertError(err, payment_system.ErrorTypeServer, payment_system.CreatePaymentStatusErrorPaymentSystem)
+	assert.Equal(suite.T(), http.StatusInternalServerError, rsp.StatusCode)
+	assert.Equal(suite.T(), int32(3), atomic.LoadInt32(&suite.calls))
+}
+
+func (suite *PaymentSystemClientTestSuite) TestPaymentSystemClient_NotIdempotent_NotRetried() {
+	suite.statuses = []int{http.StatusServiceUnavailable}
+
+	_, err := suite.send(false)
+	suite.assertError(err, payment_system.ErrorTypeServer, payment_system.CreatePaymentStatusErrorPaymentSystem)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.calls))
+}
+
+func (suite *PaymentSystemClientTestSuite) TestPaymentSystemClient_Rejected_NotRetried() {
+	suite.statuses = []int{http.StatusBadRequest}
+
+	_, err := suite.send(true)
+	suite.assertError(err, payment_system.ErrorTypeRejected, payment_system.PaymentStatusErrorValidation)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.calls))
+
+	suite.statuses = append(suite.statuses, http.StatusUnauthorized)
+
+	_, err = suite.send(true)
+	suite.assertError(err, payment_system.ErrorTypeUnauthorized, payment_system.PaymentStatusErrorSystem)
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&suite.calls))
+}
+
+func (suite *PaymentSystemClientTestSuite) TestPaymentSystemClient_Timeout_Error() {
+	suite.pss.Client.Timeout = 20 * time.Millisecond
+	suite.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&suite.calls, 1)
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	_, err := suite.send(true)
+	suite.assertError(err, payment_system.ErrorTypeTimeout, payment_system.CreatePaymentStatusErrorPaymentSystem)
+	assert.Equal(suite.T(), int32(3), atomic.LoadInt32(&suite.calls))
+}
+
+func (suite *PaymentSystemClientTestSuite) TestPaymentSystemClient_Connection_Error() {
+	suite.server.Close()
+
+	_, err := suite.send(true)
+	suite.assertError(err, payment_system.ErrorTypeConnection, payment_system.CreatePaymentStatusErrorPaymentSystem)
+}
+
+func (suite *PaymentSystemClientTestSuite) TestPaymentSystemClient_CircuitBreaker() {
+	suite.statuses = []int{
+		http.StatusOK,
+		http.StatusOK,
+		http.StatusInternalServerError,
+		http.StatusInternalServerError,
+	}
+
+	for i := 0; i < 3; i++ {
+		_, _ = suite.send(false)
+	}
+
+	assert.Equal(suite.T(), payment_system.CircuitStateClosed, suite.getState())
+
+	// second failure of four requests reaches error rate
+	_, err := suite.send(false)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), payment_system.CircuitStateOpen, suite.getState())
+	assert.Equal(suite.T(), float64(payment_system.CircuitStateOpen), suite.getStateMetric())
+
+	_, err = suite.send(true)
+	suite.assertError(err, payment_system.ErrorTypeCircuitOpen, payment_system.CreatePaymentStatusErrorPaymentSystem)
+	assert.Equal(suite.T(), int32(4), atomic.LoadInt32(&suite.calls))
+
+	// probe request allowed after open timeout and closes circuit on success
+	time.Sleep(60 * time.Millisecond)
+
+	_, err = suite.send(false)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(5), atomic.LoadInt32(&suite.calls))
+	assert.Equal(suite.T(), payment_system.CircuitStateClosed, suite.getState())
+	assert.Equal(suite.T(), float64(payment_system.CircuitStateClosed), suite.getStateMetric())
+}
+
+func (suite *PaymentSystemClientTestSuite) TestPaymentSystemClient_CardPayConnection_Error() {
+	suite.server.Close()
+
+	config := map[string]interface{}{
+		payment_system.PaymentSystemHandlerCardPay: map[interface{}]interface{}{
+			"create_payment_url": suite.server.URL,
+			"BANKCARD": map[interface{}]interface{}{
+				"terminal_id":          suite.terminal,
+				"secret_word":          "secret",
+				"callback_secret_word": "callback_secret",
+			},
+		},
+	}
+
+	r, err := payment_system.NewRegistry(config, suite.pss)
+	assert.NoError(suite.T(), err)
+
+	o := &model.Order{
+		Id: bson.NewObjectId(),
+		PaymentMethod: &model.OrderPaymentMethod{
+			Params: &model.PaymentMethodParams{Handler: payment_system.PaymentSystemHandlerCardPay, ExternalId: "BANKCARD"},
+		},
+	}
+	p, err := r.GetProvider(o)
+	assert.NoError(suite.T(), err)
+
+	rsp := p.CreatePayment()
+	assert.Equal(suite.T(), payment_system.CreatePaymentStatusErrorPaymentSystem, rsp.Status)
+}
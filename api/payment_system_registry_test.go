@@ -0,0 +1,231 @@
+package api
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/payment_system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+type PaymentSystemRegistryTestSuite struct {
+	suite.Suite
+	pss *payment_system.PaymentSystemSetting
+}
+
+func Test_PaymentSystemRegistry(t *testing.T) {
+	suite.Run(t, new(PaymentSystemRegistryTestSuite))
+}
+
+func (suite *PaymentSystemRegistryTestSuite) SetupTest() {
+	suite.pss = &payment_system.PaymentSystemSetting{Logger: zap.NewNop().Sugar()}
+}
+
+func (suite *PaymentSystemRegistryTestSuite) TearDownTest() {}
+
+// Payment systems settings in the same form as it decoded from yaml file
+func (suite *PaymentSystemRegistryTestSuite) getConfig() map[string]interface{} {
+	return map[string]interface{}{
+		payment_system.PaymentSystemHandlerCardPay: map[interface{}]interface{}{
+			"create_payment_url": "https://sandbox.cardpay.com",
+			"BANKCARD": map[interface{}]interface{}{
+				"terminal_id":          "1",
+				"secret_word":          "secret",
+				"callback_secret_word": "callback_secret",
+			},
+		},
+	}
+}
+
+func (suite *PaymentSystemRegistryTestSuite) getOrder(handler, externalId string) *model.Order {
+	return &model.Order{
+		Id: bson.NewObjectId(),
+		PaymentMethod: &model.OrderPaymentMethod{
+			Id:     bson.NewObjectId(),
+			Params: &model.PaymentMethodParams{Handler: handler, ExternalId: externalId},
+		},
+	}
+}
+
+func (suite *PaymentSystemRegistryTestSuite) TestPaymentSystemRegistry_NewRegistry_Ok() {
+	r, err := payment_system.NewRegistry(suite.getConfig(), suite.pss)
+	assert.NoError(suite.T(), err)
+
+	c, err := r.GetCapabilities(payment_system.PaymentSystemHandlerCardPay)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), c.CreatePayment)
+	assert.True(suite.T(), c.Refund)
+	assert.False(suite.T(), c.Recurring)
+	assert.False(suite.T(), c.Payout)
+	assert.True(suite.T(), c.SupportsPaymentMethodType(model.PaymentMethodTypeBankCard))
+
+	p, err := r.GetProvider(suite.getOrder(payment_system.PaymentSystemHandlerCardPay, "BANKCARD"))
+	assert.NoError(suite.T(), err)
+	assert.IsType(suite.T(), &payment_system.CardPay{}, p)
+}
+
+func (suite *PaymentSystemRegistryTestSuite) TestPaymentSystemRegistry_NewRegistry_Error() {
+	tests := []struct {
+		name    string
+		modify  func(map[string]interface{})
+		message string
+	}{
+		{
+			name: "unknown provider",
+			modify: func(c map[string]interface{}) {
+				c["unknown"] = map[interface{}]interface{}{}
+			},
+			message: `payment system "unknown" is not registered`,
+		},
+		{
+			name: "url not found",
+			modify: func(c map[string]interface{}) {
+				delete(c[payment_system.PaymentSystemHandlerCardPay].(map[interface{}]interface{}), "create_payment_url")
+			},
+			message: `payment system "cardpay" settings are invalid: field create_payment_url is required`,
+		},
+		{
+			name: "url invalid",
+			modify: func(c map[string]interface{}) {
+				c[payment_system.PaymentSystemHandlerCardPay].(map[interface{}]interface{})["create_payment_url"] = "cardpay"
+			},
+			message: `payment system "cardpay" settings are invalid: field create_payment_url must be absolute url`,
+		},
+		{
+			name: "terminals not found",
+			modify: func(c map[string]interface{}) {
+				delete(c[payment_system.PaymentSystemHandlerCardPay].(map[interface{}]interface{}), "BANKCARD")
+			},
+			message: `payment system "cardpay" settings are invalid: settings of payment methods terminals not found`,
+		},
+		{
+			name: "terminal field not found",
+			modify: func(c map[string]interface{}) {
+				cp := c[payment_system.PaymentSystemHandlerCardPay].(map[interface{}]interface{})
+				delete(cp["BANKCARD"].(map[interface{}]interface{}), "callback_secret_word")
+			},
+			message: `payment system "cardpay" settings are invalid: field BANKCARD.callback_secret_word is required`,
+		},
+	}
+
+	for _, tt := range tests {
+		config := suite.getConfig()
+		tt.modify(config)
+
+		r, err := payment_system.NewRegistry(config, suite.pss)
+		assert.Nil(suite.T(), r, tt.name)
+
+		if assert.Error(suite.T(), err, tt.name) {
+			assert.Equal(suite.T(), tt.message, err.Error(), tt.name)
+		}
+	}
+}
+
+func (suite *PaymentSystemRegistryTestSuite) TestPaymentSystemRegistry_NewRegistry_UnknownField_Error() {
+	config := suite.getConfig()
+	cp := config[payment_system.PaymentSystemHandlerCardPay].(map[interface{}]interface{})
+	cp["BANKCARD"].(map[interface{}]interface{})["terminal_code"] = "1"
+
+	_, err := payment_system.NewRegistry(config, suite.pss)
+	assert.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), `payment system "cardpay" settings are invalid`)
+	assert.Contains(suite.T(), err.Error(), "terminal_code")
+}
+
+func (suite *PaymentSystemRegistryTestSuite) TestPaymentSystemRegistry_GetProvider_Error() {
+	r, err := payment_system.NewRegistry(suite.getConfig(), suite.pss)
+	assert.NoError(suite.T(), err)
+
+	_, err = r.GetProvider(suite.getOrder("unknown", "BANKCARD"))
+	assert.Error(suite.T(), err)
+
+	_, err = r.GetProvider(suite.getOrder(payment_system.PaymentSystemHandlerCardPay, "QIWI"))
+	assert.Error(suite.T(), err)
+
+	_, err = r.GetProvider(&model.Order{Id: bson.NewObjectId()})
+	assert.Error(suite.T(), err)
+
+	r, err = payment_system.NewRegistry(map[string]interface{}{}, suite.pss)
+	assert.NoError(suite.T(), err)
+
+	_, err = r.GetProvider(suite.getOrder(payment_system.PaymentSystemHandlerCardPay, "BANKCARD"))
+	assert.Error(suite.T(), err)
+
+	_, err = r.GetCapabilities(payment_system.PaymentSystemHandlerCardPay)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *PaymentSystemRegistryTestSuite) TestPaymentSystemRegistry_CheckPaymentMethod() {
+	r, err := payment_system.NewRegistry(suite.getConfig(), suite.pss)
+	assert.NoError(suite.T(), err)
+
+	pm := &model.PaymentMethod{
+		Type:   model.PaymentMethodTypeEWallet,
+		Params: &model.PaymentMethodParams{Handler: payment_system.PaymentSystemHandlerCardPay},
+	}
+	assert.NoError(suite.T(), r.CheckPaymentMethod(pm))
+
+	pm.Type = "voucher"
+	err = r.CheckPaymentMethod(pm)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), `payment method type "voucher" is not supported by payment system "cardpay"`, err.Error())
+
+	pm.Params.Handler = "unknown"
+	assert.Error(suite.T(), r.CheckPaymentMethod(pm))
+}
+
+func (suite *PaymentSystemRegistryTestSuite) TestPaymentSystemRegistry_LoadRegistry() {
+	r, err := payment_system.LoadRegistry("", suite.pss)
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), r)
+
+	_, err = payment_system.LoadRegistry("not_exists.yml", suite.pss)
+	assert.Error(suite.T(), err)
+
+	f, err := ioutil.TempFile("", "payment_systems")
+	assert.NoError(suite.T(), err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("cardpay:\n  create_payment_url: \"https://sandbox.cardpay.com\"\n  BANKCARD:\n" +
+		"    terminal_id: \"1\"\n    secret_word: \"secret\"\n    callback_secret_word: \"callback_secret\"\n")
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), f.Close())
+
+	r, err = payment_system.LoadRegistry(f.Name(), suite.pss)
+	assert.NoError(suite.T(), err)
+
+	_, err = r.GetProvider(suite.getOrder(payment_system.PaymentSystemHandlerCardPay, "BANKCARD"))
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *PaymentSystemRegistryTestSuite) TestPaymentSystemRegistry_CheckCallbackSignature() {
+	r, err := payment_system.NewRegistry(suite.getConfig(), suite.pss)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), r.CanCheckCallbackSignature(payment_system.PaymentSystemHandlerCardPay))
+
+	body := `{"callback_time": "2019-05-29T12:00:00Z"}`
+	h := sha512.Sum512([]byte(body + "callback_secret"))
+	signature := hex.EncodeToString(h[:])
+
+	err = r.CheckCallbackSignature(payment_system.PaymentSystemHandlerCardPay, "BANKCARD", body, signature)
+	assert.NoError(suite.T(), err)
+
+	err = r.CheckCallbackSignature(payment_system.PaymentSystemHandlerCardPay, "BANKCARD", body+" ", signature)
+	assert.EqualError(suite.T(), err, "request signature is invalid")
+
+	err = r.CheckCallbackSignature(payment_system.PaymentSystemHandlerCardPay, "QIWI", body, signature)
+	assert.EqualError(suite.T(), err, "payment system settings not found")
+
+	r, err = payment_system.NewRegistry(map[string]interface{}{}, suite.pss)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), r.CanCheckCallbackSignature(payment_system.PaymentSystemHandlerCardPay))
+
+	err = r.CheckCallbackSignature(payment_system.PaymentSystemHandlerCardPay, "BANKCARD", body, signature)
+	assert.EqualError(suite.T(), err, "payment system settings not found")
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"github.com/globalsign/mgo/bson"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
@@ -17,17 +16,32 @@ func (api *Api) InitProductRoutes() *Api {
 		Api: api,
 	}
 
-	api.authUserRouteGroup.GET("/products", productApiV1.getProductsList)
-	api.authUserRouteGroup.POST("/products", productApiV1.createProduct)
-	api.authUserRouteGroup.GET("/products/:id", productApiV1.getProduct)
-	api.authUserRouteGroup.PUT("/products/:id", productApiV1.updateProduct)
-	api.authUserRouteGroup.DELETE("/products/:id", productApiV1.deleteProduct)
+	api.authUserRouteGroup.GET("/products", productApiV1.getProductsList, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.POST("/products", productApiV1.createProduct, api.requireRoles(rolesMerchantWrite...))
+	api.authUserRouteGroup.GET("/products/:id", productApiV1.getProduct, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.PUT("/products/:id", productApiV1.updateProduct, api.requireRoles(rolesMerchantWrite...))
+	api.authUserRouteGroup.DELETE("/products/:id", productApiV1.deleteProduct, api.requireRoles(rolesMerchantWrite...))
 
 	return api
 }
 
+// @Summary Get products
 // @Description Get list of products for authenticated merchant
 // @Example GET /admin/api/v1/products?name=car&sku=ru_0&project_id=5bdc39a95d1e1100019fb7df&offset=0&limit=10
+// @Tags Product
+// @Produce json
+// @Security BearerAuth
+// @Param name query string false "product name to filter products"
+// @Param sku query string false "product sku to filter products"
+// @Param project_id query string false "project unique identifier to filter products"
+// @Param limit query integer false "maximum number of returning products. default value is 100"
+// @Param offset query integer false "offset from which you want to return the list of products. default value is 0"
+// @Success 200 {object} product.ListProductsResponse "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/products [get]
 func (r *productRoute) getProductsList(ctx echo.Context) error {
 	req := &grpc.ListProductsRequest{}
 	err := (&ProductsGetProductsListBinder{}).Bind(req, ctx)
@@ -36,7 +50,7 @@ func (r *productRoute) getProductsList(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
 	}
 
-	merchant, err := r.billingService.GetMerchantBy(context.TODO(), &grpc.GetMerchantByRequest{UserId: r.authUser.Id})
+	merchant, err := r.billingService.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: getRequestContext(ctx).AuthUser.Id})
 	if err != nil || merchant.Item == nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
@@ -48,7 +62,7 @@ func (r *productRoute) getProductsList(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := r.billingService.ListProducts(context.TODO(), req)
+	res, err := r.billingService.ListProducts(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -56,8 +70,19 @@ func (r *productRoute) getProductsList(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
+// @Summary Get product
 // @Description Get product for authenticated merchant
 // @Example GET /admin/api/v1/products/5c99288068add43f74be9c1d
+// @Tags Product
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product unique identifier"
+// @Success 200 {object} product.Product "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/products/{id} [get]
 func (r *productRoute) getProduct(ctx echo.Context) error {
 
 	id := ctx.Param(requestParameterId)
@@ -65,7 +90,7 @@ func (r *productRoute) getProduct(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, errorIncorrectProductId)
 	}
 
-	merchant, err := r.billingService.GetMerchantBy(context.TODO(), &grpc.GetMerchantByRequest{UserId: r.authUser.Id})
+	merchant, err := r.billingService.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: getRequestContext(ctx).AuthUser.Id})
 	if err != nil || merchant.Item == nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
@@ -80,7 +105,7 @@ func (r *productRoute) getProduct(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := r.billingService.GetProduct(context.TODO(), req)
+	res, err := r.billingService.GetProduct(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -88,15 +113,26 @@ func (r *productRoute) getProduct(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
+// @Summary Delete product
 // @Description Delete product for authenticated merchant
 // @Example DELETE /admin/api/v1/products/5c99288068add43f74be9c1d
+// @Tags Product
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product unique identifier"
+// @Success 204 "Product deleted"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/products/{id} [delete]
 func (r *productRoute) deleteProduct(ctx echo.Context) error {
 	id := ctx.Param(requestParameterId)
 	if id == "" || bson.IsObjectIdHex(id) == false {
 		return echo.NewHTTPError(http.StatusBadRequest, errorIncorrectProductId)
 	}
 
-	merchant, err := r.billingService.GetMerchantBy(context.TODO(), &grpc.GetMerchantByRequest{UserId: r.authUser.Id})
+	merchant, err := r.billingService.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: getRequestContext(ctx).AuthUser.Id})
 	if err != nil || merchant.Item == nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
@@ -111,7 +147,7 @@ func (r *productRoute) deleteProduct(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	_, err = r.billingService.DeleteProduct(context.TODO(), req)
+	_, err = r.billingService.DeleteProduct(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -119,6 +155,7 @@ func (r *productRoute) deleteProduct(ctx echo.Context) error {
 	return ctx.NoContent(http.StatusNoContent)
 }
 
+// @Summary Create product
 // @Description Create new product for authenticated merchant
 // @Example curl -X POST -H "Accept: application/json" -H "Content-Type: application/json" \
 //      -H "Authorization: Bearer %access_token_here%" \
@@ -126,10 +163,22 @@ func (r *productRoute) deleteProduct(ctx echo.Context) error {
 //          "default_currency": "USD", "enabled": true, "prices": [{"amount": 12.93, "currency": "USD"}],
 //          "description": {"en": "Doom II description"}, "long_description": {}, "project_id": "5bdc39a95d1e1100019fb7df"}' \
 //      https://api.paysuper.online/admin/api/v1/products
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body product.Product true "Product data"
+// @Success 200 {object} product.Product "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/products [post]
 func (r *productRoute) createProduct(ctx echo.Context) error {
 	return r.createOrUpdateProduct(ctx, &ProductsCreateProductBinder{})
 }
 
+// @Summary Update product
 // @Description Update existing product for authenticated merchant
 // @Example curl -X PUT -H "Accept: application/json" -H "Content-Type: application/json" \
 //      -H "Authorization: Bearer %access_token_here%" \
@@ -137,6 +186,18 @@ func (r *productRoute) createProduct(ctx echo.Context) error {
 //          "default_currency": "USD", "enabled": true, "prices": [{"amount": 146.00, "currency": "USD"}],
 //          "description": {"en": "Doom IV description"}, "long_description": {}, "project_id": "5bdc39a95d1e1100019fb7df"}' \
 //      https://api.paysuper.online/admin/api/v1/products/5c99288068add43f74be9c1d
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product unique identifier"
+// @Param data body product.Product true "Product data"
+// @Success 200 {object} product.Product "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/products/{id} [put]
 func (r *productRoute) updateProduct(ctx echo.Context) error {
 	return r.createOrUpdateProduct(ctx, &ProductsUpdateProductBinder{})
 }
@@ -149,7 +210,7 @@ func (r *productRoute) createOrUpdateProduct(ctx echo.Context, binder echo.Binde
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	merchant, err := r.billingService.GetMerchantBy(context.TODO(), &grpc.GetMerchantByRequest{UserId: r.authUser.Id})
+	merchant, err := r.billingService.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: getRequestContext(ctx).AuthUser.Id})
 	if err != nil || merchant.Item == nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
@@ -161,7 +222,7 @@ func (r *productRoute) createOrUpdateProduct(ctx echo.Context, binder echo.Binde
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	res, err := r.billingService.CreateOrUpdateProduct(context.TODO(), req)
+	res, err := r.billingService.CreateOrUpdateProduct(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -14,8 +14,9 @@ import (
 
 type ProductTestSuite struct {
 	suite.Suite
-	router *productRoute
-	api    *Api
+	router   *productRoute
+	api      *Api
+	authUser *AuthUser
 }
 
 func Test_Product(t *testing.T) {
@@ -23,13 +24,14 @@ func Test_Product(t *testing.T) {
 }
 
 func (suite *ProductTestSuite) SetupTest() {
+	suite.authUser = &AuthUser{
+		Id: "ffffffffffffffffffffffff",
+	}
+
 	suite.api = &Api{
 		Http:           echo.New(),
 		validate:       validator.New(),
 		billingService: mock.NewBillingServerOkMock(),
-		authUser: &AuthUser{
-			Id: "ffffffffffffffffffffffff",
-		},
 	}
 
 	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
@@ -44,6 +46,7 @@ func (suite *ProductTestSuite) TestProduct_getProductsList_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/products")
 
@@ -59,6 +62,7 @@ func (suite *ProductTestSuite) TestProduct_getProduct_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/products/:" + requestParameterId)
 	ctx.SetParamNames(requestParameterId)
@@ -76,6 +80,7 @@ func (suite *ProductTestSuite) TestProduct_deleteProduct_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/products/:" + requestParameterId)
 	ctx.SetParamNames(requestParameterId)
@@ -97,6 +102,7 @@ func (suite *ProductTestSuite) TestProduct_createProduct_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/products")
 
@@ -116,6 +122,7 @@ func (suite *ProductTestSuite) TestProduct_updateProduct_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/products/:" + requestParameterId)
 	ctx.SetParamNames(requestParameterId)
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
@@ -14,15 +13,28 @@ type projectRoute onboardingRoute
 func (api *Api) InitProjectRoutes() *Api {
 	route := &projectRoute{Api: api}
 
-	api.authUserRouteGroup.GET("/projects", route.listProjects)
-	api.authUserRouteGroup.GET("/projects/:id", route.getProject)
-	api.authUserRouteGroup.POST("/projects", route.createProject)
-	api.authUserRouteGroup.PATCH("/projects/:id", route.updateProject)
-	api.authUserRouteGroup.DELETE("/projects/:id", route.deleteProject)
+	api.authUserRouteGroup.GET("/projects", route.listProjects, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.GET("/projects/:id", route.getProject, api.requireProjectAccess(requestParameterId, rolesMerchantRead...))
+	api.authUserRouteGroup.POST("/projects", route.createProject, api.requireRoles(rolesMerchantWrite...))
+	api.authUserRouteGroup.PATCH("/projects/:id", route.updateProject, api.requireProjectAccess(requestParameterId, rolesMerchantWrite...))
+	api.authUserRouteGroup.DELETE("/projects/:id", route.deleteProject, api.requireProjectAccess(requestParameterId, rolesMerchantWrite...))
 
 	return api
 }
 
+// @Summary Create project
+// @Description Create new project for authenticated merchant
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body projects.CreateRequest true "Creating project data"
+// @Success 201 {object} model.Project "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error"
+// @Router /admin/api/v1/projects [post]
 func (r *projectRoute) createProject(ctx echo.Context) error {
 	req := &billing.Project{}
 	err := ctx.Bind(req)
@@ -37,7 +49,13 @@ func (r *projectRoute) createProject(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ChangeProject(context.TODO(), req)
+	err = r.checkMerchantAccess(ctx, req.MerchantId, rolesMerchantWrite...)
+
+	if err != nil {
+		return err
+	}
+
+	rsp, err := r.billingService.ChangeProject(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -50,6 +68,21 @@ func (r *projectRoute) createProject(ctx echo.Context) error {
 	return ctx.JSON(http.StatusCreated, rsp)
 }
 
+// @Summary Update project
+// @Description Update project for authenticated merchant
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project identifier"
+// @Param data body model.Project true "Project object with new data"
+// @Success 200 {object} model.Project "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error"
+// @Router /admin/api/v1/projects/{id} [patch]
 func (r *projectRoute) updateProject(ctx echo.Context) error {
 	req := &billing.Project{}
 	binder := &ChangeProjectRequestBinder{Api: r.Api}
@@ -65,7 +98,7 @@ func (r *projectRoute) updateProject(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ChangeProject(context.TODO(), req)
+	rsp, err := r.billingService.ChangeProject(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -78,6 +111,17 @@ func (r *projectRoute) updateProject(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp.Item)
 }
 
+// @Summary Get project
+// @Description "Get data about project"
+// @Tags Project
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project identifier"
+// @Success 200 {object} model.Project "OK"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 404 {object} model.Error "Project not found"
+// @Failure 500 {object} model.Error "Some unknown error"
+// @Router /admin/api/v1/projects/{id} [get]
 func (r *projectRoute) getProject(ctx echo.Context) error {
 	req := &grpc.GetProjectRequest{
 		ProjectId: ctx.Param(requestParameterId),
@@ -89,7 +133,7 @@ func (r *projectRoute) getProject(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.GetProject(context.TODO(), req)
+	rsp, err := r.billingService.GetProject(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -102,6 +146,23 @@ func (r *projectRoute) getProject(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp)
 }
 
+// @Summary List projects
+// @Description Get list of project for authenticated merchant
+// @Tags Project
+// @Produce json
+// @Security BearerAuth
+// @Param merchant_id query string false "merchant identifier. required if user has access to several merchants"
+// @Param quick_search query string false "string to quick search by project name"
+// @Param status query array false "array of project statuses"
+// @Param sort query array false "fields list for sorting"
+// @Param limit query integer false "maximum number of returning records. default value is 100"
+// @Param offset query integer false "offset from which you want to return the list of records. default value is 0"
+// @Success 200 {array} model.Project "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error"
+// @Router /admin/api/v1/projects [get]
 func (r *projectRoute) listProjects(ctx echo.Context) error {
 	req := &grpc.ListProjectsRequest{}
 	err := ctx.Bind(req)
@@ -114,13 +175,30 @@ func (r *projectRoute) listProjects(ctx echo.Context) error {
 		req.Limit = LimitDefault
 	}
 
+	authUser := getRequestContext(ctx).AuthUser
+
+	// merchant user can't list projects of all merchants
+	if req.MerchantId == "" && !authUser.Roles[RoleSystemAdmin] {
+		req.MerchantId = getAuthUserMerchantId(authUser)
+
+		if req.MerchantId == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, errorIncorrectMerchantId)
+		}
+	}
+
 	err = r.validate.Struct(req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.ListProjects(context.TODO(), req)
+	err = r.checkMerchantAccess(ctx, req.MerchantId, rolesMerchantRead...)
+
+	if err != nil {
+		return err
+	}
+
+	rsp, err := r.billingService.ListProjects(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -129,6 +207,18 @@ func (r *projectRoute) listProjects(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, rsp)
 }
 
+// @Summary Delete project
+// @Description Delete project for authenticated merchant
+// @Tags Project
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Project identifier"
+// @Success 200 {string} string "OK"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error"
+// @Router /admin/api/v1/projects/{id} [delete]
 func (r *projectRoute) deleteProject(ctx echo.Context) error {
 	req := &grpc.GetProjectRequest{
 		ProjectId: ctx.Param(requestParameterId),
@@ -140,7 +230,7 @@ func (r *projectRoute) deleteProject(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
 	}
 
-	rsp, err := r.billingService.DeleteProject(context.TODO(), req)
+	rsp, err := r.billingService.DeleteProject(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -2,10 +2,13 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -18,10 +21,13 @@ import (
 	"testing"
 )
 
+const projectTestMerchantId = "5c8f6a914dad6a0001839408"
+
 type ProjectTestSuite struct {
 	suite.Suite
-	router *projectRoute
-	api    *Api
+	router   *projectRoute
+	api      *Api
+	authUser *AuthUser
 }
 
 func Test_project(t *testing.T) {
@@ -33,9 +39,12 @@ func (suite *ProjectTestSuite) SetupTest() {
 		Http:           echo.New(),
 		validate:       validator.New(),
 		billingService: mock.NewBillingServerOkMock(),
-		authUser: &AuthUser{
-			Id: "ffffffffffffffffffffffff",
-		},
+	}
+
+	suite.authUser = &AuthUser{
+		Id:        "ffffffffffffffffffffffff",
+		Roles:     map[string]bool{RoleMerchantOwner: true},
+		Merchants: map[string]bool{projectTestMerchantId: true},
 	}
 
 	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
@@ -46,7 +55,7 @@ func (suite *ProjectTestSuite) TearDownTest() {}
 
 func (suite *ProjectTestSuite) TestProject_CreateProject_Ok() {
 	body := &billing.Project{
-		MerchantId:         bson.NewObjectId().Hex(),
+		MerchantId:         projectTestMerchantId,
 		Name:               map[string]string{"en": "A", "ru": "А"},
 		CallbackCurrency:   "RUB",
 		CallbackProtocol:   pkg.ProjectCallbackProtocolEmpty,
@@ -63,6 +72,7 @@ func (suite *ProjectTestSuite) TestProject_CreateProject_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err = suite.router.createProject(ctx)
 	assert.NoError(suite.T(), err)
@@ -77,6 +87,7 @@ func (suite *ProjectTestSuite) TestProject_CreateProject_BindError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err := suite.router.createProject(ctx)
 	assert.Error(suite.T(), err)
@@ -89,7 +100,7 @@ func (suite *ProjectTestSuite) TestProject_CreateProject_BindError() {
 
 func (suite *ProjectTestSuite) TestProject_CreateProject_ValidationError() {
 	body := &billing.Project{
-		MerchantId:         bson.NewObjectId().Hex(),
+		MerchantId:         projectTestMerchantId,
 		Name:               map[string]string{"en": "A", "ru": "А"},
 		CallbackCurrency:   "RUB",
 		CallbackProtocol:   pkg.ProjectCallbackProtocolEmpty,
@@ -106,6 +117,7 @@ func (suite *ProjectTestSuite) TestProject_CreateProject_ValidationError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err = suite.router.createProject(ctx)
 	assert.Error(suite.T(), err)
@@ -118,7 +130,7 @@ func (suite *ProjectTestSuite) TestProject_CreateProject_ValidationError() {
 
 func (suite *ProjectTestSuite) TestProject_CreateProject_BillingServerError() {
 	body := &billing.Project{
-		MerchantId:         bson.NewObjectId().Hex(),
+		MerchantId:         projectTestMerchantId,
 		Name:               map[string]string{"en": "A", "ru": "А"},
 		CallbackCurrency:   "RUB",
 		CallbackProtocol:   pkg.ProjectCallbackProtocolEmpty,
@@ -135,6 +147,7 @@ func (suite *ProjectTestSuite) TestProject_CreateProject_BillingServerError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	suite.router.billingService = mock.NewBillingServerSystemErrorMock()
 	err = suite.router.createProject(ctx)
@@ -148,7 +161,7 @@ func (suite *ProjectTestSuite) TestProject_CreateProject_BillingServerError() {
 
 func (suite *ProjectTestSuite) TestProject_CreateProject_BillingServerResultError() {
 	body := &billing.Project{
-		MerchantId:         bson.NewObjectId().Hex(),
+		MerchantId:         projectTestMerchantId,
 		Name:               map[string]string{"en": "A", "ru": "А"},
 		CallbackCurrency:   "RUB",
 		CallbackProtocol:   pkg.ProjectCallbackProtocolEmpty,
@@ -165,6 +178,7 @@ func (suite *ProjectTestSuite) TestProject_CreateProject_BillingServerResultErro
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	suite.router.billingService = mock.NewBillingServerErrorMock()
 	err = suite.router.createProject(ctx)
@@ -183,6 +197,7 @@ func (suite *ProjectTestSuite) TestProject_UpdateProject_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -201,6 +216,7 @@ func (suite *ProjectTestSuite) TestProject_UpdateProject_BindError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -222,6 +238,7 @@ func (suite *ProjectTestSuite) TestProject_UpdateProject_ValidationError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -243,6 +260,7 @@ func (suite *ProjectTestSuite) TestProject_UpdateProject_BillingServerError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -265,6 +283,7 @@ func (suite *ProjectTestSuite) TestProject_UpdateProject_BillingServerResultErro
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -285,6 +304,7 @@ func (suite *ProjectTestSuite) TestProject_GetProject_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -301,6 +321,7 @@ func (suite *ProjectTestSuite) TestProject_GetProject_ValidationError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err := suite.router.getProject(ctx)
 	assert.Error(suite.T(), err)
@@ -316,6 +337,7 @@ func (suite *ProjectTestSuite) TestProject_GetProject_BillingServerError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -336,6 +358,7 @@ func (suite *ProjectTestSuite) TestProject_GetProject_BillingServerResultError()
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -359,6 +382,7 @@ func (suite *ProjectTestSuite) TestProject_ListProjects_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err := suite.router.listProjects(ctx)
 	assert.NoError(suite.T(), err)
@@ -374,6 +398,7 @@ func (suite *ProjectTestSuite) TestProject_ListProjects_BindError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err := suite.router.listProjects(ctx)
 	assert.Error(suite.T(), err)
@@ -392,6 +417,7 @@ func (suite *ProjectTestSuite) TestProject_ListProjects_ValidationError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err := suite.router.listProjects(ctx)
 	assert.Error(suite.T(), err)
@@ -410,6 +436,7 @@ func (suite *ProjectTestSuite) TestProject_ListProjects_BillingServerError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	suite.router.billingService = mock.NewBillingServerSystemErrorMock()
 	err := suite.router.listProjects(ctx)
@@ -426,6 +453,7 @@ func (suite *ProjectTestSuite) TestProject_DeleteProject_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -442,6 +470,7 @@ func (suite *ProjectTestSuite) TestProject_DeleteProject_ValidateError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	err := suite.router.deleteProject(ctx)
 	assert.Error(suite.T(), err)
@@ -457,6 +486,7 @@ func (suite *ProjectTestSuite) TestProject_DeleteProject_BillingServerError() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -477,6 +507,7 @@ func (suite *ProjectTestSuite) TestProject_DeleteProject_BillingServerResultErro
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/projects/:id")
 	ctx.SetParamNames(requestParameterId)
@@ -491,3 +522,97 @@ func (suite *ProjectTestSuite) TestProject_DeleteProject_BillingServerResultErro
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 	assert.Equal(suite.T(), mock.SomeError, httpErr.Message)
 }
+
+func (suite *ProjectTestSuite) TestProject_CreateProject_OtherMerchant_AccessDenied() {
+	body := &billing.Project{
+		MerchantId:         bson.NewObjectId().Hex(),
+		Name:               map[string]string{"en": "A", "ru": "А"},
+		CallbackCurrency:   "RUB",
+		CallbackProtocol:   pkg.ProjectCallbackProtocolEmpty,
+		LimitsCurrency:     "RUB",
+		MinPaymentAmount:   0,
+		MaxPaymentAmount:   15000,
+		IsProductsCheckout: false,
+	}
+
+	b, err := json.Marshal(&body)
+	assert.NoError(suite.T(), err)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	err = suite.router.createProject(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageAccessDenied, httpErr.Message)
+}
+
+func (suite *ProjectTestSuite) TestProject_ListProjects_OtherMerchant_AccessDenied() {
+	q := make(url.Values)
+	q.Set(requestParameterMerchantId, bson.NewObjectId().Hex())
+
+	req := httptest.NewRequest(http.MethodGet, "/?"+q.Encode(), nil)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	err := suite.router.listProjects(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+}
+
+func (suite *ProjectTestSuite) TestProject_ListProjects_MerchantOfUser_Ok() {
+	billingService := &projectTestListBillingService{BillingService: mock.NewBillingServerOkMock()}
+	suite.router.billingService = billingService
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	err := suite.router.listProjects(ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Equal(suite.T(), projectTestMerchantId, billingService.merchantId)
+}
+
+func (suite *ProjectTestSuite) TestProject_ListProjects_SeveralMerchants_Error() {
+	suite.authUser.Merchants[bson.NewObjectId().Hex()] = true
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	err := suite.router.listProjects(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), errorIncorrectMerchantId, httpErr.Message)
+}
+
+// Billing service which remember merchant of projects list request
+type projectTestListBillingService struct {
+	grpc.BillingService
+	merchantId string
+}
+
+func (s *projectTestListBillingService) ListProjects(
+	ctx context.Context,
+	in *grpc.ListProjectsRequest,
+	opts ...client.CallOption,
+) (*grpc.ListProjectsResponse, error) {
+	s.merchantId = in.MerchantId
+	return s.BillingService.ListProjects(ctx, in, opts...)
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis"
+	"github.com/labstack/echo/v4"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitNameIp      = "ip"
+	rateLimitNameProject = "project"
+	rateLimitNameOrder   = "order"
+
+	rateLimitRedisKeyPrefix      = "paysuper:rate_limit:"
+	rateLimitMemoryPurgeInterval = 1000
+
+	// Token bucket refill and take. Tokens count saved with fraction part, because bucket
+	// refilled proportionally to time elapsed from previous request
+	rateLimitRedisScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated_at")
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+
+if tokens == nil or updated == nil then
+	tokens = burst
+	updated = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate / 1000)
+
+local allowed = 0
+local wait = 0
+
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated_at", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate))
+
+return {allowed, wait}
+`
+)
+
+// RateLimit is a token bucket parameters. Bucket contains up to Burst tokens and refilled
+// with Rate tokens per second. Every request takes one token from bucket
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l *RateLimit) enabled() bool {
+	return l != nil && l.Rate > 0 && l.Burst > 0
+}
+
+// RateLimiter is a storage of token buckets
+type RateLimiter interface {
+	// Take one token from bucket with passed key. If bucket is empty, then allowed flag will be false
+	// and returned duration after which token will be available
+	Allow(ctx context.Context, key string, limit *RateLimit) (bool, time.Duration, error)
+}
+
+type tokenBucket struct {
+	limit     RateLimit
+	tokens    float64
+	updatedAt time.Time
+}
+
+type memoryRateLimiter struct {
+	mx      sync.Mutex
+	buckets map[string]*tokenBucket
+	calls   int
+	now     func() time.Time
+}
+
+// Create rate limiter which keep token buckets in memory of current process.
+// Must be used only for single instance installations and tests
+func NewMemoryRateLimiter() RateLimiter {
+	return &memoryRateLimiter{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+func (l *memoryRateLimiter) Allow(_ context.Context, key string, limit *RateLimit) (bool, time.Duration, error) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := l.now()
+	l.calls++
+
+	if l.calls%rateLimitMemoryPurgeInterval == 0 {
+		l.purge(now)
+	}
+
+	b, ok := l.buckets[key]
+
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), updatedAt: now}
+		l.buckets[key] = b
+	}
+
+	b.limit = *limit
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updatedAt).Seconds()*limit.Rate)
+	b.updatedAt = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+
+	return false, wait, nil
+}
+
+// Remove buckets which already refilled completely, because such buckets equal to new buckets
+func (l *memoryRateLimiter) purge(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updatedAt).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type redisRateLimiter struct {
+	client redis.Cmdable
+	script *redis.Script
+}
+
+// Create rate limiter which keep token buckets in redis or any storage compatible with redis protocol.
+// Bucket updated by lua script, so limits shared between all service instances
+func NewRedisRateLimiter(client redis.Cmdable) RateLimiter {
+	return &redisRateLimiter{client: client, script: redis.NewScript(rateLimitRedisScript)}
+}
+
+func (l *redisRateLimiter) Allow(_ context.Context, key string, limit *RateLimit) (bool, time.Duration, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	res, err := l.script.Run(l.client, []string{rateLimitRedisKeyPrefix + key}, limit.Rate, limit.Burst, now).Result()
+
+	if err != nil {
+		return false, 0, err
+	}
+
+	values, ok := res.([]interface{})
+
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit script result %v", res)
+	}
+
+	allowed, _ := values[0].(int64)
+	wait, _ := values[1].(int64)
+
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
+
+// Get key of token bucket from request. If empty key returned, then request will not be limited
+type rateLimitKeyFunc func(ctx echo.Context) string
+
+// Limit requests by token bucket with key returned by passed function. If limit exceeded, then request
+// will be rejected with 429 status and Retry-After header. If rate limiter not available, then
+// requests will not be limited
+func (api *Api) rateLimitMiddleware(name string, limit *RateLimit, keyFunc rateLimitKeyFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if api.rateLimiter == nil || !limit.enabled() {
+				return next(ctx)
+			}
+
+			key := keyFunc(ctx)
+
+			if key == "" {
+				return next(ctx)
+			}
+
+			allowed, wait, err := api.rateLimiter.Allow(ctx.Request().Context(), name+":"+key, limit)
+
+			if err != nil {
+				api.logError(ctx.Request().Context(), "Rate limiter failed", []interface{}{"error", err.Error(), "limit", name})
+				return next(ctx)
+			}
+
+			if !allowed {
+				incRateLimitExceeded(name, ctx.Path())
+
+				ctx.Response().Header().Set(HeaderRetryAfter, strconv.Itoa(getRetryAfterSeconds(wait)))
+				return echo.NewHTTPError(http.StatusTooManyRequests, errorMessageRateLimitExceeded)
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// Limit requests from one ip address
+func (api *Api) rateLimitByIp() echo.MiddlewareFunc {
+	limit := &RateLimit{Rate: api.config.RateLimitIpRate, Burst: api.config.RateLimitIpBurst}
+
+	return api.rateLimitMiddleware(rateLimitNameIp, limit, func(ctx echo.Context) string {
+		return ctx.RealIP()
+	})
+}
+
+// Limit requests to one project
+func (api *Api) rateLimitByProject(keyFunc rateLimitKeyFunc) echo.MiddlewareFunc {
+	limit := &RateLimit{Rate: api.config.RateLimitProjectRate, Burst: api.config.RateLimitProjectBurst}
+	return api.rateLimitMiddleware(rateLimitNameProject, limit, keyFunc)
+}
+
+// Limit requests to one order
+func (api *Api) rateLimitByOrder(keyFunc rateLimitKeyFunc) echo.MiddlewareFunc {
+	limit := &RateLimit{Rate: api.config.RateLimitOrderRate, Burst: api.config.RateLimitOrderBurst}
+	return api.rateLimitMiddleware(rateLimitNameOrder, limit, keyFunc)
+}
+
+// Get project identifier from html form or query string of order create request
+func getFormProjectId(ctx echo.Context) string {
+	return ctx.FormValue(requestParameterOrderProjectId)
+}
+
+// Get project identifier from json body of order create request
+func getJsonProjectId(ctx echo.Context) string {
+	data := &struct {
+		ProjectId string `json:"project"`
+	}{}
+
+	if err := json.Unmarshal([]byte(getRequestContext(ctx).RawBody), data); err != nil {
+		return ""
+	}
+
+	return data.ProjectId
+}
+
+// Get order identifier from json body of payment create request
+func getPaymentOrderId(ctx echo.Context) string {
+	data := &struct {
+		OrderId string `json:"order_id"`
+	}{}
+
+	if err := json.Unmarshal([]byte(getRequestContext(ctx).RawBody), data); err != nil {
+		return ""
+	}
+
+	return data.OrderId
+}
+
+// Get order identifier from route parameter
+func getParamOrderId(ctx echo.Context) string {
+	return ctx.Param(requestParameterOrderId)
+}
+
+func getRetryAfterSeconds(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	rateLimitTestIp        = "127.0.0.1"
+	rateLimitTestProjectId = "5be2c3022b9bb6000765d132"
+	rateLimitTestOrderId   = "fd7b4e8f-3a65-4a6d-8e21-0c2d3c4b5a6e"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+	api *Api
+}
+
+func Test_RateLimit(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
+
+func (suite *RateLimitTestSuite) SetupTest() {
+	suite.api = &Api{
+		Http: echo.New(),
+		config: &config.Config{
+			RateLimit: config.RateLimit{
+				RateLimitIpRate:       1,
+				RateLimitIpBurst:      5,
+				RateLimitProjectRate:  1,
+				RateLimitProjectBurst: 2,
+				RateLimitOrderRate:    0.1,
+				RateLimitOrderBurst:   1,
+			},
+		},
+		rateLimiter: NewMemoryRateLimiter(),
+	}
+
+	handler := func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}
+
+	suite.api.Http.Use(suite.api.RawBodyMiddleware)
+	suite.api.Http.GET("/ip", handler, suite.api.rateLimitByIp())
+	suite.api.Http.POST("/order", handler, suite.api.rateLimitByProject(getJsonProjectId))
+	suite.api.Http.POST("/order/create", handler, suite.api.rateLimitByProject(getFormProjectId))
+	suite.api.Http.POST("/payment", handler, suite.api.rateLimitByOrder(getPaymentOrderId))
+	suite.api.Http.PATCH("/orders/:order_id/language", handler, suite.api.rateLimitByOrder(getParamOrderId))
+}
+
+func (suite *RateLimitTestSuite) TearDownTest() {}
+
+func (suite *RateLimitTestSuite) serve(method, path, ip, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderXRealIP, ip)
+
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+
+	rsp := httptest.NewRecorder()
+	suite.api.Http.ServeHTTP(rsp, req)
+
+	return rsp
+}
+
+func (suite *RateLimitTestSuite) TestRateLimit_MemoryRateLimiter_Ok() {
+	now := time.Now()
+	limiter := &memoryRateLimiter{buckets: make(map[string]*tokenBucket), now: func() time.Time { return now }}
+	limit := &RateLimit{Rate: 2, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := limiter.Allow(context.TODO(), "key", limit)
+		assert.NoError(suite.T(), err)
+		assert.True(suite.T(), allowed)
+	}
+
+	allowed, wait, err := limiter.Allow(context.TODO(), "key", limit)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), allowed)
+	assert.Equal(suite.T(), 500*time.Millisecond, wait)
+
+	allowed, _, err = limiter.Allow(context.TODO(), "other_key", limit)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), allowed)
+
+	now = now.Add(500 * time.Millisecond)
+
+	allowed, _, err = limiter.Allow(context.TODO(), "key", limit)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), allowed)
+}
+
+func (suite *RateLimitTestSuite) TestRateLimit_MemoryRateLimiter_Purge_Ok() {
+	now := time.Now()
+	limiter := &memoryRateLimiter{buckets: make(map[string]*tokenBucket), now: func() time.Time { return now }}
+
+	_, _, err := limiter.Allow(context.TODO(), "fast", &RateLimit{Rate: 10, Burst: 1})
+	assert.NoError(suite.T(), err)
+	_, _, err = limiter.Allow(context.TODO(), "slow", &RateLimit{Rate: 0.01, Burst: 1})
+	assert.NoError(suite.T(), err)
+
+	limiter.purge(now.Add(time.Second))
+
+	assert.NotContains(suite.T(), limiter.buckets, "fast")
+	assert.Contains(suite.T(), limiter.buckets, "slow")
+}
+
+func (suite *RateLimitTestSuite) TestRateLimit_ByIp_Ok() {
+	counter := rateLimitExceeded.WithLabelValues(rateLimitNameIp, "/ip")
+	count := testutil.ToFloat64(counter)
+
+	for i := 0; i < 5; i++ {
+		rsp := suite.serve(http.MethodGet, "/ip", rateLimitTestIp, "", "")
+		assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	}
+
+	rsp := suite.serve(http.MethodGet, "/ip", rateLimitTestIp, "", "")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rsp.Code)
+	assert.Equal(suite.T(), "1", rsp.Header().Get(HeaderRetryAfter))
+	assert.Equal(suite.T(), count+1, testutil.ToFloat64(counter))
+
+	rsp = suite.serve(http.MethodGet, "/ip", "127.0.0.2", "", "")
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+}
+
+func (suite *RateLimitTestSuite) TestRateLimit_ByJsonProject_Ok() {
+	body := `{"project": "` + rateLimitTestProjectId + `", "amount": 10}`
+
+	for i := 0; i < 2; i++ {
+		rsp := suite.serve(http.MethodPost, "/order", rateLimitTestIp, echo.MIMEApplicationJSON, body)
+		assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	}
+
+	rsp := suite.serve(http.MethodPost, "/order", "127.0.0.2", echo.MIMEApplicationJSON, body)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rsp.Code)
+
+	rsp = suite.serve(http.MethodPost, "/order", rateLimitTestIp, echo.MIMEApplicationJSON, `{"amount": 10}`)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+}
+
+func (suite *RateLimitTestSuite) TestRateLimit_ByFormProject_Ok() {
+	body := requestParameterOrderProjectId + "=" + rateLimitTestProjectId + "&PP_AMOUNT=10"
+
+	for i := 0; i < 2; i++ {
+		rsp := suite.serve(http.MethodPost, "/order/create", rateLimitTestIp, echo.MIMEApplicationForm, body)
+		assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	}
+
+	rsp := suite.serve(http.MethodPost, "/order/create", rateLimitTestIp, echo.MIMEApplicationForm, body)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rsp.Code)
+}
+
+func (suite *RateLimitTestSuite) TestRateLimit_ByOrder_Ok() {
+	body := `{"order_id": "` + rateLimitTestOrderId + `", "pan": "4000000000000002"}`
+
+	rsp := suite.serve(http.MethodPost, "/payment", rateLimitTestIp, echo.MIMEApplicationJSON, body)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	rsp = suite.serve(http.MethodPost, "/payment", rateLimitTestIp, echo.MIMEApplicationJSON, body)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rsp.Code)
+	assert.Equal(suite.T(), "10", rsp.Header().Get(HeaderRetryAfter))
+
+	rsp = suite.serve(http.MethodPatch, "/orders/"+rateLimitTestOrderId+"/language", rateLimitTestIp, "", "")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rsp.Code)
+}
+
+func (suite *RateLimitTestSuite) TestRateLimit_LimitDisabled_Ok() {
+	suite.api.config.RateLimitIpRate = 0
+	suite.api.Http.GET("/ip_disabled", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}, suite.api.rateLimitByIp())
+
+	for i := 0; i < 10; i++ {
+		rsp := suite.serve(http.MethodGet, "/ip_disabled", rateLimitTestIp, "", "")
+		assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	}
+}
+
+func (suite *RateLimitTestSuite) TestRateLimit_GetRetryAfterSeconds_Ok() {
+	assert.Equal(suite.T(), 1, getRetryAfterSeconds(0))
+	assert.Equal(suite.T(), 1, getRetryAfterSeconds(100*time.Millisecond))
+	assert.Equal(suite.T(), 3, getRetryAfterSeconds(2100*time.Millisecond))
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-payment-link/proto"
+	"net/http"
+)
+
+const (
+	RoleSystemAdmin       = "system_admin"
+	RoleMerchantOwner     = "merchant_owner"
+	RoleMerchantDeveloper = "merchant_developer"
+	RoleMerchantFinance   = "merchant_finance"
+)
+
+var (
+	// roles which can change merchant settings and execute money operations
+	rolesMerchantOwner = []string{RoleMerchantOwner}
+	// roles which can change merchant projects, products and payment links
+	rolesMerchantWrite = []string{RoleMerchantOwner, RoleMerchantDeveloper}
+	// roles which can read merchant financial information
+	rolesMerchantFinance = []string{RoleMerchantOwner, RoleMerchantFinance}
+	// roles which can read any merchant information
+	rolesMerchantRead = []string{RoleMerchantOwner, RoleMerchantDeveloper, RoleMerchantFinance}
+)
+
+// RoleStore is a source of roles and merchants assigned to authorized user
+type RoleStore interface {
+	GetUserRoles(ctx context.Context, userId string) (*model.UserRole, error)
+}
+
+type compositeRoleStore []RoleStore
+
+// Create role store which merge assignments of user from all passed stores
+func NewCompositeRoleStore(stores ...RoleStore) RoleStore {
+	return compositeRoleStore(stores)
+}
+
+func (s compositeRoleStore) GetUserRoles(ctx context.Context, userId string) (*model.UserRole, error) {
+	ur := &model.UserRole{UserId: userId}
+
+	for _, store := range s {
+		assignment, err := store.GetUserRoles(ctx, userId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ur.Roles = append(ur.Roles, assignment.Roles...)
+		ur.Merchants = append(ur.Merchants, assignment.Merchants...)
+	}
+
+	return ur, nil
+}
+
+// Role store which assign merchant owner role to user who registered merchant on billing server
+type merchantOwnerRoleStore struct {
+	billingService grpc.BillingService
+}
+
+func (s *merchantOwnerRoleStore) GetUserRoles(ctx context.Context, userId string) (*model.UserRole, error) {
+	ur := &model.UserRole{UserId: userId}
+	rsp, err := s.billingService.GetMerchantBy(ctx, &grpc.GetMerchantByRequest{UserId: userId})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rsp.Status == pkg.ResponseStatusNotFound {
+		return ur, nil
+	}
+
+	if rsp.Status != pkg.ResponseStatusOk {
+		return nil, echo.NewHTTPError(int(rsp.Status), rsp.Message)
+	}
+
+	ur.Roles = append(ur.Roles, RoleMerchantOwner)
+	ur.Merchants = append(ur.Merchants, rsp.Item.Id)
+
+	return ur, nil
+}
+
+// Resolve identifier of merchant which owns resource with passed identifier
+type merchantResolver func(ctx context.Context, id string) (string, error)
+
+// Access policy of route. Authorized user must have one of policy roles and if policy contains
+// resource route parameter then resource must belong to one of merchants of user.
+// System administrator has access to all routes.
+type accessPolicy struct {
+	roles    []string
+	param    string
+	resolver merchantResolver
+}
+
+func (p *accessPolicy) hasRole(authUser *AuthUser) bool {
+	for _, role := range p.roles {
+		if authUser.Roles[role] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Restrict access to route to users with one of passed roles
+func (api *Api) requireRoles(roles ...string) echo.MiddlewareFunc {
+	return api.accessPolicyMiddleware(&accessPolicy{roles: roles})
+}
+
+// Restrict access to route to users with one of passed roles and with access to merchant
+// which identifier passed in route parameter
+func (api *Api) requireMerchantAccess(param string, roles ...string) echo.MiddlewareFunc {
+	resolver := func(_ context.Context, id string) (string, error) {
+		return id, nil
+	}
+
+	return api.accessPolicyMiddleware(&accessPolicy{roles: roles, param: param, resolver: resolver})
+}
+
+// Restrict access to route to users with one of passed roles and with access to merchant
+// which owns project with identifier passed in route parameter
+func (api *Api) requireProjectAccess(param string, roles ...string) echo.MiddlewareFunc {
+	return api.accessPolicyMiddleware(&accessPolicy{roles: roles, param: param, resolver: api.getProjectMerchantId})
+}
+
+// Restrict access to route to users with one of passed roles and with access to merchant
+// which owns payment link with identifier passed in route parameter
+func (api *Api) requirePaylinkAccess(param string, roles ...string) echo.MiddlewareFunc {
+	return api.accessPolicyMiddleware(&accessPolicy{roles: roles, param: param, resolver: api.getPaylinkMerchantId})
+}
+
+func (api *Api) accessPolicyMiddleware(p *accessPolicy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			authUser := getRequestContext(ctx).AuthUser
+
+			if authUser.Roles[RoleSystemAdmin] {
+				return next(ctx)
+			}
+
+			if !p.hasRole(authUser) {
+				return echo.NewHTTPError(http.StatusForbidden, errorMessageAccessDenied)
+			}
+
+			if p.resolver == nil {
+				return next(ctx)
+			}
+
+			id := ctx.Param(p.param)
+
+			if id == "" {
+				return echo.NewHTTPError(http.StatusBadRequest, errorIdIsEmpty)
+			}
+
+			merchantId, err := p.resolver(ctx.Request().Context(), id)
+
+			if err != nil {
+				return err
+			}
+
+			if !authUser.Merchants[merchantId] {
+				return echo.NewHTTPError(http.StatusForbidden, errorMessageAccessDenied)
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// Check that authorized user has one of passed roles and access to merchant which identifier passed in
+// query or body of request. Must be used by routes which can't declare merchant in route parameter
+func (api *Api) checkMerchantAccess(ctx echo.Context, merchantId string, roles ...string) error {
+	authUser := getRequestContext(ctx).AuthUser
+
+	if authUser.Roles[RoleSystemAdmin] {
+		return nil
+	}
+
+	p := &accessPolicy{roles: roles}
+
+	if !p.hasRole(authUser) || !authUser.Merchants[merchantId] {
+		return echo.NewHTTPError(http.StatusForbidden, errorMessageAccessDenied)
+	}
+
+	return nil
+}
+
+// Check that authorized user has one of passed roles and access to merchant which owns project with
+// identifier passed in query or body of request
+func (api *Api) checkProjectAccess(ctx echo.Context, projectId string, roles ...string) error {
+	if getRequestContext(ctx).AuthUser.Roles[RoleSystemAdmin] {
+		return nil
+	}
+
+	merchantId, err := api.getProjectMerchantId(ctx.Request().Context(), projectId)
+
+	if err != nil {
+		return err
+	}
+
+	return api.checkMerchantAccess(ctx, merchantId, roles...)
+}
+
+// Get merchant of authorized user if user has access to only one merchant
+func getAuthUserMerchantId(authUser *AuthUser) string {
+	if len(authUser.Merchants) != 1 {
+		return ""
+	}
+
+	for merchantId := range authUser.Merchants {
+		return merchantId
+	}
+
+	return ""
+}
+
+// Fill roles and merchants of authorized user from role store
+func (api *Api) loadAuthUserRoles(ctx context.Context, authUser *AuthUser) error {
+	ur, err := api.roleStore.GetUserRoles(ctx, authUser.Id)
+
+	if err != nil {
+		api.logError(ctx, "Load roles of authorized user failed", []interface{}{"error", err.Error(), "user_id", authUser.Id})
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	for _, role := range ur.Roles {
+		authUser.Roles[role] = true
+	}
+
+	for _, merchantId := range ur.Merchants {
+		authUser.Merchants[merchantId] = true
+	}
+
+	return nil
+}
+
+func (api *Api) getProjectMerchantId(ctx context.Context, id string) (string, error) {
+	req := &grpc.GetProjectRequest{ProjectId: id}
+	rsp, err := api.billingService.GetProject(ctx, req)
+
+	if err != nil {
+		api.logError(ctx, `Call billing server method "GetProject" failed`, []interface{}{"error", err.Error(), "request", req})
+		return "", echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	if rsp.Status != pkg.ResponseStatusOk {
+		return "", echo.NewHTTPError(int(rsp.Status), rsp.Message)
+	}
+
+	return rsp.Item.MerchantId, nil
+}
+
+func (api *Api) getPaylinkMerchantId(ctx context.Context, id string) (string, error) {
+	req := &paylink.PaylinkRequest{Id: id}
+	rsp, err := api.paylinkService.GetPaylink(ctx, req)
+
+	if err != nil {
+		api.logError(ctx, `Call payment link service method "GetPaylink" failed`, []interface{}{"error", err.Error(), "request", req})
+		return "", echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	if rsp == nil || rsp.MerchantId == "" {
+		return "", echo.NewHTTPError(http.StatusNotFound, errorMessagePaylinkNotFound)
+	}
+
+	return rsp.MerchantId, nil
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"github.com/ProtocolONE/authone-jwt-verifier-golang"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-payment-link/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	rbacTestMerchantId        = "5c8f6a914dad6a0001839408"
+	rbacTestPaylinkId         = "5cc2d3cba6c3a10001a2c26d"
+	rbacTestUserSystemAdmin   = "system_admin_user"
+	rbacTestUserOwner         = "merchant_owner_user"
+	rbacTestUserDeveloper     = "merchant_developer_user"
+	rbacTestUserFinance       = "merchant_finance_user"
+	rbacTestUserWithoutRoles  = "user_without_roles"
+	rbacTestUserRoleStoreFail = "role_store_fail_user"
+)
+
+type rbacTestRoleStore map[string]*model.UserRole
+
+func (s rbacTestRoleStore) GetUserRoles(_ context.Context, userId string) (*model.UserRole, error) {
+	if userId == rbacTestUserRoleStoreFail {
+		return nil, errors.New("some error")
+	}
+
+	ur, ok := s[userId]
+
+	if !ok {
+		return &model.UserRole{UserId: userId}, nil
+	}
+
+	return ur, nil
+}
+
+// Payment link service which doesn't return payment link and error
+type rbacTestPaylinkNotFoundService struct {
+	paylink.PaylinkService
+}
+
+func (s *rbacTestPaylinkNotFoundService) GetPaylink(
+	_ context.Context,
+	_ *paylink.PaylinkRequest,
+	_ ...client.CallOption,
+) (*paylink.Paylink, error) {
+	return nil, nil
+}
+
+type RbacTestSuite struct {
+	suite.Suite
+	api *Api
+}
+
+func Test_Rbac(t *testing.T) {
+	suite.Run(t, new(RbacTestSuite))
+}
+
+func (suite *RbacTestSuite) SetupTest() {
+	suite.api = &Api{
+		Http:           echo.New(),
+		validate:       validator.New(),
+		billingService: mock.NewBillingServerOkMock(),
+		paylinkService: mock.NewPaymentLinkOkMock(),
+		roleStore: rbacTestRoleStore{
+			rbacTestUserSystemAdmin: {Roles: []string{RoleSystemAdmin}},
+			rbacTestUserOwner:       {Roles: []string{RoleMerchantOwner}, Merchants: []string{rbacTestMerchantId}},
+			rbacTestUserDeveloper:   {Roles: []string{RoleMerchantDeveloper}, Merchants: []string{rbacTestMerchantId}},
+			rbacTestUserFinance:     {Roles: []string{RoleMerchantFinance}, Merchants: []string{rbacTestMerchantId}},
+		},
+	}
+
+	// emulate jwt and user details middlewares
+	authMock := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set(requestContextKeyJwtUser, &jwtverifier.UserInfo{UserID: ctx.Request().Header.Get(requestContextTestHeaderUserId)})
+			return suite.api.AuthUserMiddleware(func(ctx echo.Context) error {
+				err := suite.api.loadAuthUserRoles(ctx.Request().Context(), getRequestContext(ctx).AuthUser)
+
+				if err != nil {
+					return err
+				}
+
+				return next(ctx)
+			})(ctx)
+		}
+	}
+
+	handler := func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	}
+
+	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
+	suite.api.authUserRouteGroup.Use(authMock)
+	suite.api.authUserRouteGroup.PUT("/merchants/:id/change-status", handler, suite.api.requireRoles(RoleSystemAdmin))
+	suite.api.authUserRouteGroup.GET("/merchants/:merchant_id/methods", handler, suite.api.requireMerchantAccess(requestParameterMerchantId, rolesMerchantRead...))
+	suite.api.authUserRouteGroup.PUT("/merchants/:merchant_id/methods", handler, suite.api.requireMerchantAccess(requestParameterMerchantId, rolesMerchantOwner...))
+	suite.api.authUserRouteGroup.GET("/projects/:id", handler, suite.api.requireProjectAccess(requestParameterId, rolesMerchantRead...))
+	suite.api.authUserRouteGroup.DELETE("/paylinks/:id", handler, suite.api.requirePaylinkAccess(requestParameterId, rolesMerchantWrite...))
+}
+
+func (suite *RbacTestSuite) TearDownTest() {}
+
+func (suite *RbacTestSuite) serve(method, path, userId string) int {
+	req := httptest.NewRequest(method, apiAuthUserGroupPath+path, nil)
+	req.Header.Set(requestContextTestHeaderUserId, userId)
+	rsp := httptest.NewRecorder()
+
+	suite.api.Http.ServeHTTP(rsp, req)
+
+	return rsp.Code
+}
+
+func (suite *RbacTestSuite) TestRbac_RequireRoles_Ok() {
+	code := suite.serve(http.MethodPut, "/merchants/"+rbacTestMerchantId+"/change-status", rbacTestUserSystemAdmin)
+	assert.Equal(suite.T(), http.StatusOK, code)
+}
+
+func (suite *RbacTestSuite) TestRbac_RequireRoles_AccessDenied_Error() {
+	users := []string{rbacTestUserOwner, rbacTestUserDeveloper, rbacTestUserFinance, rbacTestUserWithoutRoles}
+
+	for _, userId := range users {
+		code := suite.serve(http.MethodPut, "/merchants/"+rbacTestMerchantId+"/change-status", userId)
+		assert.Equal(suite.T(), http.StatusForbidden, code, userId)
+	}
+}
+
+func (suite *RbacTestSuite) TestRbac_RequireMerchantAccess_Ok() {
+	users := []string{rbacTestUserSystemAdmin, rbacTestUserOwner, rbacTestUserDeveloper, rbacTestUserFinance}
+
+	for _, userId := range users {
+		code := suite.serve(http.MethodGet, "/merchants/"+rbacTestMerchantId+"/methods", userId)
+		assert.Equal(suite.T(), http.StatusOK, code, userId)
+	}
+
+	code := suite.serve(http.MethodPut, "/merchants/"+rbacTestMerchantId+"/methods", rbacTestUserOwner)
+	assert.Equal(suite.T(), http.StatusOK, code)
+}
+
+func (suite *RbacTestSuite) TestRbac_RequireMerchantAccess_ReadOnlyRole_Error() {
+	users := []string{rbacTestUserDeveloper, rbacTestUserFinance}
+
+	for _, userId := range users {
+		code := suite.serve(http.MethodPut, "/merchants/"+rbacTestMerchantId+"/methods", userId)
+		assert.Equal(suite.T(), http.StatusForbidden, code, userId)
+	}
+}
+
+func (suite *RbacTestSuite) TestRbac_RequireMerchantAccess_ForeignMerchant_Error() {
+	code := suite.serve(http.MethodGet, "/merchants/"+bson.NewObjectId().Hex()+"/methods", rbacTestUserOwner)
+	assert.Equal(suite.T(), http.StatusForbidden, code)
+
+	code = suite.serve(http.MethodGet, "/merchants/"+bson.NewObjectId().Hex()+"/methods", rbacTestUserSystemAdmin)
+	assert.Equal(suite.T(), http.StatusOK, code)
+}
+
+func (suite *RbacTestSuite) TestRbac_RequireProjectAccess_ForeignProject_Error() {
+	code := suite.serve(http.MethodGet, "/projects/"+bson.NewObjectId().Hex(), rbacTestUserOwner)
+	assert.Equal(suite.T(), http.StatusForbidden, code)
+
+	code = suite.serve(http.MethodGet, "/projects/"+bson.NewObjectId().Hex(), rbacTestUserSystemAdmin)
+	assert.Equal(suite.T(), http.StatusOK, code)
+}
+
+func (suite *RbacTestSuite) TestRbac_RequirePaylinkAccess_Ok() {
+	code := suite.serve(http.MethodDelete, "/paylinks/"+rbacTestPaylinkId, rbacTestUserDeveloper)
+	assert.Equal(suite.T(), http.StatusOK, code)
+
+	code = suite.serve(http.MethodDelete, "/paylinks/"+rbacTestPaylinkId, rbacTestUserFinance)
+	assert.Equal(suite.T(), http.StatusForbidden, code)
+}
+
+func (suite *RbacTestSuite) TestRbac_RequirePaylinkAccess_PaylinkNotFound_Error() {
+	suite.api.paylinkService = &rbacTestPaylinkNotFoundService{PaylinkService: mock.NewPaymentLinkOkMock()}
+
+	code := suite.serve(http.MethodDelete, "/paylinks/"+rbacTestPaylinkId, rbacTestUserDeveloper)
+	assert.Equal(suite.T(), http.StatusNotFound, code)
+}
+
+func (suite *RbacTestSuite) TestRbac_LoadAuthUserRoles_RoleStoreError() {
+	code := suite.serve(http.MethodGet, "/merchants/"+rbacTestMerchantId+"/methods", rbacTestUserRoleStoreFail)
+	assert.Equal(suite.T(), http.StatusInternalServerError, code)
+}
+
+func (suite *RbacTestSuite) TestRbac_CompositeRoleStore_Ok() {
+	store := NewCompositeRoleStore(
+		suite.api.roleStore,
+		&merchantOwnerRoleStore{billingService: mock.NewBillingServerOkMock()},
+	)
+
+	ur, err := store.GetUserRoles(context.TODO(), rbacTestUserFinance)
+
+	if assert.NoError(suite.T(), err) {
+		assert.Contains(suite.T(), ur.Roles, RoleMerchantFinance)
+		assert.Contains(suite.T(), ur.Roles, RoleMerchantOwner)
+		assert.Contains(suite.T(), ur.Merchants, rbacTestMerchantId)
+		assert.Contains(suite.T(), ur.Merchants, mock.OnboardingMerchantMock.Id)
+	}
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/paysuper/paysuper-management-api/payment_system"
+	"github.com/paysuper/paysuper-management-api/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const (
+	redactionTestPan          = "4000000000000002"
+	redactionTestPanMasked    = "************0002"
+	redactionTestCvv          = "cvv_value_987"
+	redactionTestEmail        = "customer@unit.test"
+	redactionTestPhone        = "+79000000000"
+	redactionTestHolder       = "UNIT TEST HOLDER"
+	redactionTestSecretKey    = "unit_test_secret_key"
+	redactionTestBearerToken  = "Bearer unit_test_bearer_token"
+	redactionTestAccessToken  = "unit_test_access_token"
+	redactionTestRefreshToken = "unit_test_refresh_token"
+	redactionTestPassword     = "unit_test_cardpay_password"
+)
+
+var redactionTestSensitiveValues = []string{
+	redactionTestPan,
+	redactionTestCvv,
+	redactionTestEmail,
+	redactionTestPhone,
+	redactionTestHolder,
+	redactionTestSecretKey,
+	redactionTestBearerToken,
+	redactionTestAccessToken,
+	redactionTestRefreshToken,
+	redactionTestPassword,
+}
+
+type RedactionTestSuite struct {
+	suite.Suite
+	api  *Api
+	logs *observer.ObservedLogs
+}
+
+func Test_Redaction(t *testing.T) {
+	suite.Run(t, new(RedactionTestSuite))
+}
+
+func (suite *RedactionTestSuite) SetupTest() {
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	suite.logs = logs
+	suite.api = &Api{
+		Http:     echo.New(),
+		logger:   zap.New(core).Sugar(),
+		redactor: utils.NewRedactor(utils.DefaultRedactionRules),
+	}
+
+	suite.api.apiAuthProjectGroup = suite.api.Http.Group(apiAuthProjectGroupPath)
+	suite.api.apiAuthProjectGroup.Use(middleware.BodyDump(suite.api.logBodyDump))
+	suite.api.apiAuthProjectGroup.POST("/redaction_test", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, map[string]interface{}{
+			"access_token":  redactionTestAccessToken,
+			"refresh_token": redactionTestRefreshToken,
+			"project":       map[string]string{"secret_key": redactionTestSecretKey},
+		})
+	})
+}
+
+func (suite *RedactionTestSuite) TearDownTest() {}
+
+// Get all logged messages and fields as single string
+func (suite *RedactionTestSuite) getLogsOutput(logs *observer.ObservedLogs) string {
+	var out string
+
+	for _, entry := range logs.All() {
+		out += entry.Message + "\n"
+
+		for k, v := range entry.ContextMap() {
+			out += fmt.Sprintf("%s: %v\n", k, v)
+		}
+	}
+
+	return out
+}
+
+func (suite *RedactionTestSuite) assertNotContainsSensitiveValues(out string) {
+	for _, value := range redactionTestSensitiveValues {
+		assert.NotContains(suite.T(), out, value)
+	}
+}
+
+func (suite *RedactionTestSuite) TestRedaction_BodyDump_Json_Ok() {
+	body := `{
+		"pan": "` + redactionTestPan + `",
+		"cvv": "` + redactionTestCvv + `",
+		"card_holder": "` + redactionTestHolder + `",
+		"customer": {"email": "` + redactionTestEmail + `", "phone": "` + redactionTestPhone + `"},
+		"description": "payment by card ` + redactionTestPan + `",
+		"amount": 100
+	}`
+	req := httptest.NewRequest(http.MethodPost, apiAuthProjectGroupPath+"/redaction_test", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, redactionTestBearerToken)
+
+	rsp := httptest.NewRecorder()
+	suite.api.Http.ServeHTTP(rsp, req)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Contains(suite.T(), rsp.Body.String(), redactionTestAccessToken)
+
+	out := suite.getLogsOutput(suite.logs)
+	assert.NotEmpty(suite.T(), out)
+	suite.assertNotContainsSensitiveValues(out)
+
+	hash := sha256.Sum256([]byte(redactionTestEmail))
+
+	assert.Contains(suite.T(), out, redactionTestPanMasked)
+	assert.Contains(suite.T(), out, hex.EncodeToString(hash[:]))
+	assert.NotContains(suite.T(), out, `"cvv"`)
+	assert.Contains(suite.T(), out, `"amount":100`)
+}
+
+func (suite *RedactionTestSuite) TestRedaction_BodyDump_Form_Ok() {
+	form := url.Values{
+		"PP_PROJECT_ID":  []string{"5be2c3022b9bb6000765d132"},
+		"PP_PAYER_EMAIL": []string{redactionTestEmail},
+		"PP_PAYER_PHONE": []string{redactionTestPhone},
+		"secret_key":     []string{redactionTestSecretKey},
+	}
+	req := httptest.NewRequest(http.MethodPost, apiAuthProjectGroupPath+"/redaction_test", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	rsp := httptest.NewRecorder()
+	suite.api.Http.ServeHTTP(rsp, req)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	out := suite.getLogsOutput(suite.logs)
+	suite.assertNotContainsSensitiveValues(out)
+	assert.Contains(suite.T(), out, "5be2c3022b9bb6000765d132")
+}
+
+func (suite *RedactionTestSuite) TestRedaction_PaymentSystemTransport_Ok() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		_, _ = w.Write([]byte(`{"access_token": "` + redactionTestAccessToken + `", "refresh_token": "` + redactionTestRefreshToken + `"}`))
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	client := (&payment_system.PaymentSystemSetting{Logger: zap.New(core).Sugar()}).GetLoggableHttpClient()
+
+	form := url.Values{
+		"grant_type":    []string{"password"},
+		"terminal_code": []string{"1234"},
+		"password":      []string{redactionTestPassword},
+	}
+	req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewBufferString(form.Encode()))
+	assert.NoError(suite.T(), err)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.Header.Set(echo.HeaderAuthorization, redactionTestBearerToken)
+
+	rsp, err := client.Do(req)
+
+	if assert.NoError(suite.T(), err) {
+		_ = rsp.Body.Close()
+	}
+
+	out := suite.getLogsOutput(logs)
+	assert.NotEmpty(suite.T(), out)
+	suite.assertNotContainsSensitiveValues(out)
+	assert.Contains(suite.T(), out, "terminal_code=1234")
+}
+
+func (suite *RedactionTestSuite) TestRedaction_RedactBody_NotStructured_Ok() {
+	r := utils.NewRedactor(utils.DefaultRedactionRules)
+
+	out := r.RedactBody(echo.MIMETextPlain, []byte("card "+redactionTestPan+" at 1558000000000"))
+	assert.Equal(suite.T(), "card "+redactionTestPanMasked+" at 1558000000000", out)
+
+	out = r.RedactBody(echo.MIMEApplicationJSON, []byte(`{"pan": "`+redactionTestPan))
+	assert.Equal(suite.T(), `{"pan": "`+redactionTestPanMasked, out)
+}
+
+func (suite *RedactionTestSuite) TestRedaction_CustomRules_Ok() {
+	rules, err := utils.ParseRedactionRules("user.name:hash, locale:remove")
+
+	if !assert.NoError(suite.T(), err) || !assert.Len(suite.T(), rules, 2) {
+		return
+	}
+
+	r := utils.NewRedactor(rules)
+	out := r.RedactBody(echo.MIMEApplicationJSON, []byte(`{"user": {"name": "John", "locale": "en"}, "name": "project"}`))
+
+	assert.NotContains(suite.T(), out, "John")
+	assert.NotContains(suite.T(), out, "locale")
+	assert.Contains(suite.T(), out, `"name":"project"`)
+}
+
+func (suite *RedactionTestSuite) TestRedaction_ParseRules_Error() {
+	_, err := utils.ParseRedactionRules("email")
+	assert.Error(suite.T(), err)
+
+	_, err = utils.ParseRedactionRules("email:encrypt")
+	assert.Error(suite.T(), err)
+}
@@ -0,0 +1,436 @@
+package api
+
+import (
+	"fmt"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"net/http"
+	"time"
+)
+
+// RefundApprovalStore is a storage of refund approval rules of merchants and refunds waiting for approval
+type RefundApprovalStore interface {
+	FindRules(merchantId string) ([]*model.RefundApprovalRule, error)
+	InsertRule(rule *model.RefundApprovalRule) error
+	DeleteRule(id bson.ObjectId, merchantId string) error
+	Insert(approval *model.RefundApproval) error
+	// Get approval of refund to order. If approval not exists then mgo.ErrNotFound will be returned
+	FindById(id bson.ObjectId, orderId string) (*model.RefundApproval, error)
+	FindPendingByOrderId(orderId string) ([]*model.RefundApproval, error)
+	FindByMerchantId(merchantId string, status string, limit int32, offset int32) (*model.RefundApprovalList, error)
+	// Save decision about refund if approval still has status prev. Otherwise mgo.ErrNotFound will be returned
+	UpdateStatus(approval *model.RefundApproval, prev string) error
+}
+
+type refundApprovalRoute struct {
+	*orderRoute
+}
+
+func (api *Api) initRefundApprovalRoutes() *Api {
+	route := &refundApprovalRoute{
+		orderRoute: &orderRoute{
+			Api: api,
+			orderManager: manager.InitOrderManager(
+				api.database,
+				api.logger,
+				api.notifierPub,
+				api.repository,
+				api.geoService,
+			),
+		},
+	}
+	route.orderStore = route.orderManager
+
+	api.authUserRouteGroup.GET("/refunds/approval_rules", route.listRules, api.requireRoles(rolesMerchantOwner...))
+	api.authUserRouteGroup.POST("/refunds/approval_rules", route.createRule, api.requireRoles(rolesMerchantOwner...))
+	api.authUserRouteGroup.DELETE("/refunds/approval_rules/:rule_id", route.deleteRule, api.requireRoles(rolesMerchantOwner...))
+	api.authUserRouteGroup.GET("/refunds/approvals", route.listApprovals, api.requireRoles(rolesMerchantFinance...))
+	api.authUserRouteGroup.POST("/order/:order_id/refunds/approvals/:approval_id/approve", route.approveRefund, api.requireRoles(rolesMerchantOwner...))
+	api.authUserRouteGroup.POST("/order/:order_id/refunds/approvals/:approval_id/reject", route.rejectRefund, api.requireRoles(rolesMerchantOwner...))
+
+	return api
+}
+
+// @Summary Get refund approval rules
+// @Description Get rules of merchant of authenticated user which select refunds must be approved by second user
+// @Tags Order
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.RefundApprovalRule "OK"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/refunds/approval_rules [get]
+func (r *refundApprovalRoute) listRules(ctx echo.Context) error {
+	merchant, err := r.getAuthMerchant(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	rules, err := r.refundApprovalStore.FindRules(merchant.Id)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusOK, rules)
+}
+
+// @Summary Create refund approval rule
+// @Description Create rule of merchant of authenticated user. Refunds with amount greater than amount of rule
+// @Description in currency of rule to orders paid by payment method of rule must be approved by second user.
+// @Description Empty currency or payment method of rule matches any currency or payment method
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body model.RefundApprovalRule true "Rule data"
+// @Success 201 {object} model.RefundApprovalRule "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/refunds/approval_rules [post]
+func (r *refundApprovalRoute) createRule(ctx echo.Context) error {
+	rule := &model.RefundApprovalRule{}
+	err := ctx.Bind(rule)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
+	}
+
+	err = r.validate.Struct(rule)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
+	}
+
+	merchant, err := r.getAuthMerchant(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	rule.MerchantId = merchant.Id
+	rule.CreatorId = getRequestContext(ctx).AuthUser.Id
+	err = r.refundApprovalStore.InsertRule(rule)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusCreated, rule)
+}
+
+// @Summary Delete refund approval rule
+// @Description Delete rule of merchant of authenticated user. Refunds already waiting for approval by this rule
+// @Description still must be approved or rejected
+// @Tags Order
+// @Security BearerAuth
+// @Param rule_id path string true "rule unique identifier"
+// @Success 204 "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/refunds/approval_rules/{rule_id} [delete]
+func (r *refundApprovalRoute) deleteRule(ctx echo.Context) error {
+	id := ctx.Param(requestParameterRuleId)
+
+	if !bson.IsObjectIdHex(id) {
+		return echo.NewHTTPError(http.StatusBadRequest, errorIncorrectRefundApprovalRuleId)
+	}
+
+	merchant, err := r.getAuthMerchant(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	err = r.refundApprovalStore.DeleteRule(bson.ObjectIdHex(id), merchant.Id)
+
+	if err == mgo.ErrNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, model.ResponseMessageNotFound)
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// @Summary Get refunds approvals
+// @Description Get refunds of merchant of authenticated user which wait for approval or were approved or
+// @Description rejected early, sorted by date of request from newest
+// @Tags Order
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "approval status: pending_approval, approved or rejected"
+// @Param limit query integer false "maximum number of returning approvals. default value is 100"
+// @Param offset query integer false "offset from which you want to return the list of approvals. default value is 0"
+// @Success 200 {object} model.RefundApprovalList "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/refunds/approvals [get]
+func (r *refundApprovalRoute) listApprovals(ctx echo.Context) error {
+	status := ctx.QueryParam(requestParameterStatus)
+
+	if status != "" && status != model.RefundApprovalStatusPending &&
+		status != model.RefundApprovalStatusApproved && status != model.RefundApprovalStatusRejected {
+		return echo.NewHTTPError(http.StatusBadRequest, errorMessageRefundApprovalStatusIncorrect)
+	}
+
+	rc := getRequestContext(ctx)
+	offset, err := getListingCursorOffset(rc, rc.Offset)
+
+	if err != nil {
+		return err
+	}
+
+	merchant, err := r.getAuthMerchant(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	list, err := r.refundApprovalStore.FindByMerchantId(merchant.Id, status, rc.Limit, offset)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	cursors, err := getListingCursors(rc, offset, rc.Limit, int32(list.Count))
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.JSON(http.StatusOK, &struct {
+		*model.RefundApprovalList
+		*listingCursors
+	}{list, cursors})
+}
+
+// @Summary Approve refund
+// @Description Approve refund waiting for approval and create it in billing server on behalf of user who
+// @Description requested refund. Refund must be approved by user other than user who requested refund
+// @Tags Order
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "order identifier"
+// @Param approval_id path string true "approval unique identifier"
+// @Success 200 {object} model.RefundApproval "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 409 {object} model.Error "Refund already approved or rejected, or other refund of order is in process"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/{order_id}/refunds/approvals/{approval_id}/approve [post]
+func (r *refundApprovalRoute) approveRefund(ctx echo.Context) error {
+	o, a, err := r.findPendingApproval(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	unlock, err := r.lockOrderRefunds(ctx, a.OrderId)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	// refunds created after request of this refund can decrease refundable amount
+	preview, err := r.getOrderRefundPreview(ctx.Request().Context(), o, a.OrderId, a.Id)
+
+	if err != nil {
+		return err
+	}
+
+	if a.Amount > preview.RefundableAmount {
+		return echo.NewHTTPError(
+			http.StatusBadRequest,
+			fmt.Sprintf(
+				errorMessageRefundAmountExceeded,
+				model.GetCurrencyPrecision(preview.Currency),
+				preview.RefundableAmount,
+				preview.Currency,
+			),
+		)
+	}
+
+	now := time.Now()
+	a.Status = model.RefundApprovalStatusApproved
+	a.ApproverId = getRequestContext(ctx).AuthUser.Id
+	a.DecidedAt = &now
+
+	// approval saved before refund creation to not create refund twice by concurrent requests
+	err = r.saveDecision(a, model.RefundApprovalStatusPending)
+
+	if err != nil {
+		return err
+	}
+
+	req := &grpc.CreateRefundRequest{
+		OrderId:   a.OrderId,
+		Amount:    a.Amount,
+		Reason:    a.Reason,
+		CreatorId: a.CreatorId,
+	}
+	rsp, err := r.billingService.CreateRefund(ctx.Request().Context(), req)
+
+	if err != nil || rsp.Status != pkg.ResponseStatusOk {
+		// refund can be approved again if billing server not created it
+		r.revertApproval(ctx, a)
+
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+		}
+
+		return echo.NewHTTPError(int(rsp.Status), rsp.Message)
+	}
+
+	a.RefundId = rsp.Item.Id
+	err = r.refundApprovalStore.UpdateStatus(a, model.RefundApprovalStatusApproved)
+
+	if err != nil {
+		r.logError(
+			ctx.Request().Context(),
+			"Save of created refund to approval failed",
+			[]interface{}{"error", err.Error(), "approval_id", a.Id.Hex(), "refund_id", a.RefundId},
+		)
+	}
+
+	return ctx.JSON(http.StatusOK, a)
+}
+
+// @Summary Reject refund
+// @Description Reject refund waiting for approval. Refund must be rejected by user other than user who
+// @Description requested refund
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path string true "order identifier"
+// @Param approval_id path string true "approval unique identifier"
+// @Param data body model.RefundApprovalDecision false "Reason of rejection"
+// @Success 200 {object} model.RefundApproval "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 409 {object} model.Error "Refund already approved or rejected"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/order/{order_id}/refunds/approvals/{approval_id}/reject [post]
+func (r *refundApprovalRoute) rejectRefund(ctx echo.Context) error {
+	req := &model.RefundApprovalDecision{}
+	err := ctx.Bind(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
+	}
+
+	err = r.validate.Struct(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
+	}
+
+	_, a, err := r.findPendingApproval(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	a.Status = model.RefundApprovalStatusRejected
+	a.ApproverId = getRequestContext(ctx).AuthUser.Id
+	a.Comment = req.Comment
+	a.DecidedAt = &now
+	err = r.saveDecision(a, model.RefundApprovalStatusPending)
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, a)
+}
+
+// Get approval of refund to order of merchant of authenticated user which can be approved or rejected
+// by authenticated user
+func (r *refundApprovalRoute) findPendingApproval(ctx echo.Context) (*model.Order, *model.RefundApproval, error) {
+	id := ctx.Param(requestParameterApprovalId)
+
+	if !bson.IsObjectIdHex(id) {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, errorIncorrectRefundApprovalId)
+	}
+
+	orderId := ctx.Param(requestParameterOrderId)
+	o, err := r.getMerchantOrder(ctx, orderId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a, err := r.refundApprovalStore.FindById(bson.ObjectIdHex(id), orderId)
+
+	if err == mgo.ErrNotFound {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, model.ResponseMessageNotFound)
+	}
+
+	if err != nil {
+		return nil, nil, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	if a.Status != model.RefundApprovalStatusPending {
+		return nil, nil, echo.NewHTTPError(http.StatusConflict, errorMessageRefundApprovalDecided)
+	}
+
+	if a.CreatorId == getRequestContext(ctx).AuthUser.Id {
+		return nil, nil, echo.NewHTTPError(http.StatusForbidden, errorMessageRefundApproverIsCreator)
+	}
+
+	return o, a, nil
+}
+
+func (r *refundApprovalRoute) saveDecision(a *model.RefundApproval, prev string) error {
+	err := r.refundApprovalStore.UpdateStatus(a, prev)
+
+	// decision about refund made by other user after approval was read
+	if err == mgo.ErrNotFound {
+		return echo.NewHTTPError(http.StatusConflict, errorMessageRefundApprovalDecided)
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return nil
+}
+
+func (r *refundApprovalRoute) revertApproval(ctx echo.Context, a *model.RefundApproval) {
+	a.Status = model.RefundApprovalStatusPending
+	a.ApproverId = ""
+	a.DecidedAt = nil
+	err := r.refundApprovalStore.UpdateStatus(a, model.RefundApprovalStatusApproved)
+
+	if err != nil {
+		r.logError(
+			ctx.Request().Context(),
+			"Revert of refund approval failed",
+			[]interface{}{"error", err.Error(), "approval_id", a.Id.Hex()},
+		)
+	}
+}
@@ -0,0 +1,428 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/globalsign/mgo/bson"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/config"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type RefundApprovalTestSuite struct {
+	suite.Suite
+	router   *refundApprovalRoute
+	api      *Api
+	authUser *AuthUser
+	store    *mock.RefundApprovalStoreMock
+	order    *model.Order
+}
+
+// billing server which can't create refunds
+type createRefundSystemErrorMock struct {
+	grpc.BillingService
+}
+
+func (s *createRefundSystemErrorMock) CreateRefund(
+	ctx context.Context,
+	in *grpc.CreateRefundRequest,
+	opts ...client.CallOption,
+) (*grpc.CreateRefundResponse, error) {
+	return nil, errors.New(mock.SomeError)
+}
+
+func Test_RefundApproval(t *testing.T) {
+	suite.Run(t, new(RefundApprovalTestSuite))
+}
+
+func (suite *RefundApprovalTestSuite) SetupTest() {
+	suite.authUser = &AuthUser{Id: "ffffffffffffffffffffffff"}
+	suite.order = &model.Order{
+		Id:                                  bson.NewObjectId(),
+		Uuid:                                uuid.New().String(),
+		Project:                             &model.ProjectOrder{Id: bson.NewObjectId(), Merchant: mock.OnboardingMerchantMock},
+		PaymentMethod:                       &model.OrderPaymentMethod{Id: bson.NewObjectId()},
+		PaymentMethodIncomeAmount:           100,
+		PaymentMethodIncomeCurrency:         &model.Currency{CodeA3: "RUB"},
+		AmountOutMerchantAccountingCurrency: 50,
+		Status:                              model.OrderStatusProjectComplete,
+	}
+	suite.store = mock.NewRefundApprovalStoreMock(
+		&model.RefundApprovalRule{Id: bson.NewObjectId(), MerchantId: mock.OnboardingMerchantMock.Id, Amount: 50},
+	)
+
+	suite.api = &Api{
+		Http:                echo.New(),
+		validate:            validator.New(),
+		billingService:      mock.NewBillingServerOkMock(),
+		refundApprovalStore: suite.store,
+		idempotencyStore:    NewMemoryIdempotencyStore(),
+		config: &config.Config{
+			Environment: "test",
+		},
+	}
+	suite.router = &refundApprovalRoute{
+		orderRoute: &orderRoute{Api: suite.api, orderStore: mock.NewOrderStoreMock(suite.order)},
+	}
+
+	err := suite.api.validate.RegisterValidation("uuid", suite.api.UuidValidator)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *RefundApprovalTestSuite) TearDownTest() {}
+
+func (suite *RefundApprovalTestSuite) createRefund(data string) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(data))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = &AuthUser{Id: "creator"}
+
+	ctx.SetPath("/order/:order_id/refunds")
+	ctx.SetParamNames(requestParameterOrderId)
+	ctx.SetParamValues(suite.order.Uuid)
+
+	return rsp, suite.router.createRefund(ctx)
+}
+
+func (suite *RefundApprovalTestSuite) decide(action string, a *model.RefundApproval, user string) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"comment": "customer is fraudster"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rsp := httptest.NewRecorder()
+	ctx := suite.api.Http.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = &AuthUser{Id: user}
+
+	ctx.SetPath("/order/:order_id/refunds/approvals/:approval_id/" + action)
+	ctx.SetParamNames(requestParameterOrderId, requestParameterApprovalId)
+	ctx.SetParamValues(a.OrderId, a.Id.Hex())
+
+	if action == "approve" {
+		return rsp, suite.router.approveRefund(ctx)
+	}
+
+	return rsp, suite.router.rejectRefund(ctx)
+}
+
+func (suite *RefundApprovalTestSuite) insertApproval(amount float64) *model.RefundApproval {
+	a := &model.RefundApproval{
+		OrderId:    suite.order.Uuid,
+		MerchantId: mock.OnboardingMerchantMock.Id,
+		Amount:     amount,
+		Currency:   "RUB",
+		Reason:     model.RefundReasonCustomerRequest,
+		CreatorId:  "creator",
+	}
+	err := suite.store.Insert(a)
+	assert.NoError(suite.T(), err)
+
+	return a
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_RuleMatch() {
+	pm := suite.order.PaymentMethod.Id.Hex()
+
+	tests := []struct {
+		name    string
+		rule    *model.RefundApprovalRule
+		matched bool
+	}{
+		{name: "amount greater than threshold", rule: &model.RefundApprovalRule{Amount: 50}, matched: true},
+		{name: "amount equal to threshold", rule: &model.RefundApprovalRule{Amount: 60}},
+		{name: "same currency", rule: &model.RefundApprovalRule{Amount: 50, Currency: "RUB"}, matched: true},
+		{name: "other currency", rule: &model.RefundApprovalRule{Amount: 50, Currency: "USD"}},
+		{name: "same payment method", rule: &model.RefundApprovalRule{PaymentMethodId: pm}, matched: true},
+		{name: "other payment method", rule: &model.RefundApprovalRule{PaymentMethodId: bson.NewObjectId().Hex()}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(suite.T(), tt.matched, tt.rule.Match(suite.order, "RUB", 60), tt.name)
+	}
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_CreateRefund_NotMatched_Ok() {
+	rsp, err := suite.createRefund(`{"amount": 50, "reason": "customer_request"}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusCreated, rsp.Code)
+
+	list, err := suite.store.FindByMerchantId(mock.OnboardingMerchantMock.Id, "", LimitDefault, OffsetDefault)
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), list.Count)
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_CreateRefund_PendingApproval() {
+	rsp, err := suite.createRefund(`{"amount": 60, "reason": "customer_request"}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusAccepted, rsp.Code)
+
+	a := &model.RefundApproval{}
+	err = json.Unmarshal(rsp.Body.Bytes(), a)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.RefundApprovalStatusPending, a.Status)
+	assert.Equal(suite.T(), "creator", a.CreatorId)
+	assert.Equal(suite.T(), "RUB", a.Currency)
+	assert.Equal(suite.T(), float64(60), a.Amount)
+
+	// refund waiting for approval decreases refundable amount
+	preview, err := suite.router.getOrderRefundPreview(context.TODO(), suite.order, suite.order.Uuid, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), float64(60), preview.PendingApprovalAmount)
+	assert.Equal(suite.T(), float64(20), preview.RefundableAmount)
+	assert.Equal(suite.T(), float64(10), preview.MerchantRefundableAmount)
+
+	_, err = suite.createRefund(`{"amount": 30, "reason": "customer_request"}`)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_Approve_Ok() {
+	a := suite.insertApproval(60)
+
+	rsp, err := suite.decide("approve", a, suite.authUser.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	a, err = suite.store.FindById(a.Id, a.OrderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.RefundApprovalStatusApproved, a.Status)
+	assert.Equal(suite.T(), suite.authUser.Id, a.ApproverId)
+	assert.NotEmpty(suite.T(), a.RefundId)
+	assert.NotNil(suite.T(), a.DecidedAt)
+
+	_, err = suite.decide("approve", a, suite.authUser.Id)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusConflict, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageRefundApprovalDecided, httpErr.Message)
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_Reject_Ok() {
+	a := suite.insertApproval(60)
+
+	rsp, err := suite.decide("reject", a, suite.authUser.Id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	a, err = suite.store.FindById(a.Id, a.OrderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.RefundApprovalStatusRejected, a.Status)
+	assert.Equal(suite.T(), suite.authUser.Id, a.ApproverId)
+	assert.Equal(suite.T(), "customer is fraudster", a.Comment)
+	assert.Empty(suite.T(), a.RefundId)
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_Decide_Error() {
+	a := suite.insertApproval(60)
+	other := suite.insertApproval(30)
+	other.OrderId = uuid.New().String()
+
+	tests := []struct {
+		name     string
+		action   string
+		approval *model.RefundApproval
+		user     string
+		code     int
+		message  interface{}
+	}{
+		{
+			name:     "approved by creator",
+			action:   "approve",
+			approval: a,
+			user:     a.CreatorId,
+			code:     http.StatusForbidden,
+			message:  errorMessageRefundApproverIsCreator,
+		},
+		{
+			name:     "rejected by creator",
+			action:   "reject",
+			approval: a,
+			user:     a.CreatorId,
+			code:     http.StatusForbidden,
+			message:  errorMessageRefundApproverIsCreator,
+		},
+		{
+			name:     "incorrect approval identifier",
+			action:   "approve",
+			approval: &model.RefundApproval{OrderId: a.OrderId},
+			user:     suite.authUser.Id,
+			code:     http.StatusBadRequest,
+			message:  errorIncorrectRefundApprovalId,
+		},
+		{
+			name:     "approval not found",
+			action:   "approve",
+			approval: &model.RefundApproval{Id: bson.NewObjectId(), OrderId: a.OrderId},
+			user:     suite.authUser.Id,
+			code:     http.StatusNotFound,
+			message:  model.ResponseMessageNotFound,
+		},
+		{
+			name:     "order not found",
+			action:   "reject",
+			approval: other,
+			user:     suite.authUser.Id,
+			code:     http.StatusNotFound,
+			message:  model.ResponseMessageNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := suite.decide(tt.action, tt.approval, tt.user)
+
+		if !assert.Error(suite.T(), err, tt.name) {
+			continue
+		}
+
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok, tt.name)
+		assert.Equal(suite.T(), tt.code, httpErr.Code, tt.name)
+		assert.Equal(suite.T(), tt.message, httpErr.Message, tt.name)
+	}
+
+	a, err := suite.store.FindById(a.Id, a.OrderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.RefundApprovalStatusPending, a.Status)
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_Approve_AmountExceeded_Error() {
+	a := suite.insertApproval(60)
+	suite.insertApproval(30)
+
+	_, err := suite.decide("approve", a, suite.authUser.Id)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_Approve_BillingServerError() {
+	a := suite.insertApproval(60)
+	suite.router.billingService = &createRefundSystemErrorMock{BillingService: mock.NewBillingServerOkMock()}
+
+	_, err := suite.decide("approve", a, suite.authUser.Id)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusInternalServerError, httpErr.Code)
+
+	// refund not created, so it can be approved again
+	a, err = suite.store.FindById(a.Id, a.OrderId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.RefundApprovalStatusPending, a.Status)
+	assert.Empty(suite.T(), a.ApproverId)
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_ListApprovals() {
+	suite.insertApproval(60)
+	a := suite.insertApproval(70)
+	a.Status = model.RefundApprovalStatusRejected
+	assert.NoError(suite.T(), suite.store.UpdateStatus(a, model.RefundApprovalStatusPending))
+
+	tests := []struct {
+		status string
+		count  int
+	}{
+		{status: "", count: 2},
+		{status: model.RefundApprovalStatusPending, count: 1},
+		{status: model.RefundApprovalStatusApproved, count: 0},
+	}
+
+	for _, tt := range tests {
+		rsp := httptest.NewRecorder()
+		ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/?status="+tt.status, nil), rsp)
+		getRequestContext(ctx).AuthUser = suite.authUser
+
+		err := suite.router.listApprovals(ctx)
+
+		if !assert.NoError(suite.T(), err, tt.status) {
+			continue
+		}
+
+		list := &model.RefundApprovalList{}
+		err = json.Unmarshal(rsp.Body.Bytes(), list)
+		assert.NoError(suite.T(), err, tt.status)
+		assert.Equal(suite.T(), tt.count, list.Count, tt.status)
+		assert.Len(suite.T(), list.Items, tt.count, tt.status)
+	}
+
+	ctx := suite.api.Http.NewContext(httptest.NewRequest(http.MethodGet, "/?status=new", nil), httptest.NewRecorder())
+	getRequestContext(ctx).AuthUser = suite.authUser
+
+	err := suite.router.listApprovals(ctx)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageRefundApprovalStatusIncorrect, httpErr.Message)
+}
+
+func (suite *RefundApprovalTestSuite) TestRefundApproval_CreateRule() {
+	tests := []struct {
+		name    string
+		data    string
+		code    int
+		message string
+	}{
+		{name: "ok", data: `{"amount": 1000, "currency": "USD"}`, code: http.StatusCreated},
+		{name: "negative amount", data: `{"amount": -1}`, code: http.StatusBadRequest, message: "min"},
+		{name: "incorrect currency", data: `{"amount": 1, "currency": "US"}`, code: http.StatusBadRequest, message: "len"},
+		{
+			name:    "incorrect payment method",
+			data:    `{"amount": 1, "payment_method_id": "card"}`,
+			code:    http.StatusBadRequest,
+			message: "hexadecimal",
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.data))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rsp := httptest.NewRecorder()
+		ctx := suite.api.Http.NewContext(req, rsp)
+		getRequestContext(ctx).AuthUser = suite.authUser
+
+		err := suite.router.createRule(ctx)
+
+		if tt.code == http.StatusCreated {
+			assert.NoError(suite.T(), err, tt.name)
+			assert.Equal(suite.T(), tt.code, rsp.Code, tt.name)
+			continue
+		}
+
+		if !assert.Error(suite.T(), err, tt.name) {
+			continue
+		}
+
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok, tt.name)
+		assert.Equal(suite.T(), tt.code, httpErr.Code, tt.name)
+
+		vErr, ok := httpErr.Message.(*model.Error)
+		assert.True(suite.T(), ok, tt.name)
+		assert.Contains(suite.T(), vErr.Message, tt.message, tt.name)
+	}
+
+	rules, err := suite.store.FindRules(mock.OnboardingMerchantMock.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), rules, 2)
+	assert.Equal(suite.T(), suite.authUser.Id, rules[1].CreatorId)
+}
@@ -10,15 +10,21 @@ import (
 	"github.com/ProtocolONE/geoip-service/pkg"
 	"github.com/ProtocolONE/geoip-service/pkg/proto"
 	"github.com/ProtocolONE/rabbitmq/pkg"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-redis/redis"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/micro/go-micro"
+	"github.com/micro/go-plugins/wrapper/trace/opencensus"
 	k8s "github.com/micro/kubernetes/go/micro"
+	"github.com/minio/minio-go"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/config"
 	"github.com/paysuper/paysuper-management-api/database/dao"
 	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"github.com/paysuper/paysuper-management-api/payment_system"
 	"github.com/paysuper/paysuper-management-api/utils"
 	paylinkServiceConst "github.com/paysuper/paysuper-payment-link/pkg"
 	"github.com/paysuper/paysuper-payment-link/proto"
@@ -27,7 +33,7 @@ import (
 	taxServiceConst "github.com/paysuper/paysuper-tax-service/pkg"
 	"github.com/paysuper/paysuper-tax-service/proto"
 	"github.com/sidmal/slug"
-	"github.com/ttacon/libphonenumber"
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 	"gopkg.in/go-playground/validator.v9"
 	"html/template"
@@ -35,6 +41,7 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"sync"
 	"time"
 )
 
@@ -50,27 +57,20 @@ type Template struct {
 }
 
 type ServerInitParams struct {
-	Config      *config.Config
-	Database    dao.Database
-	Logger      *zap.SugaredLogger
-	HttpScheme  string
-	K8sHost     string
-	AmqpAddress string
-	Auth1       *config.Auth1
-}
-
-type Merchant struct {
-	Identifier string
-}
-
-type GetParams struct {
-	limit  int32
-	offset int32
-	sort   []string
-}
-
-type Order struct {
-	PayerPhone *libphonenumber.PhoneNumber
+	Config              *config.Config
+	Database            dao.Database
+	Logger              *zap.SugaredLogger
+	HttpScheme          string
+	K8sHost             string
+	AmqpAddress         string
+	Auth1               *config.Auth1
+	RoleStore           RoleStore
+	IdempotencyStore    IdempotencyStore
+	RefundApprovalStore RefundApprovalStore
+	WebhookInbox        WebhookInbox
+	CustomerTokenStore  CustomerTokenStore
+	TraceExporter       trace.Exporter
+	RateLimiter         RateLimiter
 }
 
 type AuthUser struct {
@@ -94,7 +94,6 @@ type Api struct {
 	jwtVerifier         *jwtverifier.JwtVerifier
 
 	authUserRouteGroup *echo.Group
-	authUser           *AuthUser
 
 	httpScheme string
 
@@ -108,16 +107,29 @@ type Api struct {
 	taxService     tax_service.TaxService
 	paylinkService paylink.PaylinkService
 
+	roleStore           RoleStore
+	idempotencyStore    IdempotencyStore
+	refundApprovalStore RefundApprovalStore
+	webhookInbox        WebhookInbox
+	customerTokenStore  CustomerTokenStore
+	rateLimiter         RateLimiter
+	redactor            *utils.Redactor
+	openApiSpec         *openapi3.Swagger
+	paymentSystems      *payment_system.Registry
+
+	readinessChecks   []*readinessCheck
+	readinessChecksMx sync.Mutex
+
+	// client of file storage, created on onboarding routes initialization
+	s3Client *minio.Client
+	// functions running in background while server is running, stopped by cancel of service context
+	backgroundWorkers []func(ctx context.Context)
+
 	AmqpAddress string
 	notifierPub *rabbitmq.Broker
 
 	k8sHost      string
-	rawBody      string
 	reqSignature string
-
-	Merchant
-	GetParams
-	Order
 }
 
 func NewServer(p *ServerInitParams) (*Api, error) {
@@ -133,6 +145,114 @@ func NewServer(p *ServerInitParams) (*Api, error) {
 	}
 	api.InitService()
 
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(p.Config.TracingSampleProbability)})
+
+	if p.TraceExporter != nil {
+		trace.RegisterExporter(p.TraceExporter)
+	}
+
+	api.roleStore = p.RoleStore
+
+	if api.roleStore == nil {
+		api.roleStore = NewCompositeRoleStore(
+			manager.InitUserRoleManager(p.Database, p.Logger),
+			&merchantOwnerRoleStore{billingService: api.billingService},
+		)
+	}
+
+	api.idempotencyStore = p.IdempotencyStore
+
+	if api.idempotencyStore == nil {
+		if p.Config.IdempotencyStorage == config.IdempotencyStorageMemory {
+			api.idempotencyStore = NewMemoryIdempotencyStore()
+		} else {
+			api.idempotencyStore = manager.InitIdempotencyManager(p.Database, p.Logger)
+		}
+	}
+
+	api.refundApprovalStore = p.RefundApprovalStore
+
+	if api.refundApprovalStore == nil {
+		api.refundApprovalStore = manager.InitRefundApprovalManager(p.Database, p.Logger)
+	}
+
+	api.webhookInbox = p.WebhookInbox
+
+	if api.webhookInbox == nil {
+		if p.Config.WebhookInboxStorage == config.WebhookInboxStorageMemory {
+			api.webhookInbox = NewMemoryWebhookInbox()
+		} else {
+			api.webhookInbox = manager.InitWebhookInboxManager(p.Database, p.Logger)
+		}
+	}
+
+	api.customerTokenStore = p.CustomerTokenStore
+
+	if api.customerTokenStore == nil {
+		if p.Config.CustomerTokenStorage == config.CustomerTokenStorageMemory {
+			api.customerTokenStore = NewMemoryCustomerTokenStore()
+		} else {
+			api.customerTokenStore = manager.InitCustomerTokenManager(p.Database, p.Logger)
+		}
+	}
+
+	redactionRules, err := utils.ParseRedactionRules(p.Config.LogRedactionRules)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// custom rules placed first to override default rules for same fields
+	api.redactor = utils.NewRedactor(append(redactionRules, utils.DefaultRedactionRules...))
+
+	// notifications to projects are published by order managers created on routes initialization
+	api.notifierPub, err = rabbitmq.NewBroker(p.AmqpAddress)
+
+	if err != nil {
+		return nil, err
+	}
+
+	api.paymentSystems, err = payment_system.LoadRegistry(
+		p.Config.PaymentSystemsConfigPath,
+		&payment_system.PaymentSystemSetting{
+			Logger:   p.Logger,
+			Redactor: api.redactor,
+			Client: &payment_system.ClientSettings{
+				Timeout:            time.Duration(p.Config.PaymentSystemsTimeout) * time.Second,
+				RetryAttempts:      p.Config.PaymentSystemsRetryAttempts,
+				BreakerErrorRate:   p.Config.PaymentSystemsBreakerErrorRate,
+				BreakerMinRequests: p.Config.PaymentSystemsBreakerMinRequests,
+				BreakerOpenTimeout: time.Duration(p.Config.PaymentSystemsBreakerOpenTimeout) * time.Second,
+			},
+		},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !api.paymentSystems.CanCheckCallbackSignature(payment_system.PaymentSystemHandlerCardPay) {
+		p.Logger.Warn("CardPay settings not found, CardPay callbacks will be saved to webhook inbox after check of signature by billing server")
+	}
+
+	api.rateLimiter = p.RateLimiter
+
+	if api.rateLimiter == nil {
+		if p.Config.RateLimitStorage == config.RateLimitStorageRedis {
+			client := redis.NewClient(&redis.Options{
+				Addr:     p.Config.RateLimitRedisAddress,
+				Password: p.Config.RateLimitRedisPassword,
+			})
+			api.rateLimiter = NewRedisRateLimiter(client)
+
+			api.AddReadinessCheck("redis", func(_ context.Context) error {
+				return client.Ping().Err()
+			})
+		} else {
+			api.rateLimiter = NewMemoryRateLimiter()
+		}
+	}
+
 	jwtVerifierSettings := jwtverifier.Config{
 		ClientID:     p.Auth1.ClientId,
 		ClientSecret: p.Auth1.ClientSecret,
@@ -152,7 +272,7 @@ func NewServer(p *ServerInitParams) (*Api, error) {
 
 	api.validate.RegisterStructValidation(ProjectStructValidator, model.ProjectScalar{})
 	api.validate.RegisterStructValidation(api.OrderStructValidator, model.OrderScalar{})
-	err := api.validate.RegisterValidation("phone", api.PhoneValidator)
+	err = api.validate.RegisterValidation("phone", api.PhoneValidator)
 
 	if err != nil {
 		return nil, err
@@ -164,70 +284,76 @@ func NewServer(p *ServerInitParams) (*Api, error) {
 		return nil, err
 	}
 
-	api.accessRouteGroup = api.Http.Group("/api/v1/s")
+	api.AddReadinessCheck("mongo", api.databaseReadinessCheck)
+	api.AddReadinessCheck("billing", api.microServiceReadinessCheck(pkg.ServiceName))
+	api.AddReadinessCheck("tax", api.microServiceReadinessCheck(taxServiceConst.ServiceName))
+	api.AddReadinessCheck("paylink", api.microServiceReadinessCheck(paylinkServiceConst.ServiceName))
+	api.AddReadinessCheck("geoip", api.microServiceReadinessCheck(geoip.ServiceName))
+	api.AddReadinessCheck("repository", api.microServiceReadinessCheck(constant.PayOneRepositoryServiceName))
 
-	api.accessRouteGroup.Use(
-		jwtMiddleware.AuthOneJwtCallableWithConfig(
-			api.jwtVerifier,
-			func(ui *jwtverifier.UserInfo) {
-				api.Merchant.Identifier = ui.UserID
-			},
-		),
-	)
+	if p.Config.OpenApiValidationMode != config.OpenApiValidationDisabled && !api.isProductionEnvironment() {
+		api.openApiSpec, err = LoadOpenApiSpec(p.Config.OpenApiSpecPath)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = api.initRoutes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return api, nil
+}
+
+// Create route groups, register middlewares and all routes of api
+func (api *Api) initRoutes() error {
+	api.accessRouteGroup = api.Http.Group(apiAccessGroupPath)
+	api.accessRouteGroup.Use(api.routeGroupMiddleware(metricsRouteGroupAccess))
+	api.accessRouteGroup.Use(jwtMiddleware.AuthOneJwtWithConfig(api.jwtVerifier))
+	api.accessRouteGroup.Use(api.MerchantIdentifierMiddleware)
 	api.accessRouteGroup.Use(middleware.Logger())
 	api.accessRouteGroup.Use(middleware.Recover())
 
 	api.authUserRouteGroup = api.Http.Group(apiAuthUserGroupPath)
-	api.authUserRouteGroup.Use(
-		jwtMiddleware.AuthOneJwtCallableWithConfig(
-			api.jwtVerifier,
-			func(ui *jwtverifier.UserInfo) {
-				api.authUser = &AuthUser{
-					Id:        ui.UserID,
-					Name:      "System User",
-					Merchants: make(map[string]bool),
-					Roles:     make(map[string]bool),
-				}
-			},
-		),
-	)
+	api.authUserRouteGroup.Use(api.routeGroupMiddleware(metricsRouteGroupAuthUser))
+	api.authUserRouteGroup.Use(jwtMiddleware.AuthOneJwtWithConfig(api.jwtVerifier))
+	api.authUserRouteGroup.Use(api.AuthUserMiddleware)
 	api.authUserRouteGroup.Use(api.getUserDetailsMiddleware)
+	api.authUserRouteGroup.Use(api.idempotencyByUser())
 
 	api.webhookRouteGroup = api.Http.Group(apiWebHookGroupPath)
-	api.webhookRouteGroup.Use(middleware.BodyDump(func(ctx echo.Context, reqBody, resBody []byte) {
-		data := []interface{}{
-			"request_headers", utils.RequestResponseHeadersToString(ctx.Request().Header),
-			"request_body", string(reqBody),
-			"response_headers", utils.RequestResponseHeadersToString(ctx.Response().Header()),
-			"response_body", string(resBody),
-		}
-
-		api.logger.Infow(ctx.Path(), data...)
-	}))
+	api.webhookRouteGroup.Use(api.routeGroupMiddleware(metricsRouteGroupWebHook))
+	api.webhookRouteGroup.Use(middleware.BodyDump(api.logBodyDump))
 	api.webhookRouteGroup.Use(api.RawBodyMiddleware)
 
 	api.apiAuthProjectGroup = api.Http.Group(apiAuthProjectGroupPath)
-	api.apiAuthProjectGroup.Use(middleware.BodyDump(func(ctx echo.Context, reqBody, resBody []byte) {
-		data := []interface{}{
-			"request_headers", utils.RequestResponseHeadersToString(ctx.Request().Header),
-			"request_body", string(reqBody),
-			"response_headers", utils.RequestResponseHeadersToString(ctx.Response().Header()),
-			"response_body", string(resBody),
-		}
-
-		api.logger.Infow(ctx.Path(), data...)
-	}))
+	api.apiAuthProjectGroup.Use(api.routeGroupMiddleware(metricsRouteGroupAuthProject))
+	api.apiAuthProjectGroup.Use(middleware.BodyDump(api.logBodyDump))
 	api.apiAuthProjectGroup.Use(api.RawBodyMiddleware)
+	api.apiAuthProjectGroup.Use(api.idempotencyByProject())
+	api.Http.HTTPErrorHandler = api.HTTPErrorHandler
+	api.Http.Use(middleware.RequestID())
+	api.Http.Use(api.TracingMiddleware)
 	api.Http.Use(api.RawBodyMiddleware)
 
+	api.Http.Use(api.MetricsMiddleware)
 	api.Http.Use(api.LimitOffsetSortMiddleware)
 	api.Http.Use(middleware.Logger())
 	api.Http.Use(middleware.Recover())
 	api.Http.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowHeaders: []string{"authorization", "content-type"},
+		AllowHeaders:  []string{"authorization", "content-type", "idempotency-key"},
+		ExposeHeaders: []string{echo.HeaderXRequestID, HeaderXTraceId, HeaderRetryAfter},
 	}))
 
+	if api.openApiSpec != nil {
+		api.Http.Use(api.OpenApiValidationMiddleware)
+	}
+
 	api.
+		initHealthRoutes().
 		InitCurrencyRoutes().
 		InitCountryRoutes().
 		InitMerchantRoutes().
@@ -240,12 +366,22 @@ func NewServer(p *ServerInitParams) (*Api, error) {
 		InitSystemFeeRoutes().
 		initTaxesRoutes()
 
-	_, err = api.initOnboardingRoutes()
+	_, err := api.initOnboardingRoutes()
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
+	_, err = api.initTokenRoutes()
+
+	if err != nil {
+		return err
+	}
+
+	api.initOrderFilterRoutes()
+	api.initOrderBulkRoutes()
+	api.initRefundApprovalRoutes()
+
 	api.Http.GET("/docs", func(ctx echo.Context) error {
 		return ctx.Render(http.StatusOK, "docs.html", map[string]interface{}{})
 	})
@@ -261,7 +397,23 @@ func NewServer(p *ServerInitParams) (*Api, error) {
 		return ctx.JSON(http.StatusOK, map[string]string{"slug": got})
 	})
 
-	return api, nil
+	return nil
+}
+
+// Log request and response of route with removed sensitive data
+func (api *Api) logBodyDump(ctx echo.Context, reqBody, resBody []byte) {
+	reqHeader := ctx.Request().Header
+	resHeader := ctx.Response().Header()
+
+	data := []interface{}{
+		"request_headers", utils.RequestResponseHeadersToString(api.redactor.RedactHeaders(reqHeader)),
+		"request_body", api.redactor.RedactBody(reqHeader.Get(echo.HeaderContentType), reqBody),
+		"response_headers", utils.RequestResponseHeadersToString(api.redactor.RedactHeaders(resHeader)),
+		"response_body", api.redactor.RedactBody(resHeader.Get(echo.HeaderContentType), resBody),
+		logFieldTraceId, getTraceId(ctx.Request().Context()),
+	}
+
+	api.logger.Infow(ctx.Path(), data...)
 }
 
 func (api *Api) Start() error {
@@ -277,6 +429,10 @@ func (api *Api) Start() error {
 		}
 	}()
 
+	for _, worker := range api.backgroundWorkers {
+		go worker(api.serviceContext)
+	}
+
 	return nil
 }
 
@@ -286,6 +442,7 @@ func (api *Api) InitService() {
 	options := []micro.Option{
 		micro.Name("p1payapi"),
 		micro.Version(constant.PayOneMicroserviceVersion),
+		micro.WrapClient(opencensus.NewClientWrapper()),
 	}
 
 	if api.k8sHost == "" {
@@ -344,16 +501,21 @@ func (api *Api) getUserDetailsMiddleware(next echo.HandlerFunc) echo.HandlerFunc
 			return errors.New(errorMessageAuthorizedUserNotFound)
 		}
 
-		api.authUser.Email = u.Email
+		authUser := getRequestContext(ctx).AuthUser
+		authUser.Email = u.Email
+
+		err = api.loadAuthUserRoles(ctx.Request().Context(), authUser)
+
+		if err != nil {
+			return err
+		}
 
 		return next(ctx)
 	}
 }
 
-func (api *Api) getValidationError(err error) string {
-	vErr := err.(validator.ValidationErrors)[0]
-
-	return fmt.Sprintf(errorMessageMask, vErr.Field(), vErr.Tag())
+func (api *Api) getValidationError(err error) *model.Error {
+	return getValidationErrorResponse(err)
 }
 
 func (api *Api) onboardingBeforeHandler(st interface{}, ctx echo.Context) *echo.HTTPError {
@@ -372,7 +534,11 @@ func (api *Api) onboardingBeforeHandler(st interface{}, ctx echo.Context) *echo.
 	return nil
 }
 
-func (api *Api) logError(msg string, data []interface{}) {
+func (api *Api) logError(ctx context.Context, msg string, data []interface{}) {
+	if traceId := getTraceId(ctx); traceId != "" {
+		data = append(data, logFieldTraceId, traceId)
+	}
+
 	zap.S().Errorw(fmt.Sprintf("[PAYSUPER_MANAGEMENT_API] %s", msg), data...)
 }
 
@@ -387,8 +553,8 @@ func (api *Api) checkProjectAuthRequestSignature(ctx echo.Context, projectId str
 		return echo.NewHTTPError(http.StatusBadRequest, errorMessageSignatureHeaderIsEmpty)
 	}
 
-	req := &grpc.CheckProjectRequestSignatureRequest{Body: api.rawBody, ProjectId: projectId, Signature: signature}
-	rsp, err := api.billingService.CheckProjectRequestSignature(context.TODO(), req)
+	req := &grpc.CheckProjectRequestSignatureRequest{Body: getRequestContext(ctx).RawBody, ProjectId: projectId, Signature: signature}
+	rsp, err := api.billingService.CheckProjectRequestSignature(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
@@ -17,21 +16,31 @@ func (api *Api) InitSystemFeeRoutes() *Api {
 		Api: api,
 	}
 
-	api.authUserRouteGroup.GET("/systemfees", systemFeeApiV1.getSystemFeesList)
-	api.authUserRouteGroup.POST("/systemfees", systemFeeApiV1.addSystemFee)
+	api.authUserRouteGroup.GET("/systemfees", systemFeeApiV1.getSystemFeesList, api.requireRoles(RoleSystemAdmin))
+	api.authUserRouteGroup.POST("/systemfees", systemFeeApiV1.addSystemFee, api.requireRoles(RoleSystemAdmin))
 	return api
 }
 
+// @Summary Get system fees
 // @Description Get list of actual system fees
 // @Example GET /admin/api/v1/systemfees
+// @Tags System Fee
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} object "OK"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/systemfees [get]
 func (r *systemFeeRoute) getSystemFeesList(ctx echo.Context) error {
-	systemFees, err := r.billingService.GetActualSystemFeesList(context.TODO(), &grpc.EmptyRequest{})
+	systemFees, err := r.billingService.GetActualSystemFeesList(ctx.Request().Context(), &grpc.EmptyRequest{})
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
 	}
 	return ctx.JSON(http.StatusOK, systemFees)
 }
 
+// @Summary Add system fee
 // @Description Add new actual system fee
 // @Example curl -X POST -H "Accept: application/json" -H "Content-Type: application/json" \
 //      -H "Authorization: Bearer %access_token_here%" \
@@ -41,6 +50,17 @@ func (r *systemFeeRoute) getSystemFeesList(ctx echo.Context) error {
 //      "authorization_fee": { "percent": 0, "percent_currency": "EUR", "fix_amount": 0.1, "fix_currency": "EUR" } } ],
 //      "user_id": "5cb6e4aa68add437e8a8f0fa" }' \
 //      https://api.paysuper.online/admin/api/v1/systemfees
+// @Tags System Fee
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body systemfees.AddRequest true "System fee data"
+// @Success 200 "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/systemfees [post]
 func (r *systemFeeRoute) addSystemFee(ctx echo.Context) error {
 	req := &billing.AddSystemFeesRequest{}
 
@@ -48,14 +68,14 @@ func (r *systemFeeRoute) addSystemFee(ctx echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
 	}
-	req.UserId = r.authUser.Id
+	req.UserId = getRequestContext(ctx).AuthUser.Id
 
 	err = r.validate.Struct(req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	_, err = r.billingService.AddSystemFees(context.TODO(), req)
+	_, err = r.billingService.AddSystemFees(ctx.Request().Context(), req)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -14,8 +14,9 @@ import (
 
 type SystemFeeTestSuite struct {
 	suite.Suite
-	router *systemFeeRoute
-	api    *Api
+	router   *systemFeeRoute
+	api      *Api
+	authUser *AuthUser
 }
 
 func Test_SystemFee(t *testing.T) {
@@ -23,13 +24,14 @@ func Test_SystemFee(t *testing.T) {
 }
 
 func (suite *SystemFeeTestSuite) SetupTest() {
+	suite.authUser = &AuthUser{
+		Id: "ffffffffffffffffffffffff",
+	}
+
 	suite.api = &Api{
 		Http:           echo.New(),
 		validate:       validator.New(),
 		billingService: mock.NewBillingServerOkMock(),
-		authUser: &AuthUser{
-			Id: "ffffffffffffffffffffffff",
-		},
 	}
 
 	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
@@ -66,6 +68,7 @@ func (suite *SystemFeeTestSuite) TestSystemFees_addSystemFee_Ok() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/systemfees")
 	err := suite.router.addSystemFee(ctx)
@@ -86,6 +89,7 @@ func (suite *SystemFeeTestSuite) TestSystemFees_addSystemFee_Fail() {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rsp := httptest.NewRecorder()
 	ctx := e.NewContext(req, rsp)
+	getRequestContext(ctx).AuthUser = suite.authUser
 
 	ctx.SetPath("/systemfees")
 	err := suite.router.addSystemFee(ctx)
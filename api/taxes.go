@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-management-api/database/model"
 	"github.com/paysuper/paysuper-tax-service/proto"
@@ -16,19 +15,35 @@ type taxesRoute struct {
 func (api *Api) initTaxesRoutes() *Api {
 	route := &taxesRoute{Api: api}
 
-	api.authUserRouteGroup.GET("/taxes", route.getTaxes)
-	api.authUserRouteGroup.POST("/taxes", route.setTax)
-	api.authUserRouteGroup.DELETE("/taxes/:id", route.deleteTax)
+	api.authUserRouteGroup.GET("/taxes", route.getTaxes, api.requireRoles(rolesMerchantRead...))
+	api.authUserRouteGroup.POST("/taxes", route.setTax, api.requireRoles(RoleSystemAdmin))
+	api.authUserRouteGroup.DELETE("/taxes/:id", route.deleteTax, api.requireRoles(RoleSystemAdmin))
 
 	return api
 }
 
+// @Summary List all available tax rates in the system
+// @Description List all available tax rates in the system
+// @Tags Tax
+// @Produce json
+// @Security BearerAuth
+// @Param country query string false "country to filter response"
+// @Param city query string false "city to filter response"
+// @Param state query string false "state to filter response"
+// @Param zip query string false "zip to filter response"
+// @Param limit query integer false "maximum number of returning orders. default value is 100"
+// @Param offset query integer false "offset from which you want to return the list of orders. default value is 0"
+// @Success 200 {array} taxes.TaxRate "OK"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/taxes [get]
 func (r *taxesRoute) getTaxes(ctx echo.Context) error {
 	req := r.bindGetTaxes(ctx)
-	res, err := r.taxService.GetRates(context.TODO(), req)
+	res, err := r.taxService.GetRates(ctx.Request().Context(), req)
 
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
+		return newUpstreamError(err)
 	}
 
 	return ctx.JSON(http.StatusOK, res.Rates)
@@ -74,6 +89,20 @@ func (r *taxesRoute) bindGetTaxes(ctx echo.Context) *tax_service.GetRatesRequest
 	return structure
 }
 
+// @Summary Upsert tax rate data.
+// @Description Create or update tax rate data.
+// @Tags Tax
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body taxes.TaxRate true "Object to upsert"
+// @Success 200 {object} taxes.TaxRate "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 403 {object} model.Error "Access denied"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /admin/api/v1/taxes [post]
 func (r *taxesRoute) setTax(ctx echo.Context) error {
 	req := &tax_service.TaxRate{}
 	err := ctx.Bind(req)
@@ -82,14 +111,25 @@ func (r *taxesRoute) setTax(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request param: "+err.Error())
 	}
 
-	res, err := r.taxService.CreateOrUpdate(context.TODO(), req)
+	res, err := r.taxService.CreateOrUpdate(ctx.Request().Context(), req)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
+		return newUpstreamError(err)
 	}
 
 	return ctx.JSON(http.StatusOK, res)
 }
 
+// @Summary Delete tax rate object
+// @Description Mark tax rate object as deleted
+// @Tags Tax
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "tax rate object id"
+// @Success 200 {string} string "OK"
+// @Failure 401 {object} model.Error "Unauthorized"
+// @Failure 404 {object} model.Error "Not found"
+// @Failure 500 {object} model.Error "Some unknown error"
+// @Router /admin/api/v1/taxes/{id} [delete]
 func (r *taxesRoute) deleteTax(ctx echo.Context) error {
 	id := ctx.Param("id")
 	if id == "" {
@@ -101,9 +141,9 @@ func (r *taxesRoute) deleteTax(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, model.ResponseMessageInvalidRequestData)
 	}
 
-	res, err := r.taxService.DeleteRateById(context.TODO(), &tax_service.DeleteRateRequest{Id: uint32(value)})
+	res, err := r.taxService.DeleteRateById(ctx.Request().Context(), &tax_service.DeleteRateRequest{Id: uint32(value)})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
+		return newUpstreamError(err)
 	}
 
 	return ctx.JSON(http.StatusOK, res)
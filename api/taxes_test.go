@@ -38,7 +38,6 @@ func (suite *TaxesTestSuite) SetupTest() {
 		Http:       echo.New(),
 		validate:   validator.New(),
 		taxService: createNewTaxServiceMock(),
-		authUser:   &AuthUser{Id: "ffffffffffffffffffffffff"},
 	}
 
 	suite.api.authUserRouteGroup = suite.api.Http.Group(apiAuthUserGroupPath)
@@ -1,13 +1,148 @@
 package api
 
 import (
-	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/database/model"
 	"net/http"
+	"sort"
+	"sync"
+	"time"
 )
 
+const customerTokenLifetimeDefault = 30 * 24 * time.Hour
+
+// CustomerTokenStore is a registry of customer tokens created by billing server. Billing server can't
+// introspect and revoke tokens, so lifecycle of tokens managed by api with this registry
+type CustomerTokenStore interface {
+	Insert(t *model.CustomerToken) error
+	// Get token by identifier. If token not found then nil returned
+	Get(id string) (*model.CustomerToken, error)
+	// Find not revoked and not expired tokens of project. If user identifier is empty then tokens of all
+	// users returned. Second returned value is a count of all active tokens by query
+	FindActive(projectId string, userId string, limit int, offset int) ([]*model.CustomerToken, int, error)
+	// Revoke active token. If token not found or isn't active then false returned
+	Revoke(id string) (bool, error)
+	// Revoke all active tokens of user in project and return count of revoked tokens
+	RevokeByUserId(projectId string, userId string) (int, error)
+}
+
+type memoryCustomerTokenStore struct {
+	mx     sync.Mutex
+	tokens map[string]*model.CustomerToken
+}
+
+// Create customer token store which keep tokens in memory of current process.
+// Must be used only for single instance installations and tests
+func NewMemoryCustomerTokenStore() CustomerTokenStore {
+	return &memoryCustomerTokenStore{tokens: make(map[string]*model.CustomerToken)}
+}
+
+func (s *memoryCustomerTokenStore) Insert(t *model.CustomerToken) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	copied := *t
+	s.tokens[t.Id] = &copied
+
+	return nil
+}
+
+func (s *memoryCustomerTokenStore) Get(id string) (*model.CustomerToken, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	t, ok := s.tokens[id]
+
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *t
+
+	return &copied, nil
+}
+
+func (s *memoryCustomerTokenStore) FindActive(
+	projectId string,
+	userId string,
+	limit int,
+	offset int,
+) ([]*model.CustomerToken, int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var tokens []*model.CustomerToken
+	now := time.Now()
+
+	for _, t := range s.tokens {
+		if t.ProjectId != projectId || (userId != "" && t.UserId != userId) || !t.IsActive(now) {
+			continue
+		}
+
+		copied := *t
+		tokens = append(tokens, &copied)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+
+	count := len(tokens)
+
+	if offset > count {
+		offset = count
+	}
+
+	tokens = tokens[offset:]
+
+	if limit > 0 && limit < len(tokens) {
+		tokens = tokens[:limit]
+	}
+
+	return tokens, count, nil
+}
+
+func (s *memoryCustomerTokenStore) Revoke(id string) (bool, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	t, ok := s.tokens[id]
+
+	if !ok || !t.IsActive(now) {
+		return false, nil
+	}
+
+	t.RevokedAt = &now
+
+	return true, nil
+}
+
+func (s *memoryCustomerTokenStore) RevokeByUserId(projectId string, userId string) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	count := 0
+	now := time.Now()
+
+	for _, t := range s.tokens {
+		if t.ProjectId != projectId || t.UserId != userId || !t.IsActive(now) {
+			continue
+		}
+
+		revokedAt := now
+		t.RevokedAt = &revokedAt
+		count++
+	}
+
+	return count, nil
+}
+
 type tokenRoute struct {
 	*Api
 }
@@ -15,10 +150,25 @@ type tokenRoute struct {
 func (api *Api) initTokenRoutes() (*Api, error) {
 	route := &tokenRoute{Api: api}
 	api.apiAuthProjectGroup.POST("/tokens", route.createToken)
+	api.apiAuthProjectGroup.POST("/tokens/introspect", route.introspectToken)
+	api.apiAuthProjectGroup.POST("/tokens/revoke", route.revokeToken)
+	api.apiAuthProjectGroup.POST("/tokens/active", route.listActiveTokens)
 
 	return api, nil
 }
 
+// @Summary Create token
+// @Description Create token for process payment token create
+// @Tags Token
+// @Accept json
+// @Produce json
+// @Security XAPISignatureHeader
+// @Param data body token.TokenRequest true "Data to process payment"
+// @Success 200 {object} token.TokenResponse "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 404 {object} model.Error "Object not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /api/v1/tokens [post]
 func (r *tokenRoute) createToken(ctx echo.Context) error {
 	req := &grpc.TokenRequest{}
 	err := ctx.Bind(req)
@@ -39,7 +189,7 @@ func (r *tokenRoute) createToken(ctx echo.Context) error {
 		return err
 	}
 
-	rsp, err := r.billingService.CreateToken(context.TODO(), req)
+	rsp, err := r.billingService.CreateToken(ctx.Request().Context(), req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
@@ -49,5 +199,225 @@ func (r *tokenRoute) createToken(ctx echo.Context) error {
 		return echo.NewHTTPError(int(rsp.Status), rsp.Message)
 	}
 
+	now := time.Now()
+	t := &model.CustomerToken{
+		Id:        getCustomerTokenId(rsp.Token),
+		ProjectId: req.Settings.ProjectId,
+		CreatedAt: now,
+		ExpiresAt: now.Add(r.getCustomerTokenLifetime()),
+	}
+
+	if req.User != nil {
+		t.UserId = req.User.Id
+	}
+
+	if err = r.customerTokenStore.Insert(t); err != nil {
+		r.logger.Errorw("Customer token not saved to registry", "error", err, "project_id", t.ProjectId)
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
 	return ctx.JSON(http.StatusOK, map[string]string{"token": rsp.Token})
 }
+
+// @Summary Introspect token
+// @Description Get information about customer token. Unknown tokens and tokens of other projects returned as inactive
+// @Tags Token
+// @Accept json
+// @Produce json
+// @Security XAPISignatureHeader
+// @Param data body model.CustomerTokenIntrospectRequest true "Token to introspect"
+// @Success 200 {object} model.CustomerTokenInfo "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /api/v1/tokens/introspect [post]
+func (r *tokenRoute) introspectToken(ctx echo.Context) error {
+	req := &model.CustomerTokenIntrospectRequest{}
+	err := ctx.Bind(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
+	}
+
+	err = r.validate.Struct(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
+	}
+
+	err = r.checkProjectAuthRequestSignature(ctx, req.ProjectId)
+
+	if err != nil {
+		return err
+	}
+
+	t, err := r.customerTokenStore.Get(getCustomerTokenId(req.Token))
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	// don't disclose tokens of other projects
+	if t == nil || t.ProjectId != req.ProjectId {
+		return ctx.JSON(http.StatusOK, &model.CustomerTokenInfo{Active: false})
+	}
+
+	return ctx.JSON(http.StatusOK, &model.CustomerTokenInfo{CustomerToken: t, Active: t.IsActive(time.Now())})
+}
+
+// @Summary Revoke token
+// @Description Revoke customer token or all active tokens of user. Orders can't be created by revoked token
+// @Tags Token
+// @Accept json
+// @Produce json
+// @Security XAPISignatureHeader
+// @Param data body model.CustomerTokenRevokeRequest true "Token or user to revoke tokens"
+// @Success 204 "Token revoked"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 404 {object} model.Error "Token not found"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /api/v1/tokens/revoke [post]
+func (r *tokenRoute) revokeToken(ctx echo.Context) error {
+	req := &model.CustomerTokenRevokeRequest{}
+	err := ctx.Bind(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
+	}
+
+	err = r.validate.Struct(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
+	}
+
+	err = r.checkProjectAuthRequestSignature(ctx, req.ProjectId)
+
+	if err != nil {
+		return err
+	}
+
+	if req.Token == "" {
+		_, err = r.customerTokenStore.RevokeByUserId(req.ProjectId, req.UserId)
+
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+		}
+
+		return ctx.NoContent(http.StatusNoContent)
+	}
+
+	id := getCustomerTokenId(req.Token)
+	t, err := r.customerTokenStore.Get(id)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	if t == nil || t.ProjectId != req.ProjectId {
+		return echo.NewHTTPError(http.StatusNotFound, errorMessageCustomerTokenNotFound)
+	}
+
+	// revoke of already revoked or expired token do nothing
+	if _, err = r.customerTokenStore.Revoke(id); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// @Summary List active tokens
+// @Description Get list of not revoked and not expired customer tokens of project or of user in project
+// @Tags Token
+// @Accept json
+// @Produce json
+// @Security XAPISignatureHeader
+// @Param data body model.CustomerTokenListRequest true "Project, user and pagination parameters"
+// @Success 200 {object} model.CustomerTokenList "OK"
+// @Failure 400 {object} model.Error "Invalid request data"
+// @Failure 500 {object} model.Error "Some unknown error on server side"
+// @Router /api/v1/tokens/active [post]
+func (r *tokenRoute) listActiveTokens(ctx echo.Context) error {
+	req := &model.CustomerTokenListRequest{}
+	err := ctx.Bind(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errorQueryParamsIncorrect)
+	}
+
+	err = r.validate.Struct(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, r.getValidationError(err))
+	}
+
+	err = r.checkProjectAuthRequestSignature(ctx, req.ProjectId)
+
+	if err != nil {
+		return err
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = LimitDefault
+	}
+
+	tokens, count, err := r.customerTokenStore.FindActive(req.ProjectId, req.UserId, req.Limit, req.Offset)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	rsp := &model.CustomerTokenList{Count: count, Items: []*model.CustomerTokenInfo{}}
+
+	for _, t := range tokens {
+		rsp.Items = append(rsp.Items, &model.CustomerTokenInfo{CustomerToken: t, Active: true})
+	}
+
+	return ctx.JSON(http.StatusOK, rsp)
+}
+
+func (api *Api) getCustomerTokenLifetime() time.Duration {
+	if api.config == nil || api.config.CustomerTokenLifetime <= 0 {
+		return customerTokenLifetimeDefault
+	}
+
+	return time.Duration(api.config.CustomerTokenLifetime) * time.Second
+}
+
+// Check that customer token from request to create order isn't revoked or expired. Tokens which not found in
+// registry (e.g. created before registry was introduced) checked only by billing server
+func (api *Api) checkCustomerToken(token string) error {
+	if token == "" {
+		return nil
+	}
+
+	t, err := api.customerTokenStore.Get(getCustomerTokenId(token))
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	if t != nil && !t.IsActive(time.Now()) {
+		return echo.NewHTTPError(http.StatusBadRequest, errorMessageCustomerTokenRevoked)
+	}
+
+	return nil
+}
+
+// Get customer token from json body of order create request
+func getJsonCustomerToken(ctx echo.Context) string {
+	data := &struct {
+		Token string `json:"token"`
+	}{}
+
+	if err := json.Unmarshal([]byte(getRequestContext(ctx).RawBody), data); err != nil {
+		return ""
+	}
+
+	return data.Token
+}
+
+// Tokens saved to registry by hash, so value of token can't be got from registry
+func getCustomerTokenId(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
@@ -3,28 +3,39 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/kelseyhightower/envconfig"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/config"
+	"github.com/paysuper/paysuper-management-api/database/model"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
 	"gopkg.in/go-playground/validator.v9"
 	"gopkg.in/mgo.v2/bson"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
+const tokenTestToken = "customer_token"
+
 var tokenRoutes = [][]string{
 	{"/api/v1/tokens", http.MethodPost},
+	{"/api/v1/tokens/introspect", http.MethodPost},
+	{"/api/v1/tokens/revoke", http.MethodPost},
+	{"/api/v1/tokens/active", http.MethodPost},
 }
 
 type TokenTestSuite struct {
 	suite.Suite
-	router *tokenRoute
-	api    *Api
+	router    *tokenRoute
+	api       *Api
+	projectId string
 }
 
 func Test_Customer(t *testing.T) {
@@ -36,10 +47,10 @@ func (suite *TokenTestSuite) SetupTest() {
 		Http:           echo.New(),
 		validate:       validator.New(),
 		billingService: mock.NewBillingServerOkMock(),
-		authUser: &AuthUser{
-			Id: "ffffffffffffffffffffffff",
-		},
+		logger:         zap.NewNop().Sugar(),
 	}
+	suite.api.customerTokenStore = NewMemoryCustomerTokenStore()
+	suite.projectId = bson.NewObjectId().Hex()
 
 	suite.api.apiAuthProjectGroup = suite.api.Http.Group(apiAuthProjectGroupPath)
 	suite.api.apiAuthProjectGroup.Use(suite.api.RawBodyMiddleware)
@@ -121,6 +132,17 @@ func (suite *TokenTestSuite) TestToken_CreateToken_Ok() {
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
 	assert.NotEmpty(suite.T(), rsp.Body.String())
+
+	var response map[string]string
+	err = json.Unmarshal(rsp.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+
+	t, err := suite.api.customerTokenStore.Get(getCustomerTokenId(response["token"]))
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), t)
+	assert.Equal(suite.T(), body.Settings.ProjectId, t.ProjectId)
+	assert.Equal(suite.T(), body.User.Id, t.UserId)
+	assert.True(suite.T(), t.IsActive(time.Now()))
 }
 
 func (suite *TokenTestSuite) TestToken_CreateToken_BindError() {
@@ -402,3 +424,205 @@ func (suite *TokenTestSuite) TestToken_CreateToken_ChangeCustomer_ResultError()
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 	assert.Equal(suite.T(), mock.SomeError, httpErr.Message)
 }
+
+func (suite *TokenTestSuite) TestToken_InitRoutes_TokenRoutesRegisteredByServer_Ok() {
+	s3Cfg := config.S3{}
+	err := envconfig.Process("", &s3Cfg)
+	assert.NoError(suite.T(), err)
+
+	api := &Api{
+		Http:           echo.New(),
+		logger:         zap.NewNop().Sugar(),
+		validate:       validator.New(),
+		billingService: mock.NewBillingServerOkMock(),
+		paylinkService: mock.NewPaymentLinkOkMock(),
+		rateLimiter:    NewMemoryRateLimiter(),
+		config:         &config.Config{S3: s3Cfg},
+	}
+
+	if !assert.NoError(suite.T(), api.initRoutes()) {
+		return
+	}
+
+	registered := make(map[string]bool)
+
+	for _, r := range api.Http.Routes() {
+		registered[r.Method+" "+r.Path] = true
+	}
+
+	for _, v := range tokenRoutes {
+		assert.True(suite.T(), registered[v[1]+" "+v[0]], "route %s %s not registered", v[1], v[0])
+	}
+}
+
+func (suite *TokenTestSuite) TestToken_IntrospectToken_Ok() {
+	suite.insertToken(tokenTestToken, suite.projectId, "user_1", time.Now().Add(time.Hour))
+
+	rsp, err := suite.execute(suite.router.introspectToken, `{"project_id":"`+suite.projectId+`","token":"`+tokenTestToken+`"}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	info := &model.CustomerTokenInfo{}
+	err = json.Unmarshal(rsp.Body.Bytes(), info)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), info.Active)
+	assert.Equal(suite.T(), suite.projectId, info.ProjectId)
+	assert.Equal(suite.T(), "user_1", info.UserId)
+}
+
+func (suite *TokenTestSuite) TestToken_IntrospectToken_Expired_Inactive() {
+	suite.insertToken(tokenTestToken, suite.projectId, "user_1", time.Now().Add(-time.Hour))
+
+	rsp, err := suite.execute(suite.router.introspectToken, `{"project_id":"`+suite.projectId+`","token":"`+tokenTestToken+`"}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Contains(suite.T(), rsp.Body.String(), `"active":false`)
+	assert.Contains(suite.T(), rsp.Body.String(), `"user_id":"user_1"`)
+}
+
+func (suite *TokenTestSuite) TestToken_IntrospectToken_OtherProject_Inactive() {
+	suite.insertToken(tokenTestToken, bson.NewObjectId().Hex(), "user_1", time.Now().Add(time.Hour))
+
+	rsp, err := suite.execute(suite.router.introspectToken, `{"project_id":"`+suite.projectId+`","token":"`+tokenTestToken+`"}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Equal(suite.T(), `{"active":false}`, strings.TrimSpace(rsp.Body.String()))
+}
+
+func (suite *TokenTestSuite) TestToken_IntrospectToken_ValidationError() {
+	_, err := suite.execute(suite.router.introspectToken, `{"project_id":"`+suite.projectId+`"}`)
+	suite.assertHttpError(err, http.StatusBadRequest)
+}
+
+func (suite *TokenTestSuite) TestToken_IntrospectToken_CheckProjectRequestSignature_Error() {
+	suite.router.billingService = mock.NewBillingServerErrorMock()
+
+	_, err := suite.execute(suite.router.introspectToken, `{"project_id":"`+suite.projectId+`","token":"`+tokenTestToken+`"}`)
+	suite.assertHttpError(err, http.StatusBadRequest)
+}
+
+func (suite *TokenTestSuite) TestToken_RevokeToken_Ok() {
+	suite.insertToken(tokenTestToken, suite.projectId, "user_1", time.Now().Add(time.Hour))
+
+	rsp, err := suite.execute(suite.router.revokeToken, `{"project_id":"`+suite.projectId+`","token":"`+tokenTestToken+`"}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNoContent, rsp.Code)
+
+	t, err := suite.api.customerTokenStore.Get(getCustomerTokenId(tokenTestToken))
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), t.RevokedAt)
+	assert.False(suite.T(), t.IsActive(time.Now()))
+}
+
+func (suite *TokenTestSuite) TestToken_RevokeToken_ByUserId_Ok() {
+	suite.insertToken("token_1", suite.projectId, "user_1", time.Now().Add(time.Hour))
+	suite.insertToken("token_2", suite.projectId, "user_1", time.Now().Add(time.Hour))
+	suite.insertToken("token_3", suite.projectId, "user_2", time.Now().Add(time.Hour))
+
+	rsp, err := suite.execute(suite.router.revokeToken, `{"project_id":"`+suite.projectId+`","user_id":"user_1"}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNoContent, rsp.Code)
+
+	tokens, count, err := suite.api.customerTokenStore.FindActive(suite.projectId, "", LimitDefault, OffsetDefault)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, count)
+	assert.Equal(suite.T(), "user_2", tokens[0].UserId)
+}
+
+func (suite *TokenTestSuite) TestToken_RevokeToken_OtherProject_NotFound() {
+	suite.insertToken(tokenTestToken, bson.NewObjectId().Hex(), "user_1", time.Now().Add(time.Hour))
+
+	_, err := suite.execute(suite.router.revokeToken, `{"project_id":"`+suite.projectId+`","token":"`+tokenTestToken+`"}`)
+	suite.assertHttpError(err, http.StatusNotFound)
+
+	t, err := suite.api.customerTokenStore.Get(getCustomerTokenId(tokenTestToken))
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), t.RevokedAt)
+}
+
+func (suite *TokenTestSuite) TestToken_RevokeToken_ValidationError() {
+	_, err := suite.execute(suite.router.revokeToken, `{"project_id":"`+suite.projectId+`"}`)
+	suite.assertHttpError(err, http.StatusBadRequest)
+}
+
+func (suite *TokenTestSuite) TestToken_RevokeToken_CheckProjectRequestSignature_Error() {
+	suite.insertToken(tokenTestToken, suite.projectId, "user_1", time.Now().Add(time.Hour))
+	suite.router.billingService = mock.NewBillingServerErrorMock()
+
+	_, err := suite.execute(suite.router.revokeToken, `{"project_id":"`+suite.projectId+`","token":"`+tokenTestToken+`"}`)
+	suite.assertHttpError(err, http.StatusBadRequest)
+
+	t, err := suite.api.customerTokenStore.Get(getCustomerTokenId(tokenTestToken))
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), t.RevokedAt)
+}
+
+func (suite *TokenTestSuite) TestToken_ListActiveTokens_Ok() {
+	suite.insertToken("token_1", suite.projectId, "user_1", time.Now().Add(time.Hour))
+	suite.insertToken("token_2", suite.projectId, "user_1", time.Now().Add(-time.Hour))
+	suite.insertToken("token_3", suite.projectId, "user_2", time.Now().Add(time.Hour))
+	suite.insertToken("token_4", bson.NewObjectId().Hex(), "user_1", time.Now().Add(time.Hour))
+
+	rsp, err := suite.execute(suite.router.listActiveTokens, `{"project_id":"`+suite.projectId+`","user_id":"user_1"}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	list := &model.CustomerTokenList{}
+	err = json.Unmarshal(rsp.Body.Bytes(), list)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, list.Count)
+	assert.Len(suite.T(), list.Items, 1)
+	assert.Equal(suite.T(), getCustomerTokenId("token_1"), list.Items[0].Id)
+	assert.True(suite.T(), list.Items[0].Active)
+}
+
+func (suite *TokenTestSuite) TestToken_ListActiveTokens_Pagination_Ok() {
+	for i := 0; i < 3; i++ {
+		suite.insertToken(bson.NewObjectId().Hex(), suite.projectId, "user_1", time.Now().Add(time.Hour))
+	}
+
+	rsp, err := suite.execute(suite.router.listActiveTokens, `{"project_id":"`+suite.projectId+`","limit":2,"offset":2}`)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	list := &model.CustomerTokenList{}
+	err = json.Unmarshal(rsp.Body.Bytes(), list)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3, list.Count)
+	assert.Len(suite.T(), list.Items, 1)
+}
+
+func (suite *TokenTestSuite) TestToken_ListActiveTokens_CheckProjectRequestSignature_Error() {
+	suite.router.billingService = mock.NewBillingServerSystemErrorMock()
+
+	_, err := suite.execute(suite.router.listActiveTokens, `{"project_id":"`+suite.projectId+`"}`)
+	suite.assertHttpError(err, http.StatusInternalServerError)
+}
+
+func (suite *TokenTestSuite) insertToken(token, projectId, userId string, expiresAt time.Time) {
+	err := suite.api.customerTokenStore.Insert(&model.CustomerToken{
+		Id:        getCustomerTokenId(token),
+		ProjectId: projectId,
+		UserId:    userId,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	})
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *TokenTestSuite) execute(handler echo.HandlerFunc, body string) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderXApiSignatureHeader, "signature")
+	rsp := httptest.NewRecorder()
+
+	return rsp, suite.api.RawBodyMiddleware(handler)(suite.api.Http.NewContext(req, rsp))
+}
+
+func (suite *TokenTestSuite) assertHttpError(err error, code int) {
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), code, httpErr.Code)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
+	"go.opencensus.io/trace"
+	"net/http"
+	"sync"
+)
+
+const (
+	traceAttributeHttpMethod     = "http.method"
+	traceAttributeHttpRoute      = "http.route"
+	traceAttributeHttpUserAgent  = "http.user_agent"
+	traceAttributeHttpStatusCode = "http.status_code"
+
+	logFieldTraceId = "trace_id"
+)
+
+// MemorySpanExporter keep finished spans in memory. Must be used only for tests
+type MemorySpanExporter struct {
+	mx    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func NewMemorySpanExporter() *MemorySpanExporter {
+	return &MemorySpanExporter{}
+}
+
+func (e *MemorySpanExporter) ExportSpan(s *trace.SpanData) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	e.spans = append(e.spans, s)
+}
+
+func (e *MemorySpanExporter) Spans() []*trace.SpanData {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	spans := make([]*trace.SpanData, len(e.spans))
+	copy(spans, e.spans)
+
+	return spans
+}
+
+// Start server span for every http request. If request contains W3C trace context headers, then span will
+// be a child of remote span. Request context replaced by context with span, so request context must be passed
+// to all micro services calls for trace propagation
+func (api *Api) TracingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	format := &tracecontext.HTTPFormat{}
+
+	return func(ctx echo.Context) error {
+		req := ctx.Request()
+		name := req.Method + " " + ctx.Path()
+
+		var spanCtx context.Context
+		var span *trace.Span
+
+		if parent, ok := format.SpanContextFromRequest(req); ok {
+			spanCtx, span = trace.StartSpanWithRemoteParent(req.Context(), name, parent, trace.WithSpanKind(trace.SpanKindServer))
+		} else {
+			spanCtx, span = trace.StartSpan(req.Context(), name, trace.WithSpanKind(trace.SpanKindServer))
+		}
+
+		defer span.End()
+
+		span.AddAttributes(
+			trace.StringAttribute(traceAttributeHttpMethod, req.Method),
+			trace.StringAttribute(traceAttributeHttpRoute, ctx.Path()),
+			trace.StringAttribute(traceAttributeHttpUserAgent, req.UserAgent()),
+		)
+
+		ctx.SetRequest(req.WithContext(spanCtx))
+		ctx.Response().Header().Set(HeaderXTraceId, span.SpanContext().TraceID.String())
+
+		err := next(ctx)
+		status := ctx.Response().Status
+
+		if err != nil {
+			status = http.StatusInternalServerError
+
+			if he, ok := err.(*echo.HTTPError); ok {
+				status = he.Code
+			}
+		}
+
+		span.AddAttributes(trace.Int64Attribute(traceAttributeHttpStatusCode, int64(status)))
+		span.SetStatus(trace.Status{Code: getTraceStatusCode(status), Message: http.StatusText(status)})
+
+		return err
+	}
+}
+
+func getTraceStatusCode(httpStatus int) int32 {
+	switch {
+	case httpStatus < http.StatusBadRequest:
+		return trace.StatusCodeOK
+	case httpStatus == http.StatusUnauthorized:
+		return trace.StatusCodeUnauthenticated
+	case httpStatus == http.StatusForbidden:
+		return trace.StatusCodePermissionDenied
+	case httpStatus == http.StatusNotFound:
+		return trace.StatusCodeNotFound
+	case httpStatus == http.StatusTooManyRequests:
+		return trace.StatusCodeResourceExhausted
+	case httpStatus < http.StatusInternalServerError:
+		return trace.StatusCodeInvalidArgument
+	case httpStatus == http.StatusServiceUnavailable:
+		return trace.StatusCodeUnavailable
+	default:
+		return trace.StatusCodeUnknown
+	}
+}
+
+// Get identifier of trace from context. If context hasn't trace span, then empty string will be returned
+func getTraceId(ctx context.Context) string {
+	span := trace.FromContext(ctx)
+
+	if span == nil {
+		return ""
+	}
+
+	return span.SpanContext().TraceID.String()
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.opencensus.io/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	tracingTestTraceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tracingTestSpanId  = "00f067aa0ba902b7"
+)
+
+// billing service mock which remember trace span of last call
+type tracingTestBillingService struct {
+	grpc.BillingService
+	span *trace.Span
+}
+
+func (s *tracingTestBillingService) GetProject(
+	ctx context.Context,
+	in *grpc.GetProjectRequest,
+	opts ...client.CallOption,
+) (*grpc.ChangeProjectResponse, error) {
+	s.span = trace.FromContext(ctx)
+	return s.BillingService.GetProject(ctx, in, opts...)
+}
+
+type TracingTestSuite struct {
+	suite.Suite
+	api      *Api
+	exporter *MemorySpanExporter
+	billing  *tracingTestBillingService
+}
+
+func Test_Tracing(t *testing.T) {
+	suite.Run(t, new(TracingTestSuite))
+}
+
+func (suite *TracingTestSuite) SetupTest() {
+	suite.billing = &tracingTestBillingService{BillingService: mock.NewBillingServerOkMock()}
+	suite.api = &Api{
+		Http:           echo.New(),
+		billingService: suite.billing,
+	}
+
+	suite.exporter = NewMemorySpanExporter()
+	trace.RegisterExporter(suite.exporter)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+
+	suite.api.Http.Use(suite.api.TracingMiddleware)
+	suite.api.Http.GET("/tracing_test/ok", func(ctx echo.Context) error {
+		_, err := suite.api.getProjectMerchantId(ctx.Request().Context(), bson.NewObjectId().Hex())
+
+		if err != nil {
+			return err
+		}
+
+		return ctx.NoContent(http.StatusOK)
+	})
+	suite.api.Http.GET("/tracing_test/error", func(ctx echo.Context) error {
+		return echo.NewHTTPError(http.StatusForbidden, errorMessageAccessDenied)
+	})
+}
+
+func (suite *TracingTestSuite) TearDownTest() {
+	trace.UnregisterExporter(suite.exporter)
+}
+
+func (suite *TracingTestSuite) serve(path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	rsp := httptest.NewRecorder()
+	suite.api.Http.ServeHTTP(rsp, req)
+
+	return rsp
+}
+
+func (suite *TracingTestSuite) TestTracing_TracingMiddleware_Ok() {
+	rsp := suite.serve("/tracing_test/ok", nil)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	spans := suite.exporter.Spans()
+
+	if !assert.Len(suite.T(), spans, 1) {
+		return
+	}
+
+	span := spans[0]
+	assert.Equal(suite.T(), "GET /tracing_test/ok", span.Name)
+	assert.Equal(suite.T(), trace.SpanKindServer, span.SpanKind)
+	assert.Equal(suite.T(), span.TraceID.String(), rsp.Header().Get(HeaderXTraceId))
+	assert.Equal(suite.T(), int64(http.StatusOK), span.Attributes[traceAttributeHttpStatusCode])
+	assert.Equal(suite.T(), int32(trace.StatusCodeOK), span.Status.Code)
+
+	if assert.NotNil(suite.T(), suite.billing.span) {
+		assert.Equal(suite.T(), span.SpanContext, suite.billing.span.SpanContext())
+	}
+}
+
+func (suite *TracingTestSuite) TestTracing_TracingMiddleware_HttpError() {
+	rsp := suite.serve("/tracing_test/error", nil)
+	assert.Equal(suite.T(), http.StatusForbidden, rsp.Code)
+
+	spans := suite.exporter.Spans()
+
+	if assert.Len(suite.T(), spans, 1) {
+		assert.Equal(suite.T(), int64(http.StatusForbidden), spans[0].Attributes[traceAttributeHttpStatusCode])
+		assert.Equal(suite.T(), int32(trace.StatusCodePermissionDenied), spans[0].Status.Code)
+	}
+}
+
+func (suite *TracingTestSuite) TestTracing_TracingMiddleware_RemoteParent_Ok() {
+	headers := map[string]string{"traceparent": "00-" + tracingTestTraceId + "-" + tracingTestSpanId + "-01"}
+	rsp := suite.serve("/tracing_test/ok", headers)
+
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Equal(suite.T(), tracingTestTraceId, rsp.Header().Get(HeaderXTraceId))
+
+	spans := suite.exporter.Spans()
+
+	if assert.Len(suite.T(), spans, 1) {
+		assert.Equal(suite.T(), tracingTestTraceId, spans[0].TraceID.String())
+		assert.Equal(suite.T(), tracingTestSpanId, spans[0].ParentSpanID.String())
+		assert.True(suite.T(), spans[0].HasRemoteParent)
+	}
+}
+
+func (suite *TracingTestSuite) TestTracing_GetTraceId_WithoutSpan() {
+	assert.Empty(suite.T(), getTraceId(context.Background()))
+}
@@ -31,13 +31,11 @@ func (api *Api) OrderStructValidator(sl validator.StructLevel) {
 	o := sl.Current().Interface().(model.OrderScalar)
 
 	if o.PayerPhone != nil {
-		num, err := libphonenumber.Parse("+380 58 4162923", "US")
+		_, err := libphonenumber.Parse("+380 58 4162923", "US")
 
 		if err != nil {
 			sl.ReportError(o.PayerPhone, "PayerPhone", "PayerPhone", "PayerPhone", "")
 		}
-
-		api.Order.PayerPhone = num
 	}
 }
 
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/payment_system/entity"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	webhookMaxAgeDefault      = 72 * time.Hour
+	webhookProcessingTimeout  = time.Minute
+	webhookCallbackTimeFormat = "2006-01-02T15:04:05Z"
+
+	messageWebhookDuplicate = "Notification already processed"
+	messageWebhookStale     = "Notification is stale and ignored"
+)
+
+// WebhookInbox is a storage of payment systems callbacks. Callback saved to inbox before processing, so repeated
+// deliveries of processed callback can be detected by key of record
+type WebhookInbox interface {
+	// Insert record if record with same key not exists or existing record can be processed again: processing of
+	// it failed, it was stale or its processing lock expired. Otherwise existing record will be returned and
+	// inserted flag will be false
+	Insert(record *model.WebhookInboxRecord) (*model.WebhookInboxRecord, bool, error)
+	// Get record by key, nil returned if record not found
+	Get(id string) (*model.WebhookInboxRecord, error)
+	Update(record *model.WebhookInboxRecord) error
+	// Save repeated delivery of callback which wasn't processed
+	AddDelivery(id string, at time.Time) error
+}
+
+type memoryWebhookInbox struct {
+	mx      sync.Mutex
+	records map[string]*model.WebhookInboxRecord
+}
+
+// Create webhook inbox which keep records in memory of current process.
+// Must be used only for single instance installations and tests
+func NewMemoryWebhookInbox() WebhookInbox {
+	return &memoryWebhookInbox{records: make(map[string]*model.WebhookInboxRecord)}
+}
+
+func (s *memoryWebhookInbox) Insert(record *model.WebhookInboxRecord) (*model.WebhookInboxRecord, bool, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if exists, ok := s.records[record.Id]; ok {
+		if !exists.IsRetryable(time.Now()) {
+			copied := *exists
+			return &copied, false, nil
+		}
+
+		record.Deliveries = exists.Deliveries + 1
+		record.CreatedAt = exists.CreatedAt
+	}
+
+	copied := *record
+	s.records[record.Id] = &copied
+
+	return record, true, nil
+}
+
+func (s *memoryWebhookInbox) Get(id string) (*model.WebhookInboxRecord, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	r, ok := s.records[id]
+
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *r
+
+	return &copied, nil
+}
+
+func (s *memoryWebhookInbox) Update(record *model.WebhookInboxRecord) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	copied := *record
+	s.records[record.Id] = &copied
+
+	return nil
+}
+
+func (s *memoryWebhookInbox) AddDelivery(id string, at time.Time) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if r, ok := s.records[id]; ok {
+		r.Deliveries++
+		r.LastDeliveryAt = at
+	}
+
+	return nil
+}
+
+// Save callback of payment system to inbox before processing. If false returned, then callback must not be
+// processed: response to stale or repeated delivery of processed callback already written or error returned.
+// Stale callbacks and repeated deliveries accepted with success response, so payment system stops retries.
+// Signature of callback checked before saving, so unsigned callback can't lock processing of callback with
+// same key or be accepted as stale by forged callback time. Without settings of payment system signature
+// can be checked only by billing server, so such callback only checked for duplicate before processing and
+// saved to inbox after successful processing
+func (api *Api) receiveWebhook(
+	ctx echo.Context,
+	record *model.WebhookInboxRecord,
+	paymentMethod string,
+	callbackTime string,
+) (bool, error) {
+	req := ctx.Request()
+	now := time.Now()
+	signature := req.Header.Get(entity.CardPayPaymentResponseHeaderSignature)
+	record.SignatureChecked = api.paymentSystems != nil && api.paymentSystems.CanCheckCallbackSignature(record.Provider)
+
+	if record.SignatureChecked {
+		err := api.paymentSystems.CheckCallbackSignature(record.Provider, paymentMethod, getRequestContext(ctx).RawBody, signature)
+
+		if err != nil {
+			api.logError(req.Context(), "Webhook signature check failed", []interface{}{"error", err.Error(), "provider", record.Provider})
+			incWebHookNotifications(record.Type, metricsWebHookStatusSignatureInvalid)
+
+			return false, echo.NewHTTPError(http.StatusBadRequest, errorMessageWebhookSignatureIncorrect)
+		}
+	}
+
+	var err error
+	record.CallbackTime, err = time.Parse(webhookCallbackTimeFormat, callbackTime)
+
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusBadRequest, errorMessageWebhookCallbackTimeIncorrect)
+	}
+
+	record.Id = api.getWebhookInboxRecordId(record)
+	record.RawBody = getRequestContext(ctx).RawBody
+	record.Signature = signature
+	record.Status = model.WebhookInboxStatusProcessing
+	record.Deliveries = 1
+	record.LockedUntil = now.Add(webhookProcessingTimeout)
+	record.LastDeliveryAt = now
+	record.CreatedAt = now
+	record.UpdatedAt = now
+
+	// callback time in future not rejected to allow difference of clocks of payment system and server
+	stale := now.Sub(record.CallbackTime) > api.getWebhookMaxAge()
+
+	if !record.SignatureChecked {
+		return api.receiveUncheckedWebhook(ctx, record, stale)
+	}
+
+	if stale {
+		record.Status = model.WebhookInboxStatusStale
+	}
+
+	exists, inserted, err := api.webhookInbox.Insert(record)
+
+	if err != nil {
+		api.logError(req.Context(), "Insert webhook inbox record failed", []interface{}{"error", err.Error(), "id", record.Id})
+		return false, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	if !inserted {
+		return false, api.rejectWebhookDelivery(ctx, record, exists, now)
+	}
+
+	if stale {
+		incWebHookNotifications(record.Type, metricsWebHookStatusStale)
+
+		return false, ctx.JSON(http.StatusOK, map[string]string{"message": messageWebhookStale})
+	}
+
+	return true, nil
+}
+
+// Callback which signature can't be checked before processing isn't saved to inbox before processing, as
+// unsigned callback could lock processing of signed one. Repeated delivery of callback saved to inbox
+// after processing rejected as duplicate
+func (api *Api) receiveUncheckedWebhook(ctx echo.Context, record *model.WebhookInboxRecord, stale bool) (bool, error) {
+	now := time.Now()
+	exists, err := api.webhookInbox.Get(record.Id)
+
+	if err != nil {
+		api.logError(ctx.Request().Context(), "Get webhook inbox record failed", []interface{}{"error", err.Error(), "id", record.Id})
+		return false, echo.NewHTTPError(http.StatusInternalServerError, errorUnknown)
+	}
+
+	if exists != nil && !exists.IsRetryable(now) {
+		return false, api.rejectWebhookDelivery(ctx, record, exists, now)
+	}
+
+	// stale callback not saved, as callback time of unchecked callback can be forged
+	if stale {
+		incWebHookNotifications(record.Type, metricsWebHookStatusStale)
+
+		return false, ctx.JSON(http.StatusOK, map[string]string{"message": messageWebhookStale})
+	}
+
+	return true, nil
+}
+
+// Response to repeated delivery of callback which already processed or processed now
+func (api *Api) rejectWebhookDelivery(
+	ctx echo.Context,
+	record *model.WebhookInboxRecord,
+	exists *model.WebhookInboxRecord,
+	now time.Time,
+) error {
+	if err := api.webhookInbox.AddDelivery(exists.Id, now); err != nil {
+		api.logError(ctx.Request().Context(), "Update webhook inbox record failed", []interface{}{"error", err.Error(), "id", exists.Id})
+	}
+
+	if exists.Status == model.WebhookInboxStatusProcessing {
+		return newError(http.StatusConflict, errorCodeRequestInProcess, errorMessageWebhookInProcess)
+	}
+
+	incWebHookNotifications(record.Type, metricsWebHookStatusDuplicate)
+
+	return ctx.JSON(http.StatusOK, map[string]string{"message": messageWebhookDuplicate})
+}
+
+// Save result of callback processing. Callback which processing failed will be processed again on next delivery.
+// Callback which signature checked only by billing server saved only if it processed, as signature of failed
+// callback can be incorrect
+func (api *Api) completeWebhook(ctx echo.Context, record *model.WebhookInboxRecord, processed bool, message string) {
+	// callback rejected before processing
+	if record.Id == "" {
+		return
+	}
+
+	record.Status = model.WebhookInboxStatusProcessed
+	record.Error = message
+	record.UpdatedAt = time.Now()
+
+	if !processed {
+		record.Status = model.WebhookInboxStatusFailed
+	}
+
+	if !record.SignatureChecked {
+		if !processed {
+			return
+		}
+
+		// record of the same callback can be saved by concurrent delivery, it's processed by billing server too
+		if _, _, err := api.webhookInbox.Insert(record); err != nil {
+			api.logError(ctx.Request().Context(), "Insert webhook inbox record failed", []interface{}{"error", err.Error(), "id", record.Id})
+		}
+
+		return
+	}
+
+	if err := api.webhookInbox.Update(record); err != nil {
+		api.logError(ctx.Request().Context(), "Update webhook inbox record failed", []interface{}{"error", err.Error(), "id", record.Id})
+	}
+}
+
+func (api *Api) getWebhookInboxRecordId(r *model.WebhookInboxRecord) string {
+	h := sha256.New()
+	h.Write([]byte(strings.Join([]string{r.Provider, r.Type, r.PaymentId, r.RefundId, r.PaymentState}, "\n")))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (api *Api) getWebhookMaxAge() time.Duration {
+	if api.config == nil || api.config.WebhookMaxAge <= 0 {
+		return webhookMaxAgeDefault
+	}
+
+	return time.Duration(api.config.WebhookMaxAge) * time.Second
+}
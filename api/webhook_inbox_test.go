@@ -0,0 +1,448 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/config"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/payment_system"
+	"github.com/paysuper/paysuper-management-api/payment_system/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	webhookInboxTestCallbackTimeFormat = "2006-01-02T15:04:05Z"
+	webhookInboxTestCallbackSecret     = "secret_key"
+)
+
+// billing service which counts processed callbacks and responds with configured status
+type webhookInboxTestBillingService struct {
+	grpc.BillingService
+	calls  int32
+	status int32
+}
+
+func (s *webhookInboxTestBillingService) PaymentCallbackProcess(
+	ctx context.Context,
+	in *grpc.PaymentNotifyRequest,
+	opts ...client.CallOption,
+) (*grpc.PaymentNotifyResponse, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return &grpc.PaymentNotifyResponse{Status: atomic.LoadInt32(&s.status)}, nil
+}
+
+func (s *webhookInboxTestBillingService) ProcessRefundCallback(
+	ctx context.Context,
+	in *grpc.CallbackRequest,
+	opts ...client.CallOption,
+) (*grpc.PaymentNotifyResponse, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return &grpc.PaymentNotifyResponse{Status: atomic.LoadInt32(&s.status)}, nil
+}
+
+type WebhookInboxTestSuite struct {
+	suite.Suite
+	api     *Api
+	router  *CardPayWebHook
+	inbox   *memoryWebhookInbox
+	billing *webhookInboxTestBillingService
+}
+
+func Test_WebhookInbox(t *testing.T) {
+	suite.Run(t, new(WebhookInboxTestSuite))
+}
+
+func (suite *WebhookInboxTestSuite) SetupTest() {
+	suite.inbox = NewMemoryWebhookInbox().(*memoryWebhookInbox)
+	suite.billing = &webhookInboxTestBillingService{
+		BillingService: mock.NewBillingServerOkMock(),
+		status:         pkg.ResponseStatusOk,
+	}
+	suite.api = &Api{
+		Http:           echo.New(),
+		validate:       validator.New(),
+		billingService: suite.billing,
+		webhookInbox:   suite.inbox,
+		paymentSystems: suite.getPaymentSystems(map[string]interface{}{
+			payment_system.PaymentSystemHandlerCardPay: map[interface{}]interface{}{
+				"create_payment_url": "http://localhost",
+				"BANKCARD": map[interface{}]interface{}{
+					"terminal_id":          "123",
+					"secret_word":          "secret",
+					"callback_secret_word": webhookInboxTestCallbackSecret,
+				},
+			},
+		}),
+	}
+	suite.router = &CardPayWebHook{Api: suite.api}
+}
+
+func (suite *WebhookInboxTestSuite) getPaymentSystems(config map[string]interface{}) *payment_system.Registry {
+	r, err := payment_system.NewRegistry(config, &payment_system.PaymentSystemSetting{Logger: zap.NewNop().Sugar()})
+
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	return r
+}
+
+func (suite *WebhookInboxTestSuite) TearDownTest() {}
+
+func (suite *WebhookInboxTestSuite) getPaymentCallback(paymentId, status string, callbackTime time.Time) []byte {
+	req := &entity.CardPayPaymentNotificationWebHookRequest{
+		MerchantOrder: &entity.CardPayMerchantOrder{Id: bson.NewObjectId().Hex()},
+		PaymentMethod: "BANKCARD",
+		CallbackTime:  callbackTime.UTC().Format(webhookInboxTestCallbackTimeFormat),
+		CardAccount: &entity.CardPayBankCardAccountResponse{
+			Holder:             "UNIT TEST",
+			IssuingCountryCode: "US",
+			MaskedPan:          "400000...0002",
+		},
+		Customer: &entity.CardPayCustomer{Email: "test@unit.test", Account: "test@unit.test"},
+		PaymentData: &entity.CardPayPaymentDataResponse{
+			Id:       paymentId,
+			Amount:   100,
+			Created:  callbackTime.UTC().Format(webhookInboxTestCallbackTimeFormat),
+			Currency: "USD",
+			Status:   status,
+		},
+	}
+
+	b, err := json.Marshal(req)
+	assert.NoError(suite.T(), err)
+
+	return b
+}
+
+func (suite *WebhookInboxTestSuite) getRefundCallback(paymentId, refundId string, callbackTime time.Time) []byte {
+	req := &billing.CardPayRefundCallback{
+		MerchantOrder: &billing.CardPayMerchantOrder{Id: bson.NewObjectId().Hex()},
+		PaymentMethod: "BANKCARD",
+		PaymentData: &billing.CardPayRefundCallbackPaymentData{
+			Id:              paymentId,
+			RemainingAmount: 50,
+		},
+		RefundData: &billing.CardPayRefundCallbackRefundData{
+			Amount:   50,
+			Created:  callbackTime.UTC().Format(webhookInboxTestCallbackTimeFormat),
+			Id:       refundId,
+			Currency: "USD",
+			Status:   pkg.CardPayPaymentResponseStatusCompleted,
+			AuthCode: bson.NewObjectId().Hex(),
+			Rrn:      bson.NewObjectId().Hex(),
+		},
+		CallbackTime: callbackTime.UTC().Format(webhookInboxTestCallbackTimeFormat),
+		Customer: &billing.CardPayCustomer{
+			Email: "test@unit.test",
+			Id:    "test@unit.test",
+		},
+	}
+
+	b, err := json.Marshal(req)
+	assert.NoError(suite.T(), err)
+
+	return b
+}
+
+// Send callback signed by callback secret of terminal through raw body middleware as webhook routes do
+func (suite *WebhookInboxTestSuite) send(handler echo.HandlerFunc, body []byte) (*httptest.ResponseRecorder, error) {
+	h := sha512.New()
+	h.Write(append(body, []byte(webhookInboxTestCallbackSecret)...))
+
+	return suite.sendSigned(handler, body, hex.EncodeToString(h.Sum(nil)))
+}
+
+func (suite *WebhookInboxTestSuite) sendSigned(
+	handler echo.HandlerFunc,
+	body []byte,
+	signature string,
+) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(entity.CardPayPaymentResponseHeaderSignature, signature)
+
+	rsp := httptest.NewRecorder()
+	err := suite.api.RawBodyMiddleware(handler)(suite.api.Http.NewContext(req, rsp))
+
+	return rsp, err
+}
+
+func (suite *WebhookInboxTestSuite) assertMessage(rsp *httptest.ResponseRecorder, message string) {
+	var body map[string]string
+
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.NoError(suite.T(), json.Unmarshal(rsp.Body.Bytes(), &body))
+	assert.Equal(suite.T(), message, body["message"])
+}
+
+func (suite *WebhookInboxTestSuite) getRecord() *model.WebhookInboxRecord {
+	suite.inbox.mx.Lock()
+	defer suite.inbox.mx.Unlock()
+
+	if !assert.Len(suite.T(), suite.inbox.records, 1) {
+		suite.FailNow("webhook inbox record not found")
+	}
+
+	for _, r := range suite.inbox.records {
+		copied := *r
+		return &copied
+	}
+
+	return nil
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_PaymentDuplicate_NotProcessed() {
+	body := suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, time.Now())
+
+	rsp, err := suite.send(suite.router.paymentCallback, body)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	rsp, err = suite.send(suite.router.paymentCallback, body)
+	assert.NoError(suite.T(), err)
+	suite.assertMessage(rsp, messageWebhookDuplicate)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.billing.calls))
+
+	r := suite.getRecord()
+	assert.Equal(suite.T(), model.WebhookInboxStatusProcessed, r.Status)
+	assert.Equal(suite.T(), model.WebhookInboxTypePayment, r.Type)
+	assert.Equal(suite.T(), pkg.PaymentSystemHandlerCardPay, r.Provider)
+	assert.Equal(suite.T(), "123456", r.PaymentId)
+	assert.Equal(suite.T(), pkg.CardPayPaymentResponseStatusCompleted, r.PaymentState)
+	assert.Equal(suite.T(), string(body), r.RawBody)
+	assert.NotEmpty(suite.T(), r.Signature)
+	assert.Equal(suite.T(), 2, r.Deliveries)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_PaymentOtherStatus_Processed() {
+	_, err := suite.send(suite.router.paymentCallback, suite.getPaymentCallback("123456", entity.CardPayPaymentResponseStatusAuthorized, time.Now()))
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.send(suite.router.paymentCallback, suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, time.Now()))
+	assert.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&suite.billing.calls))
+	assert.Len(suite.T(), suite.inbox.records, 2)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_Stale_NotProcessed() {
+	callbackTime := time.Now().Add(-webhookMaxAgeDefault - time.Hour)
+
+	rsp, err := suite.send(suite.router.paymentCallback, suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, callbackTime))
+	assert.NoError(suite.T(), err)
+	suite.assertMessage(rsp, messageWebhookStale)
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&suite.billing.calls))
+	assert.Equal(suite.T(), model.WebhookInboxStatusStale, suite.getRecord().Status)
+
+	// stale callback doesn't block processing of actual callback with same payment status
+	_, err = suite.send(suite.router.paymentCallback, suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, time.Now()))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.billing.calls))
+
+	r := suite.getRecord()
+	assert.Equal(suite.T(), model.WebhookInboxStatusProcessed, r.Status)
+	assert.Equal(suite.T(), 2, r.Deliveries)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_MaxAgeFromConfig_Stale() {
+	suite.api.config = &config.Config{WebhookInbox: config.WebhookInbox{WebhookMaxAge: 60}}
+	body := suite.getRefundCallback("123456", "654321", time.Now().Add(-2*time.Minute))
+
+	rsp, err := suite.send(suite.router.refundCallback, body)
+	assert.NoError(suite.T(), err)
+	suite.assertMessage(rsp, messageWebhookStale)
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&suite.billing.calls))
+
+	r := suite.getRecord()
+	assert.Equal(suite.T(), model.WebhookInboxStatusStale, r.Status)
+	assert.Equal(suite.T(), string(body), r.RawBody)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_FailedProcessing_ProcessedAgain() {
+	body := suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, time.Now())
+	atomic.StoreInt32(&suite.billing.status, pkg.StatusErrorValidation)
+
+	_, err := suite.send(suite.router.paymentCallback, body)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), model.WebhookInboxStatusFailed, suite.getRecord().Status)
+
+	atomic.StoreInt32(&suite.billing.status, pkg.ResponseStatusOk)
+
+	rsp, err := suite.send(suite.router.paymentCallback, body)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&suite.billing.calls))
+
+	r := suite.getRecord()
+	assert.Equal(suite.T(), model.WebhookInboxStatusProcessed, r.Status)
+	assert.Equal(suite.T(), 2, r.Deliveries)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_InProcess_Conflict() {
+	body := suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, time.Now())
+	record := &model.WebhookInboxRecord{
+		Provider:     pkg.PaymentSystemHandlerCardPay,
+		Type:         model.WebhookInboxTypePayment,
+		PaymentId:    "123456",
+		PaymentState: pkg.CardPayPaymentResponseStatusCompleted,
+		Status:       model.WebhookInboxStatusProcessing,
+		Deliveries:   1,
+		LockedUntil:  time.Now().Add(time.Minute),
+	}
+	record.Id = suite.api.getWebhookInboxRecordId(record)
+	assert.NoError(suite.T(), suite.inbox.Update(record))
+
+	_, err := suite.send(suite.router.paymentCallback, body)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusConflict, httpErr.Code)
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&suite.billing.calls))
+
+	// processing of callback interrupted, so record taken by next delivery after lock expiration
+	record.LockedUntil = time.Now().Add(-time.Second)
+	assert.NoError(suite.T(), suite.inbox.Update(record))
+
+	_, err = suite.send(suite.router.paymentCallback, body)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.billing.calls))
+	assert.Equal(suite.T(), model.WebhookInboxStatusProcessed, suite.getRecord().Status)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_RefundDuplicate_NotProcessed() {
+	first := suite.getRefundCallback("123456", "1", time.Now())
+
+	rsp, err := suite.send(suite.router.refundCallback, first)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	// other refund of the same payment
+	_, err = suite.send(suite.router.refundCallback, suite.getRefundCallback("123456", "2", time.Now()))
+	assert.NoError(suite.T(), err)
+
+	rsp, err = suite.send(suite.router.refundCallback, first)
+	assert.NoError(suite.T(), err)
+	suite.assertMessage(rsp, messageWebhookDuplicate)
+
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&suite.billing.calls))
+	assert.Len(suite.T(), suite.inbox.records, 2)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_CallbackTimeIncorrect_Error() {
+	body := suite.getRefundCallback("123456", "1", time.Now())
+	body = bytes.Replace(body, []byte(time.Now().UTC().Format("2006-01-02T")), []byte("2006/01/02T"), -1)
+
+	_, err := suite.send(suite.router.refundCallback, body)
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageWebhookCallbackTimeIncorrect, httpErr.Message)
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&suite.billing.calls))
+	assert.Empty(suite.T(), suite.inbox.records)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_SignatureIncorrect_NotSaved() {
+	body := suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, time.Now())
+
+	_, err := suite.sendSigned(suite.router.paymentCallback, body, "forged_signature")
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), errorMessageWebhookSignatureIncorrect, httpErr.Message)
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&suite.billing.calls))
+	assert.Empty(suite.T(), suite.inbox.records)
+
+	// forged callback doesn't lock processing of signed callback with same payment status
+	rsp, err := suite.send(suite.router.paymentCallback, body)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.billing.calls))
+	assert.Equal(suite.T(), model.WebhookInboxStatusProcessed, suite.getRecord().Status)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_StaleSignatureIncorrect_NotSaved() {
+	body := suite.getRefundCallback("123456", "1", time.Now().Add(-webhookMaxAgeDefault-time.Hour))
+
+	_, err := suite.sendSigned(suite.router.refundCallback, body, "forged_signature")
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Empty(suite.T(), suite.inbox.records)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_PaymentSystemNotConfigured_SavedAfterProcessing() {
+	suite.api.paymentSystems = suite.getPaymentSystems(map[string]interface{}{})
+	body := suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, time.Now())
+
+	rsp, err := suite.send(suite.router.paymentCallback, body)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+
+	rsp, err = suite.send(suite.router.paymentCallback, body)
+	assert.NoError(suite.T(), err)
+	suite.assertMessage(rsp, messageWebhookDuplicate)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&suite.billing.calls))
+
+	r := suite.getRecord()
+	assert.Equal(suite.T(), model.WebhookInboxStatusProcessed, r.Status)
+	assert.Equal(suite.T(), string(body), r.RawBody)
+	assert.Equal(suite.T(), 2, r.Deliveries)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_PaymentSystemNotConfiguredFailedProcessing_NotSaved() {
+	suite.api.paymentSystems = suite.getPaymentSystems(map[string]interface{}{})
+	body := suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, time.Now())
+
+	// signature of callback rejected by billing server
+	atomic.StoreInt32(&suite.billing.status, pkg.StatusErrorValidation)
+
+	_, err := suite.sendSigned(suite.router.paymentCallback, body, "forged_signature")
+	assert.Error(suite.T(), err)
+	assert.Empty(suite.T(), suite.inbox.records)
+
+	// forged callback doesn't lock processing of signed callback with same payment status
+	atomic.StoreInt32(&suite.billing.status, pkg.ResponseStatusOk)
+
+	rsp, err := suite.send(suite.router.paymentCallback, body)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, rsp.Code)
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&suite.billing.calls))
+	assert.Equal(suite.T(), model.WebhookInboxStatusProcessed, suite.getRecord().Status)
+}
+
+func (suite *WebhookInboxTestSuite) TestWebhookInbox_PaymentSystemNotConfiguredStale_NotSaved() {
+	suite.api.paymentSystems = suite.getPaymentSystems(map[string]interface{}{})
+	callbackTime := time.Now().Add(-webhookMaxAgeDefault - time.Hour)
+
+	rsp, err := suite.send(suite.router.paymentCallback, suite.getPaymentCallback("123456", pkg.CardPayPaymentResponseStatusCompleted, callbackTime))
+	assert.NoError(suite.T(), err)
+	suite.assertMessage(rsp, messageWebhookStale)
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&suite.billing.calls))
+	assert.Empty(suite.T(), suite.inbox.records)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"github.com/paysuper/paysuper-management-api/internal/cardpay"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Local CardPay sandbox for development. Set create_payment_url of cardpay payment system to url of
+// emulator and terminals credentials to credentials passed to emulator, e.g.:
+//
+//	go run ./cmd/cardpay_emulator -terminals BANKCARD_TERMINAL:secret:callback_secret
+//
+// Payment scenario chosen by card number, see cardpay.Card* constants
+func main() {
+	addr := flag.String("addr", getEnv("CARDPAY_EMULATOR_ADDR", ":8081"), "address to listen on")
+	url := flag.String("url", getEnv("CARDPAY_EMULATOR_URL", "http://127.0.0.1:8081"), "public url of emulator")
+	callbackUrl := flag.String(
+		"callback-url",
+		getEnv("CARDPAY_EMULATOR_CALLBACK_URL", "http://127.0.0.1:3001"),
+		"url of payments API which receives callbacks, callbacks disabled if empty",
+	)
+	terminals := flag.String(
+		"terminals",
+		getEnv("CARDPAY_EMULATOR_TERMINALS", ""),
+		"terminals in format code:password:callback_secret separated by comma",
+	)
+	flag.Parse()
+
+	t, err := cardpay.ParseTerminals(*terminals)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if len(t) == 0 {
+		log.Fatalln("at least one terminal required")
+	}
+
+	if err := cardpay.ValidateUrl(*url); err != nil {
+		log.Fatalln(err)
+	}
+
+	if *callbackUrl != "" {
+		if err := cardpay.ValidateUrl(*callbackUrl); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	e := cardpay.NewEmulator(t...)
+	e.Url = *url
+	e.CallbackUrl = *callbackUrl
+
+	srv := &http.Server{Addr: *addr, Handler: e, ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second}
+
+	log.Printf("CardPay emulator listening on %s\n", *addr)
+
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf("CardPay emulator crashed with error: %s\n", err)
+	}
+}
+
+func getEnv(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+
+	return def
+}
@@ -9,6 +9,22 @@ import (
 
 const (
 	DefaultJwtSignAlgorithm = "RS256"
+
+	IdempotencyStorageMemory = "memory"
+	IdempotencyStorageMongo  = "mongo"
+
+	WebhookInboxStorageMemory = "memory"
+	WebhookInboxStorageMongo  = "mongo"
+
+	CustomerTokenStorageMemory = "memory"
+	CustomerTokenStorageMongo  = "mongo"
+
+	RateLimitStorageMemory = "memory"
+	RateLimitStorageRedis  = "redis"
+
+	OpenApiValidationDisabled = "disabled"
+	OpenApiValidationLog      = "log"
+	OpenApiValidationEnforce  = "enforce"
 )
 
 type Database struct {
@@ -40,11 +56,98 @@ type S3 struct {
 	Secure      bool   `envconfig:"S3_SECURE" default:"false"`
 }
 
+type Idempotency struct {
+	IdempotencyStorage string `envconfig:"IDEMPOTENCY_STORAGE" default:"mongo"`
+	IdempotencyKeyTtl  int64  `envconfig:"IDEMPOTENCY_KEY_TTL" default:"86400"`
+}
+
+// Inbox of payment systems callbacks. Callbacks with callback time older than max age (in seconds)
+// accepted without processing. Callbacks saved to inbox after check of signature by payment systems
+// settings, callbacks of payment systems not configured in PAYMENT_SYSTEMS_CONFIG_PATH saved to inbox after
+// successful processing, when their signature checked by billing server
+type WebhookInbox struct {
+	WebhookInboxStorage string `envconfig:"WEBHOOK_INBOX_STORAGE" default:"mongo"`
+	WebhookMaxAge       int64  `envconfig:"WEBHOOK_MAX_AGE" default:"259200"`
+}
+
+// Registry of customer tokens created by projects to introspect and revoke them. Lifetime of token (in seconds)
+// must be equal to lifetime of customer tokens in billing server
+type CustomerToken struct {
+	CustomerTokenStorage  string `envconfig:"CUSTOMER_TOKEN_STORAGE" default:"mongo"`
+	CustomerTokenLifetime int64  `envconfig:"CUSTOMER_TOKEN_LIFETIME" default:"2592000"`
+}
+
+// Token bucket rate limits of public order and payment routes. Rate is a count of requests
+// per second and burst is a maximal count of requests at once. Zero rate disable limit
+type RateLimit struct {
+	RateLimitStorage       string  `envconfig:"RATE_LIMIT_STORAGE" default:"memory"`
+	RateLimitRedisAddress  string  `envconfig:"RATE_LIMIT_REDIS_ADDRESS" default:"127.0.0.1:6379"`
+	RateLimitRedisPassword string  `envconfig:"RATE_LIMIT_REDIS_PASSWORD"`
+	RateLimitIpRate        float64 `envconfig:"RATE_LIMIT_IP_RATE" default:"1"`
+	RateLimitIpBurst       int     `envconfig:"RATE_LIMIT_IP_BURST" default:"30"`
+	RateLimitProjectRate   float64 `envconfig:"RATE_LIMIT_PROJECT_RATE" default:"50"`
+	RateLimitProjectBurst  int     `envconfig:"RATE_LIMIT_PROJECT_BURST" default:"500"`
+	RateLimitOrderRate     float64 `envconfig:"RATE_LIMIT_ORDER_RATE" default:"0.1"`
+	RateLimitOrderBurst    int     `envconfig:"RATE_LIMIT_ORDER_BURST" default:"10"`
+}
+
+// Additional rules of sensitive data removing from logs in format "path:action,path:action".
+// Allowed actions: pan, remove, hash, mask
+type LogRedaction struct {
+	LogRedactionRules string `envconfig:"LOG_REDACTION_RULES"`
+}
+
+type Tracing struct {
+	TracingSampleProbability float64 `envconfig:"TRACING_SAMPLE_PROBABILITY" default:"0.1"`
+}
+
+// Validation of requests and responses by api specification. Validation never enabled in production
+// environment. In "log" mode violations only written to log, in "enforce" mode invalid requests rejected
+type OpenApi struct {
+	OpenApiSpecPath       string `envconfig:"OPENAPI_SPEC_PATH" default:"spec/swagger.yaml"`
+	OpenApiValidationMode string `envconfig:"OPENAPI_VALIDATION_MODE" default:"disabled"`
+}
+
+// Scheduled reports by saved order filters. Scheduler checks filters with reached time of report
+// generation once per interval (in seconds)
+type OrderReport struct {
+	OrderReportSchedulerInterval int64 `envconfig:"ORDER_REPORT_SCHEDULER_INTERVAL" default:"60"`
+}
+
+// Background jobs of bulk operations with orders. Pending jobs checked once per interval (in seconds)
+type OrderBulkJob struct {
+	OrderBulkJobInterval int64 `envconfig:"ORDER_BULK_JOB_INTERVAL" default:"5"`
+}
+
+// Path to yaml file with settings of payment systems providers. Server not started if settings of any
+// provider are invalid. Providers are not available if path is empty.
+// Requests to payment systems limited by timeout (in seconds), idempotent requests repeated on errors
+// of payment system. Requests to terminal stopped for breaker open timeout (in seconds) when rate of
+// errors in last requests reaches breaker error rate
+type PaymentSystems struct {
+	PaymentSystemsConfigPath         string  `envconfig:"PAYMENT_SYSTEMS_CONFIG_PATH"`
+	PaymentSystemsTimeout            int64   `envconfig:"PAYMENT_SYSTEMS_TIMEOUT" default:"10"`
+	PaymentSystemsRetryAttempts      int     `envconfig:"PAYMENT_SYSTEMS_RETRY_ATTEMPTS" default:"3"`
+	PaymentSystemsBreakerErrorRate   float64 `envconfig:"PAYMENT_SYSTEMS_BREAKER_ERROR_RATE" default:"0.5"`
+	PaymentSystemsBreakerMinRequests int     `envconfig:"PAYMENT_SYSTEMS_BREAKER_MIN_REQUESTS" default:"20"`
+	PaymentSystemsBreakerOpenTimeout int64   `envconfig:"PAYMENT_SYSTEMS_BREAKER_OPEN_TIMEOUT" default:"30"`
+}
+
 type Config struct {
 	Jwt
 	Database
 	Auth1
 	S3
+	Idempotency
+	WebhookInbox
+	CustomerToken
+	Tracing
+	RateLimit
+	LogRedaction
+	OpenApi
+	OrderReport
+	OrderBulkJob
+	PaymentSystems
 
 	HttpScheme     string `envconfig:"HTTP_SCHEME" default:"https"`
 	KubernetesHost string `envconfig:"KUBERNETES_SERVICE_HOST" required:"false"`
@@ -0,0 +1,271 @@
+package dao
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/globalsign/mgo/bson"
+	"reflect"
+	"strings"
+)
+
+const (
+	cursorFieldId = "_id"
+)
+
+var (
+	ErrCursorInvalid         = errors.New("cursor is invalid")
+	ErrCursorSortUnsupported = errors.New("cursor pagination supports sorting by one field only")
+)
+
+// Cursor is position in listing which transferred to client as opaque token. Keyset cursor contains
+// value of sort field and identifier of document on the border of page. Offset cursor used by listings
+// which can't be paginated by keyset
+type Cursor struct {
+	Value    interface{}   `bson:"v"`
+	Id       bson.ObjectId `bson:"i,omitempty"`
+	Offset   int32         `bson:"o,omitempty"`
+	Backward bool          `bson:"b,omitempty"`
+}
+
+// CursorPaginator build query conditions to select page of documents after (or before) cursor
+// and make cursors to next and previous pages from selected documents
+type CursorPaginator struct {
+	field  string
+	desc   bool
+	cursor *Cursor
+	limit  int
+	next   string
+	prev   string
+}
+
+func EncodeCursor(cursor *Cursor) (string, error) {
+	b, err := bson.Marshal(cursor)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+
+	if err != nil {
+		return nil, ErrCursorInvalid
+	}
+
+	cursor := &Cursor{}
+
+	if err = bson.Unmarshal(b, cursor); err != nil || cursor.Offset < 0 {
+		return nil, ErrCursorInvalid
+	}
+
+	// documents and arrays can't be used as sort value, so cursor with them was built not by us
+	switch cursor.Value.(type) {
+	case bson.M, []interface{}:
+		return nil, ErrCursorInvalid
+	}
+
+	return cursor, nil
+}
+
+// Create paginator of listing sorted by one field and document identifier. Nil cursor means first page
+func NewCursorPaginator(sort []string, cursor *Cursor, limit int32) (*CursorPaginator, error) {
+	p := &CursorPaginator{cursor: cursor, limit: int(limit)}
+
+	for _, s := range sort {
+		desc := strings.HasPrefix(s, "-")
+		name := strings.TrimLeft(s, "-+")
+
+		if name == cursorFieldId {
+			if p.field == "" {
+				p.desc = desc
+			}
+
+			continue
+		}
+
+		if p.field != "" {
+			return nil, ErrCursorSortUnsupported
+		}
+
+		p.field = name
+		p.desc = desc
+	}
+
+	if cursor != nil && cursor.Id == "" {
+		return nil, ErrCursorInvalid
+	}
+
+	return p, nil
+}
+
+// Add condition of cursor position to filters
+func (p *CursorPaginator) Filter(filters bson.M) bson.M {
+	if p.cursor == nil {
+		return filters
+	}
+
+	op := "$gt"
+
+	if p.desc != p.cursor.Backward {
+		op = "$lt"
+	}
+
+	var cond bson.M
+
+	if p.field == "" {
+		cond = bson.M{cursorFieldId: bson.M{op: p.cursor.Id}}
+	} else {
+		// null values sorted before any other values, but excluded by comparison operators,
+		// so documents without value of sort field selected separately
+		var or []bson.M
+		value := p.cursor.Value
+
+		if value == nil {
+			if op == "$gt" {
+				or = append(or, bson.M{p.field: bson.M{"$ne": nil}})
+			}
+		} else {
+			or = append(or, bson.M{p.field: bson.M{op: value}})
+
+			if op == "$lt" {
+				or = append(or, bson.M{p.field: nil})
+			}
+		}
+
+		or = append(or, bson.M{p.field: value, cursorFieldId: bson.M{op: p.cursor.Id}})
+		cond = bson.M{"$or": or}
+	}
+
+	return bson.M{"$and": []bson.M{filters, cond}}
+}
+
+// Sort of query. Backward page selected in reverse order and reversed back by Apply
+func (p *CursorPaginator) Sort() []string {
+	prefix := ""
+
+	if p.desc != (p.cursor != nil && p.cursor.Backward) {
+		prefix = "-"
+	}
+
+	if p.field == "" {
+		return []string{prefix + cursorFieldId}
+	}
+
+	return []string{prefix + p.field, prefix + cursorFieldId}
+}
+
+// Limit of query. One document more than page size selected to check that next page exists
+func (p *CursorPaginator) Limit() int {
+	return p.limit + 1
+}
+
+// Cut selected documents to page size, restore order of documents and make cursors to neighbour pages.
+// Result must be pointer to slice of documents selected by query built by paginator
+func (p *CursorPaginator) Apply(result interface{}) error {
+	rv := reflect.ValueOf(result).Elem()
+	more := rv.Len() > p.limit
+
+	if more {
+		rv.Set(rv.Slice(0, p.limit))
+	}
+
+	backward := p.cursor != nil && p.cursor.Backward
+	count := rv.Len()
+
+	if backward {
+		swap := reflect.Swapper(rv.Interface())
+
+		for i := 0; i < count/2; i++ {
+			swap(i, count-1-i)
+		}
+	}
+
+	var err error
+
+	if count == 0 {
+		// page after last (or before first) document is empty, so only way back available
+		if p.cursor != nil {
+			back := &Cursor{Value: p.cursor.Value, Id: p.cursor.Id, Backward: !backward}
+
+			if backward {
+				p.next, err = EncodeCursor(back)
+			} else {
+				p.prev, err = EncodeCursor(back)
+			}
+		}
+
+		return err
+	}
+
+	if more || backward {
+		if p.next, err = p.getCursor(rv.Index(count-1).Interface(), false); err != nil {
+			return err
+		}
+	}
+
+	if (more && backward) || (p.cursor != nil && !backward) {
+		if p.prev, err = p.getCursor(rv.Index(0).Interface(), true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Cursor to page after last document of current page. Empty if next page not exists
+func (p *CursorPaginator) Next() string {
+	return p.next
+}
+
+// Cursor to page before first document of current page. Empty if previous page not exists
+func (p *CursorPaginator) Prev() string {
+	return p.prev
+}
+
+func (p *CursorPaginator) getCursor(doc interface{}, backward bool) (string, error) {
+	b, err := bson.Marshal(doc)
+
+	if err != nil {
+		return "", err
+	}
+
+	m := bson.M{}
+
+	if err = bson.Unmarshal(b, m); err != nil {
+		return "", err
+	}
+
+	id, ok := m[cursorFieldId].(bson.ObjectId)
+
+	if !ok {
+		return "", ErrCursorInvalid
+	}
+
+	cursor := &Cursor{Id: id, Backward: backward}
+
+	if p.field != "" {
+		cursor.Value = getDocumentValue(m, p.field)
+	}
+
+	return EncodeCursor(cursor)
+}
+
+// Get value of document field by dot notation path
+func getDocumentValue(doc bson.M, path string) interface{} {
+	var value interface{} = doc
+
+	for _, name := range strings.Split(path, ".") {
+		m, ok := value.(bson.M)
+
+		if !ok {
+			return nil
+		}
+
+		value = m[name]
+	}
+
+	return value
+}
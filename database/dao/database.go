@@ -3,6 +3,7 @@ package dao
 type Database interface {
 	Open(Connection) error
 	Close()
+	Ping() error
 	Repository(string) Repository
 	Driver() interface{}
 	Database() interface{}
@@ -71,6 +71,11 @@ func (s *Source) Clone() (dao.Database, error) {
 	return clone, nil
 }
 
+// Ping checks that the database server is available.
+func (s *Source) Ping() error {
+	return s.session.Ping()
+}
+
 // Repository returns a repository by name.
 func (s *Source) Repository(name string) dao.Repository {
 	s.repositoriesMu.Lock()
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"time"
+)
+
+func (rep *Repository) InsertCustomerToken(t *model.CustomerToken) error {
+	return rep.Collection.Insert(t)
+}
+
+func (rep *Repository) FindCustomerTokenById(id string) (*model.CustomerToken, error) {
+	var t *model.CustomerToken
+	err := rep.Collection.FindId(id).One(&t)
+
+	return t, err
+}
+
+func (rep *Repository) FindActiveCustomerTokens(
+	projectId string,
+	userId string,
+	now time.Time,
+	limit int,
+	offset int,
+) ([]*model.CustomerToken, error) {
+	var tokens []*model.CustomerToken
+	err := rep.Collection.Find(getActiveCustomerTokensQuery(projectId, userId, now)).
+		Sort("-created_at").
+		Skip(offset).
+		Limit(limit).
+		All(&tokens)
+
+	return tokens, err
+}
+
+func (rep *Repository) CountActiveCustomerTokens(projectId string, userId string, now time.Time) (int, error) {
+	return rep.Collection.Find(getActiveCustomerTokensQuery(projectId, userId, now)).Count()
+}
+
+// Revoke token only if it is active, mgo.ErrNotFound returned otherwise
+func (rep *Repository) RevokeCustomerToken(id string, now time.Time) error {
+	query := bson.M{"_id": id, "revoked_at": nil, "expires_at": bson.M{"$gt": now}}
+
+	return rep.Collection.Update(query, bson.M{"$set": bson.M{"revoked_at": now}})
+}
+
+func (rep *Repository) RevokeCustomerTokensByUserId(projectId string, userId string, now time.Time) (int, error) {
+	info, err := rep.Collection.UpdateAll(
+		getActiveCustomerTokensQuery(projectId, userId, now),
+		bson.M{"$set": bson.M{"revoked_at": now}},
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Updated, nil
+}
+
+func getActiveCustomerTokensQuery(projectId string, userId string, now time.Time) bson.M {
+	query := bson.M{"project_id": projectId, "revoked_at": nil, "expires_at": bson.M{"$gt": now}}
+
+	if userId != "" {
+		query["user_id"] = userId
+	}
+
+	return query
+}
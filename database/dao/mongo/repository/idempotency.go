@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"time"
+)
+
+func (rep *Repository) InsertIdempotencyRecord(r *model.IdempotencyRecord) error {
+	return rep.Collection.Insert(r)
+}
+
+func (rep *Repository) UpdateIdempotencyRecord(r *model.IdempotencyRecord) error {
+	return rep.Collection.UpdateId(r.Id, r)
+}
+
+func (rep *Repository) FindIdempotencyRecordById(id string) (*model.IdempotencyRecord, error) {
+	var r *model.IdempotencyRecord
+	err := rep.Collection.FindId(id).One(&r)
+
+	return r, err
+}
+
+func (rep *Repository) DeleteIdempotencyRecordById(id string) error {
+	return rep.Collection.RemoveId(id)
+}
+
+func (rep *Repository) DeleteExpiredIdempotencyRecord(id string, expiredAt time.Time) error {
+	return rep.Collection.Remove(bson.M{"_id": id, "expires_at": bson.M{"$lte": expiredAt}})
+}
@@ -2,9 +2,14 @@ package repository
 
 import (
 	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/dao"
 	"github.com/paysuper/paysuper-management-api/database/model"
 )
 
+const (
+	orderIteratorBatchSize = 500
+)
+
 func (rep *Repository) FindOrderByProjectOrderId(prjOrderId string) (*model.Order, error) {
 	var o *model.Order
 	err := rep.Collection.Find(bson.M{"project_order_id": prjOrderId}).One(&o)
@@ -19,12 +24,68 @@ func (rep *Repository) FindOrderById(id bson.ObjectId) (*model.Order, error) {
 	return o, err
 }
 
+func (rep *Repository) FindOrderByUuid(uuid string) (*model.Order, error) {
+	var o *model.Order
+	err := rep.Collection.Find(bson.M{"uuid": uuid}).One(&o)
+
+	return o, err
+}
+
 func (rep *Repository) InsertOrder(order *model.Order) error {
 	return rep.Collection.Insert(order)
 }
 
-func (rep *Repository) UpdateOrder(o *model.Order) error {
-	return rep.Collection.UpdateId(o.Id, o)
+// Replace order only if its status not changed since it was read, otherwise mgo.ErrNotFound returned
+func (rep *Repository) UpdateOrder(o *model.Order, prevStatus int) error {
+	return rep.Collection.Update(bson.M{"_id": o.Id, "status": prevStatus}, o)
+}
+
+// Set review mark of order only if order not marked yet
+func (rep *Repository) UpdateOrderReview(id bson.ObjectId, review *model.OrderReview) error {
+	return rep.Collection.Update(
+		bson.M{"_id": id, "review": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"review": review}},
+	)
+}
+
+func (rep *Repository) AddOrderNote(id bson.ObjectId, note *model.OrderNote) error {
+	return rep.Collection.UpdateId(id, bson.M{"$push": bson.M{"notes": note}})
+}
+
+func (rep *Repository) FindOrderIds(filters bson.M, sort []string, limit int) ([]bson.ObjectId, error) {
+	var o []*model.Order
+	err := rep.Collection.Find(filters).Select(bson.M{"_id": 1}).Sort(sort...).Limit(limit).All(&o)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]bson.ObjectId, 0, len(o))
+
+	for _, v := range o {
+		ids = append(ids, v.Id)
+	}
+
+	return ids, nil
+}
+
+// Get count and sum of amounts in merchant accounting currency of orders matched by filters for every status
+func (rep *Repository) GetOrderAmountsByStatus(filters bson.M) ([]*model.OrderStatusAmount, error) {
+	var a []*model.OrderStatusAmount
+
+	q := []bson.M{
+		{"$match": filters},
+		{
+			"$group": bson.M{
+				"_id":    "$status",
+				"count":  bson.M{"$sum": 1},
+				"amount": bson.M{"$sum": "$amount_in_merchant_ac"},
+			},
+		},
+	}
+	err := rep.Collection.Pipe(q).All(&a)
+
+	return a, err
 }
 
 func (rep *Repository) FindAllOrders(filters bson.M, sort []string, limit int32, offset int32) ([]*model.Order, error) {
@@ -34,10 +95,30 @@ func (rep *Repository) FindAllOrders(filters bson.M, sort []string, limit int32,
 	return o, err
 }
 
+func (rep *Repository) FindOrdersByCursor(filters bson.M, paginator *dao.CursorPaginator) ([]*model.Order, error) {
+	var o []*model.Order
+	err := rep.Collection.Find(paginator.Filter(filters)).Sort(paginator.Sort()...).Limit(paginator.Limit()).All(&o)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return o, paginator.Apply(&o)
+}
+
+func (rep *Repository) IterateOrders(filters bson.M, sort []string) dao.Iterator {
+	return rep.Collection.Find(filters).Sort(sort...).Batch(orderIteratorBatchSize).Iter()
+}
+
 func (rep *Repository) GetOrdersCountByConditions(filters bson.M) (int, error) {
 	return rep.Collection.Find(filters).Count()
 }
 
+// Count of orders stops on limit, so cost of count not grows with count of orders matched by filters
+func (rep *Repository) GetOrdersCountEstimate(filters bson.M, limit int) (int, error) {
+	return rep.Collection.Find(filters).Limit(limit).Count()
+}
+
 func (rep *Repository) GetRevenueDynamic(rdr *model.RevenueDynamicRequest) ([]map[string]interface{}, error) {
 	var result []map[string]interface{}
 
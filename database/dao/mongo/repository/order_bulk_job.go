@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"time"
+)
+
+func (rep *Repository) InsertOrderBulkJob(j *model.OrderBulkJob) error {
+	return rep.Collection.Insert(j)
+}
+
+func (rep *Repository) FindOrderBulkJobById(id bson.ObjectId, userId string) (*model.OrderBulkJob, error) {
+	var j *model.OrderBulkJob
+	err := rep.Collection.Find(bson.M{"_id": id, "user_id": userId}).One(&j)
+
+	return j, err
+}
+
+// Move oldest pending job to in progress status. Job which progress not updated since staleBefore
+// considered as abandoned by stopped instance of service and can be reserved again
+func (rep *Repository) ReserveOrderBulkJob(now time.Time, staleBefore time.Time) (*model.OrderBulkJob, error) {
+	var j *model.OrderBulkJob
+
+	query := bson.M{
+		"$or": []bson.M{
+			{"status": model.OrderBulkJobStatusPending},
+			{"status": model.OrderBulkJobStatusInProgress, "updated_at": bson.M{"$lt": staleBefore}},
+		},
+	}
+	change := mgo.Change{
+		Update:    bson.M{"$set": bson.M{"status": model.OrderBulkJobStatusInProgress, "updated_at": now}},
+		ReturnNew: true,
+	}
+	_, err := rep.Collection.Find(query).Sort("created_at").Apply(change, &j)
+
+	return j, err
+}
+
+func (rep *Repository) UpdateOrderBulkJobProgress(j *model.OrderBulkJob) error {
+	return rep.Collection.UpdateId(
+		j.Id,
+		bson.M{
+			"$set": bson.M{
+				"status":      j.Status,
+				"error":       j.Error,
+				"processed":   j.Processed,
+				"failed":      j.Failed,
+				"results":     j.Results,
+				"updated_at":  j.UpdatedAt,
+				"finished_at": j.FinishedAt,
+			},
+		},
+	)
+}
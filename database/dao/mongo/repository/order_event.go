@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+)
+
+func (rep *Repository) InsertOrderEvent(e *model.OrderEvent) error {
+	return rep.Collection.Insert(e)
+}
+
+func (rep *Repository) FindOrderEventsByOrderId(orderId bson.ObjectId) ([]*model.OrderEvent, error) {
+	var e []*model.OrderEvent
+	err := rep.Collection.Find(bson.M{"order_id": orderId}).Sort("created_at", "_id").All(&e)
+
+	return e, err
+}
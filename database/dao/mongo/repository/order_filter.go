@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"time"
+)
+
+func (rep *Repository) InsertOrderFilter(f *model.OrderFilter) error {
+	return rep.Collection.Insert(f)
+}
+
+func (rep *Repository) UpdateOrderFilter(f *model.OrderFilter) error {
+	return rep.Collection.Update(bson.M{"_id": f.Id, "user_id": f.UserId}, f)
+}
+
+func (rep *Repository) DeleteOrderFilter(id bson.ObjectId, userId string) error {
+	return rep.Collection.Remove(bson.M{"_id": id, "user_id": userId})
+}
+
+func (rep *Repository) FindOrderFilterById(id bson.ObjectId, userId string) (*model.OrderFilter, error) {
+	var f *model.OrderFilter
+	err := rep.Collection.Find(bson.M{"_id": id, "user_id": userId}).One(&f)
+
+	return f, err
+}
+
+func (rep *Repository) FindOrderFiltersByUserId(userId string) ([]*model.OrderFilter, error) {
+	var f []*model.OrderFilter
+	err := rep.Collection.Find(bson.M{"user_id": userId}).Sort("name", "_id").All(&f)
+
+	return f, err
+}
+
+func (rep *Repository) FindScheduledOrderFilters(now time.Time) ([]*model.OrderFilter, error) {
+	var f []*model.OrderFilter
+	err := rep.Collection.Find(bson.M{"schedule.next_run_at": bson.M{"$lte": now}}).All(&f)
+
+	return f, err
+}
+
+// Move date of next report generation only if it not moved by other instance of service yet
+func (rep *Repository) UpdateOrderFilterNextRunAt(id bson.ObjectId, prev time.Time, next time.Time) error {
+	return rep.Collection.Update(
+		bson.M{"_id": id, "schedule.next_run_at": prev},
+		bson.M{"$set": bson.M{"schedule.next_run_at": next}},
+	)
+}
+
+func (rep *Repository) UpdateOrderFilterLastReport(id bson.ObjectId, runAt time.Time, name string) error {
+	return rep.Collection.Update(
+		bson.M{"_id": id, "schedule": bson.M{"$ne": nil}},
+		bson.M{"$set": bson.M{"schedule.last_run_at": runAt, "schedule.last_report": name}},
+	)
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+)
+
+func (rep *Repository) InsertRefundApprovalRule(r *model.RefundApprovalRule) error {
+	return rep.Collection.Insert(r)
+}
+
+func (rep *Repository) DeleteRefundApprovalRule(id bson.ObjectId, merchantId string) error {
+	return rep.Collection.Remove(bson.M{"_id": id, "merchant_id": merchantId})
+}
+
+func (rep *Repository) FindRefundApprovalRulesByMerchantId(merchantId string) ([]*model.RefundApprovalRule, error) {
+	var r []*model.RefundApprovalRule
+	err := rep.Collection.Find(bson.M{"merchant_id": merchantId}).Sort("amount", "_id").All(&r)
+
+	return r, err
+}
+
+func (rep *Repository) InsertRefundApproval(a *model.RefundApproval) error {
+	return rep.Collection.Insert(a)
+}
+
+func (rep *Repository) FindRefundApprovalById(id bson.ObjectId, orderId string) (*model.RefundApproval, error) {
+	var a *model.RefundApproval
+	err := rep.Collection.Find(bson.M{"_id": id, "order_id": orderId}).One(&a)
+
+	return a, err
+}
+
+func (rep *Repository) FindRefundApprovalsByOrderId(orderId string, status string) ([]*model.RefundApproval, error) {
+	var a []*model.RefundApproval
+	err := rep.Collection.Find(bson.M{"order_id": orderId, "status": status}).Sort("created_at").All(&a)
+
+	return a, err
+}
+
+func (rep *Repository) FindRefundApprovalsByMerchantId(
+	merchantId string,
+	status string,
+	limit int,
+	offset int,
+) ([]*model.RefundApproval, error) {
+	var a []*model.RefundApproval
+	err := rep.Collection.Find(getRefundApprovalsQuery(merchantId, status)).
+		Sort("-created_at").
+		Skip(offset).
+		Limit(limit).
+		All(&a)
+
+	return a, err
+}
+
+func (rep *Repository) CountRefundApprovalsByMerchantId(merchantId string, status string) (int, error) {
+	return rep.Collection.Find(getRefundApprovalsQuery(merchantId, status)).Count()
+}
+
+// Save decision about refund. Decision saved only if approval still has status prev, so same refund
+// can't be approved twice by concurrent requests
+func (rep *Repository) UpdateRefundApprovalStatus(a *model.RefundApproval, prev string) error {
+	return rep.Collection.Update(
+		bson.M{"_id": a.Id, "status": prev},
+		bson.M{
+			"$set": bson.M{
+				"status":      a.Status,
+				"approver_id": a.ApproverId,
+				"comment":     a.Comment,
+				"refund_id":   a.RefundId,
+				"decided_at":  a.DecidedAt,
+			},
+		},
+	)
+}
+
+func getRefundApprovalsQuery(merchantId string, status string) bson.M {
+	query := bson.M{"merchant_id": merchantId}
+
+	if status != "" {
+		query["status"] = status
+	}
+
+	return query
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+)
+
+func (rep *Repository) FindUserRoleByUserId(userId string) (*model.UserRole, error) {
+	var ur *model.UserRole
+	err := rep.Collection.Find(bson.M{"user_id": userId}).One(&ur)
+
+	return ur, err
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"time"
+)
+
+func (rep *Repository) InsertWebhookInboxRecord(r *model.WebhookInboxRecord) error {
+	return rep.Collection.Insert(r)
+}
+
+func (rep *Repository) UpdateWebhookInboxRecord(r *model.WebhookInboxRecord) error {
+	return rep.Collection.UpdateId(r.Id, r)
+}
+
+func (rep *Repository) FindWebhookInboxRecordById(id string) (*model.WebhookInboxRecord, error) {
+	var r *model.WebhookInboxRecord
+	err := rep.Collection.FindId(id).One(&r)
+
+	return r, err
+}
+
+// Replace record only if existing record can be processed again, mgo.ErrNotFound returned otherwise
+func (rep *Repository) ReplaceRetryableWebhookInboxRecord(r *model.WebhookInboxRecord, now time.Time) error {
+	query := bson.M{
+		"_id": r.Id,
+		"$or": []bson.M{
+			{"status": bson.M{"$in": []string{model.WebhookInboxStatusFailed, model.WebhookInboxStatusStale}}},
+			{"status": model.WebhookInboxStatusProcessing, "locked_until": bson.M{"$lte": now}},
+		},
+	}
+
+	return rep.Collection.Update(query, r)
+}
+
+func (rep *Repository) AddWebhookInboxRecordDelivery(id string, at time.Time) error {
+	return rep.Collection.UpdateId(id, bson.M{
+		"$inc": bson.M{"deliveries": 1},
+		"$set": bson.M{"last_delivery_at": at},
+	})
+}
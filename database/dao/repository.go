@@ -4,8 +4,15 @@ import (
 	"github.com/globalsign/mgo/bson"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-management-api/database/model"
+	"time"
 )
 
+// Iterator read query results one by one from database cursor
+type Iterator interface {
+	Next(result interface{}) bool
+	Close() error
+}
+
 type Repository interface {
 	FindCurrencyById(int) (*model.Currency, error)
 	FindCurrenciesByName(string) ([]*model.Currency, error)
@@ -36,12 +43,48 @@ type Repository interface {
 
 	FindOrderByProjectOrderId(string) (*model.Order, error)
 	FindOrderById(bson.ObjectId) (*model.Order, error)
+	FindOrderByUuid(string) (*model.Order, error)
 	FindAllOrders(filters bson.M, sort []string, limit int32, offset int32) ([]*model.Order, error)
+	FindOrdersByCursor(filters bson.M, paginator *CursorPaginator) ([]*model.Order, error)
+	IterateOrders(filters bson.M, sort []string) Iterator
 	GetOrdersCountByConditions(filters bson.M) (int, error)
+	GetOrdersCountEstimate(filters bson.M, limit int) (int, error)
 	GetRevenueDynamic(*model.RevenueDynamicRequest) ([]map[string]interface{}, error)
 	GetAccountingPayment(rdr *model.RevenueDynamicRequest, mId string) ([]map[string]interface{}, error)
+	FindOrderIds(filters bson.M, sort []string, limit int) ([]bson.ObjectId, error)
+	GetOrderAmountsByStatus(filters bson.M) ([]*model.OrderStatusAmount, error)
 	InsertOrder(*model.Order) error
-	UpdateOrder(*model.Order) error
+	UpdateOrder(o *model.Order, prevStatus int) error
+	UpdateOrderReview(bson.ObjectId, *model.OrderReview) error
+	AddOrderNote(bson.ObjectId, *model.OrderNote) error
+
+	InsertOrderEvent(*model.OrderEvent) error
+	FindOrderEventsByOrderId(bson.ObjectId) ([]*model.OrderEvent, error)
+
+	InsertOrderFilter(*model.OrderFilter) error
+	UpdateOrderFilter(*model.OrderFilter) error
+	DeleteOrderFilter(id bson.ObjectId, userId string) error
+	FindOrderFilterById(id bson.ObjectId, userId string) (*model.OrderFilter, error)
+	FindOrderFiltersByUserId(string) ([]*model.OrderFilter, error)
+	FindScheduledOrderFilters(time.Time) ([]*model.OrderFilter, error)
+	UpdateOrderFilterNextRunAt(id bson.ObjectId, prev time.Time, next time.Time) error
+	UpdateOrderFilterLastReport(id bson.ObjectId, runAt time.Time, name string) error
+
+	InsertOrderBulkJob(*model.OrderBulkJob) error
+	FindOrderBulkJobById(id bson.ObjectId, userId string) (*model.OrderBulkJob, error)
+	ReserveOrderBulkJob(now time.Time, staleBefore time.Time) (*model.OrderBulkJob, error)
+	UpdateOrderBulkJobProgress(*model.OrderBulkJob) error
+
+	InsertRefundApprovalRule(*model.RefundApprovalRule) error
+	DeleteRefundApprovalRule(id bson.ObjectId, merchantId string) error
+	FindRefundApprovalRulesByMerchantId(string) ([]*model.RefundApprovalRule, error)
+
+	InsertRefundApproval(*model.RefundApproval) error
+	FindRefundApprovalById(id bson.ObjectId, orderId string) (*model.RefundApproval, error)
+	FindRefundApprovalsByOrderId(orderId string, status string) ([]*model.RefundApproval, error)
+	FindRefundApprovalsByMerchantId(merchantId string, status string, limit int, offset int) ([]*model.RefundApproval, error)
+	CountRefundApprovalsByMerchantId(merchantId string, status string) (int, error)
+	UpdateRefundApprovalStatus(a *model.RefundApproval, prev string) error
 
 	FindCurrenciesPair(int, int) (*model.CurrencyRate, error)
 
@@ -51,4 +94,25 @@ type Repository interface {
 	FindVatByCountryAndSubdivision(string, string) (*model.Vat, error)
 
 	InsertLog(*model.Log) error
+
+	FindUserRoleByUserId(string) (*model.UserRole, error)
+
+	InsertIdempotencyRecord(*model.IdempotencyRecord) error
+	UpdateIdempotencyRecord(*model.IdempotencyRecord) error
+	FindIdempotencyRecordById(string) (*model.IdempotencyRecord, error)
+	DeleteIdempotencyRecordById(string) error
+	DeleteExpiredIdempotencyRecord(string, time.Time) error
+
+	InsertWebhookInboxRecord(*model.WebhookInboxRecord) error
+	UpdateWebhookInboxRecord(*model.WebhookInboxRecord) error
+	FindWebhookInboxRecordById(string) (*model.WebhookInboxRecord, error)
+	ReplaceRetryableWebhookInboxRecord(r *model.WebhookInboxRecord, now time.Time) error
+	AddWebhookInboxRecordDelivery(id string, at time.Time) error
+
+	InsertCustomerToken(*model.CustomerToken) error
+	FindCustomerTokenById(string) (*model.CustomerToken, error)
+	FindActiveCustomerTokens(projectId string, userId string, now time.Time, limit int, offset int) ([]*model.CustomerToken, error)
+	CountActiveCustomerTokens(projectId string, userId string, now time.Time) (int, error)
+	RevokeCustomerToken(id string, now time.Time) error
+	RevokeCustomerTokensByUserId(projectId string, userId string, now time.Time) (int, error)
 }
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			return db.C("user_role").EnsureIndex(
+				mgo.Index{
+					Name:   "user_role_user_id_unq",
+					Key:    []string{"user_id"},
+					Unique: true,
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			return db.C("user_role").DropCollection()
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
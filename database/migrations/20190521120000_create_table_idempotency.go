@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+	"time"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			return db.C("idempotency").EnsureIndex(
+				mgo.Index{
+					Name:        "idempotency_expires_at_ttl",
+					Key:         []string{"expires_at"},
+					ExpireAfter: time.Second,
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			return db.C("idempotency").DropCollection()
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			return db.C("order_event").EnsureIndex(
+				mgo.Index{
+					Name: "order_event_order_id_created_at",
+					Key:  []string{"order_id", "created_at"},
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			return db.C("order_event").DropCollection()
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C("order_filter").EnsureIndex(
+				mgo.Index{
+					Name: "order_filter_user_id",
+					Key:  []string{"user_id"},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			return db.C("order_filter").EnsureIndex(
+				mgo.Index{
+					Name:   "order_filter_schedule_next_run_at",
+					Key:    []string{"schedule.next_run_at"},
+					Sparse: true,
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			return db.C("order_filter").DropCollection()
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			col := db.C(manager.TableOrder)
+
+			// orders always searched inside projects of merchant, so project is first field of index.
+			// text index isn't used because it doesn't support search by beginning of value
+			err := col.EnsureIndex(
+				mgo.Index{
+					Name: "order_project_id_search_keys",
+					Key:  []string{"project.id", "search_keys"},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			o := &model.Order{}
+			it := col.Find(bson.M{"search_keys": bson.M{"$exists": false}}).Iter()
+
+			for it.Next(o) {
+				o.SetSearchKeys()
+
+				if err := col.UpdateId(o.Id, bson.M{"$set": bson.M{"search_keys": o.SearchKeys}}); err != nil {
+					_ = it.Close()
+					return err
+				}
+
+				o = &model.Order{}
+			}
+
+			return it.Close()
+		},
+		func(db *mgo.Database) error {
+			col := db.C(manager.TableOrder)
+
+			if err := col.DropIndexName("order_project_id_search_keys"); err != nil {
+				return err
+			}
+
+			_, err := col.UpdateAll(bson.M{}, bson.M{"$unset": bson.M{"search_keys": ""}})
+
+			return err
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
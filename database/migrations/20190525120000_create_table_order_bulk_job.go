@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+	"time"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C("order_bulk_job").EnsureIndex(
+				mgo.Index{
+					Name: "order_bulk_job_user_id",
+					Key:  []string{"user_id"},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			err = db.C("order_bulk_job").EnsureIndex(
+				mgo.Index{
+					Name: "order_bulk_job_status_created_at",
+					Key:  []string{"status", "created_at"},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			// results of jobs needed only for short time after job completion
+			return db.C("order_bulk_job").EnsureIndex(
+				mgo.Index{
+					Name:        "order_bulk_job_created_at_ttl",
+					Key:         []string{"created_at"},
+					ExpireAfter: 30 * 24 * time.Hour,
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			return db.C("order_bulk_job").DropCollection()
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			// orders created before public identifiers were introduced have no uuid
+			return db.C(manager.TableOrder).EnsureIndex(
+				mgo.Index{
+					Name:   "order_uuid",
+					Key:    []string{"uuid"},
+					Sparse: true,
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			return db.C(manager.TableOrder).DropIndexName("order_uuid")
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
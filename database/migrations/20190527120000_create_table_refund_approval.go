@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C("refund_approval_rule").EnsureIndex(
+				mgo.Index{
+					Name: "refund_approval_rule_merchant_id",
+					Key:  []string{"merchant_id"},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			err = db.C("refund_approval").EnsureIndex(
+				mgo.Index{
+					Name: "refund_approval_order_id_status",
+					Key:  []string{"order_id", "status"},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			return db.C("refund_approval").EnsureIndex(
+				mgo.Index{
+					Name: "refund_approval_merchant_id_status_created_at",
+					Key:  []string{"merchant_id", "status", "-created_at"},
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			err := db.C("refund_approval_rule").DropCollection()
+
+			if err != nil {
+				return err
+			}
+
+			return db.C("refund_approval").DropCollection()
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			// refunds and chargebacks of merchant selected by projects, status and date of status change
+			return db.C(manager.TableOrder).EnsureIndex(
+				mgo.Index{
+					Name: "order_project_id_status_updated_at",
+					Key:  []string{"project.id", "status", "updated_at"},
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			return db.C(manager.TableOrder).DropIndexName("order_project_id_status_updated_at")
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C("webhook_inbox").EnsureIndex(
+				mgo.Index{
+					Name: "webhook_inbox_order_id_created_at",
+					Key:  []string{"order_id", "-created_at"},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			return db.C("webhook_inbox").EnsureIndex(
+				mgo.Index{
+					Name: "webhook_inbox_provider_payment_id",
+					Key:  []string{"provider", "payment_id"},
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			return db.C("webhook_inbox").DropCollection()
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
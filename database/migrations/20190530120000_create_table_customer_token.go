@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/xakep666/mongo-migrate"
+	"time"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C("customer_token").EnsureIndex(
+				mgo.Index{
+					Name: "customer_token_project_id_user_id_created_at",
+					Key:  []string{"project_id", "user_id", "-created_at"},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			// expired tokens can't be used, so they kept only for a week for introspection
+			return db.C("customer_token").EnsureIndex(
+				mgo.Index{
+					Name:        "customer_token_expires_at_ttl",
+					Key:         []string{"expires_at"},
+					ExpireAfter: 7 * 24 * time.Hour,
+				},
+			)
+		},
+		func(db *mgo.Database) error {
+			return db.C("customer_token").DropCollection()
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
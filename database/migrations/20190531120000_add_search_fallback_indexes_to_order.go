@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/manager"
+	"github.com/xakep666/mongo-migrate"
+	"strings"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			col := db.C(manager.TableOrder)
+
+			// orders created by billing server have no search keys and searched by source fields
+			for _, field := range manager.OrderSearchFallbackFields {
+				err := col.EnsureIndex(
+					mgo.Index{
+						Name: getOrderSearchFallbackIndexName(field),
+						Key:  []string{"project.id", field},
+					},
+				)
+
+				if err != nil {
+					return err
+				}
+			}
+
+			// names of project and payment method added to search keys
+			o := &model.Order{}
+			it := col.Find(bson.M{"search_keys": bson.M{"$exists": true}}).Iter()
+
+			for it.Next(o) {
+				o.SetSearchKeys()
+
+				if err := col.UpdateId(o.Id, bson.M{"$set": bson.M{"search_keys": o.SearchKeys}}); err != nil {
+					_ = it.Close()
+					return err
+				}
+
+				o = &model.Order{}
+			}
+
+			return it.Close()
+		},
+		func(db *mgo.Database) error {
+			col := db.C(manager.TableOrder)
+
+			for _, field := range manager.OrderSearchFallbackFields {
+				if err := col.DropIndexName(getOrderSearchFallbackIndexName(field)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
+
+func getOrderSearchFallbackIndexName(field string) string {
+	return "order_project_id_" + strings.Replace(field, ".", "_", -1)
+}
@@ -1,6 +1,42 @@
 package model
 
-import "time"
+import (
+	"math"
+	"time"
+)
+
+const currencyPrecisionDefault = 2
+
+// Count of digits after decimal separator in amounts of currencies which minor unit isn't 1/100 of
+// major unit, by ISO 4217
+var currencyPrecisions = map[string]int{
+	"BIF": 0,
+	"CLP": 0,
+	"DJF": 0,
+	"GNF": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KMF": 0,
+	"KRW": 0,
+	"PYG": 0,
+	"RWF": 0,
+	"UGX": 0,
+	"UYI": 0,
+	"VND": 0,
+	"VUV": 0,
+	"XAF": 0,
+	"XOF": 0,
+	"XPF": 0,
+	"BHD": 3,
+	"IQD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"CLF": 4,
+	"UYW": 4,
+}
 
 type Name struct {
 	// english name
@@ -22,3 +58,18 @@ type Currency struct {
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time `bson:"updated_at" json:"-"`
 }
+
+// Get count of digits after decimal separator in amounts of currency with 3 chars ISO 4217 code
+func GetCurrencyPrecision(codeA3 string) int {
+	if p, ok := currencyPrecisions[codeA3]; ok {
+		return p
+	}
+
+	return currencyPrecisionDefault
+}
+
+// Round amount to minor unit of currency with 3 chars ISO 4217 code
+func RoundCurrencyAmount(amount float64, codeA3 string) float64 {
+	k := math.Pow10(GetCurrencyPrecision(codeA3))
+	return math.Round(amount*k) / k
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// CustomerToken is a token of project customer created by billing server. Token saved by its hash, so value
+// of token can't be restored from database. Billing server can't revoke tokens, so orders by revoked tokens
+// rejected by api
+type CustomerToken struct {
+	Id        string     `bson:"_id" json:"id"`
+	ProjectId string     `bson:"project_id" json:"project_id"`
+	UserId    string     `bson:"user_id" json:"user_id"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time  `bson:"expires_at" json:"expires_at"`
+	RevokedAt *time.Time `bson:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// Token can be used to create order if it isn't revoked and isn't expired
+func (t *CustomerToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && t.ExpiresAt.After(now)
+}
+
+type CustomerTokenIntrospectRequest struct {
+	ProjectId string `json:"project_id" validate:"required,hexadecimal,len=24"`
+	Token     string `json:"token" validate:"required"`
+}
+
+// Request to revoke one token or all active tokens of user in project
+type CustomerTokenRevokeRequest struct {
+	ProjectId string `json:"project_id" validate:"required,hexadecimal,len=24"`
+	Token     string `json:"token" validate:"required_without=UserId"`
+	UserId    string `json:"user_id" validate:"required_without=Token"`
+}
+
+type CustomerTokenListRequest struct {
+	ProjectId string `json:"project_id" validate:"required,hexadecimal,len=24"`
+	UserId    string `json:"user_id"`
+	Limit     int    `json:"limit" validate:"min=0,max=1000"`
+	Offset    int    `json:"offset" validate:"min=0"`
+}
+
+type CustomerTokenInfo struct {
+	*CustomerToken
+	// token can be used to create order. false for unknown, expired and revoked tokens
+	Active bool `json:"active"`
+}
+
+type CustomerTokenList struct {
+	Count int                  `json:"count"`
+	Items []*CustomerTokenInfo `json:"items"`
+}
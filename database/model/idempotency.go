@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	IdempotencyRecordStatusProcessing = "processing"
+	IdempotencyRecordStatusCompleted  = "completed"
+)
+
+type IdempotencyRecord struct {
+	Id                  string    `bson:"_id" json:"id"`
+	Fingerprint         string    `bson:"fingerprint" json:"fingerprint"`
+	Status              string    `bson:"status" json:"status"`
+	ResponseStatus      int       `bson:"response_status" json:"response_status"`
+	ResponseContentType string    `bson:"response_content_type" json:"response_content_type"`
+	ResponseBody        []byte    `bson:"response_body" json:"response_body"`
+	CreatedAt           time.Time `bson:"created_at" json:"created_at"`
+	ExpiresAt           time.Time `bson:"expires_at" json:"expires_at"`
+}
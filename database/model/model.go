@@ -11,6 +11,17 @@ const (
 	QueryParameterNameLimit  = "limit"
 	QueryParameterNameOffset = "offset"
 	QueryParameterNameSort   = "sort[]"
+	QueryParameterNameCursor = "cursor"
+	QueryParameterNameCount  = "count"
+
+	// count of listing calculated by all documents matched to filters
+	CountModeExact = "exact"
+	// count of listing calculated up to CountEstimateLimit documents, so it's lower bound of real count
+	CountModeEstimated = "estimated"
+	// count of listing not calculated
+	CountModeNone = "none"
+
+	CountEstimateLimit = 10000
 
 	ResponseMessageInvalidRequestData = "Invalid request data"
 	ResponseMessageAccessDenied       = "Access denied"
@@ -37,10 +48,29 @@ const (
 var DefaultSort = []string{"_id"}
 
 type Error struct {
+	// stable machine-readable error code. list of codes published in api specification
+	Code string `json:"code"`
+	// text error description
+	Message string `json:"message"`
+	// list of error details, for example list of invalid request fields
+	Details []*ErrorDetail `json:"details"`
+	// unique identifier of request. must be sent to support for error investigation
+	RequestId string `json:"request_id"`
+}
+
+type ErrorDetail struct {
+	// name of request field which contains error
+	Field string `json:"field,omitempty"`
+	// machine-readable code of error, for example name of failed validation rule
+	Code string `json:"code"`
 	// text error description
 	Message string `json:"message"`
 }
 
+func (e *Error) Error() string {
+	return e.Message
+}
+
 type SimpleItem struct {
 	// unique identifier of item
 	Id bson.ObjectId `json:"id"`
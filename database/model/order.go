@@ -198,6 +198,8 @@ type OrderScalar struct {
 type Order struct {
 	// unique order identifier in Protocol One
 	Id bson.ObjectId `bson:"_id" json:"id"`
+	// public unique order identifier, used by billing server to identify order in refunds
+	Uuid string `bson:"uuid" json:"uuid"`
 	// object described main entities of project in Protocol One payment solution
 	Project *ProjectOrder `bson:"project" json:"project"`
 	// unique order identifier in project. if was send in create order process
@@ -273,6 +275,12 @@ type Order struct {
 	UrlSuccess             string                 `bson:"url_success" json:"url_success"`
 	// URL for redirect user after failed payment. This field can be send if it allowed in project admin panel
 	UrlFail string `bson:"url_fail" json:"url_fail"`
+	// normalized values of order fields by which order can be found by search query
+	SearchKeys []string `bson:"search_keys" json:"-"`
+	// mark of order which must be checked manually
+	Review *OrderReview `bson:"review,omitempty" json:"review,omitempty"`
+	// comments added to order by merchant users
+	Notes []*OrderNote `bson:"notes,omitempty" json:"notes,omitempty"`
 }
 
 type OrderSimple struct {
@@ -294,6 +302,8 @@ type OrderSimple struct {
 	ProjectAmountIncome *OrderSimpleAmountObject `json:"project_amount_income"`
 	// object which contains main information about accounting finances of project which will send to project
 	ProjectAmountOutcome *OrderSimpleAmountObject `json:"project_amount_outcome"`
+	// object which contains order amount received from payer in merchant accounting currency
+	MerchantAmountIncome *OrderSimpleAmountObject `json:"merchant_amount_income"`
 	// object which contains main information about finances of payment system which received of payment system
 	PaymentMethodAmountIncome *OrderSimpleAmountObject `json:"payment_method_amount_income"`
 	// object which contains main information about fixed package which was buy
@@ -321,8 +331,14 @@ type OrderSimple struct {
 type OrderPaginate struct {
 	// total count of selected orders
 	Count int `json:"count"`
+	// mode in which count of orders calculated: exact, estimated or none
+	CountMode string `json:"count_mode"`
 	// array of selected orders
 	Items []*OrderSimple `json:"items"`
+	// cursor to get next page of orders. returned in cursor pagination mode only if next page exists
+	NextCursor string `json:"next_cursor,omitempty"`
+	// cursor to get previous page of orders. returned in cursor pagination mode only if previous page exists
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 type OrderPaymentNotification struct {
@@ -471,3 +487,20 @@ func (pd *RevenueDynamicPointDate) String() string {
 func (order *Order) CanProcessNotify() bool {
 	return order.Status == OrderStatusPaymentSystemCreate || order.Status == OrderStatusPaymentSystemReject
 }
+
+// Notification to project can be sent again only for paid orders which not completed by project
+func (order *Order) CanResendNotification() bool {
+	return order.Status == OrderStatusPaymentSystemComplete || order.Status == OrderStatusProjectInProgress ||
+		order.Status == OrderStatusProjectPending
+}
+
+// Refund can be created for paid orders. Order which refunded partially can be refunded again
+func (order *Order) CanRefund() bool {
+	switch order.Status {
+	case OrderStatusPaymentSystemComplete, OrderStatusProjectInProgress, OrderStatusProjectComplete,
+		OrderStatusProjectPending, OrderStatusProjectReject, OrderStatusRefund:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"time"
+)
+
+const (
+	OrderBulkActionResendNotification = "resend_notification"
+	OrderBulkActionMarkForReview      = "mark_for_review"
+	OrderBulkActionAddNote            = "add_note"
+
+	OrderBulkJobStatusPending    = "pending"
+	OrderBulkJobStatusInProgress = "in_progress"
+	OrderBulkJobStatusCompleted  = "completed"
+	OrderBulkJobStatusFailed     = "failed"
+
+	OrderBulkResultStatusOk      = "ok"
+	OrderBulkResultStatusSkipped = "skipped"
+	OrderBulkResultStatusFailed  = "failed"
+
+	// max count of orders in one job. results of all orders stored in job document, so count is limited
+	OrderBulkMaxOrders = 1000
+)
+
+// OrderBulkJob is action executed for many orders in background. Orders selected by list of identifiers
+// or by query of orders list, query resolved to list of identifiers when job created
+type OrderBulkJob struct {
+	// unique job identifier
+	Id bson.ObjectId `bson:"_id" json:"id"`
+	// identifier of user who created job
+	UserId string `bson:"user_id" json:"-"`
+	// identifier of merchant of user who created job
+	MerchantId string `bson:"merchant_id" json:"merchant_id"`
+	// action executed for every order: resend_notification, mark_for_review or add_note
+	Action string `bson:"action" json:"action" validate:"required,oneof=resend_notification mark_for_review add_note"`
+	// text of note added to orders by add_note action
+	Note string `bson:"note" json:"note,omitempty" validate:"max=1000"`
+	// list of orders identifiers. must be specified if query not specified
+	OrderIds []bson.ObjectId `bson:"order_ids" json:"order_ids" validate:"max=1000"`
+	// query parameters of orders list, for example {"status[]": ["7"]}. must be specified if order_ids not specified
+	Query map[string][]string `bson:"query" json:"query,omitempty"`
+	// job status: pending, in_progress, completed or failed
+	Status string `bson:"status" json:"status"`
+	// reason why job failed
+	Error string `bson:"error" json:"error,omitempty"`
+	// count of orders in job
+	Total int `bson:"total" json:"total"`
+	// count of processed orders
+	Processed int `bson:"processed" json:"processed"`
+	// count of orders which action failed for
+	Failed int `bson:"failed" json:"failed"`
+	// results of action for processed orders in order of identifiers in order_ids
+	Results []*OrderBulkResult `bson:"results" json:"results"`
+	// date of job creation
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	// date of last job progress update
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	// date of job completion
+	FinishedAt *time.Time `bson:"finished_at" json:"finished_at,omitempty"`
+}
+
+// OrderBulkResult is result of job action for one order
+type OrderBulkResult struct {
+	// unique order identifier
+	OrderId bson.ObjectId `bson:"order_id" json:"order_id"`
+	// result status: ok, skipped or failed
+	Status string `bson:"status" json:"status"`
+	// reason why action skipped or failed
+	Message string `bson:"message" json:"message,omitempty"`
+}
+
+// OrderReview is mark of order which must be checked manually
+type OrderReview struct {
+	// identifier of user who marked order
+	UserId string `bson:"user_id" json:"user_id"`
+	// date when order marked
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// OrderNote is text comment added to order by user
+type OrderNote struct {
+	// identifier of user who added note
+	UserId string `bson:"user_id" json:"user_id"`
+	// note text
+	Text string `bson:"text" json:"text"`
+	// date when note added
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Add result of action for next order of job
+func (j *OrderBulkJob) AddResult(id bson.ObjectId, status string, message string) {
+	j.Results = append(j.Results, &OrderBulkResult{OrderId: id, Status: status, Message: message})
+	j.Processed++
+
+	if status == OrderBulkResultStatusFailed {
+		j.Failed++
+	}
+}
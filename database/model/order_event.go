@@ -0,0 +1,54 @@
+package model
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"time"
+)
+
+const (
+	OrderEventSourceWebhook = "webhook"
+	OrderEventSourceAdmin   = "admin"
+	OrderEventSourceForm    = "form"
+)
+
+// OrderEvent is record of order status transition. Events only inserted and never updated,
+// so list of events of order is full history of order statuses
+type OrderEvent struct {
+	// unique event identifier
+	Id bson.ObjectId `bson:"_id" json:"id"`
+	// unique identifier of order
+	OrderId    bson.ObjectId `bson:"order_id" json:"order_id"`
+	StatusFrom int           `bson:"status_from" json:"-"`
+	StatusTo   int           `bson:"status_to" json:"-"`
+	// order status before transition
+	From *Status `bson:"-" json:"status_from"`
+	// order status after transition
+	To *Status `bson:"-" json:"status_to"`
+	// who changed order status. for example name of payment system or identifier of user
+	Actor string `bson:"actor" json:"actor"`
+	// channel by which order status changed: webhook, admin or form
+	Source string `bson:"source" json:"source"`
+	// reason of status change, for example error returned by payment system
+	Reason string `bson:"reason" json:"reason,omitempty"`
+	// sha256 hash of request which changed order status
+	PayloadHash string `bson:"payload_hash" json:"payload_hash,omitempty"`
+	// date of status change
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+type OrderTimeline struct {
+	// unique identifier of order
+	OrderId bson.ObjectId `json:"order_id"`
+	// current order status
+	Status *Status `json:"status"`
+	// list of order status transitions from oldest to newest
+	Items []*OrderEvent `json:"items"`
+}
+
+func NewOrderStatus(status int) *Status {
+	return &Status{
+		Status:      status,
+		Name:        OrderStatusesNames[status],
+		Description: OrderStatusesDescription[status],
+	}
+}
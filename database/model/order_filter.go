@@ -0,0 +1,89 @@
+package model
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"time"
+)
+
+const (
+	OrderFilterSchedulePeriodDaily  = "daily"
+	OrderFilterSchedulePeriodWeekly = "weekly"
+)
+
+// OrderFilterQueryFields is list of query parameters of orders list which can be saved in filter
+var OrderFilterQueryFields = map[string]bool{
+	OrderFilterFieldProjects:        true,
+	OrderFilterFieldId:              true,
+	OrderFilterFieldPaymentMethods:  true,
+	OrderFilterFieldCountries:       true,
+	OrderFilterFieldStatuses:        true,
+	OrderFilterFieldAccount:         true,
+	OrderFilterFieldPMDateFrom:      true,
+	OrderFilterFieldPMDateTo:        true,
+	OrderFilterFieldProjectDateFrom: true,
+	OrderFilterFieldProjectDateTo:   true,
+	OrderFilterFieldQuickFilter:     true,
+	QueryParameterNameSort:          true,
+}
+
+// OrderFilter is named query of orders list saved by user
+type OrderFilter struct {
+	// unique filter identifier
+	Id bson.ObjectId `bson:"_id" json:"id"`
+	// identifier of user who created filter
+	UserId string `bson:"user_id" json:"-"`
+	// identifier of merchant of user who created filter
+	MerchantId string `bson:"merchant_id" json:"merchant_id"`
+	// filter name
+	Name string `bson:"name" json:"name" validate:"required,max=255"`
+	// query parameters of orders list, for example {"status[]": ["4", "9"], "country[]": ["RU"]}
+	Query map[string][]string `bson:"query" json:"query" validate:"required"`
+	// schedule of orders report generation by filter. report not generated if schedule not specified
+	Schedule *OrderFilterSchedule `bson:"schedule" json:"schedule,omitempty"`
+	// date of filter creation
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	// date of last filter update
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// OrderFilterSchedule describes when orders report by filter must be generated. All times are in UTC
+type OrderFilterSchedule struct {
+	// period of report generation: daily or weekly
+	Period string `bson:"period" json:"period" validate:"required,oneof=daily weekly"`
+	// day of week of report generation for weekly period, from 0 (sunday) to 6 (saturday)
+	Weekday int `bson:"weekday" json:"weekday" validate:"min=0,max=6"`
+	// hour of report generation
+	Hour int `bson:"hour" json:"hour" validate:"min=0,max=23"`
+	// report file format: csv, xlsx or jsonl
+	Format string `bson:"format" json:"format" validate:"required,oneof=csv xlsx jsonl"`
+	// list of report columns. all columns exported if list is empty
+	Columns []string `bson:"columns" json:"columns"`
+	// date of next report generation
+	NextRunAt time.Time `bson:"next_run_at" json:"next_run_at"`
+	// date of last report generation
+	LastRunAt *time.Time `bson:"last_run_at" json:"last_run_at,omitempty"`
+	// name of file of last generated report in storage
+	LastReport string `bson:"last_report" json:"-"`
+}
+
+// Get first date of report generation after specified date
+func (s *OrderFilterSchedule) GetNextRunAt(after time.Time) time.Time {
+	after = after.UTC()
+	next := time.Date(after.Year(), after.Month(), after.Day(), s.Hour, 0, 0, 0, time.UTC)
+
+	if s.Period == OrderFilterSchedulePeriodWeekly {
+		next = next.AddDate(0, 0, (s.Weekday-int(next.Weekday())+7)%7)
+
+		if !next.After(after) {
+			next = next.AddDate(0, 0, 7)
+		}
+
+		return next
+	}
+
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next
+}
@@ -0,0 +1,80 @@
+package model
+
+const (
+	OrderReversalFieldDateFrom   = "date_from"
+	OrderReversalFieldDateTo     = "date_to"
+	OrderReversalFieldCurrencies = "currency[]"
+)
+
+// Statuses of orders which payment returned to payer by refund or by chargeback
+var OrderReversalStatuses = []int{OrderStatusRefund, OrderStatusChargeback}
+
+// OrderStatusAmount is count and sum of amounts in merchant accounting currency of orders in one status
+type OrderStatusAmount struct {
+	Status int     `bson:"_id"`
+	Count  int     `bson:"count"`
+	Amount float64 `bson:"amount"`
+}
+
+// OrderReversalSummary contains totals of refunded and charged back orders in merchant accounting currency
+type OrderReversalSummary struct {
+	// merchant accounting currency by ISO 4217
+	Currency string `json:"currency"`
+	// count of refunded orders
+	RefundCount int `json:"refund_count"`
+	// sum of refunded orders
+	RefundAmount float64 `json:"refund_amount"`
+	// count of charged back orders
+	ChargebackCount int `json:"chargeback_count"`
+	// sum of charged back orders
+	ChargebackAmount float64 `json:"chargeback_amount"`
+	// count of refunded and charged back orders
+	Count int `json:"count"`
+	// sum of refunded and charged back orders
+	Amount float64 `json:"amount"`
+}
+
+// OrderReversalList is page of refunded and charged back orders with totals of all orders matched by filters
+type OrderReversalList struct {
+	*OrderPaginate
+	// totals of all orders matched by filters, not of current page only
+	Summary *OrderReversalSummary `json:"summary"`
+}
+
+// Check that orders in status can be selected to list of refunded and charged back orders
+func IsOrderReversalStatus(status int) bool {
+	for _, s := range OrderReversalStatuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Calculate totals of refunded and charged back orders by amounts of orders grouped by status
+func NewOrderReversalSummary(currency string, amounts []*OrderStatusAmount) *OrderReversalSummary {
+	s := &OrderReversalSummary{Currency: currency}
+
+	for _, a := range amounts {
+		switch a.Status {
+		case OrderStatusRefund:
+			s.RefundCount += a.Count
+			s.RefundAmount += a.Amount
+		case OrderStatusChargeback:
+			s.ChargebackCount += a.Count
+			s.ChargebackAmount += a.Amount
+		default:
+			continue
+		}
+
+		s.Count += a.Count
+		s.Amount += a.Amount
+	}
+
+	s.RefundAmount = RoundCurrencyAmount(s.RefundAmount, currency)
+	s.ChargebackAmount = RoundCurrencyAmount(s.ChargebackAmount, currency)
+	s.Amount = RoundCurrencyAmount(s.Amount, currency)
+
+	return s
+}
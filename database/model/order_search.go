@@ -0,0 +1,101 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// count of last digits of masked card number stored as separate search key
+	orderSearchPanSuffixLength = 4
+)
+
+// Normalize value of order field or search query to form in which search keys are stored. Keys stored
+// in lower case, and phone numbers stored without formatting characters
+func NormalizeOrderSearchValue(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if !isOrderSearchPhone(value) {
+		return value
+	}
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || r == '+' {
+			return r
+		}
+
+		return -1
+	}, value)
+}
+
+// Set keys by which order can be found by search query: order identifier, order identifier in project,
+// payer account in project, payer email and phone, account in payment system (masked card number
+// for bank cards), order identifier in payment system, names of project and payment method
+func (order *Order) SetSearchKeys() {
+	values := []string{
+		order.Id.Hex(),
+		order.ProjectOrderId,
+		order.ProjectAccount,
+		order.PaymentMethodPayerAccount,
+		order.PaymentMethodOrderId,
+	}
+
+	if order.PayerData != nil {
+		if order.PayerData.Email != nil {
+			values = append(values, *order.PayerData.Email)
+		}
+
+		if order.PayerData.Phone != nil {
+			values = append(values, *order.PayerData.Phone)
+		}
+	}
+
+	if order.Project != nil {
+		values = append(values, order.Project.Name)
+	}
+
+	if order.PaymentMethod != nil {
+		values = append(values, order.PaymentMethod.Name)
+	}
+
+	// masked card number searched by last digits of card too
+	if pan := order.PaymentMethodPayerAccount; strings.Contains(pan, "*") || strings.Contains(pan, "...") {
+		if len(pan) > orderSearchPanSuffixLength {
+			values = append(values, pan[len(pan)-orderSearchPanSuffixLength:])
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	exists := make(map[string]bool, len(values))
+
+	for _, value := range values {
+		key := NormalizeOrderSearchValue(value)
+
+		if key == "" || exists[key] {
+			continue
+		}
+
+		keys = append(keys, key)
+		exists[key] = true
+	}
+
+	order.SearchKeys = keys
+}
+
+// Value is phone number if it contains only digits and phone formatting characters
+func isOrderSearchPhone(value string) bool {
+	digits := 0
+
+	for _, r := range value {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case r == '+' || r == '-' || r == '(' || r == ')' || r == ' ':
+			continue
+		default:
+			return false
+		}
+	}
+
+	return digits > 0
+}
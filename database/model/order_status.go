@@ -0,0 +1,161 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	orderStatusErrorTransitionNotAllowed = "order status transition from \"%s\" (%d) to \"%s\" (%d) not allowed"
+	orderStatusErrorTransitionGuard      = "order status transition from \"%s\" (%d) to \"%s\" (%d) rejected: %s"
+
+	orderStatusGuardPaymentMethodNotFound = "payment method of order not specified"
+	orderStatusGuardPaymentDataNotFound   = "payment system order identifier, amount or currency not specified"
+)
+
+// OrderStatusTransition is allowed edge of order status state machine
+type OrderStatusTransition struct {
+	// check that order data allows transition. returned error is reason why transition rejected
+	Guard func(o *Order) error
+	// called after order moved to new status
+	Hook func(o *Order)
+}
+
+// OrderStatusTransitionError returned if order can't be moved from current status to requested status
+type OrderStatusTransitionError struct {
+	From   int
+	To     int
+	Reason string
+}
+
+// Allowed transitions between order statuses. Key of map is status from which order can be moved,
+// key of nested map is status to which order can be moved from it. Any transition which not described
+// in this map is illegal. Statuses without outgoing transitions are final
+var OrderStatusTransitions = map[int]map[int]*OrderStatusTransition{
+	OrderStatusNew: {
+		OrderStatusPaymentSystemCreate:         {Guard: orderStatusGuardPaymentMethod},
+		OrderStatusPaymentSystemRejectOnCreate: {},
+	},
+	OrderStatusPaymentSystemRejectOnCreate: {
+		OrderStatusPaymentSystemCreate: {Guard: orderStatusGuardPaymentMethod},
+	},
+	OrderStatusPaymentSystemCreate: {
+		OrderStatusPaymentSystemReject:   {},
+		OrderStatusPaymentSystemComplete: {Guard: orderStatusGuardPaymentData},
+		OrderStatusPaymentSystemDeclined: {},
+		OrderStatusPaymentSystemCanceled: {},
+	},
+	OrderStatusPaymentSystemReject: {
+		OrderStatusPaymentSystemComplete: {Guard: orderStatusGuardPaymentData},
+		OrderStatusPaymentSystemDeclined: {},
+		OrderStatusPaymentSystemCanceled: {},
+	},
+	OrderStatusPaymentSystemComplete: {
+		OrderStatusProjectInProgress: {Hook: orderStatusHookProjectRequested},
+		OrderStatusRefund:            {},
+		OrderStatusChargeback:        {},
+	},
+	OrderStatusProjectInProgress: {
+		OrderStatusProjectComplete: {},
+		OrderStatusProjectPending:  {},
+		OrderStatusProjectReject:   {},
+	},
+	OrderStatusProjectPending: {
+		OrderStatusProjectInProgress: {Hook: orderStatusHookProjectRequested},
+		OrderStatusProjectComplete:   {},
+		OrderStatusProjectReject:     {},
+		OrderStatusRefund:            {},
+		OrderStatusChargeback:        {},
+	},
+	OrderStatusProjectComplete: {
+		OrderStatusRefund:     {},
+		OrderStatusChargeback: {},
+	},
+	OrderStatusProjectReject: {
+		OrderStatusRefund:     {},
+		OrderStatusChargeback: {},
+	},
+}
+
+func (e *OrderStatusTransitionError) Error() string {
+	from, to := OrderStatusesNames[e.From], OrderStatusesNames[e.To]
+
+	if e.Reason == "" {
+		return fmt.Sprintf(orderStatusErrorTransitionNotAllowed, from, e.From, to, e.To)
+	}
+
+	return fmt.Sprintf(orderStatusErrorTransitionGuard, from, e.From, to, e.To, e.Reason)
+}
+
+// Check that order in status "from" can be moved to status "to". Staying in same status is always allowed
+func CheckOrderStatusTransition(o *Order, from, to int) error {
+	if from == to {
+		return nil
+	}
+
+	t, ok := OrderStatusTransitions[from][to]
+
+	if !ok {
+		return &OrderStatusTransitionError{From: from, To: to}
+	}
+
+	if t.Guard == nil {
+		return nil
+	}
+
+	if err := t.Guard(o); err != nil {
+		return &OrderStatusTransitionError{From: from, To: to, Reason: err.Error()}
+	}
+
+	return nil
+}
+
+// Move order to new status through state machine. Order status not changed if transition not allowed
+func (order *Order) TransitStatus(to int) error {
+	from := order.Status
+
+	if err := CheckOrderStatusTransition(order, from, to); err != nil {
+		return err
+	}
+
+	if from == to {
+		return nil
+	}
+
+	order.Status = to
+	order.UpdatedAt = time.Now()
+
+	if t := OrderStatusTransitions[from][to]; t.Hook != nil {
+		t.Hook(order)
+	}
+
+	return nil
+}
+
+func orderStatusGuardPaymentMethod(o *Order) error {
+	if o.PaymentMethod == nil {
+		return errors.New(orderStatusGuardPaymentMethodNotFound)
+	}
+
+	return nil
+}
+
+// Order can be paid only when payment system sent identifier of payment and paid amount
+func orderStatusGuardPaymentData(o *Order) error {
+	if o.PaymentMethodOrderId == "" || o.PaymentMethodIncomeAmount <= 0 {
+		return errors.New(orderStatusGuardPaymentDataNotFound)
+	}
+
+	// currency code received from payment system replaced by currency object before order saving
+	if o.PaymentMethodIncomeCurrencyA3 == "" && o.PaymentMethodIncomeCurrency == nil {
+		return errors.New(orderStatusGuardPaymentDataNotFound)
+	}
+
+	return nil
+}
+
+func orderStatusHookProjectRequested(o *Order) {
+	now := time.Now()
+	o.ProjectLastRequestedAt = &now
+}
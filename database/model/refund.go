@@ -0,0 +1,133 @@
+package model
+
+import (
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"math"
+)
+
+const (
+	RefundReasonFraud           = "fraud"
+	RefundReasonCustomerRequest = "customer_request"
+	RefundReasonDuplicate       = "duplicate"
+	RefundReasonTechnical       = "technical"
+
+	RefundStatusCreated    = 0
+	RefundStatusRejected   = 1
+	RefundStatusInProgress = 2
+	RefundStatusCompleted  = 3
+)
+
+// RefundReason is item of catalogue of reasons which refund to order can be created by
+type RefundReason struct {
+	// reason code which must be sent in request of refund creation
+	Code string `json:"code"`
+	// reason description
+	Description string `json:"description"`
+}
+
+// Catalogue of refund reasons
+var RefundReasons = []*RefundReason{
+	{Code: RefundReasonFraud, Description: "Payment made by fraudster"},
+	{Code: RefundReasonCustomerRequest, Description: "Customer asked for refund"},
+	{Code: RefundReasonDuplicate, Description: "Order paid twice"},
+	{Code: RefundReasonTechnical, Description: "Product not delivered because of technical problem"},
+}
+
+// RefundPreview contains amounts which can be refunded to order after previous refunds
+type RefundPreview struct {
+	// unique order identifier
+	OrderId string `json:"order_id"`
+	// order payment currency by ISO 4217. refunds created in this currency
+	Currency string `json:"currency"`
+	// amount paid by payer
+	Amount float64 `json:"amount"`
+	// sum of previous refunds to order which not rejected by payment system
+	RefundedAmount float64 `json:"refunded_amount"`
+	// amount which can be refunded yet
+	RefundableAmount float64 `json:"refundable_amount"`
+	// merchant accounting currency by ISO 4217
+	MerchantCurrency string `json:"merchant_currency"`
+	// amount paid by payer in merchant accounting currency
+	MerchantAmount float64 `json:"merchant_amount"`
+	// sum of previous refunds in merchant accounting currency
+	MerchantRefundedAmount float64 `json:"merchant_refunded_amount"`
+	// amount which can be refunded yet in merchant accounting currency
+	MerchantRefundableAmount float64 `json:"merchant_refundable_amount"`
+	// sum of refunds to order which wait for approval. these refunds decrease refundable amount
+	PendingApprovalAmount float64 `json:"pending_approval_amount"`
+	// count of previous refunds to order which not rejected by payment system
+	RefundsCount int `json:"refunds_count"`
+	// catalogue of reasons which refund can be created by
+	Reasons []*RefundReason `json:"reasons"`
+}
+
+// Check that reason code exists in catalogue of refund reasons
+func IsRefundReasonValid(code string) bool {
+	for _, r := range RefundReasons {
+		if r.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Calculate amounts which can be refunded to order after previous refunds. Rejected refunds not decrease
+// refundable amount. Nothing can be refunded for not paid orders and for orders with chargeback.
+// Amounts in merchant accounting currency calculated by rate of order payment
+func NewRefundPreview(o *Order, orderId string, refunds []*billing.Refund) *RefundPreview {
+	p := &RefundPreview{
+		OrderId:        orderId,
+		Amount:         o.PaymentMethodIncomeAmount,
+		MerchantAmount: o.AmountOutMerchantAccountingCurrency,
+		Reasons:        RefundReasons,
+	}
+
+	if o.PaymentMethodIncomeCurrency != nil {
+		p.Currency = o.PaymentMethodIncomeCurrency.CodeA3
+	}
+
+	if o.Project != nil && o.Project.Merchant != nil && o.Project.Merchant.Banking != nil &&
+		o.Project.Merchant.Banking.Currency != nil {
+		p.MerchantCurrency = o.Project.Merchant.Banking.Currency.CodeA3
+	}
+
+	for _, r := range refunds {
+		if r.Status == RefundStatusRejected {
+			continue
+		}
+
+		p.RefundedAmount += r.Amount
+		p.RefundsCount++
+	}
+
+	p.RefundedAmount = RoundCurrencyAmount(p.RefundedAmount, p.Currency)
+
+	if p.Amount > 0 {
+		p.MerchantRefundedAmount = RoundCurrencyAmount(p.RefundedAmount*p.MerchantAmount/p.Amount, p.MerchantCurrency)
+	}
+
+	if o.CanRefund() {
+		p.RefundableAmount = RoundCurrencyAmount(math.Max(p.Amount-p.RefundedAmount, 0), p.Currency)
+		p.MerchantRefundableAmount = RoundCurrencyAmount(
+			math.Max(p.MerchantAmount-p.MerchantRefundedAmount, 0),
+			p.MerchantCurrency,
+		)
+	}
+
+	return p
+}
+
+// Decrease refundable amounts by amount of refund which waits for approval
+func (p *RefundPreview) AddPendingApproval(a *RefundApproval) {
+	p.PendingApprovalAmount = RoundCurrencyAmount(p.PendingApprovalAmount+a.Amount, p.Currency)
+	p.RefundableAmount = RoundCurrencyAmount(math.Max(p.RefundableAmount-a.Amount, 0), p.Currency)
+
+	if p.Amount > 0 {
+		merchantAmount := a.Amount * p.MerchantAmount / p.Amount
+		p.MerchantRefundableAmount = RoundCurrencyAmount(
+			math.Max(p.MerchantRefundableAmount-merchantAmount, 0),
+			p.MerchantCurrency,
+		)
+	}
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"time"
+)
+
+const (
+	RefundApprovalStatusPending  = "pending_approval"
+	RefundApprovalStatusApproved = "approved"
+	RefundApprovalStatusRejected = "rejected"
+)
+
+// RefundApprovalRule is rule of merchant which selects refunds must be approved by second user before creation
+type RefundApprovalRule struct {
+	// unique rule identifier
+	Id bson.ObjectId `bson:"_id" json:"id"`
+	// identifier of merchant which rule belongs to
+	MerchantId string `bson:"merchant_id" json:"merchant_id"`
+	// refunds with amount greater than this amount must be approved
+	Amount float64 `bson:"amount" json:"amount" validate:"min=0"`
+	// currency of refund by ISO 4217. if empty then rule applied to refunds in any currency
+	Currency string `bson:"currency" json:"currency,omitempty" validate:"omitempty,len=3"`
+	// identifier of payment method of order. if empty then rule applied to orders paid by any payment method
+	PaymentMethodId string `bson:"payment_method_id" json:"payment_method_id,omitempty" validate:"omitempty,hexadecimal,len=24"`
+	// identifier of user who created rule
+	CreatorId string `bson:"creator_id" json:"creator_id"`
+	// date of rule creation
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// RefundApproval is refund request which waits for approval by second user. Refund created in billing
+// server only after approval
+type RefundApproval struct {
+	// unique approval identifier
+	Id bson.ObjectId `bson:"_id" json:"id"`
+	// unique order identifier
+	OrderId string `bson:"order_id" json:"order_id"`
+	// identifier of merchant of order
+	MerchantId string `bson:"merchant_id" json:"merchant_id"`
+	// identifier of rule which requires approval of refund
+	RuleId bson.ObjectId `bson:"rule_id" json:"rule_id"`
+	// refund amount
+	Amount float64 `bson:"amount" json:"amount"`
+	// refund currency by ISO 4217
+	Currency string `bson:"currency" json:"currency"`
+	// refund reason code
+	Reason string `bson:"reason" json:"reason"`
+	// identifier of user who requested refund
+	CreatorId string `bson:"creator_id" json:"creator_id"`
+	// approval status: pending_approval, approved or rejected
+	Status string `bson:"status" json:"status"`
+	// identifier of user who approved or rejected refund
+	ApproverId string `bson:"approver_id" json:"approver_id,omitempty"`
+	// reason why refund rejected
+	Comment string `bson:"comment" json:"comment,omitempty"`
+	// identifier of refund created in billing server after approval
+	RefundId string `bson:"refund_id" json:"refund_id,omitempty"`
+	// date of refund request
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	// date when refund approved or rejected
+	DecidedAt *time.Time `bson:"decided_at" json:"decided_at,omitempty"`
+}
+
+// RefundApprovalList is page of refunds approvals of merchant
+type RefundApprovalList struct {
+	// total count of approvals matched by filter
+	Count int `json:"count"`
+	// approvals on requested page
+	Items []*RefundApproval `json:"items"`
+}
+
+// RefundApprovalDecision contains details of decision about refund waiting for approval
+type RefundApprovalDecision struct {
+	// reason why refund rejected
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+// Check that refund to order must be approved by rule
+func (r *RefundApprovalRule) Match(o *Order, currency string, amount float64) bool {
+	if amount <= r.Amount {
+		return false
+	}
+
+	if r.Currency != "" && r.Currency != currency {
+		return false
+	}
+
+	if r.PaymentMethodId != "" && (o.PaymentMethod == nil || o.PaymentMethod.Id.Hex() != r.PaymentMethodId) {
+		return false
+	}
+
+	return true
+}
+
+// Find first rule which requires approval of refund to order. If approval not required then nil will be returned
+func FindRefundApprovalRule(rules []*RefundApprovalRule, o *Order, currency string, amount float64) *RefundApprovalRule {
+	for _, r := range rules {
+		if r.Match(o, currency, amount) {
+			return r
+		}
+	}
+
+	return nil
+}
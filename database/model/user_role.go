@@ -0,0 +1,15 @@
+package model
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"time"
+)
+
+type UserRole struct {
+	Id        bson.ObjectId `bson:"_id" json:"id"`
+	UserId    string        `bson:"user_id" json:"user_id"`
+	Roles     []string      `bson:"roles" json:"roles"`
+	Merchants []string      `bson:"merchants" json:"merchants"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+}
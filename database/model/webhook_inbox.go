@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	WebhookInboxTypePayment = "payment"
+	WebhookInboxTypeRefund  = "refund"
+
+	WebhookInboxStatusProcessing = "processing"
+	WebhookInboxStatusProcessed  = "processed"
+	WebhookInboxStatusFailed     = "failed"
+	WebhookInboxStatusStale      = "stale"
+)
+
+// WebhookInboxRecord is a callback of payment system saved before processing. Key of record built from
+// provider, payment identifier and status of payment (and identifier of refund for refund callbacks),
+// so repeated deliveries of the same callback have the same key
+type WebhookInboxRecord struct {
+	Id           string    `bson:"_id" json:"id"`
+	Provider     string    `bson:"provider" json:"provider"`
+	Type         string    `bson:"type" json:"type"`
+	OrderId      string    `bson:"order_id" json:"order_id"`
+	PaymentId    string    `bson:"payment_id" json:"payment_id"`
+	RefundId     string    `bson:"refund_id,omitempty" json:"refund_id,omitempty"`
+	PaymentState string    `bson:"payment_state" json:"payment_state"`
+	CallbackTime time.Time `bson:"callback_time" json:"callback_time"`
+	RawBody      string    `bson:"raw_body" json:"raw_body"`
+	Signature    string    `bson:"signature" json:"signature"`
+	Status       string    `bson:"status" json:"status"`
+	Error        string    `bson:"error,omitempty" json:"error,omitempty"`
+	// record in processing status can be taken by other delivery after this time
+	LockedUntil time.Time `bson:"locked_until" json:"locked_until"`
+	// count of deliveries of callback, including first delivery
+	Deliveries     int       `bson:"deliveries" json:"deliveries"`
+	LastDeliveryAt time.Time `bson:"last_delivery_at" json:"last_delivery_at"`
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `bson:"updated_at" json:"updated_at"`
+	// signature of callback checked by settings of payment system before processing. Record of callback
+	// which signature checked only by billing server saved to inbox after successful processing
+	SignatureChecked bool `bson:"-" json:"-"`
+}
+
+// Record can be replaced by new delivery of callback if it wasn't processed successfully and isn't processed now
+func (r *WebhookInboxRecord) IsRetryable(now time.Time) bool {
+	switch r.Status {
+	case WebhookInboxStatusFailed, WebhookInboxStatusStale:
+		return true
+	case WebhookInboxStatusProcessing:
+		return !r.LockedUntil.After(now)
+	}
+
+	return false
+}
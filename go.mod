@@ -9,7 +9,9 @@ require (
 	github.com/apex/log v1.1.0
 	github.com/centrifugal/gocent v2.0.2+incompatible
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/getkin/kin-openapi v0.2.0
 	github.com/globalsign/mgo v0.0.0-20181015135952-eeefdecb41b8
+	github.com/go-redis/redis v6.15.2+incompatible
 	github.com/go-ini/ini v1.42.0 // indirect
 	github.com/golang/protobuf v1.3.1 // indirects
 	github.com/google/uuid v1.1.0
@@ -26,6 +28,7 @@ require (
 	github.com/paysuper/paysuper-payment-link v0.0.0-20190410180823-800306b3fd7c
 	github.com/paysuper/paysuper-recurring-repository v1.0.105
 	github.com/paysuper/paysuper-tax-service v0.0.0-20190308105725-016a09c27fbd
+	github.com/prometheus/client_golang v0.9.2
 	github.com/rainycape/unidecode v0.0.0-20150907023854-cb7f23ec59be // indirect
 	github.com/satori/go.uuid v1.2.0
 	github.com/sidmal/slug v1.4.2
@@ -34,6 +37,7 @@ require (
 	github.com/swaggo/swag v1.4.1 // indirect
 	github.com/ttacon/libphonenumber v1.0.1
 	github.com/xakep666/mongo-migrate v0.1.0
+	go.opencensus.io v0.19.0
 	go.uber.org/zap v1.9.1
 	gopkg.in/go-playground/validator.v9 v9.26.0
 	gopkg.in/mgo.v2 v2.0.0-20180705113604-9856a29383ce
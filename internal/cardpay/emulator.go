@@ -0,0 +1,760 @@
+package cardpay
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/paysuper/paysuper-management-api/payment_system/entity"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PathAuthToken        = "/api/auth/token"
+	PathPayments         = "/api/payments"
+	PathRefunds          = "/api/refunds"
+	Path3ds              = "/3ds/"
+	PathWebHookPayment   = "/webhook/cardpay/notify"
+	PathWebHookRefund    = "/webhook/cardpay/refund"
+	PaymentStatusPending = "IN_PROGRESS"
+
+	// test cards of emulator, payments by other cards approved
+	CardApprove         = "4000000000000002"
+	CardDecline         = "4000000000000010"
+	CardCancel          = "4000000000000028"
+	Card3ds             = "4000000000000036"
+	CardDelayedCallback = "4000000000000044"
+
+	DeclineCodeDefault   = "01"
+	DeclineReasonDefault = "Declined by emulator"
+
+	defaultTokenLifetime        = 15 * time.Minute
+	defaultRefreshTokenLifetime = time.Hour
+	defaultCallbackDelay        = 5 * time.Second
+
+	dateFormat           = "2006-01-02T15:04:05Z"
+	grantTypePassword    = "password"
+	grantTypeRefresh     = "refresh_token"
+	paymentMethodCard    = "BANKCARD"
+	paymentMethodBitcoin = "BITCOIN"
+	issuingCountryCode   = "US"
+
+	errorInvalidCredentials = "invalid terminal credentials"
+	errorInvalidToken       = "access token is invalid or expired"
+	errorInvalidRequest     = "request is invalid: %s"
+	errorPaymentNotFound    = "payment not found"
+	errorRefundNotAllowed   = "payment can't be refunded in status %s"
+	errorRefundAmount       = "refund amount exceeds remaining amount of payment"
+)
+
+var page3ds = template.Must(template.New("3ds").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<h1>CardPay emulator 3-D Secure</h1>
+<p>Payment {{.Id}}: {{.Amount}} {{.Currency}}</p>
+<form method="post">
+<button name="result" value="success">Confirm</button>
+<button name="result" value="failure">Fail</button>
+</form>
+</body>
+</html>
+`))
+
+// Scenario describes how emulator processes payment
+type Scenario struct {
+	// final status of payment: COMPLETED, DECLINED or CANCELLED
+	Status string
+	// payer redirected to 3-D Secure page of emulator and payment processed only after confirmation on it
+	ThreeDS bool
+	// delay between processing of payment and callback about it
+	CallbackDelay time.Duration
+	DeclineCode   string
+	DeclineReason string
+}
+
+// Terminal is credentials of CardPay terminal accepted by emulator
+type Terminal struct {
+	Code           string
+	Password       string
+	CallbackSecret string
+}
+
+// Callback is result of callback sent by emulator
+type Callback struct {
+	Path       string
+	Body       []byte
+	Signature  string
+	StatusCode int
+	Err        error
+}
+
+// Emulator is in-memory emulation of CardPay API which sends signed callbacks to payments API. Emulator is
+// http.Handler, so it can be started by httptest.NewServer in tests or by http.ListenAndServe as dev server
+type Emulator struct {
+	// url of payments API which receives callbacks on webhook paths, callbacks not sent if url is empty
+	CallbackUrl string
+	// public url of emulator used in redirects to 3-D Secure page
+	Url                  string
+	TokenLifetime        time.Duration
+	RefreshTokenLifetime time.Duration
+	Client               *http.Client
+
+	mu            sync.Mutex
+	terminals     map[string]*Terminal
+	tokens        map[string]*issuedToken
+	refreshTokens map[string]*issuedToken
+	payments      map[string]*payment
+	scenarios     map[string]*Scenario
+	cards         map[string]*Scenario
+	callbacks     []*Callback
+	lastId        int64
+	pending       sync.WaitGroup
+	mux           *http.ServeMux
+}
+
+type issuedToken struct {
+	terminal string
+	expire   time.Time
+}
+
+type payment struct {
+	id        string
+	terminal  string
+	order     *entity.CardPayOrder
+	scenario  *Scenario
+	status    string
+	created   time.Time
+	remaining float64
+}
+
+type errorResponse struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// Create emulator which accepts credentials of terminals
+func NewEmulator(terminals ...*Terminal) *Emulator {
+	e := &Emulator{
+		TokenLifetime:        defaultTokenLifetime,
+		RefreshTokenLifetime: defaultRefreshTokenLifetime,
+		Client:               &http.Client{Timeout: 10 * time.Second},
+		terminals:            make(map[string]*Terminal),
+		tokens:               make(map[string]*issuedToken),
+		refreshTokens:        make(map[string]*issuedToken),
+		payments:             make(map[string]*payment),
+		scenarios:            make(map[string]*Scenario),
+		cards: map[string]*Scenario{
+			CardApprove:         {Status: entity.CardPayPaymentResponseStatusCompleted},
+			CardDecline:         {Status: entity.CardPayPaymentResponseStatusDeclined},
+			CardCancel:          {Status: entity.CardPayPaymentResponseStatusCancelled},
+			Card3ds:             {Status: entity.CardPayPaymentResponseStatusCompleted, ThreeDS: true},
+			CardDelayedCallback: {Status: entity.CardPayPaymentResponseStatusCompleted, CallbackDelay: defaultCallbackDelay},
+		},
+	}
+
+	for _, t := range terminals {
+		e.terminals[t.Code] = t
+	}
+
+	e.mux = http.NewServeMux()
+	e.mux.HandleFunc(PathAuthToken, e.handleToken)
+	e.mux.HandleFunc(PathPayments, e.handleCreatePayment)
+	e.mux.HandleFunc(PathPayments+"/", e.handleGetPayment)
+	e.mux.HandleFunc(PathRefunds, e.handleRefund)
+	e.mux.HandleFunc(Path3ds, e.handle3ds)
+
+	return e
+}
+
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+// Set scenario of payments of merchant order. Scenario of order has priority over scenario of test card
+func (e *Emulator) SetScenario(orderId string, s *Scenario) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.scenarios[orderId] = s
+}
+
+// Set scenario of payments by card
+func (e *Emulator) SetCardScenario(pan string, s *Scenario) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.cards[pan] = s
+}
+
+// Wait until all scheduled callbacks sent
+func (e *Emulator) Wait() {
+	e.pending.Wait()
+}
+
+// Get results of sent callbacks in order of sending
+func (e *Emulator) GetCallbacks() []*Callback {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]*Callback(nil), e.callbacks...)
+}
+
+// Get identifier of last payment of merchant order, empty string returned if payment not found
+func (e *Emulator) GetPaymentId(orderId string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var id string
+	var last time.Time
+
+	for _, p := range e.payments {
+		if p.order.MerchantOrder.Id == orderId && !p.created.Before(last) {
+			id, last = p.id, p.created
+		}
+	}
+
+	return id
+}
+
+// Get current status of payment, empty string returned if payment not found
+func (e *Emulator) GetPaymentStatus(id string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if p, ok := e.payments[id]; ok {
+		return p.status
+	}
+
+	return ""
+}
+
+func (e *Emulator) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		e.writeError(w, http.StatusBadRequest, fmt.Sprintf(errorInvalidRequest, err))
+		return
+	}
+
+	code := r.PostForm.Get("terminal_code")
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	t, ok := e.terminals[code]
+
+	if !ok {
+		e.writeError(w, http.StatusUnauthorized, errorInvalidCredentials)
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case grantTypePassword:
+		if r.PostForm.Get("password") != t.Password {
+			e.writeError(w, http.StatusUnauthorized, errorInvalidCredentials)
+			return
+		}
+	case grantTypeRefresh:
+		rt := r.PostForm.Get("refresh_token")
+		it, ok := e.refreshTokens[rt]
+
+		if !ok || it.terminal != code || time.Now().After(it.expire) {
+			e.writeError(w, http.StatusUnauthorized, errorInvalidToken)
+			return
+		}
+
+		delete(e.refreshTokens, rt)
+	default:
+		e.writeError(w, http.StatusBadRequest, fmt.Sprintf(errorInvalidRequest, "grant_type"))
+		return
+	}
+
+	now := time.Now()
+	access, refresh := newSecret(), newSecret()
+
+	e.tokens[access] = &issuedToken{terminal: code, expire: now.Add(e.TokenLifetime)}
+	e.refreshTokens[refresh] = &issuedToken{terminal: code, expire: now.Add(e.RefreshTokenLifetime)}
+
+	e.writeJson(w, http.StatusOK, map[string]interface{}{
+		"token_type":         "bearer",
+		"access_token":       access,
+		"refresh_token":      refresh,
+		"expires_in":         int(e.TokenLifetime / time.Second),
+		"refresh_expires_in": int(e.RefreshTokenLifetime / time.Second),
+	})
+}
+
+func (e *Emulator) handleCreatePayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	terminal, ok := e.authorize(w, r)
+
+	if !ok {
+		return
+	}
+
+	req := &entity.CardPayOrder{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		e.writeError(w, http.StatusBadRequest, fmt.Sprintf(errorInvalidRequest, err))
+		return
+	}
+
+	if err := validateOrder(req); err != "" {
+		e.writeError(w, http.StatusBadRequest, fmt.Sprintf(errorInvalidRequest, err))
+		return
+	}
+
+	e.mu.Lock()
+
+	e.lastId++
+	p := &payment{
+		id:        strconv.FormatInt(e.lastId, 10),
+		terminal:  terminal,
+		order:     req,
+		scenario:  e.getScenario(req),
+		status:    PaymentStatusPending,
+		created:   time.Now().UTC(),
+		remaining: req.PaymentData.Amount,
+	}
+	e.payments[p.id] = p
+
+	e.mu.Unlock()
+
+	redirectUrl := e.Url + Path3ds + p.id
+
+	if !p.scenario.ThreeDS {
+		redirectUrl = e.process(p, p.scenario.Status)
+	}
+
+	e.writeJson(w, http.StatusOK, &entity.CardPayOrderResponse{RedirectUrl: redirectUrl})
+}
+
+func (e *Emulator) handleGetPayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	terminal, ok := e.authorize(w, r)
+
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	p, ok := e.payments[strings.TrimPrefix(r.URL.Path, PathPayments+"/")]
+
+	if !ok || p.terminal != terminal {
+		e.mu.Unlock()
+		e.writeError(w, http.StatusNotFound, errorPaymentNotFound)
+		return
+	}
+
+	data := p.getPaymentData()
+	e.mu.Unlock()
+
+	e.writeJson(w, http.StatusOK, &entity.CardPayPaymentResponse{PaymentData: data})
+}
+
+func (e *Emulator) handleRefund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	terminal, ok := e.authorize(w, r)
+
+	if !ok {
+		return
+	}
+
+	req := &entity.CardPayRefund{}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		e.writeError(w, http.StatusBadRequest, fmt.Sprintf(errorInvalidRequest, err))
+		return
+	}
+
+	if req.PaymentData == nil || req.RefundData == nil || req.RefundData.Amount <= 0 {
+		e.writeError(w, http.StatusBadRequest, fmt.Sprintf(errorInvalidRequest, "refund_data"))
+		return
+	}
+
+	e.mu.Lock()
+
+	p, ok := e.payments[req.PaymentData.Id]
+
+	if !ok || p.terminal != terminal {
+		e.mu.Unlock()
+		e.writeError(w, http.StatusNotFound, errorPaymentNotFound)
+		return
+	}
+
+	if p.status != entity.CardPayPaymentResponseStatusCompleted &&
+		p.status != entity.CardPayPaymentResponseStatusPartiallyRefunded {
+		e.mu.Unlock()
+		e.writeError(w, http.StatusBadRequest, fmt.Sprintf(errorRefundNotAllowed, p.status))
+		return
+	}
+
+	if req.RefundData.Currency != p.order.PaymentData.Currency || req.RefundData.Amount > p.remaining {
+		e.mu.Unlock()
+		e.writeError(w, http.StatusBadRequest, errorRefundAmount)
+		return
+	}
+
+	e.lastId++
+	p.remaining = math.Round((p.remaining-req.RefundData.Amount)*100) / 100
+	p.status = entity.CardPayPaymentResponseStatusPartiallyRefunded
+
+	if p.remaining <= 0 {
+		p.status = entity.CardPayPaymentResponseStatusRefunded
+	}
+
+	refund := &entity.CardPayRefundNotificationRefundData{
+		Id:       strconv.FormatInt(e.lastId, 10),
+		Amount:   req.RefundData.Amount,
+		Currency: req.RefundData.Currency,
+		Created:  time.Now().UTC().Format(dateFormat),
+		Status:   entity.CardPayPaymentResponseStatusCompleted,
+		AuthCode: newSecret()[:6],
+		Rrn:      newSecret()[:12],
+	}
+	callback := &entity.CardPayRefundNotificationWebHookRequest{
+		MerchantOrder: &entity.CardPayMerchantOrder{Id: p.order.MerchantOrder.Id},
+		PaymentMethod: p.order.PaymentMethod,
+		CallbackTime:  refund.Created,
+		Customer:      p.order.Customer,
+		PaymentData: &entity.CardPayRefundNotificationPaymentData{
+			Id:              p.id,
+			RemainingAmount: p.remaining,
+		},
+		RefundData: refund,
+	}
+	secret := e.terminals[terminal].CallbackSecret
+
+	e.mu.Unlock()
+
+	e.schedule(PathWebHookRefund, callback, secret, 0)
+	e.writeJson(w, http.StatusCreated, map[string]interface{}{
+		"refund_data":  refund,
+		"payment_data": callback.PaymentData,
+	})
+}
+
+// Show 3-D Secure page of payment on GET request and complete payment on POST request from this page.
+// Payment declined if payer failed 3-D Secure, otherwise payment completed by its scenario
+func (e *Emulator) handle3ds(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, Path3ds)
+
+	e.mu.Lock()
+	p, ok := e.payments[id]
+	pending := ok && p.status == PaymentStatusPending
+	e.mu.Unlock()
+
+	if !pending || !p.scenario.ThreeDS {
+		e.writeError(w, http.StatusNotFound, errorPaymentNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = page3ds.Execute(w, map[string]interface{}{
+			"Id":       p.id,
+			"Amount":   p.order.PaymentData.Amount,
+			"Currency": p.order.PaymentData.Currency,
+		})
+	case http.MethodPost:
+		status := p.scenario.Status
+
+		if r.FormValue("result") == "failure" {
+			status = entity.CardPayPaymentResponseStatusDeclined
+		}
+
+		redirectUrl := e.process(p, status)
+
+		if redirectUrl == "" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		http.Redirect(w, r, redirectUrl, http.StatusFound)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Set final status of payment, schedule callback about it and return url to redirect payer to.
+// Payment processed only once, so repeated confirmation of 3-D Secure doesn't send callback again
+func (e *Emulator) process(p *payment, status string) string {
+	e.mu.Lock()
+
+	if p.status != PaymentStatusPending {
+		e.mu.Unlock()
+		return ""
+	}
+
+	p.status = status
+	data := p.getPaymentData()
+	callback := &entity.CardPayPaymentNotificationWebHookRequest{
+		MerchantOrder: &entity.CardPayMerchantOrder{Id: p.order.MerchantOrder.Id},
+		PaymentMethod: p.order.PaymentMethod,
+		CallbackTime:  time.Now().UTC().Add(p.scenario.CallbackDelay).Format(dateFormat),
+		Customer:      p.order.Customer,
+		PaymentData:   data,
+	}
+
+	switch p.order.PaymentMethod {
+	case paymentMethodCard:
+		card := p.order.CardAccount.Card
+		callback.CardAccount = &entity.CardPayBankCardAccountResponse{
+			Holder:             card.HolderName,
+			IssuingCountryCode: issuingCountryCode,
+			MaskedPan:          card.Pan[:6] + "..." + card.Pan[len(card.Pan)-4:],
+			Token:              newSecret(),
+		}
+	case paymentMethodBitcoin:
+		callback.CryptoCurrencyAccount = &entity.CardPayCryptoCurrencyAccountResponse{
+			CryptoAddress:       p.order.CryptoCurrencyAccount.RollbackAddress,
+			CryptoTransactionId: newSecret(),
+			PrcAmount:           p.order.PaymentData.Amount,
+			PrcCurrency:         p.order.PaymentData.Currency,
+		}
+	default:
+		callback.EWalletAccount = p.order.EWalletAccount
+	}
+
+	secret := e.terminals[p.terminal].CallbackSecret
+
+	e.mu.Unlock()
+
+	e.schedule(PathWebHookPayment, callback, secret, p.scenario.CallbackDelay)
+
+	urls := p.order.ReturnUrls
+
+	if urls == nil {
+		return ""
+	}
+
+	switch status {
+	case entity.CardPayPaymentResponseStatusDeclined:
+		return urls.DeclineUrl
+	case entity.CardPayPaymentResponseStatusCancelled:
+		return urls.CancelUrl
+	}
+
+	return urls.SuccessUrl
+}
+
+// Send callback signed by callback secret of terminal to payments API after delay
+func (e *Emulator) schedule(path string, req interface{}, secret string, delay time.Duration) {
+	if e.CallbackUrl == "" {
+		return
+	}
+
+	b, err := json.Marshal(req)
+
+	if err != nil {
+		return
+	}
+
+	h := sha512.New()
+	h.Write([]byte(string(b) + secret))
+
+	cb := &Callback{Path: path, Body: b, Signature: hex.EncodeToString(h.Sum(nil))}
+
+	e.pending.Add(1)
+
+	go func() {
+		defer e.pending.Done()
+
+		time.Sleep(delay)
+		e.send(cb)
+
+		e.mu.Lock()
+		e.callbacks = append(e.callbacks, cb)
+		e.mu.Unlock()
+	}()
+}
+
+func (e *Emulator) send(cb *Callback) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(e.CallbackUrl, "/")+cb.Path, bytes.NewReader(cb.Body))
+
+	if err != nil {
+		cb.Err = err
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(entity.CardPayPaymentResponseHeaderSignature, cb.Signature)
+
+	rsp, err := e.Client.Do(req)
+
+	if err != nil {
+		cb.Err = err
+		return
+	}
+
+	cb.StatusCode = rsp.StatusCode
+
+	if err := rsp.Body.Close(); err != nil {
+		return
+	}
+}
+
+// Get terminal of access token from authorization header. Response with error written if token is invalid
+func (e *Emulator) authorize(w http.ResponseWriter, r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(parts) == 2 && strings.EqualFold(parts[0], "bearer") {
+		if t, ok := e.tokens[parts[1]]; ok && time.Now().Before(t.expire) {
+			return t.terminal, true
+		}
+	}
+
+	e.writeError(w, http.StatusUnauthorized, errorInvalidToken)
+
+	return "", false
+}
+
+// Get scenario of payment by merchant order, then by card. Payment approved if scenario not set
+func (e *Emulator) getScenario(o *entity.CardPayOrder) *Scenario {
+	s, ok := e.scenarios[o.MerchantOrder.Id]
+
+	if !ok && o.CardAccount != nil && o.CardAccount.Card != nil {
+		s, ok = e.cards[o.CardAccount.Card.Pan]
+	}
+
+	if !ok || s == nil {
+		s = &Scenario{Status: entity.CardPayPaymentResponseStatusCompleted}
+	}
+
+	return s
+}
+
+func (p *payment) getPaymentData() *entity.CardPayPaymentDataResponse {
+	data := &entity.CardPayPaymentDataResponse{
+		Id:       p.id,
+		Amount:   p.order.PaymentData.Amount,
+		Created:  p.created.Format(dateFormat),
+		Currency: p.order.PaymentData.Currency,
+		Is3d:     p.scenario.ThreeDS,
+		Note:     p.order.PaymentData.Note,
+		Status:   p.status,
+	}
+
+	if p.status == entity.CardPayPaymentResponseStatusDeclined {
+		data.DeclineCode = p.scenario.DeclineCode
+		data.DeclineReason = p.scenario.DeclineReason
+
+		if data.DeclineCode == "" {
+			data.DeclineCode, data.DeclineReason = DeclineCodeDefault, DeclineReasonDefault
+		}
+	}
+
+	return data
+}
+
+func (e *Emulator) writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (e *Emulator) writeError(w http.ResponseWriter, status int, message string) {
+	e.writeJson(w, status, &errorResponse{Name: http.StatusText(status), Message: message})
+}
+
+// Check required fields of payment request, name of first invalid field returned
+func validateOrder(o *entity.CardPayOrder) string {
+	switch {
+	case o.Request == nil || o.Request.Id == "":
+		return "request.id"
+	case o.MerchantOrder == nil || o.MerchantOrder.Id == "":
+		return "merchant_order.id"
+	case o.PaymentData == nil || o.PaymentData.Amount <= 0:
+		return "payment_data.amount"
+	case o.PaymentData.Currency == "":
+		return "payment_data.currency"
+	case o.Customer == nil || o.Customer.Email == "":
+		return "customer.email"
+	}
+
+	switch o.PaymentMethod {
+	case paymentMethodCard:
+		if o.CardAccount == nil || o.CardAccount.Card == nil || len(o.CardAccount.Card.Pan) < 12 {
+			return "card_account.card.pan"
+		}
+	case paymentMethodBitcoin:
+		if o.CryptoCurrencyAccount == nil || o.CryptoCurrencyAccount.RollbackAddress == "" {
+			return "cryptocurrency_account.rollback_address"
+		}
+	case "":
+		return "payment_method"
+	default:
+		if o.EWalletAccount == nil || o.EWalletAccount.Id == "" {
+			return "ewallet_account.id"
+		}
+	}
+
+	return ""
+}
+
+func newSecret() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// Parse terminals from list of code:password:callback_secret separated by comma
+func ParseTerminals(s string) ([]*Terminal, error) {
+	var terminals []*Terminal
+
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("terminal %q must be in format code:password:callback_secret", item)
+		}
+
+		terminals = append(terminals, &Terminal{Code: parts[0], Password: parts[1], CallbackSecret: parts[2]})
+	}
+
+	return terminals, nil
+}
+
+// Check that url is absolute url of http server
+func ValidateUrl(s string) error {
+	u, err := url.ParseRequestURI(s)
+
+	if err != nil || !u.IsAbs() {
+		return fmt.Errorf("url %q must be absolute", s)
+	}
+
+	return nil
+}
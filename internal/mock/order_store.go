@@ -0,0 +1,38 @@
+package mock
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/paysuper/paysuper-management-api/database/model"
+)
+
+type OrderStoreMock struct {
+	orders map[string]*model.Order
+}
+
+func NewOrderStoreMock(orders ...*model.Order) *OrderStoreMock {
+	s := &OrderStoreMock{orders: make(map[string]*model.Order)}
+
+	for _, o := range orders {
+		s.orders[o.Uuid] = o
+	}
+
+	return s
+}
+
+func (s *OrderStoreMock) FindByUuid(uuid string) (*model.Order, error) {
+	o, ok := s.orders[uuid]
+
+	if !ok {
+		return nil, mgo.ErrNotFound
+	}
+
+	return o, nil
+}
+
+func (s *OrderStoreMock) GetTimeline(o *model.Order) (*model.OrderTimeline, error) {
+	return &model.OrderTimeline{
+		OrderId: o.Id,
+		Status:  model.NewOrderStatus(o.Status),
+		Items:   []*model.OrderEvent{},
+	}, nil
+}
@@ -0,0 +1,148 @@
+package mock
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"sync"
+	"time"
+)
+
+type RefundApprovalStoreMock struct {
+	mx        sync.Mutex
+	rules     []*model.RefundApprovalRule
+	approvals []*model.RefundApproval
+}
+
+func NewRefundApprovalStoreMock(rules ...*model.RefundApprovalRule) *RefundApprovalStoreMock {
+	return &RefundApprovalStoreMock{rules: rules}
+}
+
+func (s *RefundApprovalStoreMock) FindRules(merchantId string) ([]*model.RefundApprovalRule, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	rules := []*model.RefundApprovalRule{}
+
+	for _, r := range s.rules {
+		if r.MerchantId == merchantId {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, nil
+}
+
+func (s *RefundApprovalStoreMock) InsertRule(rule *model.RefundApprovalRule) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	rule.Id = bson.NewObjectId()
+	rule.CreatedAt = time.Now()
+	s.rules = append(s.rules, rule)
+
+	return nil
+}
+
+func (s *RefundApprovalStoreMock) DeleteRule(id bson.ObjectId, merchantId string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for i, r := range s.rules {
+		if r.Id == id && r.MerchantId == merchantId {
+			s.rules = append(s.rules[:i], s.rules[i+1:]...)
+			return nil
+		}
+	}
+
+	return mgo.ErrNotFound
+}
+
+func (s *RefundApprovalStoreMock) Insert(approval *model.RefundApproval) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	approval.Id = bson.NewObjectId()
+	approval.Status = model.RefundApprovalStatusPending
+	approval.CreatedAt = time.Now()
+
+	copied := *approval
+	s.approvals = append(s.approvals, &copied)
+
+	return nil
+}
+
+func (s *RefundApprovalStoreMock) FindById(id bson.ObjectId, orderId string) (*model.RefundApproval, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for _, a := range s.approvals {
+		if a.Id == id && a.OrderId == orderId {
+			copied := *a
+			return &copied, nil
+		}
+	}
+
+	return nil, mgo.ErrNotFound
+}
+
+func (s *RefundApprovalStoreMock) FindPendingByOrderId(orderId string) ([]*model.RefundApproval, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var approvals []*model.RefundApproval
+
+	for _, a := range s.approvals {
+		if a.OrderId == orderId && a.Status == model.RefundApprovalStatusPending {
+			copied := *a
+			approvals = append(approvals, &copied)
+		}
+	}
+
+	return approvals, nil
+}
+
+func (s *RefundApprovalStoreMock) FindByMerchantId(
+	merchantId string,
+	status string,
+	limit int32,
+	offset int32,
+) (*model.RefundApprovalList, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	list := &model.RefundApprovalList{Items: []*model.RefundApproval{}}
+
+	for i := len(s.approvals) - 1; i >= 0; i-- {
+		a := s.approvals[i]
+
+		if a.MerchantId != merchantId || (status != "" && a.Status != status) {
+			continue
+		}
+
+		if list.Count >= int(offset) && len(list.Items) < int(limit) {
+			copied := *a
+			list.Items = append(list.Items, &copied)
+		}
+
+		list.Count++
+	}
+
+	return list, nil
+}
+
+func (s *RefundApprovalStoreMock) UpdateStatus(approval *model.RefundApproval, prev string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for i, a := range s.approvals {
+		if a.Id == approval.Id && a.Status == prev {
+			copied := *approval
+			s.approvals[i] = &copied
+
+			return nil
+		}
+	}
+
+	return mgo.ErrNotFound
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/paysuper/paysuper-management-api/database/dao"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"go.uber.org/zap"
+	"time"
+)
+
+type CustomerTokenManager Manager
+
+func InitCustomerTokenManager(database dao.Database, logger *zap.SugaredLogger) *CustomerTokenManager {
+	return &CustomerTokenManager{Database: database, Logger: logger}
+}
+
+func (cm *CustomerTokenManager) Insert(t *model.CustomerToken) error {
+	err := cm.Database.Repository(TableCustomerToken).InsertCustomerToken(t)
+
+	if err != nil {
+		cm.Logger.Errorf("Query to insert to table \"%s\" ended with error: %s", TableCustomerToken, err)
+	}
+
+	return err
+}
+
+// Get token by identifier. If token not found then nil returned
+func (cm *CustomerTokenManager) Get(id string) (*model.CustomerToken, error) {
+	t, err := cm.Database.Repository(TableCustomerToken).FindCustomerTokenById(id)
+
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		cm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableCustomerToken, err)
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (cm *CustomerTokenManager) FindActive(
+	projectId string,
+	userId string,
+	limit int,
+	offset int,
+) ([]*model.CustomerToken, int, error) {
+	rep := cm.Database.Repository(TableCustomerToken)
+	now := time.Now()
+	tokens, err := rep.FindActiveCustomerTokens(projectId, userId, now, limit, offset)
+
+	if err != nil {
+		cm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableCustomerToken, err)
+		return nil, 0, err
+	}
+
+	count, err := rep.CountActiveCustomerTokens(projectId, userId, now)
+
+	if err != nil {
+		cm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableCustomerToken, err)
+		return nil, 0, err
+	}
+
+	return tokens, count, nil
+}
+
+// Revoke active token. If token not found or isn't active then false returned
+func (cm *CustomerTokenManager) Revoke(id string) (bool, error) {
+	err := cm.Database.Repository(TableCustomerToken).RevokeCustomerToken(id, time.Now())
+
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		cm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableCustomerToken, err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (cm *CustomerTokenManager) RevokeByUserId(projectId string, userId string) (int, error) {
+	count, err := cm.Database.Repository(TableCustomerToken).RevokeCustomerTokensByUserId(projectId, userId, time.Now())
+
+	if err != nil {
+		cm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableCustomerToken, err)
+	}
+
+	return count, err
+}
@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/paysuper/paysuper-management-api/database/dao"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"go.uber.org/zap"
+	"time"
+)
+
+type IdempotencyManager Manager
+
+func InitIdempotencyManager(database dao.Database, logger *zap.SugaredLogger) *IdempotencyManager {
+	return &IdempotencyManager{Database: database, Logger: logger}
+}
+
+// Insert record if record with same key not exists or already expired. If not expired record
+// with same key exists, then existing record will be returned and inserted flag will be false
+func (im *IdempotencyManager) Insert(r *model.IdempotencyRecord) (*model.IdempotencyRecord, bool, error) {
+	rep := im.Database.Repository(TableIdempotency)
+	err := rep.InsertIdempotencyRecord(r)
+
+	if err == nil {
+		return r, true, nil
+	}
+
+	if !mgo.IsDup(err) {
+		im.Logger.Errorf("Query to insert to table \"%s\" ended with error: %s", TableIdempotency, err)
+		return nil, false, err
+	}
+
+	exists, err := rep.FindIdempotencyRecordById(r.Id)
+
+	if err != nil {
+		im.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableIdempotency, err)
+		return nil, false, err
+	}
+
+	if exists.ExpiresAt.After(time.Now()) {
+		return exists, false, nil
+	}
+
+	// mongo ttl monitor remove expired documents with delay, so expired record must be removed manually
+	err = rep.DeleteExpiredIdempotencyRecord(r.Id, time.Now())
+
+	if err != nil && err != mgo.ErrNotFound {
+		im.Logger.Errorf("Query to delete from table \"%s\" ended with error: %s", TableIdempotency, err)
+		return nil, false, err
+	}
+
+	err = rep.InsertIdempotencyRecord(r)
+
+	if err != nil {
+		im.Logger.Errorf("Query to insert to table \"%s\" ended with error: %s", TableIdempotency, err)
+		return nil, false, err
+	}
+
+	return r, true, nil
+}
+
+func (im *IdempotencyManager) Update(r *model.IdempotencyRecord) error {
+	err := im.Database.Repository(TableIdempotency).UpdateIdempotencyRecord(r)
+
+	if err != nil {
+		im.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableIdempotency, err)
+	}
+
+	return err
+}
+
+func (im *IdempotencyManager) Delete(id string) error {
+	err := im.Database.Repository(TableIdempotency).DeleteIdempotencyRecordById(id)
+
+	if err != nil && err != mgo.ErrNotFound {
+		im.Logger.Errorf("Query to delete from table \"%s\" ended with error: %s", TableIdempotency, err)
+		return err
+	}
+
+	return nil
+}
@@ -20,6 +20,16 @@ const (
 	TableLog           = "log"
 	TableVat           = "vat"
 	TableCommission    = "commission"
+	TableUserRole      = "user_role"
+	TableIdempotency   = "idempotency"
+	TableOrderEvent    = "order_event"
+	TableOrderFilter   = "order_filter"
+	TableOrderBulkJob  = "order_bulk_job"
+
+	TableRefundApproval     = "refund_approval"
+	TableRefundApprovalRule = "refund_approval_rule"
+	TableWebhookInbox       = "webhook_inbox"
+	TableCustomerToken      = "customer_token"
 
 	errorMessageMask = "Field validation for '%s' failed on the '%s' tag"
 )
@@ -3,13 +3,16 @@ package manager
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/ProtocolONE/geoip-service/pkg/proto"
 	"github.com/ProtocolONE/rabbitmq/pkg"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-management-api/database/dao"
 	"github.com/paysuper/paysuper-management-api/database/model"
@@ -21,6 +24,7 @@ import (
 	"github.com/streadway/amqp"
 	"go.uber.org/zap"
 	"net/url"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -54,6 +58,10 @@ const (
 	orderErrorOrderPSPAccountingCurrencyNotFound       = "unknown PSP accounting currency"
 	orderErrorOrderDeclined                            = "payment system decline order with specified identifier early"
 	orderErrorOrderCanceled                            = "payment system cancel order with specified identifier early"
+	orderErrorNotificationResendNotAllowed             = "notification can be resent only for paid orders which not completed by project"
+	orderErrorReviewAlreadyRequested                   = "order already marked for review"
+	orderErrorStatusChangedConcurrently                = "order status changed by other request"
+	orderErrorNotifierNotConfigured                    = "publisher of notifications to projects not configured"
 
 	orderErrorCreatePaymentRequiredFieldIdNotFound            = "required field with order identifier not found"
 	orderErrorCreatePaymentRequiredFieldPaymentMethodNotFound = "required field with payment method identifier not found"
@@ -67,24 +75,23 @@ const (
 type OrderManager struct {
 	*Manager
 
-	projectManager         *ProjectManager
-	paymentSystemManager   *PaymentSystemManager
-	paymentMethodManager   *PaymentMethodManager
-	currencyRateManager    *CurrencyRateManager
-	currencyManager        *CurrencyManager
-	pspAccountingCurrency  *model.Currency
-	paymentSystemsSettings *payment_system.PaymentSystemSetting
-	vatManager             *VatManager
-	commissionManager      *CommissionManager
-	centrifugoSecret       string
+	projectManager        *ProjectManager
+	paymentSystemManager  *PaymentSystemManager
+	paymentMethodManager  *PaymentMethodManager
+	currencyRateManager   *CurrencyRateManager
+	currencyManager       *CurrencyManager
+	pspAccountingCurrency *model.Currency
+	vatManager            *VatManager
+	commissionManager     *CommissionManager
+	centrifugoSecret      string
 
 	rep repository.RepositoryService
 	geo proto.GeoIpService
-	ctx context.Context
 	pub *rabbitmq.Broker
 }
 
 type check struct {
+	ctx           context.Context
 	order         *model.OrderScalar
 	project       *model.Project
 	oCurrency     *model.Currency
@@ -103,6 +110,12 @@ type FindAll struct {
 	Limit    int32
 	Offset   int32
 	SortBy   []string
+	// orders selected by cursor instead of limit and offset if paginator specified
+	Paginator *dao.CursorPaginator
+	// one of model.CountMode* constants, exact count calculated by default
+	CountMode string
+	// additional conditions of orders search which can't be specified by query parameters of orders list
+	Conditions bson.M
 }
 
 type OrderHttp struct {
@@ -129,14 +142,13 @@ func InitOrderManager(
 
 		rep: repository,
 		geo: geoService,
-		ctx: context.TODO(),
 		pub: publisher,
 	}
 
 	return om
 }
 
-func (om *OrderManager) Process(order *model.OrderScalar) (*model.Order, error) {
+func (om *OrderManager) Process(ctx context.Context, order *model.OrderScalar) (*model.Order, error) {
 	var pm *model.PaymentMethod
 	var pmOutcomeData *pmOutcomeData
 	var gRecord *proto.GeoIpDataResponse
@@ -163,6 +175,7 @@ func (om *OrderManager) Process(order *model.OrderScalar) (*model.Order, error)
 	}
 
 	check := &check{
+		ctx: ctx,
 		order: &model.OrderScalar{
 			Amount:           order.Amount,
 			Currency:         order.Currency,
@@ -230,7 +243,8 @@ func (om *OrderManager) Process(order *model.OrderScalar) (*model.Order, error)
 	}
 
 	nOrder := &model.Order{
-		Id: id,
+		Id:   id,
+		Uuid: uuid.New().String(),
 		Project: &model.ProjectOrder{
 			Id:                p.Id,
 			Name:              p.Name,
@@ -301,6 +315,8 @@ func (om *OrderManager) Process(order *model.OrderScalar) (*model.Order, error)
 		nOrder.UrlFail = *order.UrlFail
 	}
 
+	nOrder.SetSearchKeys()
+
 	if err = om.Database.Repository(TableOrder).InsertOrder(nOrder); err != nil {
 		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrder, err)
 
@@ -457,6 +473,17 @@ func (om *OrderManager) ProcessOrderCommissions(o *model.Order) (*model.Order, e
 	return o, nil
 }
 
+// Get order by public identifier. If order not exists then mgo.ErrNotFound will be returned
+func (om *OrderManager) FindByUuid(id string) (*model.Order, error) {
+	o, err := om.Database.Repository(TableOrder).FindOrderByUuid(id)
+
+	if err != nil && err != mgo.ErrNotFound {
+		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrder, err)
+	}
+
+	return o, err
+}
+
 func (om *OrderManager) FindById(id string) *model.Order {
 	o, err := om.Database.Repository(TableOrder).FindOrderById(bson.ObjectIdHex(id))
 
@@ -675,7 +702,7 @@ func (om *OrderManager) getOrderFixedPackage(c *check) (*proto.GeoIpDataResponse
 		region = *c.order.Region
 	}
 
-	data, err := om.geo.GetIpData(om.ctx, &proto.GeoIpDataRequest{IP: c.order.CreateOrderIp})
+	data, err := om.geo.GetIpData(c.ctx, &proto.GeoIpDataRequest{IP: c.order.CreateOrderIp})
 
 	if err != nil {
 		return nil, nil, errors.New(orderErrorPayerRegionUnknown + " ====> " + err.Error())
@@ -743,135 +770,216 @@ func (om *OrderManager) checkSignature(check *check) error {
 }
 
 func (om *OrderManager) FindAll(params *FindAll) (*model.OrderPaginate, error) {
-	var f bson.M
-	var pFilter []bson.ObjectId
+	f := om.getFindAllFilter(params)
+	rep := om.Database.Repository(TableOrder)
+	result := &model.OrderPaginate{CountMode: params.CountMode}
 
-	for k := range params.Projects {
-		pFilter = append(pFilter, k)
-	}
+	var err error
 
-	filter := bson.M{"project.id": bson.M{"$in": pFilter}}
+	switch params.CountMode {
+	case model.CountModeNone:
+		break
+	case model.CountModeEstimated:
+		result.Count, err = rep.GetOrdersCountEstimate(f, model.CountEstimateLimit)
 
-	if quickFilter, ok := params.Values[model.OrderFilterFieldQuickFilter]; ok {
-		r := bson.RegEx{Pattern: ".*" + quickFilter[0] + ".*", Options: "i"}
-
-		filter["$or"] = []bson.M{
-			{"project.name": bson.M{"$regex": r}},
-			{"project_account": bson.M{"$regex": r}},
-			{"project_order_id": bson.M{"$regex": r, "$exists": true}},
-			{"fixed_package.name": bson.M{"$regex": r, "$exists": true}},
-			{"payment_method.name": bson.M{"$regex": r, "$exists": true}},
-			{"id_string": bson.M{"$regex": r, "$exists": true}},
+		if result.Count < model.CountEstimateLimit {
+			result.CountMode = model.CountModeExact
 		}
-
-		f = filter
-	} else {
-		f = om.ProcessFilters(params.Values, filter)
+	default:
+		result.CountMode = model.CountModeExact
+		result.Count, err = rep.GetOrdersCountByConditions(f)
 	}
 
-	co, err := om.Database.Repository(TableOrder).GetOrdersCountByConditions(f)
-
 	if err != nil {
 		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrder, err)
 	}
 
-	o, err := om.Database.Repository(TableOrder).FindAllOrders(f, params.SortBy, params.Limit, params.Offset)
+	var o []*model.Order
+
+	if params.Paginator != nil {
+		o, err = rep.FindOrdersByCursor(f, params.Paginator)
+		result.NextCursor = params.Paginator.Next()
+		result.PrevCursor = params.Paginator.Prev()
+	} else {
+		o, err = rep.FindAllOrders(f, params.SortBy, params.Limit, params.Offset)
+	}
 
 	if err != nil {
 		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrder, err)
 	}
 
-	var ot []*model.OrderSimple
-
 	if o != nil && len(o) > 0 {
-		ot, err = om.transformOrders(o, params)
+		result.Items, err = om.transformOrders(o, params)
 
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return &model.OrderPaginate{Count: co, Items: ot}, nil
+	return result, nil
+}
+
+// Pass all orders matched by filters of FindAll to callback function one by one. Orders read from
+// database cursor, so any count of orders can be processed without loading all of them to memory.
+// Limit and offset of params ignored
+func (om *OrderManager) FindAllIterate(params *FindAll, fn func(*model.OrderSimple) error) error {
+	it := om.Database.Repository(TableOrder).IterateOrders(om.getFindAllFilter(params), params.SortBy)
+	o := &model.Order{}
+
+	for it.Next(o) {
+		err := fn(om.transformOrder(o, params))
+
+		if err != nil {
+			_ = it.Close()
+			return err
+		}
+
+		o = &model.Order{}
+	}
+
+	err := it.Close()
+
+	if err != nil {
+		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrder, err)
+	}
+
+	return err
+}
+
+func (om *OrderManager) getFindAllFilter(params *FindAll) bson.M {
+	var pFilter []bson.ObjectId
+
+	for k := range params.Projects {
+		pFilter = append(pFilter, k)
+	}
+
+	filter := bson.M{"project.id": bson.M{"$in": pFilter}}
+
+	if quickFilter, ok := params.Values[model.OrderFilterFieldQuickFilter]; ok {
+		// filters can add own $or condition, so condition of quick filter added by $and
+		if q := ParseOrderSearchQuery(quickFilter[0]); q.Value != "" {
+			filter["$and"] = []bson.M{q.GetFilter()}
+		}
+	}
+
+	for k, v := range params.Conditions {
+		filter[k] = v
+	}
+
+	return om.ProcessFilters(params.Values, filter)
+}
+
+// Get totals in merchant accounting currency of refunded and charged back orders matched by filters of FindAll
+func (om *OrderManager) GetReversalSummary(params *FindAll) (*model.OrderReversalSummary, error) {
+	a, err := om.Database.Repository(TableOrder).GetOrderAmountsByStatus(om.getFindAllFilter(params))
+
+	if err != nil {
+		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrder, err)
+		return nil, err
+	}
+
+	currency := ""
+
+	if params.Merchant.Banking != nil && params.Merchant.Banking.Currency != nil {
+		currency = params.Merchant.Banking.Currency.CodeA3
+	}
+
+	return model.NewOrderReversalSummary(currency, a), nil
 }
 
 func (om *OrderManager) transformOrders(orders []*model.Order, params *FindAll) ([]*model.OrderSimple, error) {
 	var tOrders []*model.OrderSimple
 
 	for _, oValue := range orders {
-		tOrder := &model.OrderSimple{
-			Id: oValue.Id,
-			Project: &model.SimpleItem{
-				Id:   oValue.Project.Id,
-				Name: oValue.Project.Name,
-			},
-			Account:        oValue.ProjectAccount,
-			ProjectOrderId: oValue.ProjectOrderId,
-			PayerData:      oValue.PayerData,
-			ProjectAmountIncome: &model.OrderSimpleAmountObject{
-				Amount: oValue.ProjectIncomeAmount,
-				Currency: &model.SimpleCurrency{
-					CodeInt: oValue.ProjectIncomeCurrency.CodeInt,
-					CodeA3:  oValue.ProjectIncomeCurrency.CodeA3,
-					Name:    oValue.ProjectIncomeCurrency.Name,
-				},
+		tOrders = append(tOrders, om.transformOrder(oValue, params))
+	}
+
+	return tOrders, nil
+}
+
+func (om *OrderManager) transformOrder(oValue *model.Order, params *FindAll) *model.OrderSimple {
+	tOrder := &model.OrderSimple{
+		Id: oValue.Id,
+		Project: &model.SimpleItem{
+			Id:   oValue.Project.Id,
+			Name: oValue.Project.Name,
+		},
+		Account:        oValue.ProjectAccount,
+		ProjectOrderId: oValue.ProjectOrderId,
+		PayerData:      oValue.PayerData,
+		ProjectAmountIncome: &model.OrderSimpleAmountObject{
+			Amount: oValue.ProjectIncomeAmount,
+			Currency: &model.SimpleCurrency{
+				CodeInt: oValue.ProjectIncomeCurrency.CodeInt,
+				CodeA3:  oValue.ProjectIncomeCurrency.CodeA3,
+				Name:    oValue.ProjectIncomeCurrency.Name,
 			},
-			Status: &model.Status{
-				Status:      oValue.Status,
-				Name:        model.OrderStatusesNames[oValue.Status],
-				Description: model.OrderStatusesDescription[oValue.Status],
+		},
+		Status: &model.Status{
+			Status:      oValue.Status,
+			Name:        model.OrderStatusesNames[oValue.Status],
+			Description: model.OrderStatusesDescription[oValue.Status],
+		},
+		VatAmount: oValue.VatAmount,
+		CreatedAt: oValue.CreatedAt.Unix(),
+	}
+
+	if oValue.PaymentMethodIncomeAmount > 0 {
+		tOrder.PaymentMethodAmountIncome = &model.OrderSimpleAmountObject{
+			Amount: oValue.PaymentMethodIncomeAmount,
+			Currency: &model.SimpleCurrency{
+				CodeInt: oValue.PaymentMethodIncomeCurrency.CodeInt,
+				CodeA3:  oValue.PaymentMethodIncomeCurrency.CodeA3,
+				Name:    oValue.PaymentMethodIncomeCurrency.Name,
 			},
-			VatAmount: oValue.VatAmount,
-			CreatedAt: oValue.CreatedAt.Unix(),
 		}
+	}
 
-		if oValue.PaymentMethodIncomeAmount > 0 {
-			tOrder.PaymentMethodAmountIncome = &model.OrderSimpleAmountObject{
-				Amount: oValue.PaymentMethodIncomeAmount,
-				Currency: &model.SimpleCurrency{
-					CodeInt: oValue.PaymentMethodIncomeCurrency.CodeInt,
-					CodeA3:  oValue.PaymentMethodIncomeCurrency.CodeA3,
-					Name:    oValue.PaymentMethodIncomeCurrency.Name,
-				},
-			}
+	if oValue.AmountInMerchantAccountingCurrency > 0 {
+		tOrder.MerchantAmountIncome = &model.OrderSimpleAmountObject{
+			Amount:   oValue.AmountInMerchantAccountingCurrency,
+			Currency: getMerchantAccountingCurrency(params.Merchant),
 		}
+	}
 
-		if oValue.AmountOutMerchantAccountingCurrency > 0 {
-			tOrder.ProjectAmountOutcome = &model.OrderSimpleAmountObject{
-				Amount: oValue.AmountOutMerchantAccountingCurrency,
-				Currency: &model.SimpleCurrency{
-					CodeInt: int(params.Merchant.Banking.Currency.CodeInt),
-					CodeA3:  params.Merchant.Banking.Currency.CodeA3,
-					Name:    &model.Name{EN: params.Merchant.Banking.Currency.Name.En},
-				},
-			}
+	if oValue.AmountOutMerchantAccountingCurrency > 0 {
+		tOrder.ProjectAmountOutcome = &model.OrderSimpleAmountObject{
+			Amount:   oValue.AmountOutMerchantAccountingCurrency,
+			Currency: getMerchantAccountingCurrency(params.Merchant),
 		}
+	}
 
-		if oValue.PaymentMethod != nil {
-			tOrder.PaymentMethod = &model.SimpleItem{
-				Id:   oValue.PaymentMethod.Id,
-				Name: oValue.PaymentMethod.Name,
-			}
-
-			tOrder.PspFeeAmount = oValue.PspFeeAmount
-			tOrder.PaymentSystemFeeAmount = oValue.PaymentSystemFeeAmount
-			tOrder.ProjectFeeAmount = oValue.ProjectFeeAmount
-			tOrder.ToPayerFeeAmount = oValue.ToPayerFeeAmount
-
-			tOrder.PaymentRequisites = om.preparePaymentRequisites(oValue)
+	if oValue.PaymentMethod != nil {
+		tOrder.PaymentMethod = &model.SimpleItem{
+			Id:   oValue.PaymentMethod.Id,
+			Name: oValue.PaymentMethod.Name,
 		}
 
-		if oValue.PaymentMethodOrderClosedAt != nil {
-			tOrder.ConfirmedAt = oValue.PaymentMethodOrderClosedAt.Unix()
-		}
+		tOrder.PspFeeAmount = oValue.PspFeeAmount
+		tOrder.PaymentSystemFeeAmount = oValue.PaymentSystemFeeAmount
+		tOrder.ProjectFeeAmount = oValue.ProjectFeeAmount
+		tOrder.ToPayerFeeAmount = oValue.ToPayerFeeAmount
 
-		if oValue.ProjectLastRequestedAt != nil {
-			tOrder.ClosedAt = oValue.ProjectLastRequestedAt.Unix()
-		}
+		tOrder.PaymentRequisites = om.preparePaymentRequisites(oValue)
+	}
 
-		tOrders = append(tOrders, tOrder)
+	if oValue.PaymentMethodOrderClosedAt != nil {
+		tOrder.ConfirmedAt = oValue.PaymentMethodOrderClosedAt.Unix()
 	}
 
-	return tOrders, nil
+	if oValue.ProjectLastRequestedAt != nil {
+		tOrder.ClosedAt = oValue.ProjectLastRequestedAt.Unix()
+	}
+
+	return tOrder
+}
+
+func getMerchantAccountingCurrency(merchant *billing.Merchant) *model.SimpleCurrency {
+	return &model.SimpleCurrency{
+		CodeInt: int(merchant.Banking.Currency.CodeInt),
+		CodeA3:  merchant.Banking.Currency.CodeA3,
+		Name:    &model.Name{EN: merchant.Banking.Currency.Name.En},
+	}
 }
 
 // Prepare payer payment requisites for frontend
@@ -947,7 +1055,7 @@ func (om *OrderManager) ProcessFilters(values url.Values, filter bson.M) bson.M
 	}
 
 	if a, ok := values[model.OrderFilterFieldAccount]; ok {
-		ar := bson.RegEx{Pattern: ".*" + a[0] + ".*", Options: "i"}
+		ar := bson.RegEx{Pattern: ".*" + regexp.QuoteMeta(a[0]) + ".*", Options: "i"}
 		filter["$or"] = bson.M{"project_account": ar, "pm_account": ar, "payer_data.phone": ar, "payer_data.email": ar}
 	}
 
@@ -990,7 +1098,7 @@ func (om *OrderManager) ProcessFilters(values url.Values, filter bson.M) bson.M
 	return filter
 }
 
-func (om *OrderManager) ProcessCreatePayment(iData map[string]interface{}, psSettings map[string]interface{}) *payment_system.PaymentResponse {
+func (om *OrderManager) ProcessCreatePayment(iData map[string]interface{}, paymentSystems *payment_system.Registry) *payment_system.PaymentResponse {
 	return payment_system.NewPaymentResponse(payment_system.PaymentStatusErrorSystem, "some error")
 
 	/*var err error
@@ -1081,7 +1189,7 @@ func (om *OrderManager) ProcessCreatePayment(iData map[string]interface{}, psSet
 	      return payment_system.NewPaymentResponse(payment_system.PaymentStatusErrorSystem, err.Error())
 	  }
 
-	  handler, err := om.paymentSystemsSettings.GetPaymentHandler(o, psSettings)
+	  handler, err := paymentSystems.GetProvider(o)
 
 	  if err != nil {
 	      return payment_system.NewPaymentResponse(payment_system.PaymentStatusErrorSystem, err.Error())
@@ -1104,7 +1212,7 @@ func (om *OrderManager) ProcessCreatePayment(iData map[string]interface{}, psSet
 	  return res*/
 }
 
-func (om *OrderManager) ProcessNotifyPayment(opn *model.OrderPaymentNotification, psSettings map[string]interface{}) *payment_system.PaymentResponse {
+func (om *OrderManager) ProcessNotifyPayment(opn *model.OrderPaymentNotification, paymentSystems *payment_system.Registry) *payment_system.PaymentResponse {
 	o := om.FindById(opn.Id)
 
 	if o == nil {
@@ -1127,17 +1235,23 @@ func (om *OrderManager) ProcessNotifyPayment(opn *model.OrderPaymentNotification
 		return payment_system.NewPaymentResponse(payment_system.PaymentStatusOK, orderErrorOrderAlreadyComplete)
 	}
 
-	handler, err := om.paymentSystemsSettings.GetPaymentHandler(o, psSettings)
+	handler, err := paymentSystems.GetProvider(o)
 
 	if err != nil {
 		return payment_system.NewPaymentResponse(payment_system.PaymentStatusErrorSystem, err.Error())
 	}
 
+	event := &model.OrderEvent{
+		Actor:       o.PaymentMethod.Params.Handler,
+		Source:      model.OrderEventSourceWebhook,
+		PayloadHash: getOrderEventPayloadHash(opn.RawRequest),
+	}
 	o.UpdatedAt = time.Now()
 
 	res := handler.ProcessPayment(o, opn)
 
-	if res.Status == payment_system.PaymentStatusTemporary {
+	// order not changed if notification skipped or order status transition rejected
+	if res.Status == payment_system.PaymentStatusTemporary || res.Order == nil {
 		return res
 	}
 
@@ -1159,7 +1273,9 @@ func (om *OrderManager) ProcessNotifyPayment(opn *model.OrderPaymentNotification
 		}
 	}
 
-	if _, err = om.UpdateOrder(o); err != nil {
+	event.Reason = res.Error
+
+	if _, err = om.UpdateOrder(o, event); err != nil {
 		return payment_system.NewPaymentResponse(payment_system.PaymentStatusErrorSystem, model.ResponseMessageUnknownDbError)
 	}
 
@@ -1451,17 +1567,180 @@ func (om *OrderManager) updateOrderAccountingAmounts(o *model.Order) (*model.Ord
 	return o, nil
 }
 
-func (om *OrderManager) UpdateOrder(o *model.Order) (*model.Order, error) {
-	err := om.Database.Repository(TableOrder).UpdateOrder(o)
+// Update order and save event of order status transition if order status changed. Event must contain
+// initiator of change, other fields of event filled by order data
+func (om *OrderManager) UpdateOrder(o *model.Order, event *model.OrderEvent) (*model.Order, error) {
+	rep := om.Database.Repository(TableOrder)
+	prev, err := rep.FindOrderById(o.Id)
+
+	if err != nil {
+		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrder, err)
+		return nil, err
+	}
+
+	// status of order can be changed by any code before update, so every update checked by state machine
+	err = model.CheckOrderStatusTransition(o, prev.Status, o.Status)
+
+	if err != nil {
+		om.Logger.Errorw("Order status transition rejected", "order_id", o.Id.Hex(), "error", err.Error())
+		return nil, err
+	}
+
+	// payment system data and payer data can be changed by update, so search keys are refreshed
+	o.SetSearchKeys()
+	// status could be changed by other request after order was read, so order updated only if it still
+	// in status checked by state machine and transition not saved twice
+	err = rep.UpdateOrder(o, prev.Status)
+
+	if err == mgo.ErrNotFound {
+		err = &model.OrderStatusTransitionError{From: prev.Status, To: o.Status, Reason: orderErrorStatusChangedConcurrently}
+		om.Logger.Errorw("Order status transition rejected", "order_id", o.Id.Hex(), "error", err.Error())
+		return nil, err
+	}
 
 	if err != nil {
 		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrder, err)
 		return nil, err
 	}
 
+	if prev.Status == o.Status {
+		return o, nil
+	}
+
+	event.Id = bson.NewObjectId()
+	event.OrderId = o.Id
+	event.StatusFrom = prev.Status
+	event.StatusTo = o.Status
+	event.CreatedAt = time.Now()
+
+	// order already updated, so error of event saving can't be returned to initiator of change
+	err = om.Database.Repository(TableOrderEvent).InsertOrderEvent(event)
+
+	if err != nil {
+		om.Logger.Errorf("Query to insert to table \"%s\" ended with error: %s", TableOrderEvent, err)
+	}
+
 	return o, nil
 }
 
+// Send notification about paid order to project again. Order waiting for answer of project moved back
+// to in progress status, for other orders only date of last request to project updated. Order saved before
+// notification published, so notification never sent for order which status was changed by other request
+func (om *OrderManager) ResendNotification(o *model.Order, event *model.OrderEvent) error {
+	if om.pub == nil {
+		return errors.New(orderErrorNotifierNotConfigured)
+	}
+
+	if !o.CanResendNotification() {
+		return errors.New(orderErrorNotificationResendNotAllowed)
+	}
+
+	if o.Status == model.OrderStatusProjectPending {
+		if err := o.TransitStatus(model.OrderStatusProjectInProgress); err != nil {
+			return err
+		}
+	} else {
+		now := time.Now()
+		o.ProjectLastRequestedAt = &now
+		o.UpdatedAt = now
+	}
+
+	if _, err := om.UpdateOrder(o, event); err != nil {
+		return err
+	}
+
+	// order stays in status allowing resend, so failed notification can be resent again
+	err := om.pub.Publish(
+		constant.PayOneTopicNotifyPaymentName,
+		om.getPublisherOrder(o),
+		amqp.Table{"x-retry-count": int32(0)},
+	)
+
+	if err != nil {
+		om.Logger.Errorw("Publish of order notification failed", "order_id", o.Id.Hex(), "error", err.Error())
+	}
+
+	return err
+}
+
+// Mark order as requiring manual review. Order can be marked once, repeated mark returns error
+func (om *OrderManager) MarkForReview(o *model.Order, userId string) error {
+	review := &model.OrderReview{UserId: userId, CreatedAt: time.Now()}
+	err := om.Database.Repository(TableOrder).UpdateOrderReview(o.Id, review)
+
+	if err == mgo.ErrNotFound {
+		return errors.New(orderErrorReviewAlreadyRequested)
+	}
+
+	if err != nil {
+		om.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableOrder, err)
+		return err
+	}
+
+	o.Review = review
+
+	return nil
+}
+
+func (om *OrderManager) AddNote(o *model.Order, userId string, text string) error {
+	note := &model.OrderNote{UserId: userId, Text: text, CreatedAt: time.Now()}
+	err := om.Database.Repository(TableOrder).AddOrderNote(o.Id, note)
+
+	if err != nil {
+		om.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableOrder, err)
+		return err
+	}
+
+	o.Notes = append(o.Notes, note)
+
+	return nil
+}
+
+// Get identifiers of orders matched by filters of FindAll. No more than limit identifiers returned
+func (om *OrderManager) FindAllIds(params *FindAll, limit int) ([]bson.ObjectId, error) {
+	ids, err := om.Database.Repository(TableOrder).FindOrderIds(om.getFindAllFilter(params), params.SortBy, limit)
+
+	if err != nil {
+		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrder, err)
+	}
+
+	return ids, err
+}
+
+// Get history of order status transitions
+func (om *OrderManager) GetTimeline(o *model.Order) (*model.OrderTimeline, error) {
+	events, err := om.Database.Repository(TableOrderEvent).FindOrderEventsByOrderId(o.Id)
+
+	if err != nil {
+		om.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrderEvent, err)
+		return nil, err
+	}
+
+	timeline := &model.OrderTimeline{
+		OrderId: o.Id,
+		Status:  model.NewOrderStatus(o.Status),
+		Items:   []*model.OrderEvent{},
+	}
+
+	for _, e := range events {
+		e.From = model.NewOrderStatus(e.StatusFrom)
+		e.To = model.NewOrderStatus(e.StatusTo)
+		timeline.Items = append(timeline.Items, e)
+	}
+
+	return timeline, nil
+}
+
+func getOrderEventPayloadHash(payload string) string {
+	if payload == "" {
+		return ""
+	}
+
+	h := sha256.Sum256([]byte(payload))
+
+	return hex.EncodeToString(h[:])
+}
+
 // Get data about accounting payment by accounting period of merchant
 func (om *OrderManager) GetAccountingPayment(rdr *model.RevenueDynamicRequest, mId string) (*model.AccountingPayment, error) {
 	rdr.From = utils.GetTimeRangeFrom(rdr.From)
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/dao"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	// job in progress which not updated during this time considered as abandoned by stopped instance of service
+	orderBulkJobStaleTimeout = 10 * time.Minute
+)
+
+type OrderBulkJobManager Manager
+
+func InitOrderBulkJobManager(database dao.Database, logger *zap.SugaredLogger) *OrderBulkJobManager {
+	return &OrderBulkJobManager{Database: database, Logger: logger}
+}
+
+func (jm *OrderBulkJobManager) Insert(j *model.OrderBulkJob) error {
+	j.Id = bson.NewObjectId()
+	j.Status = model.OrderBulkJobStatusPending
+	j.Total = len(j.OrderIds)
+	j.Results = []*model.OrderBulkResult{}
+	j.CreatedAt = time.Now()
+	j.UpdatedAt = j.CreatedAt
+
+	err := jm.Database.Repository(TableOrderBulkJob).InsertOrderBulkJob(j)
+
+	if err != nil {
+		jm.Logger.Errorf("Query to insert to table \"%s\" ended with error: %s", TableOrderBulkJob, err)
+	}
+
+	return err
+}
+
+// Get job of user. If job not exists or created by other user then mgo.ErrNotFound will be returned
+func (jm *OrderBulkJobManager) FindById(id bson.ObjectId, userId string) (*model.OrderBulkJob, error) {
+	j, err := jm.Database.Repository(TableOrderBulkJob).FindOrderBulkJobById(id, userId)
+
+	if err != nil && err != mgo.ErrNotFound {
+		jm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrderBulkJob, err)
+	}
+
+	return j, err
+}
+
+// Reserve next job for processing by current instance of service. If there are no jobs to process
+// then nil will be returned
+func (jm *OrderBulkJobManager) Reserve(now time.Time) (*model.OrderBulkJob, error) {
+	j, err := jm.Database.Repository(TableOrderBulkJob).ReserveOrderBulkJob(now, now.Add(-orderBulkJobStaleTimeout))
+
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		jm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableOrderBulkJob, err)
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// Save results of processed orders. Job completed if all orders processed
+func (jm *OrderBulkJobManager) SaveProgress(j *model.OrderBulkJob) error {
+	j.UpdatedAt = time.Now()
+
+	if j.Processed >= j.Total && j.Status == model.OrderBulkJobStatusInProgress {
+		j.Status = model.OrderBulkJobStatusCompleted
+	}
+
+	if j.Status != model.OrderBulkJobStatusInProgress {
+		j.FinishedAt = &j.UpdatedAt
+	}
+
+	err := jm.Database.Repository(TableOrderBulkJob).UpdateOrderBulkJobProgress(j)
+
+	if err != nil {
+		jm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableOrderBulkJob, err)
+	}
+
+	return err
+}
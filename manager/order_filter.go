@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/dao"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"go.uber.org/zap"
+	"time"
+)
+
+type OrderFilterManager Manager
+
+func InitOrderFilterManager(database dao.Database, logger *zap.SugaredLogger) *OrderFilterManager {
+	return &OrderFilterManager{Database: database, Logger: logger}
+}
+
+func (fm *OrderFilterManager) FindByUserId(userId string) ([]*model.OrderFilter, error) {
+	f, err := fm.Database.Repository(TableOrderFilter).FindOrderFiltersByUserId(userId)
+
+	if err != nil {
+		fm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrderFilter, err)
+		return nil, err
+	}
+
+	if f == nil {
+		f = []*model.OrderFilter{}
+	}
+
+	return f, nil
+}
+
+// Get filter of user. If filter not exists or created by other user then mgo.ErrNotFound will be returned
+func (fm *OrderFilterManager) FindById(id bson.ObjectId, userId string) (*model.OrderFilter, error) {
+	f, err := fm.Database.Repository(TableOrderFilter).FindOrderFilterById(id, userId)
+
+	if err != nil && err != mgo.ErrNotFound {
+		fm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrderFilter, err)
+	}
+
+	return f, err
+}
+
+func (fm *OrderFilterManager) Insert(f *model.OrderFilter) error {
+	f.Id = bson.NewObjectId()
+	f.CreatedAt = time.Now()
+	f.UpdatedAt = f.CreatedAt
+	fm.setNextRunAt(f)
+
+	err := fm.Database.Repository(TableOrderFilter).InsertOrderFilter(f)
+
+	if err != nil {
+		fm.Logger.Errorf("Query to insert to table \"%s\" ended with error: %s", TableOrderFilter, err)
+	}
+
+	return err
+}
+
+// Update filter of user. Date of next report generation calculated again because schedule can be changed,
+// but information about last generated report kept
+func (fm *OrderFilterManager) Update(f *model.OrderFilter, prev *model.OrderFilter) error {
+	f.Id = prev.Id
+	f.UserId = prev.UserId
+	f.MerchantId = prev.MerchantId
+	f.CreatedAt = prev.CreatedAt
+	f.UpdatedAt = time.Now()
+	fm.setNextRunAt(f)
+
+	if f.Schedule != nil && prev.Schedule != nil {
+		f.Schedule.LastRunAt = prev.Schedule.LastRunAt
+		f.Schedule.LastReport = prev.Schedule.LastReport
+	}
+
+	err := fm.Database.Repository(TableOrderFilter).UpdateOrderFilter(f)
+
+	if err != nil && err != mgo.ErrNotFound {
+		fm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableOrderFilter, err)
+	}
+
+	return err
+}
+
+func (fm *OrderFilterManager) Delete(id bson.ObjectId, userId string) error {
+	err := fm.Database.Repository(TableOrderFilter).DeleteOrderFilter(id, userId)
+
+	if err != nil && err != mgo.ErrNotFound {
+		fm.Logger.Errorf("Query to delete from table \"%s\" ended with error: %s", TableOrderFilter, err)
+	}
+
+	return err
+}
+
+// Get filters which reports must be generated at specified date
+func (fm *OrderFilterManager) FindScheduled(now time.Time) ([]*model.OrderFilter, error) {
+	f, err := fm.Database.Repository(TableOrderFilter).FindScheduledOrderFilters(now)
+
+	if err != nil {
+		fm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableOrderFilter, err)
+	}
+
+	return f, err
+}
+
+// Reserve generation of report by filter for current instance of service. Date of next report generation
+// moved to next period, so report generated once even if several instances of service are running.
+// If report already reserved by other instance then false will be returned
+func (fm *OrderFilterManager) ReserveRun(f *model.OrderFilter, now time.Time) (bool, error) {
+	next := f.Schedule.GetNextRunAt(now)
+	err := fm.Database.Repository(TableOrderFilter).UpdateOrderFilterNextRunAt(f.Id, f.Schedule.NextRunAt, next)
+
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		fm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableOrderFilter, err)
+		return false, err
+	}
+
+	f.Schedule.NextRunAt = next
+
+	return true, nil
+}
+
+func (fm *OrderFilterManager) SetLastReport(f *model.OrderFilter, runAt time.Time, name string) error {
+	err := fm.Database.Repository(TableOrderFilter).UpdateOrderFilterLastReport(f.Id, runAt, name)
+
+	if err != nil && err != mgo.ErrNotFound {
+		fm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableOrderFilter, err)
+		return err
+	}
+
+	f.Schedule.LastRunAt = &runAt
+	f.Schedule.LastReport = name
+
+	return nil
+}
+
+func (fm *OrderFilterManager) setNextRunAt(f *model.OrderFilter) {
+	if f.Schedule == nil {
+		return
+	}
+
+	f.Schedule.NextRunAt = f.Schedule.GetNextRunAt(time.Now())
+	f.Schedule.LastRunAt = nil
+	f.Schedule.LastReport = ""
+}
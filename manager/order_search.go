@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Order fields searched by quick filter in orders without search keys, e.g. in orders created by billing server.
+// Every field indexed together with project identifier
+var OrderSearchFallbackFields = []string{
+	"project_order_id",
+	"project_account",
+	"pm_account",
+	"pm_order_id",
+	"payer_data.email",
+	"payer_data.phone",
+	"project.name",
+	"fixed_package.name",
+	"payment_method.name",
+}
+
+const (
+	// shorter queries matched with search keys exactly, because prefix search by them matches too many orders
+	orderSearchPrefixMinLength = 3
+	orderSearchExactQuote      = "\""
+)
+
+// OrderSearchQuery is parsed query of quick filter of orders list
+type OrderSearchQuery struct {
+	// normalized query value
+	Value string
+	// query value as it was entered, without quotes
+	Raw string
+	// search key must be equal to value if true, otherwise search key must start with value
+	Exact bool
+}
+
+// Parse query of quick filter. Query in double quotes matched with search keys exactly,
+// other queries matched with beginning of search keys
+func ParseOrderSearchQuery(query string) *OrderSearchQuery {
+	query = strings.TrimSpace(query)
+	exact := len(query) > 1 && strings.HasPrefix(query, orderSearchExactQuote) && strings.HasSuffix(query, orderSearchExactQuote)
+
+	if exact {
+		query = query[1 : len(query)-1]
+	}
+
+	q := &OrderSearchQuery{Value: model.NormalizeOrderSearchValue(query), Raw: strings.TrimSpace(query), Exact: exact}
+
+	if utf8.RuneCountInString(q.Value) < orderSearchPrefixMinLength {
+		q.Exact = true
+	}
+
+	return q
+}
+
+// Get condition of search keys field. Prefix matched by anchored case sensitive regular expression
+// with escaped value, so condition uses index of search keys
+func (q *OrderSearchQuery) GetCondition() interface{} {
+	if q.Exact {
+		return q.Value
+	}
+
+	return bson.RegEx{Pattern: "^" + regexp.QuoteMeta(q.Value)}
+}
+
+// Get condition of orders list by quick filter. Orders with search keys matched by keys, orders without keys
+// matched by source fields. Values of source fields aren't normalized, so they matched with query as it was
+// entered by anchored case sensitive regular expression with escaped value, which uses indexes of fields
+func (q *OrderSearchQuery) GetFilter() bson.M {
+	var raw interface{} = q.Raw
+
+	if !q.Exact {
+		raw = bson.RegEx{Pattern: "^" + regexp.QuoteMeta(q.Raw)}
+	}
+
+	fields := make([]bson.M, 0, len(OrderSearchFallbackFields)+1)
+
+	// identifier isn't a string, so it matched only by full value
+	if bson.IsObjectIdHex(q.Raw) {
+		fields = append(fields, bson.M{"_id": bson.ObjectIdHex(q.Raw)})
+	}
+
+	for _, field := range OrderSearchFallbackFields {
+		fields = append(fields, bson.M{field: raw})
+	}
+
+	return bson.M{
+		"$or": []bson.M{
+			{"search_keys": q.GetCondition()},
+			{"search_keys": bson.M{"$exists": false}, "$or": fields},
+		},
+	}
+}
@@ -307,13 +307,13 @@ func (pm *ProjectManager) processFixedPackages(fixedPackages map[string][]*model
 	return fixedPackages
 }
 
-func (pm *ProjectManager) FilterProjects(mId string, fProjects []bson.ObjectId) (map[bson.ObjectId]string, *billing.Merchant, error) {
+func (pm *ProjectManager) FilterProjects(ctx context.Context, mId string, fProjects []bson.ObjectId) (map[bson.ObjectId]string, *billing.Merchant, error) {
 	req := &grpc.ListProjectsRequest{
 		MerchantId: mId,
 		Limit:      model.DefaultLimit,
 		Offset:     model.DefaultOffset,
 	}
-	rsp, err := pm.billingService.ListProjects(context.TODO(), req)
+	rsp, err := pm.billingService.ListProjects(ctx, req)
 
 	if err != nil || rsp.Count <= 0 {
 		return nil, nil, errors.New(projectErrorMerchantNotHaveProjects)
@@ -0,0 +1,140 @@
+package manager
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/database/dao"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"go.uber.org/zap"
+	"time"
+)
+
+type RefundApprovalManager Manager
+
+func InitRefundApprovalManager(database dao.Database, logger *zap.SugaredLogger) *RefundApprovalManager {
+	return &RefundApprovalManager{Database: database, Logger: logger}
+}
+
+func (am *RefundApprovalManager) FindRules(merchantId string) ([]*model.RefundApprovalRule, error) {
+	r, err := am.Database.Repository(TableRefundApprovalRule).FindRefundApprovalRulesByMerchantId(merchantId)
+
+	if err != nil {
+		am.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableRefundApprovalRule, err)
+		return nil, err
+	}
+
+	if r == nil {
+		r = []*model.RefundApprovalRule{}
+	}
+
+	return r, nil
+}
+
+func (am *RefundApprovalManager) InsertRule(r *model.RefundApprovalRule) error {
+	r.Id = bson.NewObjectId()
+	r.CreatedAt = time.Now()
+
+	err := am.Database.Repository(TableRefundApprovalRule).InsertRefundApprovalRule(r)
+
+	if err != nil {
+		am.Logger.Errorf("Query to insert to table \"%s\" ended with error: %s", TableRefundApprovalRule, err)
+	}
+
+	return err
+}
+
+func (am *RefundApprovalManager) DeleteRule(id bson.ObjectId, merchantId string) error {
+	err := am.Database.Repository(TableRefundApprovalRule).DeleteRefundApprovalRule(id, merchantId)
+
+	if err != nil && err != mgo.ErrNotFound {
+		am.Logger.Errorf("Query to delete from table \"%s\" ended with error: %s", TableRefundApprovalRule, err)
+	}
+
+	return err
+}
+
+func (am *RefundApprovalManager) Insert(a *model.RefundApproval) error {
+	a.Id = bson.NewObjectId()
+	a.Status = model.RefundApprovalStatusPending
+	a.CreatedAt = time.Now()
+
+	err := am.Database.Repository(TableRefundApproval).InsertRefundApproval(a)
+
+	if err != nil {
+		am.Logger.Errorf("Query to insert to table \"%s\" ended with error: %s", TableRefundApproval, err)
+	}
+
+	return err
+}
+
+// Get approval of refund to order. If approval not exists or belongs to other order then mgo.ErrNotFound
+// will be returned
+func (am *RefundApprovalManager) FindById(id bson.ObjectId, orderId string) (*model.RefundApproval, error) {
+	a, err := am.Database.Repository(TableRefundApproval).FindRefundApprovalById(id, orderId)
+
+	if err != nil && err != mgo.ErrNotFound {
+		am.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableRefundApproval, err)
+	}
+
+	return a, err
+}
+
+func (am *RefundApprovalManager) FindPendingByOrderId(orderId string) ([]*model.RefundApproval, error) {
+	a, err := am.Database.Repository(TableRefundApproval).
+		FindRefundApprovalsByOrderId(orderId, model.RefundApprovalStatusPending)
+
+	if err != nil {
+		am.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableRefundApproval, err)
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Get page of merchant approvals sorted by date of creation from newest. If status is empty then
+// approvals in any status returned
+func (am *RefundApprovalManager) FindByMerchantId(
+	merchantId string,
+	status string,
+	limit int32,
+	offset int32,
+) (*model.RefundApprovalList, error) {
+	rep := am.Database.Repository(TableRefundApproval)
+	count, err := rep.CountRefundApprovalsByMerchantId(merchantId, status)
+
+	if err != nil {
+		am.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableRefundApproval, err)
+		return nil, err
+	}
+
+	list := &model.RefundApprovalList{Count: count, Items: []*model.RefundApproval{}}
+
+	if count <= int(offset) {
+		return list, nil
+	}
+
+	a, err := rep.FindRefundApprovalsByMerchantId(merchantId, status, int(limit), int(offset))
+
+	if err != nil {
+		am.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableRefundApproval, err)
+		return nil, err
+	}
+
+	if a != nil {
+		list.Items = a
+	}
+
+	return list, nil
+}
+
+// Save decision about refund if approval still has status prev. If status of approval changed by other
+// request then mgo.ErrNotFound will be returned
+func (am *RefundApprovalManager) UpdateStatus(a *model.RefundApproval, prev string) error {
+	err := am.Database.Repository(TableRefundApproval).UpdateRefundApprovalStatus(a, prev)
+
+	if err != nil && err != mgo.ErrNotFound {
+		am.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableRefundApproval, err)
+	}
+
+	return err
+}
@@ -0,0 +1,31 @@
+package manager
+
+import (
+	"context"
+	"github.com/globalsign/mgo"
+	"github.com/paysuper/paysuper-management-api/database/dao"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"go.uber.org/zap"
+)
+
+type UserRoleManager Manager
+
+func InitUserRoleManager(database dao.Database, logger *zap.SugaredLogger) *UserRoleManager {
+	return &UserRoleManager{Database: database, Logger: logger}
+}
+
+// Get roles assigned to user. If user hasn't assignments then empty assignment will be returned
+func (urm *UserRoleManager) GetUserRoles(_ context.Context, userId string) (*model.UserRole, error) {
+	ur, err := urm.Database.Repository(TableUserRole).FindUserRoleByUserId(userId)
+
+	if err == mgo.ErrNotFound {
+		return &model.UserRole{UserId: userId}, nil
+	}
+
+	if err != nil {
+		urm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableUserRole, err)
+		return nil, err
+	}
+
+	return ur, nil
+}
@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/paysuper/paysuper-management-api/database/dao"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"go.uber.org/zap"
+	"time"
+)
+
+type WebhookInboxManager Manager
+
+func InitWebhookInboxManager(database dao.Database, logger *zap.SugaredLogger) *WebhookInboxManager {
+	return &WebhookInboxManager{Database: database, Logger: logger}
+}
+
+// Insert record if record with same key not exists or existing record can be processed again: processing of
+// it failed, it was stale or its processing lock expired. Otherwise existing record will be returned and
+// inserted flag will be false
+func (wm *WebhookInboxManager) Insert(r *model.WebhookInboxRecord) (*model.WebhookInboxRecord, bool, error) {
+	rep := wm.Database.Repository(TableWebhookInbox)
+	err := rep.InsertWebhookInboxRecord(r)
+
+	if err == nil {
+		return r, true, nil
+	}
+
+	if !mgo.IsDup(err) {
+		wm.Logger.Errorf("Query to insert to table \"%s\" ended with error: %s", TableWebhookInbox, err)
+		return nil, false, err
+	}
+
+	exists, err := rep.FindWebhookInboxRecordById(r.Id)
+
+	if err != nil {
+		wm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableWebhookInbox, err)
+		return nil, false, err
+	}
+
+	if !exists.IsRetryable(time.Now()) {
+		return exists, false, nil
+	}
+
+	r.Deliveries = exists.Deliveries + 1
+	r.CreatedAt = exists.CreatedAt
+
+	// record can be replaced only by one of concurrent deliveries, other deliveries get replaced record
+	err = rep.ReplaceRetryableWebhookInboxRecord(r, time.Now())
+
+	if err == nil {
+		return r, true, nil
+	}
+
+	if err != mgo.ErrNotFound {
+		wm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableWebhookInbox, err)
+		return nil, false, err
+	}
+
+	exists, err = rep.FindWebhookInboxRecordById(r.Id)
+
+	if err != nil {
+		wm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableWebhookInbox, err)
+		return nil, false, err
+	}
+
+	return exists, false, nil
+}
+
+func (wm *WebhookInboxManager) Get(id string) (*model.WebhookInboxRecord, error) {
+	r, err := wm.Database.Repository(TableWebhookInbox).FindWebhookInboxRecordById(id)
+
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		wm.Logger.Errorf("Query from table \"%s\" ended with error: %s", TableWebhookInbox, err)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (wm *WebhookInboxManager) Update(r *model.WebhookInboxRecord) error {
+	err := wm.Database.Repository(TableWebhookInbox).UpdateWebhookInboxRecord(r)
+
+	if err != nil {
+		wm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableWebhookInbox, err)
+	}
+
+	return err
+}
+
+func (wm *WebhookInboxManager) AddDelivery(id string, at time.Time) error {
+	err := wm.Database.Repository(TableWebhookInbox).AddWebhookInboxRecordDelivery(id, at)
+
+	if err != nil {
+		wm.Logger.Errorf("Query to update table \"%s\" ended with error: %s", TableWebhookInbox, err)
+	}
+
+	return err
+}
@@ -0,0 +1,158 @@
+package payment_system
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	CircuitStateClosed   = 0
+	CircuitStateHalfOpen = 1
+	CircuitStateOpen     = 2
+)
+
+// circuit breakers of terminals of providers shared by all clients
+var circuitBreakers = struct {
+	sync.Mutex
+	items map[string]*circuitBreaker
+}{items: make(map[string]*circuitBreaker)}
+
+// circuitBreaker stops requests to terminal of payment system when rate of failed requests in window of
+// last requests reaches limit. After open timeout one probe request allowed, circuit closed if it
+// succeeded and opened again otherwise
+type circuitBreaker struct {
+	mu       sync.Mutex
+	provider string
+	terminal string
+	settings *ClientSettings
+	state    int
+	// results of last requests in ring buffer, true for failed request
+	results  []bool
+	next     int
+	count    int
+	failures int
+	openedAt time.Time
+	probing  bool
+	now      func() time.Time
+}
+
+// Get circuit breaker of terminal of provider. Breaker created with settings of first client of terminal
+func getCircuitBreaker(provider, terminal string, settings *ClientSettings) *circuitBreaker {
+	key := provider + "|" + terminal
+
+	circuitBreakers.Lock()
+	defer circuitBreakers.Unlock()
+
+	b, ok := circuitBreakers.items[key]
+
+	if !ok {
+		b = &circuitBreaker{
+			provider: provider,
+			terminal: terminal,
+			settings: settings,
+			results:  make([]bool, settings.BreakerWindow),
+			now:      time.Now,
+		}
+		circuitBreakers.items[key] = b
+		setCircuitStateMetric(provider, terminal, CircuitStateClosed)
+	}
+
+	return b
+}
+
+// Check that request can be sent now
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitStateOpen:
+		if b.now().Sub(b.openedAt) < b.settings.BreakerOpenTimeout {
+			return false
+		}
+
+		b.setState(CircuitStateHalfOpen)
+		b.probing = true
+
+		return true
+	case CircuitStateHalfOpen:
+		if b.probing {
+			return false
+		}
+
+		b.probing = true
+
+		return true
+	}
+
+	return true
+}
+
+// Save result of request allowed by breaker
+func (b *circuitBreaker) record(failure bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitStateHalfOpen:
+		b.probing = false
+
+		if failure {
+			b.open()
+		} else {
+			b.setState(CircuitStateClosed)
+		}
+
+		return
+	case CircuitStateOpen:
+		// result of request sent before circuit opened
+		return
+	}
+
+	if b.count == len(b.results) {
+		if b.results[b.next] {
+			b.failures--
+		}
+	} else {
+		b.count++
+	}
+
+	b.results[b.next] = failure
+	b.next = (b.next + 1) % len(b.results)
+
+	if failure {
+		b.failures++
+	}
+
+	if b.count >= b.settings.BreakerMinRequests &&
+		float64(b.failures)/float64(b.count) >= b.settings.BreakerErrorRate {
+		b.open()
+	}
+}
+
+func (b *circuitBreaker) open() {
+	b.openedAt = b.now()
+	b.next, b.count, b.failures = 0, 0, 0
+	b.setState(CircuitStateOpen)
+}
+
+func (b *circuitBreaker) setState(state int) {
+	b.state = state
+	setCircuitStateMetric(b.provider, b.terminal, state)
+}
+
+// Get current state of circuit breaker of terminal of provider
+func GetCircuitState(provider, terminal string) int {
+	circuitBreakers.Lock()
+	b, ok := circuitBreakers.items[provider+"|"+terminal]
+	circuitBreakers.Unlock()
+
+	if !ok {
+		return CircuitStateClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
@@ -1,22 +1,22 @@
 package payment_system
 
 import (
-	"bytes"
+	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/labstack/echo"
 	"github.com/paysuper/paysuper-management-api/database/model"
 	"github.com/paysuper/paysuper-management-api/payment_system/entity"
 	"github.com/paysuper/paysuper-management-api/payment_system/validator"
 	"github.com/satori/go.uuid"
-	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -32,6 +32,8 @@ const (
 	cardPayActionAuthenticate  = "auth"
 	cardPayActionRefresh       = "refresh"
 	cardPayActionCreatePayment = "create_payment"
+	cardPayActionRefund        = "refund"
+	cardPayActionPaymentStatus = "payment_status"
 
 	cardPayDateFormat            = "2006-01-02T15:04:05Z"
 	cardPayPaymentMethodBankCard = "BANKCARD"
@@ -40,30 +42,61 @@ const (
 	cardPayPaymentMethodNeteller = "NETELLER"
 	cardPayPaymentMethodAlipay   = "ALIPAY"
 	cardPayPaymentMethodBitcoin  = "BITCOIN"
+
+	cardPayRefundResponseStatusDeclined = "DECLINED"
+
+	cardPayErrorCreatePaymentUrlRequired = "field create_payment_url is required"
+	cardPayErrorCreatePaymentUrlInvalid  = "field create_payment_url must be absolute url"
+	cardPayErrorTerminalsNotFound        = "settings of payment methods terminals not found"
+	cardPayErrorTerminalFieldRequired    = "field %s.%s is required"
+	cardPayErrorPaymentIdNotFound        = "payment identifier of order in payment system not found"
+	cardPayErrorRefundDeclined           = "refund declined by payment system"
 )
 
 var paths = map[string]*Path{
 	cardPayActionAuthenticate: {
-		path:   "/api/auth/token",
-		method: http.MethodPost,
+		path:       "/api/auth/token",
+		method:     http.MethodPost,
+		idempotent: true,
 	},
 	cardPayActionRefresh: {
-		path:   "/api/auth/token",
-		method: http.MethodPost,
+		path:       "/api/auth/token",
+		method:     http.MethodPost,
+		idempotent: true,
 	},
 	cardPayActionCreatePayment: {
 		path:   "/api/payments",
 		method: http.MethodPost,
 	},
+	cardPayActionRefund: {
+		path:   "/api/refunds",
+		method: http.MethodPost,
+	},
+	cardPayActionPaymentStatus: {
+		path:       "/api/payments",
+		method:     http.MethodGet,
+		idempotent: true,
+	},
 }
 
-var tokens = map[string]*Token{}
-
 type CardPay struct {
 	*Settings
 	*model.Order
-	mu         sync.Mutex
-	pmSettings map[string]string
+	config   *CardPayConfig
+	terminal *CardPayTerminal
+}
+
+// CardPayConfig is settings of CardPay provider. Terminals settings placed on the same level as url
+// and keyed by external identifiers of payment methods
+type CardPayConfig struct {
+	CreatePaymentUrl string                      `yaml:"create_payment_url"`
+	Terminals        map[string]*CardPayTerminal `yaml:",inline"`
+}
+
+type CardPayTerminal struct {
+	TerminalId         string `yaml:"terminal_id"`
+	SecretWord         string `yaml:"secret_word"`
+	CallbackSecretWord string `yaml:"callback_secret_word"`
 }
 
 type Token struct {
@@ -76,183 +109,344 @@ type Token struct {
 	RefreshTokenExpireTime time.Time
 }
 
-func NewCardPayHandler(o *model.Order, settings *Settings) PaymentSystem {
-	mSettings := make(map[string]string)
-	iSettings := settings.Settings.(map[interface{}]interface{})
+func init() {
+	RegisterProvider(&ProviderDefinition{
+		Name: PaymentSystemHandlerCardPay,
+		Capabilities: &Capabilities{
+			CreatePayment: true,
+			Refund:        true,
+			PaymentMethodTypes: []string{
+				model.PaymentMethodTypeBankCard,
+				model.PaymentMethodTypeEWallet,
+				model.PaymentMethodTypeCrypto,
+			},
+		},
+		NewConfig: func() ProviderConfig { return &CardPayConfig{} },
+		New:       NewCardPayHandler,
+	})
+}
+
+func NewCardPayHandler(o *model.Order, settings *Settings) (Provider, error) {
+	config, ok := settings.Config.(*CardPayConfig)
 
-	for k, v := range iSettings {
-		mSettings[k.(string)] = v.(string)
+	if !ok {
+		return nil, errors.New(paymentSystemErrorSettingsNotFound)
 	}
 
-	return &CardPay{Settings: settings, Order: o, pmSettings: mSettings}
-}
+	terminal, ok := config.Terminals[o.PaymentMethod.Params.ExternalId]
 
-func (cp *CardPay) auth(pmKey string) error {
-	if token := cp.getToken(pmKey); token != nil {
-		return nil
+	if !ok {
+		return nil, errors.New(paymentSystemErrorSettingsNotFound)
 	}
 
-	data := url.Values{
-		cardPayRequestFieldGrantType:    []string{cardPayGrantTypePassword},
-		cardPayRequestFieldTerminalCode: []string{cp.pmSettings[settingsFieldTerminalId]},
-		cardPayRequestFieldPassword:     []string{cp.pmSettings[settingsFieldSecretWord]},
+	return &CardPay{Settings: settings, Order: o, config: config, terminal: terminal}, nil
+}
+
+func (c *CardPayConfig) Validate() error {
+	if c.CreatePaymentUrl == "" {
+		return errors.New(cardPayErrorCreatePaymentUrlRequired)
 	}
 
-	qUrl, err := cp.getUrl(cardPayActionAuthenticate)
+	if u, err := url.ParseRequestURI(c.CreatePaymentUrl); err != nil || !u.IsAbs() {
+		return errors.New(cardPayErrorCreatePaymentUrlInvalid)
+	}
 
-	if err != nil {
-		return err
+	if len(c.Terminals) == 0 {
+		return errors.New(cardPayErrorTerminalsNotFound)
 	}
 
-	client := cp.Settings.PaymentSystemSetting.GetLoggableHttpClient()
-	req, err := http.NewRequest(paths[cardPayActionAuthenticate].method, qUrl, strings.NewReader(data.Encode()))
+	keys := make([]string, 0, len(c.Terminals))
 
-	if err != nil {
-		return err
+	for k := range c.Terminals {
+		keys = append(keys, k)
 	}
 
-	req.Header.Add(echo.HeaderContentType, echo.MIMEApplicationForm)
-	req.Header.Add(echo.HeaderContentLength, strconv.Itoa(len(data.Encode())))
+	sort.Strings(keys)
 
-	resp, err := client.Do(req)
+	for _, k := range keys {
+		t := c.Terminals[k]
 
-	if err != nil {
-		return err
-	}
+		if t == nil {
+			t = &CardPayTerminal{}
+		}
 
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			return
+		fields := []struct {
+			name  string
+			value string
+		}{
+			{name: "terminal_id", value: t.TerminalId},
+			{name: "secret_word", value: t.SecretWord},
+			{name: "callback_secret_word", value: t.CallbackSecretWord},
 		}
-	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(paymentSystemErrorAuthenticateFailed)
+		for _, f := range fields {
+			if f.value == "" {
+				return fmt.Errorf(cardPayErrorTerminalFieldRequired, k, f.name)
+			}
+		}
 	}
 
-	b, err := ioutil.ReadAll(resp.Body)
+	return nil
+}
 
-	if err != nil {
-		return err
+func (c *CardPayConfig) CheckCallbackSignature(externalId, body, signature string) error {
+	terminal, ok := c.Terminals[externalId]
+
+	if !ok {
+		return errors.New(paymentSystemErrorSettingsNotFound)
 	}
 
-	if err := cp.setToken(b, pmKey); err != nil {
-		return err
+	if !checkCardPaySignature(body, signature, terminal.CallbackSecretWord) {
+		return errors.New(paymentSystemErrorRequestSignatureIsInvalid)
 	}
 
 	return nil
 }
 
-func (cp *CardPay) refresh(pmKey string) error {
+func (cp *CardPay) auth() (*Token, error) {
+	data := url.Values{
+		cardPayRequestFieldGrantType:    []string{cardPayGrantTypePassword},
+		cardPayRequestFieldTerminalCode: []string{cp.terminal.TerminalId},
+		cardPayRequestFieldPassword:     []string{cp.terminal.SecretWord},
+	}
+
+	return cp.requestToken(cardPayActionAuthenticate, data)
+}
+
+func (cp *CardPay) refresh(refreshToken string) (*Token, error) {
 	data := url.Values{
 		cardPayRequestFieldGrantType:    []string{cardPayGrantTypeRefreshToken},
-		cardPayRequestFieldTerminalCode: []string{cp.pmSettings[settingsFieldTerminalId]},
-		cardPayRequestFieldRefreshToken: []string{tokens[pmKey].RefreshToken},
+		cardPayRequestFieldTerminalCode: []string{cp.terminal.TerminalId},
+		cardPayRequestFieldRefreshToken: []string{refreshToken},
 	}
 
-	qUrl, err := cp.getUrl(cardPayActionRefresh)
+	return cp.requestToken(cardPayActionRefresh, data)
+}
+
+// Request new token of terminal by password or by refresh token
+func (cp *CardPay) requestToken(action string, data url.Values) (*Token, error) {
+	qUrl, err := cp.getUrl(action)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	client := cp.Settings.PaymentSystemSetting.GetLoggableHttpClient()
-	req, err := http.NewRequest(paths[cardPayActionRefresh].method, qUrl, strings.NewReader(data.Encode()))
+	req := &Request{
+		Method:     paths[action].method,
+		Url:        qUrl,
+		Header:     http.Header{echo.HeaderContentType: []string{echo.MIMEApplicationForm}},
+		Body:       []byte(data.Encode()),
+		Idempotent: paths[action].idempotent,
+	}
+	rsp, err := cp.getClient().Do(req)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req.Header.Add(echo.HeaderContentType, echo.MIMEApplicationForm)
-	req.Header.Add(echo.HeaderContentLength, strconv.Itoa(len(data.Encode())))
+	return newCardPayToken(rsp.Body, time.Now())
+}
+
+func (cp *CardPay) getClient() *Client {
+	return cp.Settings.PaymentSystemSetting.GetClient(PaymentSystemHandlerCardPay, cp.terminal.TerminalId)
+}
+
+// Get token of terminal from tokens shared by all CardPay handlers
+func (cp *CardPay) getToken() (*Token, error) {
+	return cardPayTokens.get(cp.getTokenKey(), cp.auth, cp.refresh)
+}
 
-	resp, err := client.Do(req)
+// Tokens are issued for terminal, url included to key to separate terminals of different CardPay environments
+func (cp *CardPay) getTokenKey() string {
+	return cp.config.CreatePaymentUrl + "|" + cp.terminal.TerminalId
+}
 
-	if err != nil {
-		return err
+func (cp *CardPay) CreatePayment() *PaymentResponse {
+	if _, err := cp.getToken(); err != nil {
+		return NewPaymentResponse(GetErrorPaymentStatus(err), err.Error())
 	}
 
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			return
-		}
-	}()
+	cpo, err := cp.getCardPayOrder()
 
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(paymentSystemErrorAuthenticateFailed)
+	if err != nil {
+		return NewPaymentResponse(PaymentStatusErrorValidation, err.Error())
 	}
 
-	b, err := ioutil.ReadAll(resp.Body)
+	b, _ := json.Marshal(cpo)
+	b, err = cp.sendAuthorizedRequest(cardPayActionCreatePayment, "", b)
 
 	if err != nil {
-		return err
+		return NewPaymentResponse(GetErrorPaymentStatus(err), err.Error())
 	}
 
-	if err := cp.setToken(b, pmKey); err != nil {
-		return err
+	var cpResponse *entity.CardPayOrderResponse
+
+	if err = json.Unmarshal(b, &cpResponse); err != nil {
+		return NewPaymentResponse(CreatePaymentStatusErrorPaymentSystem, err.Error())
 	}
 
-	return nil
+	res := NewPaymentResponse(PaymentStatusOK, model.EmptyString)
+	res.RedirectUrl = cpResponse.RedirectUrl
+
+	return res
 }
 
-func (cp *CardPay) CreatePayment() *PaymentResponse {
-	if err := cp.auth(cp.Order.PaymentMethod.Params.ExternalId); err != nil {
-		return NewPaymentResponse(PaymentStatusErrorSystem, err.Error())
+func (cp *CardPay) ProcessPayment(o *model.Order, opn *model.OrderPaymentNotification) *PaymentResponse {
+	cpReq := opn.Request.(*entity.CardPayPaymentNotificationWebHookRequest)
+
+	if err := cp.validateNotification(o, opn, cpReq); err != nil {
+		return cp.transitOrderStatus(o, model.OrderStatusPaymentSystemReject, PaymentStatusErrorValidation, err.Error())
 	}
 
-	qUrl, err := cp.getUrl(cardPayActionCreatePayment)
+	status, ok := getCardPayOrderStatus(cpReq.PaymentData.Status)
 
-	if err != nil {
-		return NewPaymentResponse(PaymentStatusErrorSystem, err.Error())
+	if !ok {
+		return NewPaymentResponse(PaymentStatusTemporary, paymentSystemErrorRequestTemporarySkipped)
 	}
 
-	cpo, err := cp.getCardPayOrder()
+	o.PaymentMethodTerminalId = cp.terminal.TerminalId
+	o.PaymentMethodOrderId = cpReq.PaymentData.Id
+	o.PaymentMethodOrderClosedAt = &cpReq.CallbackTimeTime
+	o.PaymentMethodIncomeAmount = cpReq.PaymentData.Amount
+	o.PaymentMethodIncomeCurrencyA3 = cpReq.PaymentData.Currency
+
+	return cp.transitOrderStatus(o, status, PaymentStatusOK, model.EmptyString)
+}
+
+func (cp *CardPay) CreateRefund(refund *Refund) *RefundResponse {
+	if cp.Order.PaymentMethodOrderId == "" {
+		return &RefundResponse{Status: PaymentStatusErrorValidation, Error: cardPayErrorPaymentIdNotFound}
+	}
+
+	req := &entity.CardPayRefund{
+		Request: &entity.CardPayRequest{
+			Id:   refund.Id,
+			Time: time.Now().UTC().Format(cardPayDateFormat),
+		},
+		MerchantOrder: &entity.CardPayMerchantOrder{
+			Id:          cp.Order.Id.Hex(),
+			Description: refund.Reason,
+		},
+		PaymentData: &entity.CardPayRefundPaymentData{Id: cp.Order.PaymentMethodOrderId},
+		RefundData: &entity.CardPayRefundData{
+			Amount:   refund.Amount,
+			Currency: refund.Currency,
+		},
+	}
+
+	b, _ := json.Marshal(req)
+	b, err := cp.sendAuthorizedRequest(cardPayActionRefund, "", b)
 
 	if err != nil {
-		return NewPaymentResponse(PaymentStatusErrorValidation, err.Error())
+		return &RefundResponse{Status: GetErrorPaymentStatus(err), Error: err.Error()}
 	}
 
-	b, _ := json.Marshal(cpo)
+	var rsp *entity.CardPayRefundResponse
+
+	if err = json.Unmarshal(b, &rsp); err != nil || rsp.RefundData == nil {
+		return &RefundResponse{Status: CreatePaymentStatusErrorPaymentSystem, Error: paymentSystemErrorCreateRequestFailed}
+	}
+
+	if rsp.RefundData.Status == cardPayRefundResponseStatusDeclined {
+		return &RefundResponse{Status: CreatePaymentStatusErrorPaymentSystem, Error: cardPayErrorRefundDeclined}
+	}
+
+	return &RefundResponse{Status: PaymentStatusOK, ExternalId: rsp.RefundData.Id}
+}
 
-	client := cp.Settings.PaymentSystemSetting.GetLoggableHttpClient()
-	req, err := http.NewRequest(paths[cardPayActionCreatePayment].method, qUrl, bytes.NewBuffer(b))
+func (cp *CardPay) GetPaymentStatus() *PaymentResponse {
+	o := cp.Order
 
-	token := cp.getToken(cp.Order.PaymentMethod.Params.ExternalId)
-	auth := strings.Title(token.TokenType) + " " + token.AccessToken
+	if o.PaymentMethodOrderId == "" {
+		return NewPaymentResponse(PaymentStatusErrorValidation, cardPayErrorPaymentIdNotFound)
+	}
 
-	req.Header.Add(echo.HeaderContentType, echo.MIMEApplicationJSON)
-	req.Header.Add(echo.HeaderAuthorization, auth)
+	b, err := cp.sendAuthorizedRequest(cardPayActionPaymentStatus, o.PaymentMethodOrderId, nil)
 
-	resp, err := client.Do(req)
+	if err != nil {
+		return NewPaymentResponse(GetErrorPaymentStatus(err), err.Error())
+	}
 
-	if resp.StatusCode != http.StatusOK {
+	var rsp *entity.CardPayPaymentResponse
+
+	if err = json.Unmarshal(b, &rsp); err != nil || rsp.PaymentData == nil {
 		return NewPaymentResponse(CreatePaymentStatusErrorPaymentSystem, paymentSystemErrorCreateRequestFailed)
 	}
 
-	if b, err = ioutil.ReadAll(resp.Body); err != nil {
-		return NewPaymentResponse(CreatePaymentStatusErrorPaymentSystem, err.Error())
+	status, ok := getCardPayOrderStatus(rsp.PaymentData.Status)
+
+	if !ok || status == o.Status {
+		return NewPaymentResponse(PaymentStatusTemporary, paymentSystemErrorRequestTemporarySkipped)
 	}
 
-	var cpResponse *entity.CardPayOrderResponse
+	return cp.transitOrderStatus(o, status, PaymentStatusOK, model.EmptyString)
+}
 
-	if err = json.Unmarshal(b, &cpResponse); err != nil {
-		return NewPaymentResponse(CreatePaymentStatusErrorPaymentSystem, err.Error())
+// Send request authorized by token of terminal to CardPay and return body of successful response.
+// Identifier appended to path of action if it's not empty
+func (cp *CardPay) sendAuthorizedRequest(action, id string, body []byte) ([]byte, error) {
+	token, err := cp.getToken()
+
+	if err != nil {
+		return nil, err
 	}
 
-	res := NewPaymentResponse(PaymentStatusOK, model.EmptyString)
-	res.RedirectUrl = cpResponse.RedirectUrl
+	qUrl, err := cp.getUrl(action)
 
-	return res
+	if err != nil {
+		return nil, err
+	}
+
+	if id != "" {
+		qUrl += "/" + url.PathEscape(id)
+	}
+
+	req := &Request{
+		Method: paths[action].method,
+		Url:    qUrl,
+		Header: http.Header{
+			echo.HeaderContentType:   []string{echo.MIMEApplicationJSON},
+			echo.HeaderAuthorization: []string{strings.Title(token.TokenType) + " " + token.AccessToken},
+		},
+		Body:       body,
+		Idempotent: paths[action].idempotent,
+	}
+	rsp, err := cp.getClient().Do(req)
+
+	if err != nil {
+		// token revoked by CardPay before its expiration, so new token will be requested by next request
+		if e, ok := err.(*Error); ok && e.StatusCode == http.StatusUnauthorized {
+			cardPayTokens.reset(cp.getTokenKey())
+		}
+
+		return nil, err
+	}
+
+	return rsp.Body, nil
 }
 
-func (cp *CardPay) ProcessPayment(o *model.Order, opn *model.OrderPaymentNotification) *PaymentResponse {
-	cpReq := opn.Request.(*entity.CardPayPaymentNotificationWebHookRequest)
+// Get order status corresponding to final status of payment in CardPay
+func getCardPayOrderStatus(status string) (int, bool) {
+	switch status {
+	case entity.CardPayPaymentResponseStatusDeclined:
+		return model.OrderStatusPaymentSystemDeclined, true
+	case entity.CardPayPaymentResponseStatusCancelled:
+		return model.OrderStatusPaymentSystemCanceled, true
+	case entity.CardPayPaymentResponseStatusCompleted:
+		return model.OrderStatusPaymentSystemComplete, true
+	}
 
-	o.Status = model.OrderStatusPaymentSystemReject
-	resp := NewPaymentResponse(PaymentStatusErrorValidation, model.EmptyString).SetOrder(o)
+	return 0, false
+}
 
+// Check notification request and fill payer account in order. Order status not changed by this method
+func (cp *CardPay) validateNotification(
+	o *model.Order,
+	opn *model.OrderPaymentNotification,
+	cpReq *entity.CardPayPaymentNotificationWebHookRequest,
+) error {
 	if cp.checkNotificationRequestSignature(opn.RawRequest, cpReq.Signature) == false {
-		return resp.SetError(paymentSystemErrorRequestSignatureIsInvalid)
+		return errors.New(paymentSystemErrorRequestSignatureIsInvalid)
 	}
 
 	var err error
@@ -260,11 +454,11 @@ func (cp *CardPay) ProcessPayment(o *model.Order, opn *model.OrderPaymentNotific
 	cpReq.CallbackTimeTime, err = time.Parse(cardPayDateFormat, cpReq.CallbackTime)
 
 	if err != nil {
-		return resp.SetError(paymentSystemErrorRequestTimeFieldIsInvalid)
+		return errors.New(paymentSystemErrorRequestTimeFieldIsInvalid)
 	}
 
 	if !cpReq.IsPaymentAllowedStatus() {
-		return resp.SetError(paymentSystemErrorRequestStatusIsInvalid)
+		return errors.New(paymentSystemErrorRequestStatusIsInvalid)
 	}
 
 	switch cpReq.PaymentMethod {
@@ -284,40 +478,37 @@ func (cp *CardPay) ProcessPayment(o *model.Order, opn *model.OrderPaymentNotific
 		o.PaymentMethodTxnParams = cpReq.GetCryptoCurrencyTxnParams()
 		break
 	default:
-		return resp.SetError(paymentSystemErrorRequestPaymentMethodIsInvalid)
+		return errors.New(paymentSystemErrorRequestPaymentMethodIsInvalid)
 	}
 
 	if cpReq.PaymentMethod != o.PaymentMethod.Params.ExternalId {
-		return resp.SetError(paymentSystemErrorRequestPaymentMethodIsInvalid)
+		return errors.New(paymentSystemErrorRequestPaymentMethodIsInvalid)
 	}
 
-	switch cpReq.PaymentData.Status {
-	case entity.CardPayPaymentResponseStatusDeclined:
-		o.Status = model.OrderStatusPaymentSystemDeclined
-		break
-	case entity.CardPayPaymentResponseStatusCancelled:
-		o.Status = model.OrderStatusPaymentSystemCanceled
-		break
-	case entity.CardPayPaymentResponseStatusCompleted:
-		o.Status = model.OrderStatusPaymentSystemComplete
-		break
-	default:
-		return NewPaymentResponse(PaymentStatusTemporary, paymentSystemErrorRequestTemporarySkipped)
+	return nil
+}
+
+// Move order to new status through order status state machine. If transition not allowed, then
+// order status not changed and notification rejected
+func (cp *CardPay) transitOrderStatus(o *model.Order, to, status int, message string) *PaymentResponse {
+	if err := o.TransitStatus(to); err != nil {
+		cp.logTransitionError(o, err)
+		return NewPaymentResponse(PaymentStatusErrorValidation, err.Error())
 	}
 
-	o.PaymentMethodTerminalId = cp.pmSettings[settingsFieldTerminalId]
-	o.PaymentMethodOrderId = cpReq.PaymentData.Id
-	o.PaymentMethodOrderClosedAt = &cpReq.CallbackTimeTime
-	o.PaymentMethodIncomeAmount = cpReq.PaymentData.Amount
-	o.PaymentMethodIncomeCurrencyA3 = cpReq.PaymentData.Currency
+	return NewPaymentResponse(status, message).SetOrder(o)
+}
 
-	res := NewPaymentResponse(PaymentStatusOK, model.EmptyString).SetOrder(o)
+func (cp *CardPay) logTransitionError(o *model.Order, err error) {
+	if cp.PaymentSystemSetting == nil || cp.Logger == nil {
+		return
+	}
 
-	return res
+	cp.Logger.Errorw("Order status transition rejected", "order_id", o.Id.Hex(), "handler", PaymentSystemHandlerCardPay, "error", err.Error())
 }
 
 func (cp *CardPay) getUrl(action string) (string, error) {
-	u, err := url.ParseRequestURI(cp.Url)
+	u, err := url.ParseRequestURI(cp.config.CreatePaymentUrl)
 
 	if err != nil {
 		return "", err
@@ -328,48 +519,6 @@ func (cp *CardPay) getUrl(action string) (string, error) {
 	return u.String(), nil
 }
 
-func (cp *CardPay) setToken(b []byte, pmKey string) error {
-	cp.mu.Lock()
-	defer cp.mu.Unlock()
-
-	var token *Token
-
-	if err := json.Unmarshal(b, &token); err != nil {
-		return err
-	}
-
-	token.AccessTokenExpireTime = time.Now().Add(time.Second * time.Duration(token.AccessTokenExpire))
-	token.RefreshTokenExpireTime = time.Now().Add(time.Second * time.Duration(token.RefreshTokenExpire))
-
-	tokens[pmKey] = token
-
-	return nil
-}
-
-func (cp *CardPay) getToken(pmKey string) *Token {
-	token, ok := tokens[pmKey]
-
-	if !ok {
-		return nil
-	}
-
-	tn := time.Now().Unix()
-
-	if token.AccessTokenExpire > 0 && token.AccessTokenExpireTime.Unix() >= tn {
-		return token
-	}
-
-	if token.RefreshTokenExpire <= 0 || token.RefreshTokenExpireTime.Unix() < tn {
-		return nil
-	}
-
-	if err := cp.refresh(pmKey); err != nil {
-		return nil
-	}
-
-	return tokens[pmKey]
-}
-
 func (cp *CardPay) getCardPayOrder() (*entity.CardPayOrder, error) {
 	var err error
 
@@ -506,8 +655,13 @@ func (cp *CardPay) getCryptoCurrencyCardPayOrder(cpo *entity.CardPayOrder) (*ent
 }
 
 func (cp *CardPay) checkNotificationRequestSignature(reqRaw string, reqSign string) bool {
+	return checkCardPaySignature(reqRaw, reqSign, cp.terminal.CallbackSecretWord)
+}
+
+func checkCardPaySignature(body, signature, secret string) bool {
 	h := sha512.New()
-	h.Write([]byte(reqRaw + cp.pmSettings[settingsFieldCallbackSecretWord]))
+	h.Write([]byte(body + secret))
 
-	return hex.EncodeToString(h.Sum(nil)) == reqSign
+	// signatures compared in constant time to not leak position of first mismatched byte
+	return hmac.Equal([]byte(hex.EncodeToString(h.Sum(nil))), []byte(signature))
 }
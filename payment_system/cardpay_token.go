@@ -0,0 +1,125 @@
+package payment_system
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// access token refreshed when time to its expiration less than this interval
+const cardPayTokenRefreshBefore = 30 * time.Second
+
+// tokens of CardPay terminals shared by all CardPay handlers
+var cardPayTokens = newCardPayTokenManager()
+
+// cardPayTokenManager caches tokens of CardPay terminals. Only one request to get token of terminal sent
+// at any moment, concurrent callers wait for result of this request
+type cardPayTokenManager struct {
+	mu     sync.Mutex
+	tokens map[string]*Token
+	calls  map[string]*cardPayTokenCall
+	now    func() time.Time
+}
+
+type cardPayTokenCall struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+func newCardPayTokenManager() *cardPayTokenManager {
+	return &cardPayTokenManager{
+		tokens: make(map[string]*Token),
+		calls:  make(map[string]*cardPayTokenCall),
+		now:    time.Now,
+	}
+}
+
+// Get valid access token of terminal. Token requested by refresh token when access token expires or
+// will expire soon, and by password if there is no token yet or refresh token expired or rejected.
+// While new token requested callers get current token if it's still valid
+func (m *cardPayTokenManager) get(
+	key string,
+	auth func() (*Token, error),
+	refresh func(refreshToken string) (*Token, error),
+) (*Token, error) {
+	m.mu.Lock()
+
+	now := m.now()
+	token := m.tokens[key]
+	valid := token != nil && now.Before(token.AccessTokenExpireTime)
+
+	if valid && now.Add(cardPayTokenRefreshBefore).Before(token.AccessTokenExpireTime) {
+		m.mu.Unlock()
+		return token, nil
+	}
+
+	call, ok := m.calls[key]
+
+	if ok {
+		m.mu.Unlock()
+
+		if valid {
+			return token, nil
+		}
+
+		<-call.done
+
+		return call.token, call.err
+	}
+
+	call = &cardPayTokenCall{done: make(chan struct{})}
+	m.calls[key] = call
+	m.mu.Unlock()
+
+	if token != nil && token.RefreshToken != "" && now.Before(token.RefreshTokenExpireTime) {
+		call.token, call.err = refresh(token.RefreshToken)
+	}
+
+	if call.token == nil {
+		call.token, call.err = auth()
+	}
+
+	m.mu.Lock()
+
+	if call.err == nil {
+		m.tokens[key] = call.token
+	}
+
+	delete(m.calls, key)
+	m.mu.Unlock()
+
+	close(call.done)
+
+	if call.err != nil && valid {
+		return token, nil
+	}
+
+	return call.token, call.err
+}
+
+// Remove token of terminal, so next call requests new token by password
+func (m *cardPayTokenManager) reset(key string) {
+	m.mu.Lock()
+	delete(m.tokens, key)
+	m.mu.Unlock()
+}
+
+// Parse token from response of CardPay and calculate expiration time of access and refresh tokens
+func newCardPayToken(b []byte, now time.Time) (*Token, error) {
+	var token *Token
+
+	if err := json.Unmarshal(b, &token); err != nil {
+		return nil, err
+	}
+
+	if token == nil || token.AccessToken == "" {
+		return nil, errors.New(paymentSystemErrorAuthenticateFailed)
+	}
+
+	token.AccessTokenExpireTime = now.Add(time.Second * time.Duration(token.AccessTokenExpire))
+	token.RefreshTokenExpireTime = now.Add(time.Second * time.Duration(token.RefreshTokenExpire))
+
+	return token, nil
+}
@@ -0,0 +1,275 @@
+package payment_system
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	ErrorTypeRequest      = "request"
+	ErrorTypeConnection   = "connection"
+	ErrorTypeTimeout      = "timeout"
+	ErrorTypeServer       = "server"
+	ErrorTypeRejected     = "rejected"
+	ErrorTypeUnauthorized = "unauthorized"
+	ErrorTypeCircuitOpen  = "circuit_open"
+
+	paymentSystemErrorRequestNotCreated = "request to payment system can't be created: %s"
+	paymentSystemErrorConnectionFailed  = "payment system is unavailable: %s"
+	paymentSystemErrorTimeout           = "payment system response timeout exceeded"
+	paymentSystemErrorServerStatus      = "payment system responded with error status %d"
+	paymentSystemErrorRejectedStatus    = "request rejected by payment system with status %d"
+	paymentSystemErrorCircuitOpen       = "payment system is temporarily unavailable"
+
+	defaultClientRetryAttempts      = 3
+	defaultClientRetryBackoff       = 100 * time.Millisecond
+	defaultClientRetryMaxBackoff    = 2 * time.Second
+	defaultClientBreakerWindow      = 50
+	defaultClientBreakerMinRequests = 20
+	defaultClientBreakerErrorRate   = 0.5
+	defaultClientBreakerOpenTimeout = 30 * time.Second
+)
+
+// ClientSettings contains timeouts, retries and circuit breaker settings of requests to payment systems.
+// Zero values replaced by defaults
+type ClientSettings struct {
+	// timeout of one attempt of request
+	Timeout time.Duration
+	// maximal count of attempts of idempotent request, including first attempt
+	RetryAttempts int
+	// delay before second attempt, doubled for each next attempt up to max backoff
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+	// count of last requests used to calculate error rate
+	BreakerWindow int
+	// minimal count of requests in window to open circuit
+	BreakerMinRequests int
+	// circuit opened when rate of failed requests in window reaches this value
+	BreakerErrorRate float64
+	// time after which one probe request allowed through opened circuit
+	BreakerOpenTimeout time.Duration
+}
+
+// Error of request to payment system. Type of error is one of ErrorType* constants
+type Error struct {
+	Type       string
+	StatusCode int
+	Err        error
+}
+
+// Client sends requests to payment system through circuit breaker of provider terminal. Idempotent
+// requests repeated with exponential backoff on connection errors, timeouts and server errors
+type Client struct {
+	http     *http.Client
+	settings *ClientSettings
+	breaker  *circuitBreaker
+	sleep    func(time.Duration)
+}
+
+type Request struct {
+	Method string
+	Url    string
+	Header http.Header
+	Body   []byte
+	// request can be safely repeated without side effects on payment system side
+	Idempotent bool
+}
+
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Get client of payment system for terminal of provider. Circuit breaker shared by all clients of terminal
+func (pss *PaymentSystemSetting) GetClient(provider, terminal string) *Client {
+	settings := pss.getClientSettings()
+
+	return &Client{
+		http:     pss.GetLoggableHttpClient(),
+		settings: settings,
+		breaker:  getCircuitBreaker(provider, terminal, settings),
+		sleep:    time.Sleep,
+	}
+}
+
+func (pss *PaymentSystemSetting) getClientSettings() *ClientSettings {
+	s := ClientSettings{}
+
+	if pss.Client != nil {
+		s = *pss.Client
+	}
+
+	if s.Timeout <= 0 {
+		s.Timeout = defaultHttpClientTimeout * time.Second
+	}
+
+	if s.RetryAttempts <= 0 {
+		s.RetryAttempts = defaultClientRetryAttempts
+	}
+
+	if s.RetryBackoff <= 0 {
+		s.RetryBackoff = defaultClientRetryBackoff
+	}
+
+	if s.RetryMaxBackoff <= 0 {
+		s.RetryMaxBackoff = defaultClientRetryMaxBackoff
+	}
+
+	if s.BreakerWindow <= 0 {
+		s.BreakerWindow = defaultClientBreakerWindow
+	}
+
+	if s.BreakerMinRequests <= 0 {
+		s.BreakerMinRequests = defaultClientBreakerMinRequests
+	}
+
+	if s.BreakerMinRequests > s.BreakerWindow {
+		s.BreakerMinRequests = s.BreakerWindow
+	}
+
+	if s.BreakerErrorRate <= 0 || s.BreakerErrorRate > 1 {
+		s.BreakerErrorRate = defaultClientBreakerErrorRate
+	}
+
+	if s.BreakerOpenTimeout <= 0 {
+		s.BreakerOpenTimeout = defaultClientBreakerOpenTimeout
+	}
+
+	return &s
+}
+
+// Send request to payment system. Response with status 2xx or 3xx returned without error, for other
+// statuses response returned together with error
+func (c *Client) Do(r *Request) (*Response, error) {
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.allow() {
+			return nil, &Error{Type: ErrorTypeCircuitOpen}
+		}
+
+		rsp, err := c.do(r)
+		c.breaker.record(err != nil && err.isFailure())
+
+		if err == nil {
+			return rsp, nil
+		}
+
+		if !r.Idempotent || !err.isRetryable() || attempt >= c.settings.RetryAttempts {
+			return rsp, err
+		}
+
+		c.sleep(c.getBackoff(attempt))
+	}
+}
+
+func (c *Client) do(r *Request) (*Response, *Error) {
+	req, err := http.NewRequest(r.Method, r.Url, bytes.NewReader(r.Body))
+
+	if err != nil {
+		return nil, &Error{Type: ErrorTypeRequest, Err: err}
+	}
+
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.http.Do(req)
+
+	if err != nil {
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return nil, &Error{Type: ErrorTypeTimeout, Err: err}
+		}
+
+		return nil, &Error{Type: ErrorTypeConnection, Err: err}
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			return
+		}
+	}()
+
+	b, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, &Error{Type: ErrorTypeConnection, Err: err}
+	}
+
+	rsp := &Response{StatusCode: resp.StatusCode, Body: b}
+
+	switch {
+	case resp.StatusCode < http.StatusBadRequest:
+		return rsp, nil
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return rsp, &Error{Type: ErrorTypeServer, StatusCode: resp.StatusCode}
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return rsp, &Error{Type: ErrorTypeUnauthorized, StatusCode: resp.StatusCode}
+	}
+
+	return rsp, &Error{Type: ErrorTypeRejected, StatusCode: resp.StatusCode}
+}
+
+// Get delay before next attempt. Delay is random value between half and full exponential backoff, so
+// requests failed at the same time are not repeated at the same time
+func (c *Client) getBackoff(attempt int) time.Duration {
+	d := c.settings.RetryBackoff << uint(attempt-1)
+
+	if d <= 0 || d > c.settings.RetryMaxBackoff {
+		d = c.settings.RetryMaxBackoff
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (e *Error) Error() string {
+	switch e.Type {
+	case ErrorTypeRequest:
+		return fmt.Sprintf(paymentSystemErrorRequestNotCreated, e.Err)
+	case ErrorTypeConnection:
+		return fmt.Sprintf(paymentSystemErrorConnectionFailed, e.Err)
+	case ErrorTypeTimeout:
+		return paymentSystemErrorTimeout
+	case ErrorTypeServer:
+		return fmt.Sprintf(paymentSystemErrorServerStatus, e.StatusCode)
+	case ErrorTypeUnauthorized:
+		return paymentSystemErrorAuthenticateFailed
+	case ErrorTypeCircuitOpen:
+		return paymentSystemErrorCircuitOpen
+	}
+
+	return fmt.Sprintf(paymentSystemErrorRejectedStatus, e.StatusCode)
+}
+
+// Get status of payment system response corresponding to error
+func (e *Error) PaymentStatus() int {
+	switch e.Type {
+	case ErrorTypeRequest, ErrorTypeUnauthorized:
+		return PaymentStatusErrorSystem
+	case ErrorTypeRejected:
+		return PaymentStatusErrorValidation
+	}
+
+	return CreatePaymentStatusErrorPaymentSystem
+}
+
+// Error is caused by unavailability of payment system and counted by circuit breaker
+func (e *Error) isFailure() bool {
+	return e.Type == ErrorTypeConnection || e.Type == ErrorTypeTimeout || e.Type == ErrorTypeServer
+}
+
+func (e *Error) isRetryable() bool {
+	return e.isFailure()
+}
+
+// Get status of payment system response corresponding to error returned by payment system handler
+func GetErrorPaymentStatus(err error) int {
+	if e, ok := err.(*Error); ok {
+		return e.PaymentStatus()
+	}
+
+	return PaymentStatusErrorSystem
+}
@@ -101,6 +101,37 @@ type CardPayOrderResponse struct {
 	RedirectUrl string `json:"redirect_url"`
 }
 
+type CardPayRefundPaymentData struct {
+	Id string `json:"id"`
+}
+
+type CardPayRefundData struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+type CardPayRefund struct {
+	Request       *CardPayRequest           `json:"request"`
+	MerchantOrder *CardPayMerchantOrder     `json:"merchant_order"`
+	PaymentData   *CardPayRefundPaymentData `json:"payment_data"`
+	RefundData    *CardPayRefundData        `json:"refund_data"`
+}
+
+type CardPayRefundDataResponse struct {
+	Id       string  `json:"id"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Status   string  `json:"status"`
+}
+
+type CardPayRefundResponse struct {
+	RefundData *CardPayRefundDataResponse `json:"refund_data"`
+}
+
+type CardPayPaymentResponse struct {
+	PaymentData *CardPayPaymentDataResponse `json:"payment_data"`
+}
+
 type CardPayPaymentNotificationWebHookRequest struct {
 	MerchantOrder         *CardPayMerchantOrder                 `json:"merchant_order" validate:"required"`
 	PaymentMethod         string                                `json:"payment_method" validate:"required"`
@@ -114,6 +145,31 @@ type CardPayPaymentNotificationWebHookRequest struct {
 	Signature             string                                `json:"-"`
 }
 
+type CardPayRefundNotificationPaymentData struct {
+	Id              string  `json:"id"`
+	RemainingAmount float64 `json:"remaining_amount"`
+}
+
+type CardPayRefundNotificationRefundData struct {
+	Id       string  `json:"id"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Created  string  `json:"created"`
+	Status   string  `json:"status"`
+	AuthCode string  `json:"auth_code,omitempty"`
+	Is3d     bool    `json:"is_3d,omitempty"`
+	Rrn      string  `json:"rrn,omitempty"`
+}
+
+type CardPayRefundNotificationWebHookRequest struct {
+	MerchantOrder *CardPayMerchantOrder                 `json:"merchant_order"`
+	PaymentMethod string                                `json:"payment_method"`
+	CallbackTime  string                                `json:"callback_time"`
+	Customer      *CardPayCustomer                      `json:"customer"`
+	PaymentData   *CardPayRefundNotificationPaymentData `json:"payment_data"`
+	RefundData    *CardPayRefundNotificationRefundData  `json:"refund_data"`
+}
+
 type CardPayCryptoCurrencyAccountResponse struct {
 	CryptoAddress       string  `json:"crypto_address" validate:"required,btc_addr"`
 	CryptoTransactionId string  `json:"crypto_transaction_id" validate:"required"`
@@ -12,11 +12,13 @@ import (
 
 const (
 	defaultHttpClientTimeout = 10
+	headerContentType        = "Content-Type"
 )
 
 type Transport struct {
 	Transport http.RoundTripper
 	Logger    *zap.SugaredLogger
+	Redactor  *utils.Redactor
 }
 
 type contextKey struct {
@@ -52,6 +54,14 @@ func (t *Transport) transport() http.RoundTripper {
 	return http.DefaultTransport
 }
 
+func (t *Transport) redactor() *utils.Redactor {
+	if t.Redactor != nil {
+		return t.Redactor
+	}
+
+	return utils.DefaultRedactor
+}
+
 func (t *Transport) log(reqUrl string, reqHeader http.Header, reqBody []byte, resp *http.Response) {
 	var resBody []byte
 
@@ -60,11 +70,12 @@ func (t *Transport) log(reqUrl string, reqHeader http.Header, reqBody []byte, re
 	}
 	resp.Body = ioutil.NopCloser(bytes.NewBuffer(resBody))
 
+	r := t.redactor()
 	data := []interface{}{
-		"request_headers", utils.RequestResponseHeadersToString(reqHeader),
-		"request_body", string(reqBody),
-		"response_headers", utils.RequestResponseHeadersToString(resp.Header),
-		"response_body", string(resBody),
+		"request_headers", utils.RequestResponseHeadersToString(r.RedactHeaders(reqHeader)),
+		"request_body", r.RedactBody(reqHeader.Get(headerContentType), reqBody),
+		"response_headers", utils.RequestResponseHeadersToString(r.RedactHeaders(resp.Header)),
+		"response_body", r.RedactBody(resp.Header.Get(headerContentType), resBody),
 	}
 
 	t.Logger.Infow(reqUrl, data...)
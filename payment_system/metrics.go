@@ -0,0 +1,28 @@
+package payment_system
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "paysuper"
+	metricsSubsystem = "management_api"
+)
+
+var circuitState = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "payment_system_circuit_state",
+		Help:      "State of circuit breaker of payment system terminal: 0 - closed, 1 - half open, 2 - open",
+	},
+	[]string{"provider", "terminal"},
+)
+
+func init() {
+	prometheus.MustRegister(circuitState)
+}
+
+func setCircuitStateMetric(provider, terminal string, state int) {
+	circuitState.WithLabelValues(provider, terminal).Set(float64(state))
+}
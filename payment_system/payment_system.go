@@ -1,11 +1,10 @@
 package payment_system
 
 import (
-	"errors"
 	"github.com/paysuper/paysuper-management-api/database/model"
+	"github.com/paysuper/paysuper-management-api/utils"
 	"go.uber.org/zap"
 	"net/http"
-	"time"
 )
 
 const (
@@ -24,41 +23,36 @@ const (
 	paymentSystemErrorRequestPaymentMethodIsInvalid  = "payment method from request not equal value in order"
 	paymentSystemErrorRequestTemporarySkipped        = "notification skipped with temporary status"
 
-	paymentSystemSettingsFieldNameCreatePaymentUrl = "create_payment_url"
-
 	PaymentStatusOK                       = 0
 	PaymentStatusErrorValidation          = 1
 	PaymentStatusErrorSystem              = 2
 	CreatePaymentStatusErrorPaymentSystem = 3
 	PaymentStatusTemporary                = 4
-
-	settingsFieldTerminalId         = "terminal_id"
-	settingsFieldSecretWord         = "secret_word"
-	settingsFieldCallbackSecretWord = "callback_secret_word"
 )
 
-var handlers = map[string]func(*model.Order, *Settings) PaymentSystem{
-	PaymentSystemHandlerCardPay: NewCardPayHandler,
-}
-
 type PaymentSystem interface {
 	CreatePayment() *PaymentResponse
 	ProcessPayment(*model.Order, *model.OrderPaymentNotification) *PaymentResponse
 }
 
 type PaymentSystemSetting struct {
-	Logger *zap.SugaredLogger
+	Logger   *zap.SugaredLogger
+	Redactor *utils.Redactor
+	// timeouts, retries and circuit breaker settings of requests to payment systems. defaults used if empty
+	Client *ClientSettings
 }
 
 type Settings struct {
-	Url      string
-	Settings interface{}
+	// typed settings of provider, validated on registry creation
+	Config ProviderConfig
 	*PaymentSystemSetting
 }
 
 type Path struct {
 	path   string
 	method string
+	// request can be repeated on payment system unavailability
+	idempotent bool
 }
 
 type PaymentResponse struct {
@@ -68,34 +62,10 @@ type PaymentResponse struct {
 	*model.Order
 }
 
-func (pss *PaymentSystemSetting) GetPaymentHandler(order *model.Order, config map[string]interface{}) (PaymentSystem, error) {
-	handler, ok := handlers[order.PaymentMethod.Params.Handler]
-
-	if !ok {
-		return nil, errors.New(paymentSystemErrorHandlerNotFound)
-	}
-
-	c, ok := config[order.PaymentMethod.Params.Handler]
-
-	if !ok {
-		return nil, errors.New(paymentSystemErrorSettingsNotFound)
-	}
-
-	cMap := c.(map[interface{}]interface{})
-
-	s := &Settings{
-		Url:                  cMap[paymentSystemSettingsFieldNameCreatePaymentUrl].(string),
-		Settings:             cMap[order.PaymentMethod.Params.ExternalId],
-		PaymentSystemSetting: pss,
-	}
-
-	return handler(order, s), nil
-}
-
 func (pss *PaymentSystemSetting) GetLoggableHttpClient() *http.Client {
 	return &http.Client{
-		Transport: &Transport{Logger: pss.Logger},
-		Timeout:   time.Duration(defaultHttpClientTimeout * time.Second),
+		Transport: &Transport{Logger: pss.Logger, Redactor: pss.Redactor},
+		Timeout:   pss.getClientSettings().Timeout,
 	}
 }
 
@@ -0,0 +1,245 @@
+package payment_system
+
+import (
+	"errors"
+	"fmt"
+	"github.com/paysuper/paysuper-management-api/database/model"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"sort"
+)
+
+const (
+	paymentSystemErrorProviderNotRegistered       = "payment system \"%s\" is not registered"
+	paymentSystemErrorProviderSettingsInvalid     = "payment system \"%s\" settings are invalid: %s"
+	paymentSystemErrorProviderConfigReadFailed    = "payment systems configuration \"%s\" can't be read: %s"
+	paymentSystemErrorPaymentMethodNotSupported   = "payment method type \"%s\" is not supported by payment system \"%s\""
+	paymentSystemErrorOrderPaymentMethodNotFound  = "payment method of order not found"
+	paymentSystemErrorProviderOperationNotAllowed = "operation is not supported by payment system"
+)
+
+// Capabilities of payment system provider. Operations not declared in capabilities must not be requested
+// from handler of provider
+type Capabilities struct {
+	CreatePayment bool
+	Refund        bool
+	Recurring     bool
+	Payout        bool
+	// types of payment methods which can be processed by provider (model.PaymentMethodType* constants)
+	PaymentMethodTypes []string
+}
+
+// Provider is handler of payment system registered in registry of providers. Handler created for each
+// processed order
+type Provider interface {
+	PaymentSystem
+	// Create refund of order payment in payment system
+	CreateRefund(*Refund) *RefundResponse
+	// Request actual status of order payment from payment system and move order to this status
+	GetPaymentStatus() *PaymentResponse
+}
+
+// ProviderConfig is typed settings of provider decoded from payment systems configuration
+type ProviderConfig interface {
+	// Check that settings contain all data required by provider
+	Validate() error
+}
+
+// CallbackVerifier is implemented by typed settings of providers which can check signatures of callbacks
+// sent by payment system
+type CallbackVerifier interface {
+	// Check signature of raw body of callback about payment by payment method with external identifier
+	CheckCallbackSignature(externalId, body, signature string) error
+}
+
+// ProviderDefinition describes payment system provider and how to create its handlers
+type ProviderDefinition struct {
+	// unique name of provider, equal to handler of payment methods processed by provider
+	Name         string
+	Capabilities *Capabilities
+	// create empty settings of provider to decode configuration to it
+	NewConfig func() ProviderConfig
+	// create handler of provider for order
+	New func(*model.Order, *Settings) (Provider, error)
+}
+
+// Registry contains validated settings of payment systems providers and creates providers handlers
+type Registry struct {
+	configs map[string]ProviderConfig
+	pss     *PaymentSystemSetting
+}
+
+type Refund struct {
+	// unique identifier of refund
+	Id       string
+	Amount   float64
+	Currency string
+	Reason   string
+}
+
+type RefundResponse struct {
+	Status int    `json:"-"`
+	Error  string `json:"error,omitempty"`
+	// identifier of refund in payment system
+	ExternalId string `json:"external_id,omitempty"`
+}
+
+var providers = map[string]*ProviderDefinition{}
+
+// Register payment system provider. Providers registered from init functions of theirs files, so
+// incomplete or duplicated definition is a programming error
+func RegisterProvider(def *ProviderDefinition) {
+	if def.Name == "" || def.Capabilities == nil || def.NewConfig == nil || def.New == nil {
+		panic("payment system provider definition is incomplete")
+	}
+
+	if _, ok := providers[def.Name]; ok {
+		panic(fmt.Sprintf("payment system provider \"%s\" registered twice", def.Name))
+	}
+
+	providers[def.Name] = def
+}
+
+// Create registry of providers by settings of payment systems from yaml file. If path is empty then
+// registry without configured providers returned
+func LoadRegistry(path string, pss *PaymentSystemSetting) (*Registry, error) {
+	config := make(map[string]interface{})
+
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			return nil, fmt.Errorf(paymentSystemErrorProviderConfigReadFailed, path, err)
+		}
+
+		if err = yaml.Unmarshal(b, &config); err != nil {
+			return nil, fmt.Errorf(paymentSystemErrorProviderConfigReadFailed, path, err)
+		}
+	}
+
+	return NewRegistry(config, pss)
+}
+
+// Create registry of providers by settings of payment systems. Settings of each payment system decoded
+// to typed settings of provider and validated, so error returned for unknown payment system, unknown
+// settings field or missed required settings field
+func NewRegistry(config map[string]interface{}, pss *PaymentSystemSetting) (*Registry, error) {
+	r := &Registry{configs: make(map[string]ProviderConfig), pss: pss}
+
+	names := make([]string, 0, len(config))
+
+	for name := range config {
+		names = append(names, name)
+	}
+
+	// errors reported in the same order on each start
+	sort.Strings(names)
+
+	for _, name := range names {
+		def, ok := providers[name]
+
+		if !ok {
+			return nil, fmt.Errorf(paymentSystemErrorProviderNotRegistered, name)
+		}
+
+		b, err := yaml.Marshal(config[name])
+
+		if err != nil {
+			return nil, fmt.Errorf(paymentSystemErrorProviderSettingsInvalid, name, err)
+		}
+
+		cfg := def.NewConfig()
+
+		if err = yaml.UnmarshalStrict(b, cfg); err != nil {
+			return nil, fmt.Errorf(paymentSystemErrorProviderSettingsInvalid, name, err)
+		}
+
+		if err = cfg.Validate(); err != nil {
+			return nil, fmt.Errorf(paymentSystemErrorProviderSettingsInvalid, name, err)
+		}
+
+		r.configs[name] = cfg
+	}
+
+	return r, nil
+}
+
+// Get capabilities of configured payment system provider
+func (r *Registry) GetCapabilities(name string) (*Capabilities, error) {
+	if _, ok := r.configs[name]; !ok {
+		return nil, errors.New(paymentSystemErrorSettingsNotFound)
+	}
+
+	return providers[name].Capabilities, nil
+}
+
+// Check that payment method can be processed by configured provider
+func (r *Registry) CheckPaymentMethod(pm *model.PaymentMethod) error {
+	if pm.Params == nil {
+		return errors.New(paymentSystemErrorHandlerNotFound)
+	}
+
+	c, err := r.GetCapabilities(pm.Params.Handler)
+
+	if err != nil {
+		return err
+	}
+
+	if !c.CreatePayment {
+		return errors.New(paymentSystemErrorProviderOperationNotAllowed)
+	}
+
+	if !c.SupportsPaymentMethodType(pm.Type) {
+		return fmt.Errorf(paymentSystemErrorPaymentMethodNotSupported, pm.Type, pm.Params.Handler)
+	}
+
+	return nil
+}
+
+// Check that payment system configured and signatures of its callbacks can be checked
+func (r *Registry) CanCheckCallbackSignature(name string) bool {
+	_, ok := r.configs[name].(CallbackVerifier)
+	return ok
+}
+
+// Check signature of callback sent by payment system about payment by payment method with external identifier
+func (r *Registry) CheckCallbackSignature(name, externalId, body, signature string) error {
+	v, ok := r.configs[name].(CallbackVerifier)
+
+	if !ok {
+		return errors.New(paymentSystemErrorSettingsNotFound)
+	}
+
+	return v.CheckCallbackSignature(externalId, body, signature)
+}
+
+// Create handler of provider which processes payment method of order
+func (r *Registry) GetProvider(o *model.Order) (Provider, error) {
+	if o.PaymentMethod == nil || o.PaymentMethod.Params == nil {
+		return nil, errors.New(paymentSystemErrorOrderPaymentMethodNotFound)
+	}
+
+	def, ok := providers[o.PaymentMethod.Params.Handler]
+
+	if !ok {
+		return nil, errors.New(paymentSystemErrorHandlerNotFound)
+	}
+
+	cfg, ok := r.configs[def.Name]
+
+	if !ok {
+		return nil, errors.New(paymentSystemErrorSettingsNotFound)
+	}
+
+	return def.New(o, &Settings{Config: cfg, PaymentSystemSetting: r.pss})
+}
+
+func (c *Capabilities) SupportsPaymentMethodType(t string) bool {
+	for _, v := range c.PaymentMethodTypes {
+		if v == t {
+			return true
+		}
+	}
+
+	return false
+}
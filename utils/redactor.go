@@ -0,0 +1,356 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Replace all digits of card number except last four
+	RedactionActionPan = "pan"
+	// Remove field from output
+	RedactionActionRemove = "remove"
+	// Replace value by sha256 hash, so equal values can be matched in logs without disclosure
+	RedactionActionHash = "hash"
+	// Replace value by fixed mask
+	RedactionActionMask = "mask"
+
+	redactionMask           = "***"
+	redactionHashPrefix     = "sha256:"
+	redactionPanVisibleTail = 4
+	redactionPathSeparator  = "."
+	redactionRulesSeparator = ","
+	redactionRuleSeparator  = ":"
+
+	mimeApplicationJson = "application/json"
+	mimeApplicationForm = "application/x-www-form-urlencoded"
+)
+
+var (
+	// Candidates to card numbers in values which not matched by rules
+	redactionPanRegex = regexp.MustCompile(`\b\d{13,19}\b`)
+
+	// Rules of fields which can't be written to logs. Rules applied to json bodies, form bodies and headers
+	DefaultRedactionRules = []*RedactionRule{
+		{Path: "pan", Action: RedactionActionPan},
+		{Path: "card_number", Action: RedactionActionPan},
+		{Path: "cvv", Action: RedactionActionRemove},
+		{Path: "cvc", Action: RedactionActionRemove},
+		{Path: "security_code", Action: RedactionActionRemove},
+		{Path: "expiration", Action: RedactionActionRemove},
+		{Path: "month", Action: RedactionActionRemove},
+		{Path: "year", Action: RedactionActionRemove},
+		{Path: "email", Action: RedactionActionHash},
+		{Path: "PP_PAYER_EMAIL", Action: RedactionActionHash},
+		{Path: "phone", Action: RedactionActionHash},
+		{Path: "PP_PAYER_PHONE", Action: RedactionActionHash},
+		{Path: "holder", Action: RedactionActionHash},
+		{Path: "card_holder", Action: RedactionActionHash},
+		{Path: "password", Action: RedactionActionMask},
+		{Path: "secret_key", Action: RedactionActionMask},
+		{Path: "access_token", Action: RedactionActionMask},
+		{Path: "refresh_token", Action: RedactionActionMask},
+		{Path: "token", Action: RedactionActionMask},
+		{Path: "Authorization", Action: RedactionActionMask},
+		{Path: "Cookie", Action: RedactionActionMask},
+		{Path: "Set-Cookie", Action: RedactionActionMask},
+	}
+
+	DefaultRedactor = NewRedactor(DefaultRedactionRules)
+)
+
+// RedactionRule describe action with field which path ends with rule path. Path is a field names
+// separated by dot, for example "customer.email". Array items has path of array field.
+// Rule path without dots match field with this name on any depth. Names compared without case
+type RedactionRule struct {
+	Path   string
+	Action string
+}
+
+// Redactor remove or mask sensitive data in http bodies and headers before logging
+type Redactor struct {
+	rules []*redactionRule
+}
+
+type redactionRule struct {
+	path   []string
+	action string
+}
+
+func NewRedactor(rules []*RedactionRule) *Redactor {
+	r := &Redactor{}
+
+	for _, rule := range rules {
+		r.rules = append(r.rules, &redactionRule{
+			path:   strings.Split(strings.ToLower(rule.Path), redactionPathSeparator),
+			action: rule.Action,
+		})
+	}
+
+	return r
+}
+
+// Parse rules from string in format "path:action,path:action"
+func ParseRedactionRules(s string) ([]*RedactionRule, error) {
+	var rules []*RedactionRule
+
+	for _, item := range strings.Split(s, redactionRulesSeparator) {
+		item = strings.TrimSpace(item)
+
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, redactionRuleSeparator)
+
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("redaction rule \"%s\" is invalid", item)
+		}
+
+		switch parts[1] {
+		case RedactionActionPan, RedactionActionRemove, RedactionActionHash, RedactionActionMask:
+			rules = append(rules, &RedactionRule{Path: parts[0], Action: parts[1]})
+		default:
+			return nil, fmt.Errorf("redaction rule \"%s\" has unknown action", item)
+		}
+	}
+
+	return rules, nil
+}
+
+// Redact body by its content type. Json and form bodies redacted by rules, card numbers
+// in other bodies will be masked
+func (r *Redactor) RedactBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	trimmed := bytes.TrimSpace(body)
+
+	if mediaType == mimeApplicationJson || (mediaType == "" && len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')) {
+		if redacted, err := r.RedactJson(body); err == nil {
+			return string(redacted)
+		}
+	}
+
+	if mediaType == mimeApplicationForm {
+		if redacted, err := r.RedactForm(body); err == nil {
+			return redacted
+		}
+	}
+
+	return redactionPanRegex.ReplaceAllStringFunc(string(body), redactPanMatch)
+}
+
+func (r *Redactor) RedactJson(body []byte) ([]byte, error) {
+	var data interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, errors.New("json body contains more than one value")
+	}
+
+	return json.Marshal(r.redactValue(nil, data))
+}
+
+func (r *Redactor) RedactForm(body []byte) (string, error) {
+	values, err := url.ParseQuery(string(body))
+
+	if err != nil {
+		return "", err
+	}
+
+	return r.RedactValues(values).Encode(), nil
+}
+
+// Redact url values like query string or form parameters. Returns copy of values
+func (r *Redactor) RedactValues(values url.Values) url.Values {
+	redacted := make(url.Values, len(values))
+
+	for key, items := range values {
+		action := r.match([]string{key})
+
+		if action == RedactionActionRemove {
+			continue
+		}
+
+		for _, item := range items {
+			if action != "" {
+				item = redactString(action, item)
+			} else {
+				item = redactionPanRegex.ReplaceAllStringFunc(item, redactPanMatch)
+			}
+
+			redacted[key] = append(redacted[key], item)
+		}
+	}
+
+	return redacted
+}
+
+// Redact http headers. Returns copy of headers
+func (r *Redactor) RedactHeaders(headers map[string][]string) map[string][]string {
+	return r.RedactValues(headers)
+}
+
+func (r *Redactor) redactValue(path []string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			itemPath := append(path[:len(path):len(path)], key)
+			action := r.match(itemPath)
+
+			if action == RedactionActionRemove {
+				delete(v, key)
+				continue
+			}
+
+			if action != "" {
+				v[key] = redactTree(action, item)
+				continue
+			}
+
+			v[key] = r.redactValue(itemPath, item)
+		}
+
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.redactValue(path, item)
+		}
+
+		return v
+	case string:
+		return redactionPanRegex.ReplaceAllStringFunc(v, redactPanMatch)
+	default:
+		return v
+	}
+}
+
+// Get action of first rule which path matches end of field path
+func (r *Redactor) match(path []string) string {
+	for _, rule := range r.rules {
+		if len(rule.path) > len(path) {
+			continue
+		}
+
+		tail := path[len(path)-len(rule.path):]
+		matched := true
+
+		for i, name := range rule.path {
+			if strings.ToLower(tail[i]) != name {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return rule.action
+		}
+	}
+
+	return ""
+}
+
+// Apply action to all scalar values of json subtree
+func redactTree(action string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = redactTree(action, item)
+		}
+
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactTree(action, item)
+		}
+
+		return v
+	case nil, bool:
+		return v
+	default:
+		return redactString(action, fmt.Sprintf("%v", v))
+	}
+}
+
+func redactString(action, value string) string {
+	switch action {
+	case RedactionActionPan:
+		return redactPanString(value)
+	case RedactionActionHash:
+		if value == "" {
+			return ""
+		}
+
+		hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(value))))
+
+		return redactionHashPrefix + hex.EncodeToString(hash[:])
+	default:
+		return redactionMask
+	}
+}
+
+// Mask digits sequence only if it is a valid card number, because other long numbers like timestamps
+// must stay readable
+func redactPanMatch(value string) string {
+	if !isLuhnValid(value) {
+		return value
+	}
+
+	return redactPanString(value)
+}
+
+func isLuhnValid(digits string) bool {
+	sum := 0
+	double := false
+
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+
+		if double {
+			d *= 2
+
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// Keep only last four digits of card number
+func redactPanString(value string) string {
+	var digits []rune
+
+	for _, c := range value {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, c)
+		}
+	}
+
+	if len(digits) <= redactionPanVisibleTail {
+		return strings.Repeat("*", len(digits))
+	}
+
+	return strings.Repeat("*", len(digits)-redactionPanVisibleTail) + string(digits[len(digits)-redactionPanVisibleTail:])
+}
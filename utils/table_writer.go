@@ -0,0 +1,298 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	TableFormatCsv   = "csv"
+	TableFormatXlsx  = "xlsx"
+	TableFormatJsonl = "jsonl"
+
+	xlsxSheetPath = "xl/worksheets/sheet1.xml"
+)
+
+var (
+	TableFormatContentTypes = map[string]string{
+		TableFormatCsv:   "text/csv; charset=utf-8",
+		TableFormatXlsx:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		TableFormatJsonl: "application/x-ndjson",
+	}
+
+	// Minimal set of parts of workbook with single sheet, sheet part written by rows after them
+	xlsxStaticParts = [][]string{
+		{
+			"[Content_Types].xml",
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+				`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+				`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+				`<Default Extension="xml" ContentType="application/xml"/>` +
+				`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+				`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+				`</Types>`,
+		},
+		{
+			"_rels/.rels",
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+				`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+				`</Relationships>`,
+		},
+		{
+			"xl/workbook.xml",
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+				`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+				`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+				`</workbook>`,
+		},
+		{
+			"xl/_rels/workbook.xml.rels",
+			`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+				`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+				`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+				`</Relationships>`,
+		},
+	}
+)
+
+// TableWriter write rows of table to output one by one, so table of any size can be streamed
+// without keeping it in memory. Values of row must be strings, numbers, booleans or nil
+type TableWriter interface {
+	// Write row with values in order of table columns
+	Write(row []interface{}) error
+	// Flush buffered data and write end of table. Writer can't be used after close
+	Close() error
+}
+
+type csvTableWriter struct {
+	w *csv.Writer
+}
+
+type jsonlTableWriter struct {
+	w       *bufio.Writer
+	columns [][]byte
+}
+
+type xlsxTableWriter struct {
+	zw *zip.Writer
+	w  *bufio.Writer
+}
+
+// Create writer of table in specified format and write header with column names
+func NewTableWriter(format string, w io.Writer, columns []string) (TableWriter, error) {
+	switch format {
+	case TableFormatCsv:
+		return newCsvTableWriter(w, columns)
+	case TableFormatJsonl:
+		return newJsonlTableWriter(w, columns)
+	case TableFormatXlsx:
+		return newXlsxTableWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("table format \"%s\" is not supported", format)
+	}
+}
+
+func newCsvTableWriter(w io.Writer, columns []string) (TableWriter, error) {
+	tw := &csvTableWriter{w: csv.NewWriter(w)}
+
+	if err := tw.w.Write(columns); err != nil {
+		return nil, err
+	}
+
+	return tw, nil
+}
+
+func (tw *csvTableWriter) Write(row []interface{}) error {
+	record := make([]string, len(row))
+
+	for i, v := range row {
+		record[i] = escapeCsvFormula(formatTableValue(v))
+	}
+
+	return tw.w.Write(record)
+}
+
+func (tw *csvTableWriter) Close() error {
+	tw.w.Flush()
+	return tw.w.Error()
+}
+
+func newJsonlTableWriter(w io.Writer, columns []string) (TableWriter, error) {
+	tw := &jsonlTableWriter{w: bufio.NewWriter(w)}
+
+	for _, column := range columns {
+		name, err := json.Marshal(column)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tw.columns = append(tw.columns, name)
+	}
+
+	return tw, nil
+}
+
+// Fields of object written manually to keep order of columns
+func (tw *jsonlTableWriter) Write(row []interface{}) error {
+	buf := bytes.NewBufferString("{")
+	value := new(bytes.Buffer)
+	encoder := json.NewEncoder(value)
+	encoder.SetEscapeHTML(false)
+
+	for i, v := range row {
+		value.Reset()
+
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.Write(tw.columns[i])
+		buf.WriteByte(':')
+		buf.Write(bytes.TrimSuffix(value.Bytes(), []byte("\n")))
+	}
+
+	buf.WriteString("}\n")
+	_, err := tw.w.Write(buf.Bytes())
+
+	return err
+}
+
+func (tw *jsonlTableWriter) Close() error {
+	return tw.w.Flush()
+}
+
+func newXlsxTableWriter(w io.Writer, columns []string) (TableWriter, error) {
+	tw := &xlsxTableWriter{zw: zip.NewWriter(w)}
+
+	for _, part := range xlsxStaticParts {
+		pw, err := tw.zw.Create(part[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err = io.WriteString(pw, part[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	sw, err := tw.zw.Create(xlsxSheetPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tw.w = bufio.NewWriter(sw)
+	_, err = tw.w.WriteString(
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]interface{}, len(columns))
+
+	for i, column := range columns {
+		header[i] = column
+	}
+
+	if err = tw.Write(header); err != nil {
+		return nil, err
+	}
+
+	return tw, nil
+}
+
+// Strings written as inline strings, so shared strings table not needed
+func (tw *xlsxTableWriter) Write(row []interface{}) error {
+	buf := bytes.NewBufferString("<row>")
+
+	for _, v := range row {
+		switch v.(type) {
+		case nil:
+			buf.WriteString("<c/>")
+		case int, int32, int64, float32, float64:
+			buf.WriteString(`<c t="n"><v>` + formatTableValue(v) + `</v></c>`)
+		case bool:
+			buf.WriteString(`<c t="b"><v>`)
+
+			if v.(bool) {
+				buf.WriteByte('1')
+			} else {
+				buf.WriteByte('0')
+			}
+
+			buf.WriteString(`</v></c>`)
+		default:
+			buf.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+
+			if err := xml.EscapeText(buf, []byte(formatTableValue(v))); err != nil {
+				return err
+			}
+
+			buf.WriteString(`</t></is></c>`)
+		}
+	}
+
+	buf.WriteString("</row>")
+	_, err := tw.w.Write(buf.Bytes())
+
+	return err
+}
+
+func (tw *xlsxTableWriter) Close() error {
+	if _, err := tw.w.WriteString("</sheetData></worksheet>"); err != nil {
+		return err
+	}
+
+	if err := tw.w.Flush(); err != nil {
+		return err
+	}
+
+	return tw.zw.Close()
+}
+
+func formatTableValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// Values which starts with formula symbols prefixed by quote, so spreadsheet applications
+// which open csv file will not execute them as formulas
+func escapeCsvFormula(value string) string {
+	if value == "" || !strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return value
+	}
+
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+
+	return "'" + value
+}